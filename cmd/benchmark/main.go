@@ -0,0 +1,160 @@
+// Command benchmark compares metric retrieval across vector databases and
+// embedding models. Every backend and model combination indexes the same
+// catalog into a fresh collection and answers the same labelled queries;
+// the remaining settings come from the same PRAG_* environment variables as
+// the server. With -documents, the metric document builders are compared for
+// every model instead, without a vector database.
+//
+// Usage:
+//
+//	benchmark -catalog hack/metrics.txt -queries hack/benchmark.yaml
+//	benchmark -backends sqlite3,qdrant -models sentence-transformers/LaBSE,sentence-transformers/all-MiniLM-L6-v2 -k 10
+//	benchmark -documents plain,tokenized,full -k 5
+package main
+
+import (
+	"context"
+	"flag"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+
+	"github.com/rs/zerolog"
+
+	"github.com/machadovilaca/prometheus-rag/pkg/config"
+	"github.com/machadovilaca/prometheus-rag/pkg/embeddings"
+	"github.com/machadovilaca/prometheus-rag/pkg/eval"
+	"github.com/machadovilaca/prometheus-rag/pkg/prometheus"
+)
+
+func main() {
+	catalogPath := flag.String("catalog", "hack/metrics.txt", "text exposition file with the metrics to index")
+	queriesPath := flag.String("queries", "hack/benchmark.yaml", "YAML or JSON file of labelled queries")
+	backends := flag.String("backends", "", "comma-separated vector databases, PRAG_VECTORDB_PROVIDER if empty")
+	models := flag.String("models", "", "comma-separated embedding models, PRAG_VECTORDB_ENCODER_MODEL if empty")
+	k := flag.Int("k", 5, "number of metrics retrieved per query")
+	cache := flag.Bool("cache", false, "use the embedding cache, which hides the encoding cost of repeated runs")
+	collection := flag.String("collection", "prag-benchmark", "vector database collection, recreated for every combination")
+	documents := flag.String("documents", "", "comma-separated document builders compared for every model instead of benchmarking the backends")
+	format := flag.String("format", eval.FormatMarkdown, "report format: markdown or json")
+	output := flag.String("output", "", "file the report is written to, stdout if empty")
+	flag.Parse()
+
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("failed to load configuration: %v", err)
+	}
+
+	zerolog.SetGlobalLevel(zerolog.WarnLevel)
+	if cfg.Debug {
+		zerolog.SetGlobalLevel(zerolog.DebugLevel)
+	}
+
+	metrics, err := eval.LoadCatalog(*catalogPath)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	cases, err := eval.LoadQueries(*queriesPath)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// SQLite3 collections live in a scratch database so the configured one is left untouched
+	scratch, err := os.MkdirTemp("", "prag-benchmark-")
+	if err != nil {
+		log.Fatalf("failed to create scratch directory: %v", err)
+	}
+	defer func() {
+		_ = os.RemoveAll(scratch)
+	}()
+
+	// Interrupting the run stops the remaining targets, their collections are still deleted
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	var write func(w io.Writer) error
+	if *documents != "" {
+		var comparisons []*eval.DocumentComparison
+		for _, model := range splitList(*models, cfg.VectorDB.EncoderModel) {
+			encoderConfig := cfg.ToVectorDBConfig()
+			encoderConfig.EncoderModelName = model
+			if !*cache {
+				encoderConfig.EncoderCachePath = ""
+			}
+
+			log.Printf("comparing documents of %s", model)
+			comparisons = append(comparisons, eval.CompareDocuments(ctx, encoderConfig, splitList(*documents, embeddings.DocumentPlain), metrics, cases, *k))
+		}
+
+		write = func(w io.Writer) error {
+			return eval.WriteDocumentComparison(w, comparisons, *format)
+		}
+	} else {
+		results := benchmarkTargets(ctx, cfg, *backends, *models, *collection, scratch, *cache, metrics, cases, *k)
+
+		write = func(w io.Writer) error {
+			return eval.WriteBenchmark(w, results, *format)
+		}
+	}
+
+	var w io.Writer = os.Stdout
+	if *output != "" {
+		file, err := os.Create(*output)
+		if err != nil {
+			log.Fatalf("failed to create report file: %v", err)
+		}
+		defer func() {
+			_ = file.Close()
+		}()
+		w = file
+	}
+
+	if err := write(w); err != nil {
+		log.Fatalf("failed to write report: %v", err)
+	}
+}
+
+// benchmarkTargets benchmarks every backend and model combination
+func benchmarkTargets(
+	ctx context.Context, cfg *config.Config, backends, models, collection, scratch string, cache bool,
+	metrics []*prometheus.MetricMetadata, cases []embeddings.EvalCase, k int,
+) []*eval.BenchmarkResult {
+	var results []*eval.BenchmarkResult
+	for _, backend := range splitList(backends, cfg.VectorDB.Provider) {
+		for _, model := range splitList(models, cfg.VectorDB.EncoderModel) {
+			target := eval.Target{Name: backend + "/" + model, Config: cfg.ToVectorDBConfig()}
+			target.Config.Provider = backend
+			target.Config.EncoderModelName = model
+			target.Config.CollectionName = collection
+			target.Config.Sqlite3DBPath = filepath.Join(scratch, "benchmark.db")
+			if !cache {
+				target.Config.EncoderCachePath = ""
+			}
+
+			log.Printf("benchmarking %s", target.Name)
+			results = append(results, eval.Benchmark(ctx, target, metrics, cases, k))
+		}
+	}
+
+	return results
+}
+
+// splitList splits a comma-separated flag, falling back to the configured value
+func splitList(list, fallback string) []string {
+	var values []string
+	for _, value := range strings.Split(list, ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+
+	if len(values) == 0 {
+		return []string{fallback}
+	}
+	return values
+}
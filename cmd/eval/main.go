@@ -0,0 +1,123 @@
+// Command eval measures the accuracy of the natural language to PromQL
+// pipeline on a dataset, using the vector database and encoder configured
+// through the same PRAG_* environment variables as the server.
+//
+// Usage:
+//
+//	eval -dataset hack/eval.yaml -llm replay -recordings recordings.yaml
+//	eval -dataset hack/eval.yaml -llm record -recordings recordings.yaml
+//	eval -dataset hack/eval.yaml -llm reference -format json -output report.json
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/rs/zerolog"
+
+	"github.com/machadovilaca/prometheus-rag/pkg/config"
+	"github.com/machadovilaca/prometheus-rag/pkg/eval"
+)
+
+func main() {
+	datasetPath := flag.String("dataset", "hack/eval.yaml", "YAML or JSON dataset of questions, metrics and series")
+	llmMode := flag.String("llm", eval.LLMModeReplay, "how questions are answered: live, record, replay or reference")
+	recordingsPath := flag.String("recordings", "", "file of recorded LLM answers, read in replay mode and written in record mode")
+	k := flag.Int("k", 0, "number of retrieved metrics recall is measured on, 0 for all the metrics in the prompt")
+	format := flag.String("format", eval.FormatMarkdown, "report format: markdown or json")
+	output := flag.String("output", "", "file the report is written to, stdout if empty")
+	collection := flag.String("collection", "prag-eval", "prefix of the vector database collections, recreated on every run")
+	flag.Parse()
+
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("failed to load configuration: %v", err)
+	}
+
+	zerolog.SetGlobalLevel(zerolog.WarnLevel)
+	if cfg.Debug {
+		zerolog.SetGlobalLevel(zerolog.DebugLevel)
+	}
+
+	dataset, err := eval.LoadDataset(*datasetPath)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	recordings, err := loadRecordings(*llmMode, *recordingsPath)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	cfg.VectorDB.Collection = *collection + "-metrics"
+	cfg.Examples.Collection = *collection + "-examples"
+
+	// Interrupting the run stops the remaining cases
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	report, err := eval.Run(ctx, *cfg, dataset, eval.Options{
+		LLMMode:          *llmMode,
+		Recordings:       recordings,
+		K:                *k,
+		ResetCollections: true,
+	})
+	if err != nil {
+		log.Fatalf("evaluation failed: %v", err)
+	}
+
+	if *llmMode == eval.LLMModeRecord {
+		if err := recordings.Save(*recordingsPath); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	var w io.Writer = os.Stdout
+	if *output != "" {
+		file, err := os.Create(*output)
+		if err != nil {
+			log.Fatalf("failed to create report file: %v", err)
+		}
+		defer func() {
+			_ = file.Close()
+		}()
+		w = file
+	}
+
+	if err := report.Write(w, *format); err != nil {
+		log.Fatalf("failed to write report: %v", err)
+	}
+}
+
+// loadRecordings loads the recordings replayed or extended by the LLM mode
+func loadRecordings(mode, filePath string) (*eval.Recordings, error) {
+	switch mode {
+	case eval.LLMModeReplay:
+		if filePath == "" {
+			return nil, errors.New("-recordings is required in replay mode")
+		}
+		return eval.LoadRecordings(filePath)
+	case eval.LLMModeRecord:
+		if filePath == "" {
+			return nil, errors.New("-recordings is required in record mode")
+		}
+
+		recordings, err := eval.LoadRecordings(filePath)
+		if errors.Is(err, os.ErrNotExist) {
+			return &eval.Recordings{Responses: make(map[string]string)}, nil
+		}
+		return recordings, err
+	default:
+		if filePath != "" {
+			return nil, fmt.Errorf("-recordings is only used in %s and %s modes", eval.LLMModeReplay, eval.LLMModeRecord)
+		}
+		return nil, nil
+	}
+}
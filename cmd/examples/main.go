@@ -0,0 +1,128 @@
+// Command examples manages the few-shot example library in the vector database
+// configured through the same PRAG_* environment variables as the server.
+//
+// Usage:
+//
+//	examples list
+//	examples add -question "..." -promql "..."
+//	examples import examples.yaml
+//	examples delete -id <id>
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/rs/zerolog"
+
+	"github.com/machadovilaca/prometheus-rag/pkg/config"
+	"github.com/machadovilaca/prometheus-rag/pkg/examples"
+	"github.com/machadovilaca/prometheus-rag/pkg/vectordb"
+)
+
+const usage = `usage: examples <command> [flags]
+
+commands:
+  list                                  print all examples as JSON
+  add -question <text> -promql <expr>   add or replace the example for a question
+  import <file>                         add the examples of a YAML file
+  delete -id <id>                       delete an example`
+
+func main() {
+	if len(os.Args) < 2 {
+		log.Fatal(usage)
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("failed to load configuration: %v", err)
+	}
+
+	zerolog.SetGlobalLevel(zerolog.WarnLevel)
+	if cfg.Debug {
+		zerolog.SetGlobalLevel(zerolog.DebugLevel)
+	}
+
+	vectordbConfig := cfg.ToVectorDBConfig()
+	encoder, err := vectordb.NewEncoder(vectordbConfig)
+	if err != nil {
+		log.Fatalf("failed to create encoder: %v", err)
+	}
+
+	store, err := vectordb.NewExampleStore(vectordbConfig, encoder)
+	if err != nil {
+		log.Fatalf("failed to open examples collection: %v", err)
+	}
+	defer func() {
+		_ = store.Close()
+	}()
+
+	if err := run(context.Background(), store, os.Args[1], os.Args[2:]); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func run(ctx context.Context, store vectordb.ExampleStore, command string, args []string) error {
+	flags := flag.NewFlagSet(command, flag.ExitOnError)
+
+	switch command {
+	case "list":
+		list, err := store.ListExamples(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to list examples: %w", err)
+		}
+
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(list)
+	case "add":
+		question := flags.String("question", "", "natural language question")
+		promql := flags.String("promql", "", "PromQL expression answering the question")
+		_ = flags.Parse(args)
+
+		example := &examples.Example{Question: *question, PromQL: *promql}
+		if err := store.AddExamples(ctx, []*examples.Example{example}); err != nil {
+			return fmt.Errorf("failed to add example: %w", err)
+		}
+
+		fmt.Printf("added example %s\n", example.ID)
+		return nil
+	case "import":
+		_ = flags.Parse(args)
+		if flags.NArg() != 1 {
+			return fmt.Errorf("import requires a file\n%s", usage)
+		}
+
+		entries, err := examples.LoadFile(flags.Arg(0))
+		if err != nil {
+			return err
+		}
+
+		if err := store.AddExamples(ctx, entries); err != nil {
+			return fmt.Errorf("failed to add examples: %w", err)
+		}
+
+		fmt.Printf("imported %d examples\n", len(entries))
+		return nil
+	case "delete":
+		id := flags.String("id", "", "ID of the example")
+		_ = flags.Parse(args)
+
+		deleted, err := store.DeleteExample(ctx, *id)
+		if err != nil {
+			return fmt.Errorf("failed to delete example: %w", err)
+		}
+		if !deleted {
+			return fmt.Errorf("example %s not found", *id)
+		}
+
+		fmt.Printf("deleted example %s\n", *id)
+		return nil
+	default:
+		return fmt.Errorf("unknown command '%s'\n%s", command, usage)
+	}
+}
@@ -1,17 +1,31 @@
 package main
 
 import (
+	"context"
+	"fmt"
 	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/machadovilaca/prometheus-rag/pkg/config"
 	"github.com/machadovilaca/prometheus-rag/pkg/server"
+	"github.com/machadovilaca/prometheus-rag/pkg/telemetry"
 	"github.com/rs/zerolog"
 )
 
 func main() {
+	if err := run(); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// run serves the API until SIGINT or SIGTERM, then shuts down gracefully
+func run() error {
 	cfg, err := config.Load()
 	if err != nil {
-		log.Fatalf("failed to load configuration: %v", err)
+		return fmt.Errorf("failed to load configuration: %v", err)
 	}
 
 	zerolog.SetGlobalLevel(zerolog.InfoLevel)
@@ -19,13 +33,45 @@ func main() {
 		zerolog.SetGlobalLevel(zerolog.DebugLevel)
 	}
 
+	if cfg.Tracing.Enabled {
+		shutdownTracing, err := telemetry.SetupTracing(context.Background(), cfg.ToTracingConfig())
+		if err != nil {
+			return fmt.Errorf("failed to set up tracing: %v", err)
+		}
+		defer func() {
+			// Flushes the spans of the requests drained on shutdown
+			_ = shutdownTracing(context.Background())
+		}()
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
 	server, err := server.New(cfg)
 	if err != nil {
-		log.Fatalf("failed to create server: %v", err)
+		return fmt.Errorf("failed to create server: %v", err)
 	}
 
-	err = server.Start()
-	if err != nil {
-		log.Fatalf("failed to start server: %v", err)
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- server.Start()
+	}()
+
+	select {
+	case err := <-serveErr:
+		_ = server.Shutdown(context.Background())
+		return fmt.Errorf("failed to start server: %v", err)
+	case <-ctx.Done():
+	}
+
+	// A second signal kills the process instead of waiting for the shutdown
+	stop()
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), time.Duration(cfg.Server.ShutdownTimeoutSeconds)*time.Second)
+	defer cancel()
+
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		return fmt.Errorf("failed to shut down server: %v", err)
 	}
+	return nil
 }
@@ -0,0 +1,237 @@
+// Package annotations provides a store of team-curated metric annotations,
+// such as synonyms, descriptions, example queries and deprecation notes,
+// attached to metric names or name patterns.
+package annotations
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/machadovilaca/prometheus-rag/pkg/prometheus"
+)
+
+// Annotation attaches annotations to the metrics matching a name or name pattern
+type Annotation struct {
+	// Match is a metric name or a glob pattern such as kubevirt_vmi_memory_*
+	Match string `json:"match" yaml:"match"`
+
+	prometheus.Annotations `yaml:",inline"`
+}
+
+// Validate validates the annotation
+func (a *Annotation) Validate() error {
+	if a.Match == "" {
+		return errors.New("match is required")
+	}
+
+	if _, err := path.Match(a.Match, ""); err != nil {
+		return fmt.Errorf("invalid match pattern '%s': %w", a.Match, err)
+	}
+
+	if a.IsEmpty() {
+		return errors.New("at least one of synonyms, description, examples or deprecated is required")
+	}
+
+	return nil
+}
+
+// IsPattern returns true if the annotation matches metric names by pattern instead of by name
+func (a *Annotation) IsPattern() bool {
+	return strings.ContainsAny(a.Match, "*?[")
+}
+
+// Matches returns true if the annotation applies to the given metric name
+func (a *Annotation) Matches(name string) bool {
+	if !a.IsPattern() {
+		return a.Match == name
+	}
+
+	matched, err := path.Match(a.Match, name)
+	return err == nil && matched
+}
+
+// file is the layout of the annotations YAML file
+type file struct {
+	Annotations []Annotation `yaml:"annotations"`
+}
+
+// Store holds the annotations, optionally persisted to a YAML file
+type Store struct {
+	mu          sync.RWMutex
+	path        string
+	annotations []Annotation
+}
+
+// NewStore creates a store persisted to the YAML file at filePath, loading it if
+// it exists. An empty path keeps the annotations in memory only.
+func NewStore(filePath string) (*Store, error) {
+	s := &Store{path: filePath}
+
+	if filePath == "" {
+		return s, nil
+	}
+
+	data, err := os.ReadFile(filePath)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read annotations file: %w", err)
+	}
+
+	var f file
+	if err := yaml.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("failed to parse annotations file: %w", err)
+	}
+
+	for i := range f.Annotations {
+		if err := f.Annotations[i].Validate(); err != nil {
+			return nil, fmt.Errorf("invalid annotation %d: %w", i, err)
+		}
+	}
+
+	s.annotations = f.Annotations
+	return s, nil
+}
+
+// List returns all annotations in the order they are applied
+func (s *Store) List() []Annotation {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	annotations := make([]Annotation, len(s.annotations))
+	copy(annotations, s.annotations)
+	return annotations
+}
+
+// Put adds an annotation, replacing the one with the same match if any
+func (s *Store) Put(annotation Annotation) error {
+	if err := annotation.Validate(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	annotations := make([]Annotation, 0, len(s.annotations)+1)
+	replaced := false
+	for _, existing := range s.annotations {
+		if existing.Match == annotation.Match {
+			annotations = append(annotations, annotation)
+			replaced = true
+			continue
+		}
+		annotations = append(annotations, existing)
+	}
+	if !replaced {
+		annotations = append(annotations, annotation)
+	}
+
+	return s.update(annotations)
+}
+
+// Delete removes the annotation with the given match, returning false if there is none
+func (s *Store) Delete(match string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	annotations := make([]Annotation, 0, len(s.annotations))
+	for _, existing := range s.annotations {
+		if existing.Match != match {
+			annotations = append(annotations, existing)
+		}
+	}
+
+	if len(annotations) == len(s.annotations) {
+		return false, nil
+	}
+
+	return true, s.update(annotations)
+}
+
+// Apply sets the annotations of each metric, merging every matching annotation.
+// Annotations matching the exact name take precedence over patterns, and
+// patterns are applied in the order they were defined. It returns the metrics
+// whose annotations changed.
+func (s *Store) Apply(metrics []*prometheus.MetricMetadata) []*prometheus.MetricMetadata {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var changed []*prometheus.MetricMetadata
+	for _, metric := range metrics {
+		annotations := s.merged(metric.Name)
+		if prometheus.EncodeAnnotations(annotations) != prometheus.EncodeAnnotations(metric.Annotations) {
+			changed = append(changed, metric)
+		}
+		metric.Annotations = annotations
+	}
+
+	return changed
+}
+
+func (s *Store) merged(name string) *prometheus.Annotations {
+	var result *prometheus.Annotations
+
+	apply := func(patterns bool) {
+		for _, annotation := range s.annotations {
+			if annotation.IsPattern() != patterns || !annotation.Matches(name) {
+				continue
+			}
+			if result == nil {
+				result = &prometheus.Annotations{}
+			}
+			result.Merge(annotation.Annotations)
+		}
+	}
+
+	apply(false)
+	apply(true)
+
+	return result
+}
+
+// update replaces the annotations, persisting them first if the store has a file
+func (s *Store) update(annotations []Annotation) error {
+	if s.path != "" {
+		if err := s.save(annotations); err != nil {
+			return fmt.Errorf("failed to save annotations: %w", err)
+		}
+	}
+
+	s.annotations = annotations
+	return nil
+}
+
+// save writes the annotations to a temporary file and renames it over the
+// annotations file, so readers never see a partially written file
+func (s *Store) save(annotations []Annotation) error {
+	data, err := yaml.Marshal(file{Annotations: annotations})
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), ".annotations-*.yaml")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = os.Remove(tmp.Name())
+	}()
+
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), s.path)
+}
@@ -0,0 +1,145 @@
+package annotations_test
+
+import (
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/machadovilaca/prometheus-rag/pkg/annotations"
+	"github.com/machadovilaca/prometheus-rag/pkg/prometheus"
+)
+
+var _ = Describe("Store", func() {
+	var (
+		tempDir string
+		path    string
+	)
+
+	BeforeEach(func() {
+		var err error
+		tempDir, err = os.MkdirTemp("", "annotations_test_*")
+		Expect(err).NotTo(HaveOccurred())
+		path = filepath.Join(tempDir, "annotations.yaml")
+	})
+
+	AfterEach(func() {
+		Expect(os.RemoveAll(tempDir)).To(Succeed())
+	})
+
+	writeFile := func(content string) {
+		Expect(os.WriteFile(path, []byte(content), 0o600)).To(Succeed())
+	}
+
+	It("should start empty when the file does not exist", func() {
+		store, err := annotations.NewStore(path)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(store.List()).To(BeEmpty())
+	})
+
+	It("should load annotations from YAML", func() {
+		writeFile(`
+annotations:
+  - match: kubevirt_vmi_memory_*
+    synonyms: [guest memory]
+    description: Memory as seen by the guest.
+  - match: kubevirt_vmi_phase_count
+    examples: ["sum by (phase) (kubevirt_vmi_phase_count)"]
+    deprecated: Use kubevirt_vmi_info instead.
+`)
+
+		store, err := annotations.NewStore(path)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(store.List()).To(HaveLen(2))
+		Expect(store.List()[0].Synonyms).To(Equal([]string{"guest memory"}))
+		Expect(store.List()[1].Deprecated).To(Equal("Use kubevirt_vmi_info instead."))
+	})
+
+	It("should reject invalid annotations", func() {
+		writeFile(`
+annotations:
+  - match: "kubevirt_[vmi"
+    description: Broken pattern.
+`)
+
+		_, err := annotations.NewStore(path)
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should apply matching annotations, exact names first", func() {
+		store, err := annotations.NewStore("")
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(store.Put(annotations.Annotation{
+			Match:       "kubevirt_vmi_memory_*",
+			Annotations: prometheus.Annotations{Synonyms: []string{"guest memory"}, Description: "Guest memory."},
+		})).To(Succeed())
+		Expect(store.Put(annotations.Annotation{
+			Match:       "kubevirt_vmi_memory_used_bytes",
+			Annotations: prometheus.Annotations{Synonyms: []string{"used memory"}, Description: "Memory used by the guest."},
+		})).To(Succeed())
+
+		metrics := []*prometheus.MetricMetadata{
+			{Name: "kubevirt_vmi_memory_used_bytes"},
+			{Name: "kubevirt_vmi_memory_available_bytes"},
+			{Name: "kubevirt_vmi_phase_count"},
+		}
+		changed := store.Apply(metrics)
+		Expect(changed).To(HaveLen(2))
+
+		Expect(metrics[0].Annotations.Description).To(Equal("Memory used by the guest."))
+		Expect(metrics[0].Annotations.Synonyms).To(Equal([]string{"used memory", "guest memory"}))
+		Expect(metrics[1].Annotations.Description).To(Equal("Guest memory."))
+		Expect(metrics[2].Annotations).To(BeNil())
+
+		Expect(store.Apply(metrics)).To(BeEmpty())
+	})
+
+	It("should persist edits to the file", func() {
+		store, err := annotations.NewStore(path)
+		Expect(err).NotTo(HaveOccurred())
+
+		annotation := annotations.Annotation{
+			Match:       "up",
+			Annotations: prometheus.Annotations{Synonyms: []string{"target health"}},
+		}
+		Expect(store.Put(annotation)).To(Succeed())
+
+		annotation.Synonyms = []string{"scrape health"}
+		Expect(store.Put(annotation)).To(Succeed())
+		Expect(store.Put(annotations.Annotation{
+			Match:       "node_load1",
+			Annotations: prometheus.Annotations{Description: "One minute load average."},
+		})).To(Succeed())
+
+		reloaded, err := annotations.NewStore(path)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(reloaded.List()).To(HaveLen(2))
+		Expect(reloaded.List()[0].Synonyms).To(Equal([]string{"scrape health"}))
+
+		deleted, err := reloaded.Delete("up")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(deleted).To(BeTrue())
+
+		deleted, err = reloaded.Delete("up")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(deleted).To(BeFalse())
+
+		reloaded, err = annotations.NewStore(path)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(reloaded.List()).To(HaveLen(1))
+	})
+
+	It("should require at least one annotation", func() {
+		store, err := annotations.NewStore("")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(store.Put(annotations.Annotation{Match: "up"})).NotTo(Succeed())
+	})
+
+	It("should load the example annotations", func() {
+		store, err := annotations.NewStore("../../hack/annotations.yaml")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(store.List()).NotTo(BeEmpty())
+	})
+})
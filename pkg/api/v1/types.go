@@ -0,0 +1,142 @@
+// Package v1 defines the requests, responses and errors of the version 1 of
+// the HTTP API, served under /api/v1 and described by its OpenAPI document.
+package v1
+
+import (
+	"time"
+
+	"github.com/machadovilaca/prometheus-rag/pkg/annotations"
+	"github.com/machadovilaca/prometheus-rag/pkg/audit"
+	"github.com/machadovilaca/prometheus-rag/pkg/examples"
+	"github.com/machadovilaca/prometheus-rag/pkg/prometheus"
+	"github.com/machadovilaca/prometheus-rag/pkg/quota"
+)
+
+// Prefix is the path prefix of the API
+const Prefix = "/api/v1"
+
+// Error codes, each returned with a single HTTP status
+const (
+	CodeInvalidRequest   = "invalid_request"    // 400
+	CodeUnauthorized     = "unauthorized"       // 401
+	CodeForbidden        = "forbidden"          // 403
+	CodeNotFound         = "not_found"          // 404
+	CodeMethodNotAllowed = "method_not_allowed" // 405
+	CodeRateLimited      = "rate_limited"       // 429
+	CodeQuotaExceeded    = "quota_exceeded"     // 429
+	CodeInternal         = "internal"           // 500
+	CodeDisabled         = "disabled"           // 501
+	CodeTimeout          = "timeout"            // 504
+)
+
+// Error is the body of every error response
+type Error struct {
+	// Code identifies the kind of error, for clients to act upon
+	Code string `json:"code"`
+	// Message describes the error for humans
+	Message string `json:"message"`
+	// Details holds information specific to the code, such as the exceeded quota
+	Details map[string]any `json:"details,omitempty"`
+}
+
+// QueryRequest asks for the PromQL answering a natural language question
+type QueryRequest struct {
+	Query string `json:"query"`
+}
+
+// QueryResponse is the PromQL answering a question
+type QueryResponse struct {
+	// ID identifies the query when giving feedback, empty if feedback is disabled
+	ID     string `json:"id,omitempty"`
+	PromQL string `json:"promql"`
+	// Metrics are the names of the metrics added to the prompt, most relevant first
+	Metrics []string `json:"metrics"`
+	// Cache tells whether the answer came from the response cache, absent if it is disabled
+	Cache   *CacheInfo `json:"cache,omitempty"`
+	TraceID string     `json:"trace_id,omitempty"`
+}
+
+// CacheInfo tells how the response cache answered a query
+type CacheInfo struct {
+	// Status is exact, similar or miss
+	Status string `json:"status"`
+	// Question is the cached question that answered a similar one, only set for the principal that asked it
+	Question   string     `json:"question,omitempty"`
+	Similarity float64    `json:"similarity,omitempty"`
+	CachedAt   *time.Time `json:"cached_at,omitempty"`
+}
+
+// MetricsResponse lists metrics
+type MetricsResponse struct {
+	Metrics []*prometheus.MetricMetadata `json:"metrics"`
+}
+
+// SyncReport summarizes the last synchronization of the metrics
+type SyncReport struct {
+	StartedAt time.Time `json:"started_at"`
+	// DurationSeconds is how long listing the metrics took
+	DurationSeconds float64 `json:"duration_seconds"`
+	// Metrics is the number of metric families listed
+	Metrics int `json:"metrics"`
+	// LabelRequests is the number of requests made to discover label names
+	LabelRequests int `json:"label_requests"`
+	// Failures lists the metrics stored without labels because their labels could not be discovered
+	Failures []prometheus.SyncFailure `json:"failures,omitempty"`
+	// Described is the number of metrics stored with a generated description
+	Described int `json:"described,omitempty"`
+	// DescriptionFailures is the number of metrics whose description could not be generated
+	DescriptionFailures int `json:"description_failures,omitempty"`
+	// Error is set when the synchronization failed after the listing
+	Error string `json:"error,omitempty"`
+}
+
+// AnnotationsResponse lists the metric annotations
+type AnnotationsResponse struct {
+	Annotations []annotations.Annotation `json:"annotations"`
+}
+
+// ExampleRequest adds an example to the library
+type ExampleRequest struct {
+	// ID replaces the example with the same ID, generated from the question when empty
+	ID       string `json:"id,omitempty"`
+	Question string `json:"question"`
+	PromQL   string `json:"promql"`
+	// Source is curated or approved, curated when empty
+	Source string `json:"source,omitempty"`
+}
+
+// ExamplesResponse lists the examples of the library
+type ExamplesResponse struct {
+	Examples []*examples.Example `json:"examples"`
+}
+
+// ApproveRequest approves the PromQL answering a question
+type ApproveRequest struct {
+	Query  string `json:"query"`
+	PromQL string `json:"promql"`
+}
+
+// FeedbackRequest rates the answer to a query
+type FeedbackRequest struct {
+	QueryID string `json:"query_id"`
+	// Rating is up or down
+	Rating string `json:"rating"`
+	// CorrectedPromQL is the query the user expected instead, if any
+	CorrectedPromQL string `json:"corrected_promql,omitempty"`
+}
+
+// CapturedResponse tells whether an answer was added to the example library
+type CapturedResponse struct {
+	Captured bool `json:"captured"`
+}
+
+// AuditResponse lists audit records, newest first
+type AuditResponse struct {
+	Records []*audit.Record `json:"records"`
+}
+
+// QuotaResponse is the LLM token usage of the clients
+type QuotaResponse struct {
+	DefaultLimits quota.Limits  `json:"default_limits"`
+	Usage         []quota.Usage `json:"usage"`
+}
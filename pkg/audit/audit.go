@@ -0,0 +1,182 @@
+// Package audit keeps a structured record of every query answered by the RAG:
+// who asked what, which metrics were retrieved, what the LLM answered and
+// whether the answer was valid.
+package audit
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Outcomes of an audited query
+const (
+	// OutcomeSuccess is a query answered with a valid PromQL expression
+	OutcomeSuccess = "success"
+	// OutcomeInvalid is a query the LLM answered without a valid PromQL expression
+	OutcomeInvalid = "invalid"
+	// OutcomeError is a query that failed before an answer was produced
+	OutcomeError = "error"
+)
+
+// Sink providers
+const (
+	ProviderJSONL   = "jsonl"
+	ProviderSQLite3 = "sqlite3"
+)
+
+// defaultSearchLimit is the number of records returned by a search without a limit
+const defaultSearchLimit = 100
+
+// Record is the audit record of a query
+type Record struct {
+	ID        string    `json:"id"`
+	Timestamp time.Time `json:"timestamp"`
+	// QueryID identifies the query when giving feedback, empty if it was not recorded
+	QueryID string `json:"query_id,omitempty"`
+
+	// User is the authenticated principal that asked the question, as method:name,
+	// empty if anonymous
+	User string `json:"user,omitempty"`
+	// ClientAddress is the network address the question came from
+	ClientAddress string `json:"client_address,omitempty"`
+	// TraceID is the ID of the trace of the query, empty if it was not sampled
+	TraceID string `json:"trace_id,omitempty"`
+
+	Question string `json:"question"`
+	// Metrics are the names of the metrics added to the prompt, most relevant first
+	Metrics []string `json:"metrics,omitempty"`
+	// PromptHash is the SHA-256 of the system prompt, empty when the LLM was not called
+	PromptHash string `json:"prompt_hash,omitempty"`
+	Model      string `json:"model,omitempty"`
+	// RawOutput is the answer of the LLM before it was parsed
+	RawOutput string `json:"raw_output,omitempty"`
+	PromQL    string `json:"promql,omitempty"`
+	// Cache is the response cache status, empty if the cache is disabled
+	Cache string `json:"cache,omitempty"`
+
+	// ValidationError tells why the answer is not a valid PromQL expression
+	ValidationError string `json:"validation_error,omitempty"`
+	// Error tells why the query failed
+	Error   string `json:"error,omitempty"`
+	Outcome string `json:"outcome"`
+
+	Latency time.Duration `json:"latency"`
+}
+
+// Filter selects the records returned by a search
+type Filter struct {
+	// Since and Until bound the record timestamps, zero values leave them open
+	Since time.Time
+	Until time.Time
+	// User and Outcome match exactly when set
+	User    string
+	Outcome string
+	// Limit is the maximum number of records returned, newest first
+	Limit int
+}
+
+// Validate validates the filter
+func (f *Filter) Validate() error {
+	switch f.Outcome {
+	case "", OutcomeSuccess, OutcomeInvalid, OutcomeError:
+	default:
+		return fmt.Errorf("outcome must be '%s', '%s' or '%s'", OutcomeSuccess, OutcomeInvalid, OutcomeError)
+	}
+
+	if f.Limit < 0 {
+		return fmt.Errorf("limit cannot be negative")
+	}
+
+	if !f.Since.IsZero() && !f.Until.IsZero() && f.Until.Before(f.Since) {
+		return fmt.Errorf("until cannot be before since")
+	}
+
+	return nil
+}
+
+// Matches returns true if the record is selected by the filter, regardless of the limit
+func (f *Filter) Matches(record *Record) bool {
+	if !f.Since.IsZero() && record.Timestamp.Before(f.Since) {
+		return false
+	}
+	if !f.Until.IsZero() && record.Timestamp.After(f.Until) {
+		return false
+	}
+	if f.User != "" && record.User != f.User {
+		return false
+	}
+	if f.Outcome != "" && record.Outcome != f.Outcome {
+		return false
+	}
+	return true
+}
+
+func (f *Filter) limit() int {
+	if f.Limit <= 0 {
+		return defaultSearchLimit
+	}
+	return f.Limit
+}
+
+// Sink stores audit records. Sinks are safe for concurrent use.
+type Sink interface {
+	// Record stores an audit record
+	Record(ctx context.Context, record *Record) error
+
+	// Search returns the records selected by the filter, newest first
+	Search(ctx context.Context, filter Filter) ([]*Record, error)
+
+	// Close closes the sink
+	Close() error
+}
+
+// Config is the configuration of the audit sink
+type Config struct {
+	// Provider is the sink implementation, ProviderJSONL or ProviderSQLite3
+	Provider string
+
+	JSONLPath     string
+	SQLite3DBPath string
+}
+
+// NewSink creates the configured audit sink
+func NewSink(config Config) (Sink, error) {
+	switch strings.ToLower(config.Provider) {
+	case ProviderJSONL:
+		return NewJSONLSink(config.JSONLPath)
+	case ProviderSQLite3:
+		return NewSQLiteSink(config.SQLite3DBPath)
+	default:
+		return nil, fmt.Errorf("unsupported audit provider '%s', supported providers: %s, %s",
+			config.Provider, ProviderJSONL, ProviderSQLite3)
+	}
+}
+
+// HashPrompt returns the hex-encoded SHA-256 of a prompt
+func HashPrompt(prompt string) string {
+	hash := sha256.Sum256([]byte(prompt))
+	return hex.EncodeToString(hash[:])
+}
+
+type identityKey struct{}
+
+// Identity is who asked a question
+type Identity struct {
+	User    string
+	Address string
+}
+
+// WithIdentity returns a copy of ctx carrying the identity of the client
+func WithIdentity(ctx context.Context, identity Identity) context.Context {
+	return context.WithValue(ctx, identityKey{}, identity)
+}
+
+// IdentityFromContext returns the identity of the client carried by ctx, if any
+func IdentityFromContext(ctx context.Context) Identity {
+	identity, _ := ctx.Value(identityKey{}).(Identity)
+	return identity
+}
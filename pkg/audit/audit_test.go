@@ -0,0 +1,154 @@
+package audit_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/machadovilaca/prometheus-rag/pkg/audit"
+)
+
+var _ = Describe("Sink", func() {
+	var (
+		ctx  context.Context
+		sink audit.Sink
+		base time.Time
+	)
+
+	records := func(found []*audit.Record) []string {
+		ids := make([]string, len(found))
+		for i, record := range found {
+			ids[i] = record.ID
+		}
+		return ids
+	}
+
+	behaves := func(provider string) {
+		BeforeEach(func() {
+			ctx = context.Background()
+			dir := GinkgoT().TempDir()
+
+			var err error
+			sink, err = audit.NewSink(audit.Config{
+				Provider:      provider,
+				JSONLPath:     filepath.Join(dir, "audit.jsonl"),
+				SQLite3DBPath: filepath.Join(dir, "audit.db"),
+			})
+			Expect(err).NotTo(HaveOccurred())
+			DeferCleanup(sink.Close)
+
+			base = time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+			for _, record := range []*audit.Record{
+				{ID: "1", Timestamp: base, User: "alice", Outcome: audit.OutcomeSuccess},
+				{ID: "2", Timestamp: base.Add(time.Minute), User: "bob", Outcome: audit.OutcomeInvalid},
+				{ID: "3", Timestamp: base.Add(2 * time.Minute), User: "alice", Outcome: audit.OutcomeError},
+				{ID: "4", Timestamp: base.Add(3 * time.Minute), User: "alice", Outcome: audit.OutcomeSuccess},
+			} {
+				Expect(sink.Record(ctx, record)).To(Succeed())
+			}
+		})
+
+		It("should return every record newest first", func() {
+			found, err := sink.Search(ctx, audit.Filter{})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(records(found)).To(Equal([]string{"4", "3", "2", "1"}))
+		})
+
+		It("should keep the fields of the records", func() {
+			record := &audit.Record{
+				ID:         "5",
+				Timestamp:  base.Add(time.Hour),
+				Question:   "memory usage",
+				Metrics:    []string{"node_memory_MemAvailable_bytes"},
+				PromptHash: audit.HashPrompt("prompt"),
+				PromQL:     "node_memory_MemAvailable_bytes",
+				Outcome:    audit.OutcomeSuccess,
+				Latency:    1500 * time.Millisecond,
+			}
+			Expect(sink.Record(ctx, record)).To(Succeed())
+
+			found, err := sink.Search(ctx, audit.Filter{Limit: 1})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(found).To(HaveLen(1))
+			Expect(found[0].Timestamp.Equal(record.Timestamp)).To(BeTrue())
+			found[0].Timestamp = record.Timestamp
+			Expect(found[0]).To(Equal(record))
+		})
+
+		It("should filter by time range", func() {
+			found, err := sink.Search(ctx, audit.Filter{
+				Since: base.Add(time.Minute),
+				Until: base.Add(2 * time.Minute),
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(records(found)).To(Equal([]string{"3", "2"}))
+		})
+
+		It("should filter by user and outcome", func() {
+			found, err := sink.Search(ctx, audit.Filter{User: "alice", Outcome: audit.OutcomeSuccess})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(records(found)).To(Equal([]string{"4", "1"}))
+		})
+
+		It("should limit the number of records", func() {
+			found, err := sink.Search(ctx, audit.Filter{Limit: 2})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(records(found)).To(Equal([]string{"4", "3"}))
+		})
+	}
+
+	Context("jsonl", func() {
+		behaves(audit.ProviderJSONL)
+
+		It("should skip undecodable lines", func() {
+			path := filepath.Join(GinkgoT().TempDir(), "audit.jsonl")
+			Expect(os.WriteFile(path, []byte(
+				`{"id":"1","timestamp":"2025-01-01T12:00:00Z"}`+"\n"+
+					`{"id":"2","timest`+"\n"+
+					`{"id":"3","timestamp":"2025-01-01T12:02:00Z"}`+"\n",
+			), 0600)).To(Succeed())
+
+			jsonl, err := audit.NewJSONLSink(path)
+			Expect(err).NotTo(HaveOccurred())
+			DeferCleanup(jsonl.Close)
+
+			found, err := jsonl.Search(context.Background(), audit.Filter{})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(records(found)).To(Equal([]string{"3", "1"}))
+		})
+	})
+
+	Context("sqlite3", func() {
+		behaves(audit.ProviderSQLite3)
+	})
+
+	It("should reject unsupported providers", func() {
+		_, err := audit.NewSink(audit.Config{Provider: "kafka"})
+		Expect(err).To(HaveOccurred())
+	})
+})
+
+var _ = Describe("Filter", func() {
+	It("should reject unknown outcomes", func() {
+		filter := audit.Filter{Outcome: "unknown"}
+		Expect(filter.Validate()).NotTo(Succeed())
+	})
+
+	It("should reject inverted time ranges", func() {
+		now := time.Now()
+		filter := audit.Filter{Since: now, Until: now.Add(-time.Minute)}
+		Expect(filter.Validate()).NotTo(Succeed())
+	})
+})
+
+var _ = Describe("Identity", func() {
+	It("should be carried by the context", func() {
+		ctx := audit.WithIdentity(context.Background(), audit.Identity{User: "alice", Address: "10.0.0.1:1234"})
+		Expect(audit.IdentityFromContext(ctx)).To(Equal(audit.Identity{User: "alice", Address: "10.0.0.1:1234"}))
+		Expect(audit.IdentityFromContext(context.Background())).To(BeZero())
+	})
+})
@@ -0,0 +1,108 @@
+package audit
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"github.com/rs/zerolog/log"
+)
+
+// maxLineSize is the maximum size of a record in a JSON-lines file
+const maxLineSize = 4 * 1024 * 1024
+
+// jsonlSink appends records to a JSON-lines file. Searches scan the whole
+// file, so it suits moderate volumes or files rotated by an external tool.
+type jsonlSink struct {
+	mu   sync.Mutex
+	path string
+	file *os.File
+}
+
+// NewJSONLSink opens (or creates) a JSON-lines audit file
+func NewJSONLSink(path string) (Sink, error) {
+	if path == "" {
+		return nil, fmt.Errorf("audit JSON-lines path is required")
+	}
+
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create audit directory %s: %w", dir, err)
+	}
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audit file: %w", err)
+	}
+
+	return &jsonlSink{path: path, file: file}, nil
+}
+
+func (s *jsonlSink) Record(_ context.Context, record *Record) error {
+	line, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("failed to encode audit record: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, err := s.file.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("failed to write audit record: %w", err)
+	}
+
+	return nil
+}
+
+func (s *jsonlSink) Search(ctx context.Context, filter Filter) ([]*Record, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	file, err := os.Open(s.path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audit file: %w", err)
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+
+	records := []*Record{}
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), maxLineSize)
+	for line := 1; scanner.Scan(); line++ {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		// A torn or hand-edited line must not hide the records around it
+		var record Record
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			log.Warn().Ctx(ctx).Err(err).Msgf("skipping undecodable audit record on line %d of %s", line, s.path)
+			continue
+		}
+		if filter.Matches(&record) {
+			records = append(records, &record)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read audit file: %w", err)
+	}
+
+	sort.SliceStable(records, func(i, j int) bool {
+		return records[i].Timestamp.After(records[j].Timestamp)
+	})
+
+	return records[:min(filter.limit(), len(records))], nil
+}
+
+func (s *jsonlSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.file.Close()
+}
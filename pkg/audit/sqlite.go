@@ -0,0 +1,133 @@
+package audit
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// sqliteSink stores records in a SQLite database indexed by time, user and
+// outcome. Timestamps are stored as Unix nanoseconds so ranges compare exactly.
+type sqliteSink struct {
+	db *sql.DB
+}
+
+// NewSQLiteSink opens (or creates) a SQLite audit database
+func NewSQLiteSink(path string) (Sink, error) {
+	if path == "" {
+		return nil, fmt.Errorf("audit SQLite3 db path is required")
+	}
+
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create audit directory %s: %w", dir, err)
+	}
+
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audit db: %w", err)
+	}
+
+	_, err = db.Exec(`
+		CREATE TABLE IF NOT EXISTS audit_records (
+			id TEXT PRIMARY KEY,
+			timestamp INTEGER NOT NULL,
+			user TEXT,
+			outcome TEXT NOT NULL,
+			record TEXT NOT NULL
+		);
+		CREATE INDEX IF NOT EXISTS audit_records_timestamp ON audit_records (timestamp);
+		CREATE INDEX IF NOT EXISTS audit_records_user ON audit_records (user, timestamp);
+		CREATE INDEX IF NOT EXISTS audit_records_outcome ON audit_records (outcome, timestamp);
+	`)
+	if err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("failed to create audit table: %w", err)
+	}
+
+	return &sqliteSink{db: db}, nil
+}
+
+func (s *sqliteSink) Record(ctx context.Context, record *Record) error {
+	encoded, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("failed to encode audit record: %w", err)
+	}
+
+	_, err = s.db.ExecContext(ctx, `
+		INSERT INTO audit_records (id, timestamp, user, outcome, record) VALUES (?, ?, ?, ?, ?)
+	`, record.ID, record.Timestamp.UnixNano(), record.User, record.Outcome, string(encoded))
+	if err != nil {
+		return fmt.Errorf("failed to write audit record: %w", err)
+	}
+
+	return nil
+}
+
+func (s *sqliteSink) Search(ctx context.Context, filter Filter) ([]*Record, error) {
+	var (
+		conditions []string
+		args       []any
+	)
+
+	if !filter.Since.IsZero() {
+		conditions = append(conditions, "timestamp >= ?")
+		args = append(args, filter.Since.UnixNano())
+	}
+	if !filter.Until.IsZero() {
+		conditions = append(conditions, "timestamp <= ?")
+		args = append(args, filter.Until.UnixNano())
+	}
+	if filter.User != "" {
+		conditions = append(conditions, "user = ?")
+		args = append(args, filter.User)
+	}
+	if filter.Outcome != "" {
+		conditions = append(conditions, "outcome = ?")
+		args = append(args, filter.Outcome)
+	}
+
+	query := "SELECT record FROM audit_records"
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	query += " ORDER BY timestamp DESC LIMIT ?"
+	args = append(args, filter.limit())
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search audit records: %w", err)
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	records := []*Record{}
+	for rows.Next() {
+		var encoded string
+		if err := rows.Scan(&encoded); err != nil {
+			return nil, fmt.Errorf("failed to read audit record: %w", err)
+		}
+
+		var record Record
+		if err := json.Unmarshal([]byte(encoded), &record); err != nil {
+			return nil, fmt.Errorf("failed to decode audit record: %w", err)
+		}
+		records = append(records, &record)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read audit records: %w", err)
+	}
+
+	return records, nil
+}
+
+func (s *sqliteSink) Close() error {
+	return s.db.Close()
+}
@@ -0,0 +1,264 @@
+// Package auth authenticates the clients of the HTTP API with API keys, JWT
+// bearer tokens or the headers of a trusted proxy, and restricts the
+// Prometheus sources and metrics each of them may query.
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/promql/parser"
+)
+
+// Authentication methods
+const (
+	MethodAPIKey = "api_key"
+	MethodJWT    = "jwt"
+	MethodProxy  = "proxy"
+)
+
+var (
+	// ErrNoCredentials is returned by a Method when the request does not carry its kind of credentials
+	ErrNoCredentials = errors.New("no credentials")
+
+	// ErrForbidden is returned when a principal queries a source or metric it is not allowed to
+	ErrForbidden = errors.New("access denied")
+)
+
+// Method authenticates requests carrying one kind of credentials
+type Method interface {
+	// Name is the name of the method, reported in the principal
+	Name() string
+
+	// Authenticate returns the name of the principal that sent the request,
+	// or ErrNoCredentials if the request does not carry credentials of this method
+	Authenticate(r *http.Request) (string, error)
+}
+
+// Policy restricts what a principal may do. The zero Policy allows querying
+// any source and metric, without access to the administration endpoints.
+type Policy struct {
+	// Admin allows the administration endpoints, such as the audit log
+	Admin bool `json:"admin" yaml:"admin"`
+
+	// Sources are the Prometheus addresses the principal may query, empty allows any
+	Sources []string `json:"sources,omitempty" yaml:"sources"`
+
+	// MetricPrefixes are the prefixes of the metrics the principal may query, empty allows any
+	MetricPrefixes []string `json:"metric_prefixes,omitempty" yaml:"metric_prefixes"`
+
+	// DailyTokens and MonthlyTokens override the LLM token quotas of the
+	// principal when set, 0 for no limit
+	DailyTokens   *int64 `json:"daily_tokens,omitempty" yaml:"daily_tokens"`
+	MonthlyTokens *int64 `json:"monthly_tokens,omitempty" yaml:"monthly_tokens"`
+}
+
+// Principal is an authenticated client. A nil Principal, used when
+// authentication is disabled, may query everything but is not an administrator.
+type Principal struct {
+	Name string `json:"name"`
+	// Method is the method that authenticated the principal
+	Method string `json:"method"`
+
+	Policy
+}
+
+// Key identifies the principal as method:name, since principals of different
+// methods may share a name, or is empty for a nil Principal
+func (p *Principal) Key() string {
+	if p == nil {
+		return ""
+	}
+	return p.Method + ":" + p.Name
+}
+
+// IsAdmin returns true if the principal may use the administration endpoints
+func (p *Principal) IsAdmin() bool {
+	return p != nil && p.Admin
+}
+
+// AllowsSource returns true if the principal may query the Prometheus at address
+func (p *Principal) AllowsSource(address string) bool {
+	if p == nil || len(p.Sources) == 0 {
+		return true
+	}
+
+	for _, source := range p.Sources {
+		if strings.TrimSuffix(source, "/") == strings.TrimSuffix(address, "/") {
+			return true
+		}
+	}
+	return false
+}
+
+// RestrictsMetrics returns true if the principal may only query some metrics
+func (p *Principal) RestrictsMetrics() bool {
+	return p != nil && len(p.MetricPrefixes) > 0
+}
+
+// AllowsMetric returns true if the principal may query the metric
+func (p *Principal) AllowsMetric(name string) bool {
+	if !p.RestrictsMetrics() {
+		return true
+	}
+
+	for _, prefix := range p.MetricPrefixes {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckPromQL returns an error wrapping ErrForbidden if the expression selects
+// metrics the principal may not query. Selectors without an exact metric name,
+// such as {job="node"} or {__name__=~"node_.*"}, are rejected for principals
+// restricted to metric prefixes, as they could select any metric.
+func (p *Principal) CheckPromQL(promql string) error {
+	if !p.RestrictsMetrics() {
+		return nil
+	}
+
+	expr, err := parser.ParseExpr(promql)
+	if err != nil {
+		return fmt.Errorf("failed to parse PromQL: %w", err)
+	}
+
+	var forbidden error
+	parser.Inspect(expr, func(node parser.Node, _ []parser.Node) error {
+		selector, ok := node.(*parser.VectorSelector)
+		if !ok || forbidden != nil {
+			return nil
+		}
+
+		name := selectorMetricName(selector)
+		if name == "" {
+			forbidden = fmt.Errorf("%w: selector %s does not select a metric by name", ErrForbidden, selector)
+		} else if !p.AllowsMetric(name) {
+			forbidden = fmt.Errorf("%w: metric %s is not allowed for %s", ErrForbidden, name, p.Name)
+		}
+		return nil
+	})
+
+	return forbidden
+}
+
+// selectorMetricName returns the exact metric name selected by a selector, if any
+func selectorMetricName(selector *parser.VectorSelector) string {
+	if selector.Name != "" {
+		return selector.Name
+	}
+
+	for _, matcher := range selector.LabelMatchers {
+		if matcher.Name == labels.MetricName && matcher.Type == labels.MatchEqual {
+			return matcher.Value
+		}
+	}
+	return ""
+}
+
+type principalKey struct{}
+
+// WithPrincipal returns a copy of ctx carrying the authenticated principal
+func WithPrincipal(ctx context.Context, principal *Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, principal)
+}
+
+// PrincipalFromContext returns the principal carried by ctx, nil if
+// authentication is disabled
+func PrincipalFromContext(ctx context.Context) *Principal {
+	principal, _ := ctx.Value(principalKey{}).(*Principal)
+	return principal
+}
+
+// Config is the configuration of the authenticator
+type Config struct {
+	// PolicyPath is the YAML file with the API keys and the policies of the principals
+	PolicyPath string
+
+	// JWKSPath is the JSON Web Key Set verifying JWT bearer tokens, empty disables JWT authentication
+	JWKSPath string
+	// JWTIssuer and JWTAudience are required in the tokens when set
+	JWTIssuer   string
+	JWTAudience string
+	// JWTUserClaim is the claim holding the name of the principal
+	JWTUserClaim string
+
+	// ProxyUserHeader is the header holding the name of the principal
+	// authenticated by a proxy, empty disables proxy authentication
+	ProxyUserHeader string
+	// TrustedProxies are the addresses or CIDRs of the proxies allowed to set ProxyUserHeader
+	TrustedProxies []string
+}
+
+// Authenticator authenticates requests with the first method whose
+// credentials they carry, and applies the policy of the principal
+type Authenticator struct {
+	methods  []Method
+	policies *Policies
+}
+
+// New creates an authenticator with the methods enabled in the configuration
+func New(config Config) (*Authenticator, error) {
+	policies := &Policies{}
+	if config.PolicyPath != "" {
+		var err error
+		policies, err = LoadPolicies(config.PolicyPath)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var methods []Method
+	if keys := policies.apiKeys(); len(keys) > 0 {
+		methods = append(methods, NewAPIKeyMethod(keys))
+	}
+
+	if config.JWKSPath != "" {
+		method, err := NewJWTMethod(config.JWKSPath, config.JWTIssuer, config.JWTAudience, config.JWTUserClaim)
+		if err != nil {
+			return nil, err
+		}
+		methods = append(methods, method)
+	}
+
+	if config.ProxyUserHeader != "" {
+		method, err := NewProxyMethod(config.ProxyUserHeader, config.TrustedProxies)
+		if err != nil {
+			return nil, err
+		}
+		methods = append(methods, method)
+	}
+
+	if len(methods) == 0 {
+		return nil, errors.New("no authentication method configured")
+	}
+
+	return NewAuthenticator(policies, methods...), nil
+}
+
+// NewAuthenticator creates an authenticator trying the methods in order
+func NewAuthenticator(policies *Policies, methods ...Method) *Authenticator {
+	return &Authenticator{methods: methods, policies: policies}
+}
+
+// Authenticate returns the principal that sent the request
+func (a *Authenticator) Authenticate(r *http.Request) (*Principal, error) {
+	for _, method := range a.methods {
+		name, err := method.Authenticate(r)
+		if errors.Is(err, ErrNoCredentials) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s authentication failed: %w", method.Name(), err)
+		}
+
+		return &Principal{Name: name, Method: method.Name(), Policy: a.policies.Policy(method.Name(), name)}, nil
+	}
+
+	return nil, ErrNoCredentials
+}
@@ -0,0 +1,308 @@
+package auth_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/machadovilaca/prometheus-rag/pkg/auth"
+)
+
+func hashKey(key string) string {
+	hash := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(hash[:])
+}
+
+func writeFile(name, content string) string {
+	path := filepath.Join(GinkgoT().TempDir(), name)
+	Expect(os.WriteFile(path, []byte(content), 0600)).To(Succeed())
+	return path
+}
+
+func encode(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+var _ = Describe("Authenticator", func() {
+	var (
+		rsaKey     *rsa.PrivateKey
+		ecKey      *ecdsa.PrivateKey
+		policyPath string
+		jwksPath   string
+	)
+
+	BeforeEach(func() {
+		var err error
+		rsaKey, err = rsa.GenerateKey(rand.Reader, 2048)
+		Expect(err).NotTo(HaveOccurred())
+		ecKey, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		Expect(err).NotTo(HaveOccurred())
+
+		jwks, err := json.Marshal(map[string]any{
+			"keys": []map[string]string{
+				{"kty": "RSA", "kid": "rsa", "use": "sig", "n": encode(rsaKey.N.Bytes()), "e": encode(big.NewInt(int64(rsaKey.E)).Bytes())},
+				{"kty": "EC", "kid": "ec", "crv": "P-256", "x": encode(ecKey.X.FillBytes(make([]byte, 32))), "y": encode(ecKey.Y.FillBytes(make([]byte, 32)))},
+			},
+		})
+		Expect(err).NotTo(HaveOccurred())
+		jwksPath = writeFile("jwks.json", string(jwks))
+
+		policyPath = writeFile("policy.yaml", `
+default:
+  metric_prefixes: [node_]
+principals:
+  - name: grafana
+    api_key_sha256: `+hashKey("grafana-key")+`
+    metric_prefixes: [kube_]
+  - name: alice
+    admin: true
+  - name: ops
+    api_key_sha256: `+hashKey("ops-key")+`
+    admin: true
+`)
+	})
+
+	newAuthenticator := func() *auth.Authenticator {
+		authenticator, err := auth.New(auth.Config{
+			PolicyPath:      policyPath,
+			JWKSPath:        jwksPath,
+			JWTIssuer:       "https://issuer.example.com",
+			JWTAudience:     "prometheus-rag",
+			ProxyUserHeader: "X-Forwarded-User",
+			TrustedProxies:  []string{"10.0.0.0/8", "::1"},
+		})
+		Expect(err).NotTo(HaveOccurred())
+		return authenticator
+	}
+
+	sign := func(method jwt.SigningMethod, kid string, key any, claims jwt.MapClaims) string {
+		token := jwt.NewWithClaims(method, claims)
+		token.Header["kid"] = kid
+		signed, err := token.SignedString(key)
+		Expect(err).NotTo(HaveOccurred())
+		return signed
+	}
+
+	claims := func(subject string) jwt.MapClaims {
+		return jwt.MapClaims{
+			"sub": subject,
+			"iss": "https://issuer.example.com",
+			"aud": "prometheus-rag",
+			"exp": time.Now().Add(time.Hour).Unix(),
+		}
+	}
+
+	request := func(headers map[string]string) *http.Request {
+		r := httptest.NewRequest(http.MethodPost, "/query", nil)
+		r.RemoteAddr = "192.168.1.10:5000"
+		for name, value := range headers {
+			r.Header.Set(name, value)
+		}
+		return r
+	}
+
+	It("should authenticate API keys with their policy", func() {
+		authenticator := newAuthenticator()
+
+		for _, r := range []*http.Request{
+			request(map[string]string{auth.APIKeyHeader: "grafana-key"}),
+			request(map[string]string{"Authorization": "Bearer grafana-key"}),
+		} {
+			principal, err := authenticator.Authenticate(r)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(principal.Name).To(Equal("grafana"))
+			Expect(principal.Method).To(Equal(auth.MethodAPIKey))
+			Expect(principal.MetricPrefixes).To(Equal([]string{"kube_"}))
+		}
+	})
+
+	It("should reject unknown API keys", func() {
+		_, err := newAuthenticator().Authenticate(request(map[string]string{auth.APIKeyHeader: "unknown"}))
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should reject requests without credentials", func() {
+		_, err := newAuthenticator().Authenticate(request(nil))
+		Expect(err).To(MatchError(auth.ErrNoCredentials))
+	})
+
+	It("should authenticate JWTs signed by the keys of the JWKS", func() {
+		authenticator := newAuthenticator()
+
+		token := sign(jwt.SigningMethodRS256, "rsa", rsaKey, claims("alice"))
+		principal, err := authenticator.Authenticate(request(map[string]string{"Authorization": "Bearer " + token}))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(principal.Name).To(Equal("alice"))
+		Expect(principal.Method).To(Equal(auth.MethodJWT))
+		Expect(principal.IsAdmin()).To(BeTrue())
+
+		token = sign(jwt.SigningMethodES256, "ec", ecKey, claims("bob"))
+		principal, err = authenticator.Authenticate(request(map[string]string{"Authorization": "Bearer " + token}))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(principal.Name).To(Equal("bob"))
+		Expect(principal.IsAdmin()).To(BeFalse())
+		Expect(principal.MetricPrefixes).To(Equal([]string{"node_"}))
+	})
+
+	It("should not apply the policy of an API key principal to a JWT subject with its name", func() {
+		authenticator := newAuthenticator()
+
+		principal, err := authenticator.Authenticate(request(map[string]string{auth.APIKeyHeader: "ops-key"}))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(principal.IsAdmin()).To(BeTrue())
+
+		token := sign(jwt.SigningMethodRS256, "rsa", rsaKey, claims("ops"))
+		principal, err = authenticator.Authenticate(request(map[string]string{"Authorization": "Bearer " + token}))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(principal.Name).To(Equal("ops"))
+		Expect(principal.Method).To(Equal(auth.MethodJWT))
+		Expect(principal.Key()).To(Equal("jwt:ops"))
+		Expect(principal.IsAdmin()).To(BeFalse())
+		Expect(principal.MetricPrefixes).To(Equal([]string{"node_"}))
+
+		r := request(map[string]string{"X-Forwarded-User": "ops"})
+		r.RemoteAddr = "10.1.2.3:5000"
+		principal, err = authenticator.Authenticate(r)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(principal.IsAdmin()).To(BeFalse())
+	})
+
+	It("should apply policies only to the method they are bound to", func() {
+		policyPath = writeFile("policy.yaml", `
+principals:
+  - name: alice
+    method: proxy
+    admin: true
+`)
+		authenticator := newAuthenticator()
+
+		token := sign(jwt.SigningMethodRS256, "rsa", rsaKey, claims("alice"))
+		principal, err := authenticator.Authenticate(request(map[string]string{"Authorization": "Bearer " + token}))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(principal.IsAdmin()).To(BeFalse())
+
+		r := request(map[string]string{"X-Forwarded-User": "alice"})
+		r.RemoteAddr = "10.1.2.3:5000"
+		principal, err = authenticator.Authenticate(r)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(principal.IsAdmin()).To(BeTrue())
+	})
+
+	It("should reject invalid JWTs", func() {
+		authenticator := newAuthenticator()
+
+		otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+		Expect(err).NotTo(HaveOccurred())
+
+		expired := claims("alice")
+		expired["exp"] = time.Now().Add(-time.Minute).Unix()
+		wrongIssuer := claims("alice")
+		wrongIssuer["iss"] = "https://other.example.com"
+		wrongAudience := claims("alice")
+		wrongAudience["aud"] = "other"
+
+		for _, token := range []string{
+			sign(jwt.SigningMethodRS256, "rsa", otherKey, claims("alice")),
+			sign(jwt.SigningMethodRS256, "unknown", rsaKey, claims("alice")),
+			sign(jwt.SigningMethodRS256, "rsa", rsaKey, expired),
+			sign(jwt.SigningMethodRS256, "rsa", rsaKey, wrongIssuer),
+			sign(jwt.SigningMethodRS256, "rsa", rsaKey, wrongAudience),
+			sign(jwt.SigningMethodHS256, "rsa", []byte("secret"), claims("alice")),
+		} {
+			_, err := authenticator.Authenticate(request(map[string]string{"Authorization": "Bearer " + token}))
+			Expect(err).To(HaveOccurred())
+		}
+	})
+
+	It("should trust the proxy header only from trusted proxies", func() {
+		authenticator := newAuthenticator()
+
+		r := request(map[string]string{"X-Forwarded-User": "carol"})
+		_, err := authenticator.Authenticate(r)
+		Expect(err).To(HaveOccurred())
+
+		r.RemoteAddr = "10.1.2.3:5000"
+		principal, err := authenticator.Authenticate(r)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(principal.Name).To(Equal("carol"))
+		Expect(principal.Method).To(Equal(auth.MethodProxy))
+
+		r.RemoteAddr = "[::1]:5000"
+		_, err = authenticator.Authenticate(r)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("should require an authentication method", func() {
+		_, err := auth.New(auth.Config{})
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should reject invalid policy files", func() {
+		policyPath = writeFile("policy.yaml", `
+principals:
+  - name: grafana
+    api_key_sha256: not-a-hash
+`)
+		_, err := auth.New(auth.Config{PolicyPath: policyPath})
+		Expect(err).To(HaveOccurred())
+
+		for _, principal := range []string{
+			"{name: grafana, method: api_key}",
+			"{name: grafana, method: jwt, api_key_sha256: " + hashKey("grafana-key") + "}",
+			"{name: grafana, method: ldap}",
+		} {
+			policyPath = writeFile("policy.yaml", "principals: ["+principal+"]")
+			_, err = auth.New(auth.Config{PolicyPath: policyPath, ProxyUserHeader: "X-User", TrustedProxies: []string{"10.0.0.1"}})
+			Expect(err).To(MatchError(ContainSubstring("grafana")), principal)
+		}
+	})
+})
+
+var _ = Describe("Principal", func() {
+	principal := &auth.Principal{
+		Name: "grafana",
+		Policy: auth.Policy{
+			Sources:        []string{"http://prometheus:9090"},
+			MetricPrefixes: []string{"node_", "kube_"},
+		},
+	}
+
+	It("should allow any query but no administration when authentication is disabled", func() {
+		var disabled *auth.Principal
+		Expect(disabled.IsAdmin()).To(BeFalse())
+		Expect(disabled.Key()).To(BeEmpty())
+		Expect(disabled.AllowsSource("http://prometheus:9090")).To(BeTrue())
+		Expect(disabled.CheckPromQL(`{job="node"}`)).To(Succeed())
+	})
+
+	It("should restrict the sources", func() {
+		Expect(principal.AllowsSource("http://prometheus:9090/")).To(BeTrue())
+		Expect(principal.AllowsSource("http://other:9090")).To(BeFalse())
+	})
+
+	It("should allow expressions selecting allowed metrics", func() {
+		Expect(principal.CheckPromQL(`sum(rate(node_cpu_seconds_total[5m])) / count(kube_node_info)`)).To(Succeed())
+		Expect(principal.CheckPromQL(`{__name__="node_load1"}`)).To(Succeed())
+	})
+
+	It("should reject expressions selecting other metrics", func() {
+		Expect(principal.CheckPromQL(`node_load1 + process_cpu_seconds_total`)).To(MatchError(auth.ErrForbidden))
+		Expect(principal.CheckPromQL(`{job="node"}`)).To(MatchError(auth.ErrForbidden))
+		Expect(principal.CheckPromQL(`{__name__=~"node_.*"}`)).To(MatchError(auth.ErrForbidden))
+	})
+})
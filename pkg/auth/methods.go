@@ -0,0 +1,145 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+// APIKeyHeader is the header carrying API keys, which can also be sent as bearer tokens
+const APIKeyHeader = "X-API-Key"
+
+// apiKeyMethod authenticates requests with static API keys. Only the SHA-256
+// of the keys is configured, so the policy file does not hold secrets.
+type apiKeyMethod struct {
+	// names are the names of the principals by the hash of their API key
+	names map[string]string
+}
+
+// NewAPIKeyMethod creates a method authenticating the API keys whose
+// hex-encoded SHA-256 are the keys of names
+func NewAPIKeyMethod(names map[string]string) Method {
+	return &apiKeyMethod{names: names}
+}
+
+func (m *apiKeyMethod) Name() string {
+	return MethodAPIKey
+}
+
+func (m *apiKeyMethod) Authenticate(r *http.Request) (string, error) {
+	if key := r.Header.Get(APIKeyHeader); key != "" {
+		name, ok := m.lookup(key)
+		if !ok {
+			return "", errors.New("unknown API key")
+		}
+		return name, nil
+	}
+
+	// Bearer tokens that are not API keys may be JWTs, left to the next method
+	if token, ok := bearerToken(r); ok {
+		if name, ok := m.lookup(token); ok {
+			return name, nil
+		}
+	}
+
+	return "", ErrNoCredentials
+}
+
+func (m *apiKeyMethod) lookup(key string) (string, bool) {
+	hash := sha256.Sum256([]byte(key))
+	name, ok := m.names[hex.EncodeToString(hash[:])]
+	return name, ok
+}
+
+// proxyMethod trusts the principal name set in a header by an authenticating
+// proxy, only for requests coming from the trusted proxies
+type proxyMethod struct {
+	header  string
+	trusted []netip.Prefix
+}
+
+// NewProxyMethod creates a method reading the principal name from header in
+// the requests coming from the trusted addresses or CIDRs
+func NewProxyMethod(header string, trusted []string) (Method, error) {
+	if len(trusted) == 0 {
+		return nil, errors.New("at least one trusted proxy is required")
+	}
+
+	m := &proxyMethod{header: http.CanonicalHeaderKey(header)}
+	for _, proxy := range trusted {
+		prefix, err := parsePrefix(proxy)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy '%s': %w", proxy, err)
+		}
+		m.trusted = append(m.trusted, prefix)
+	}
+
+	return m, nil
+}
+
+func (m *proxyMethod) Name() string {
+	return MethodProxy
+}
+
+func (m *proxyMethod) Authenticate(r *http.Request) (string, error) {
+	name := r.Header.Get(m.header)
+	if name == "" {
+		return "", ErrNoCredentials
+	}
+
+	// Anyone can set the header, so it is only trusted from the proxies
+	if !m.isTrusted(r.RemoteAddr) {
+		return "", fmt.Errorf("%s header sent by untrusted address %s", m.header, r.RemoteAddr)
+	}
+
+	return name, nil
+}
+
+func (m *proxyMethod) isTrusted(remoteAddr string) bool {
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		host = remoteAddr
+	}
+
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap()
+
+	for _, prefix := range m.trusted {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// parsePrefix parses a CIDR or a single address
+func parsePrefix(s string) (netip.Prefix, error) {
+	if strings.Contains(s, "/") {
+		return netip.ParsePrefix(s)
+	}
+
+	addr, err := netip.ParseAddr(s)
+	if err != nil {
+		return netip.Prefix{}, err
+	}
+	return netip.PrefixFrom(addr, addr.BitLen()), nil
+}
+
+// bearerToken returns the token of the Authorization header, if any
+func bearerToken(r *http.Request) (string, bool) {
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return "", false
+	}
+
+	token = strings.TrimSpace(token)
+	return token, token != ""
+}
@@ -0,0 +1,146 @@
+package auth
+
+import (
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// PrincipalPolicy is the policy of a named principal
+type PrincipalPolicy struct {
+	Name string `yaml:"name"`
+
+	// APIKeySHA256 is the hex-encoded SHA-256 of the API key of the principal,
+	// empty if it authenticates with another method
+	APIKeySHA256 string `yaml:"api_key_sha256"`
+
+	// Method is the method that must authenticate the principal for the policy
+	// to apply. It defaults to api_key for principals with an API key, and to
+	// both jwt and proxy for the others, so a JWT subject or proxy user named
+	// after an API key principal does not get its policy.
+	Method string `yaml:"method"`
+
+	Policy `yaml:",inline"`
+}
+
+// Policies are the policies of the principals, loaded from a YAML file such as
+//
+//	default:
+//	  metric_prefixes: [node_]
+//	principals:
+//	  - name: grafana
+//	    api_key_sha256: 9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08
+//	    metric_prefixes: [node_, kube_]
+//	  - name: alice
+//	    method: jwt
+//	    admin: true
+type Policies struct {
+	// Default is the policy of the principals that are not listed, such as
+	// users authenticated by JWT or by a proxy
+	Default Policy `yaml:"default"`
+
+	Principals []PrincipalPolicy `yaml:"principals"`
+}
+
+// LoadPolicies loads the policies from a YAML file
+func LoadPolicies(path string) (*Policies, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read auth policy file: %w", err)
+	}
+
+	var policies Policies
+	if err := yaml.Unmarshal(data, &policies); err != nil {
+		return nil, fmt.Errorf("failed to parse auth policy file: %w", err)
+	}
+
+	if err := policies.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid auth policy file: %w", err)
+	}
+
+	return &policies, nil
+}
+
+// Validate validates the policies
+func (p *Policies) Validate() error {
+	names := make(map[string]bool, len(p.Principals))
+	keys := make(map[string]bool, len(p.Principals))
+	for i, principal := range p.Principals {
+		if principal.Name == "" {
+			return fmt.Errorf("principal %d: name is required", i)
+		}
+		if names[principal.Name] {
+			return fmt.Errorf("principal %s: listed more than once", principal.Name)
+		}
+		names[principal.Name] = true
+
+		if (principal.DailyTokens != nil && *principal.DailyTokens < 0) ||
+			(principal.MonthlyTokens != nil && *principal.MonthlyTokens < 0) {
+			return fmt.Errorf("principal %s: token quotas cannot be negative", principal.Name)
+		}
+
+		switch principal.Method {
+		case "", MethodJWT, MethodProxy:
+			if principal.Method != "" && principal.APIKeySHA256 != "" {
+				return fmt.Errorf("principal %s: api_key_sha256 requires the %s method", principal.Name, MethodAPIKey)
+			}
+		case MethodAPIKey:
+			if principal.APIKeySHA256 == "" {
+				return fmt.Errorf("principal %s: the %s method requires api_key_sha256", principal.Name, MethodAPIKey)
+			}
+		default:
+			return fmt.Errorf("principal %s: unsupported method %s", principal.Name, principal.Method)
+		}
+
+		if principal.APIKeySHA256 == "" {
+			continue
+		}
+		hash, err := hex.DecodeString(principal.APIKeySHA256)
+		if err != nil || len(hash) != 32 {
+			return fmt.Errorf("principal %s: api_key_sha256 must be a hex-encoded SHA-256", principal.Name)
+		}
+		if keys[strings.ToLower(principal.APIKeySHA256)] {
+			return fmt.Errorf("principal %s: API key shared with another principal", principal.Name)
+		}
+		keys[strings.ToLower(principal.APIKeySHA256)] = true
+	}
+
+	return nil
+}
+
+// Policy returns the policy of the principal authenticated by method
+func (p *Policies) Policy(method, name string) Policy {
+	for _, principal := range p.Principals {
+		if principal.Name == name && principal.appliesTo(method) {
+			return principal.Policy
+		}
+	}
+	return p.Default
+}
+
+// appliesTo returns true if the policy applies to the principal when
+// authenticated by method
+func (p *PrincipalPolicy) appliesTo(method string) bool {
+	switch {
+	case p.Method != "":
+		return p.Method == method
+	case p.APIKeySHA256 != "":
+		return method == MethodAPIKey
+	default:
+		return method == MethodJWT || method == MethodProxy
+	}
+}
+
+// apiKeys returns the names of the principals by the hash of their API key
+func (p *Policies) apiKeys() map[string]string {
+	keys := make(map[string]string)
+	for _, principal := range p.Principals {
+		if principal.APIKeySHA256 != "" {
+			keys[strings.ToLower(principal.APIKeySHA256)] = principal.Name
+		}
+	}
+	return keys
+}
@@ -1,16 +1,27 @@
 package config
 
 import (
+	"time"
+
+	"github.com/machadovilaca/prometheus-rag/pkg/audit"
+	"github.com/machadovilaca/prometheus-rag/pkg/auth"
 	"github.com/machadovilaca/prometheus-rag/pkg/embeddings"
+	"github.com/machadovilaca/prometheus-rag/pkg/feedback"
 	"github.com/machadovilaca/prometheus-rag/pkg/llm"
 	"github.com/machadovilaca/prometheus-rag/pkg/prometheus"
+	"github.com/machadovilaca/prometheus-rag/pkg/telemetry"
+	"github.com/machadovilaca/prometheus-rag/pkg/tlsconfig"
 	"github.com/machadovilaca/prometheus-rag/pkg/vectordb"
 )
 
 // ToPrometheusConfig converts the application configuration to prometheus package configuration
 func (c *Config) ToPrometheusConfig() prometheus.Config {
 	return prometheus.Config{
-		Address: c.Prometheus.Address,
+		Address:           c.Prometheus.Address,
+		LabelsConcurrency: c.Prometheus.LabelsConcurrency,
+		LabelsRateLimit:   c.Prometheus.LabelsRateLimit,
+		LabelsBatchSize:   c.Prometheus.LabelsBatchSize,
+		LabelsLookback:    time.Duration(c.Prometheus.RefreshRateMinutes) * time.Minute,
 	}
 }
 
@@ -22,23 +33,119 @@ func (c *Config) ToVectorDBConfig() vectordb.Config {
 		QdrantHost:             c.VectorDB.QdrantHost,
 		QdrantPort:             c.VectorDB.QdrantPort,
 		CollectionName:         c.VectorDB.Collection,
+		ExamplesCollectionName: c.Examples.Collection,
 		EncoderOutputDirectory: c.VectorDB.EncoderDir,
+		EncoderWorkers:         c.VectorDB.EncoderWorkers,
+		EncoderCachePath:       c.VectorDB.EncoderCachePath,
+
+		EncoderModelName:         c.VectorDB.EncoderModel,
+		EncoderPooling:           c.VectorDB.EncoderPooling,
+		EncoderMaxSequenceLength: c.VectorDB.EncoderMaxSequenceLength,
+		EncoderDocument:          c.VectorDB.EncoderDocument,
+		OnModelMismatch:          c.VectorDB.OnModelMismatch,
+
+		EncoderProvider:   c.VectorDB.EncoderProvider,
+		EncoderBaseURL:    c.VectorDB.EncoderBaseURL,
+		EncoderAPIKey:     c.VectorDB.EncoderAPIKey,
+		EncoderBatchSize:  c.VectorDB.EncoderBatchSize,
+		EncoderTimeout:    time.Duration(c.VectorDB.EncoderTimeoutSeconds) * time.Second,
+		EncoderMaxRetries: c.VectorDB.EncoderMaxRetries,
 	}
 }
 
 // ToLLMConfig converts the application configuration to llm package configuration
-func (c *Config) ToLLMConfig(vectorDBClient vectordb.Client) llm.Config {
+func (c *Config) ToLLMConfig(vectorDBClient vectordb.Client, exampleStore vectordb.ExampleStore) llm.Config {
 	return llm.Config{
 		BaseURL:        c.LLM.BaseURL,
 		APIKey:         c.LLM.APIKey,
 		Model:          c.LLM.Model,
 		VectorDBClient: vectorDBClient,
+		ExampleStore:   exampleStore,
+		ExamplesLimit:  c.Examples.Limit,
+
+		RetrievalTimeout:  time.Duration(c.VectorDB.SearchTimeoutSeconds) * time.Second,
+		GenerationTimeout: time.Duration(c.LLM.TimeoutSeconds) * time.Second,
+	}
+}
+
+// ToEnrichmentConfig converts the application configuration to llm package enrichment configuration
+func (c *Config) ToEnrichmentConfig() llm.EnrichmentConfig {
+	return llm.EnrichmentConfig{
+		MinHelpWords: c.LLM.EnrichMinHelpWords,
+		MaxPerSync:   c.LLM.EnrichMaxPerSync,
+		Concurrency:  c.LLM.EnrichConcurrency,
 	}
 }
 
 // ToEmbeddingsConfig converts the application configuration to embeddings package configuration
 func (c *Config) ToEmbeddingsConfig() embeddings.Config {
 	return embeddings.Config{
-		ModelsDir: c.VectorDB.EncoderDir,
+		Provider:          c.VectorDB.EncoderProvider,
+		ModelsDir:         c.VectorDB.EncoderDir,
+		ModelName:         c.VectorDB.EncoderModel,
+		Pooling:           c.VectorDB.EncoderPooling,
+		MaxSequenceLength: c.VectorDB.EncoderMaxSequenceLength,
+		Document:          c.VectorDB.EncoderDocument,
+		Workers:           c.VectorDB.EncoderWorkers,
+		CachePath:         c.VectorDB.EncoderCachePath,
+		BaseURL:           c.VectorDB.EncoderBaseURL,
+		APIKey:            c.VectorDB.EncoderAPIKey,
+		BatchSize:         c.VectorDB.EncoderBatchSize,
+		Timeout:           time.Duration(c.VectorDB.EncoderTimeoutSeconds) * time.Second,
+		MaxRetries:        c.VectorDB.EncoderMaxRetries,
+	}
+}
+
+// ToFeedbackConfig converts the application configuration to feedback package configuration
+func (c *Config) ToFeedbackConfig(encoder embeddings.Encoder) feedback.Config {
+	return feedback.Config{
+		DBPath:              c.Feedback.DBPath,
+		Encoder:             encoder,
+		SimilarityThreshold: c.Feedback.SimilarityThreshold,
+		MinNegative:         c.Feedback.MinNegative,
+	}
+}
+
+// ToTracingConfig converts the application configuration to telemetry package tracing configuration
+func (c *Config) ToTracingConfig() telemetry.TracingConfig {
+	return telemetry.TracingConfig{
+		Endpoint:    c.Tracing.Endpoint,
+		Insecure:    c.Tracing.Insecure,
+		SampleRatio: c.Tracing.SampleRatio,
+		ServiceName: c.Tracing.ServiceName,
+	}
+}
+
+// ToTLSConfig converts the application configuration to tlsconfig package configuration
+func (c *Config) ToTLSConfig() tlsconfig.Config {
+	return tlsconfig.Config{
+		CertFile:      c.Server.TLSCertFile,
+		KeyFile:       c.Server.TLSKeyFile,
+		ClientCAFile:  c.Server.TLSClientCAFile,
+		ClientAuth:    c.Server.TLSClientAuth,
+		HTTP2:         c.Server.HTTP2,
+		CheckInterval: time.Duration(c.Server.TLSReloadIntervalSeconds) * time.Second,
+	}
+}
+
+// ToAuditConfig converts the application configuration to audit package configuration
+func (c *Config) ToAuditConfig() audit.Config {
+	return audit.Config{
+		Provider:      c.Audit.Provider,
+		JSONLPath:     c.Audit.JSONLPath,
+		SQLite3DBPath: c.Audit.SQLite3DBPath,
+	}
+}
+
+// ToAuthConfig converts the application configuration to auth package configuration
+func (c *Config) ToAuthConfig() auth.Config {
+	return auth.Config{
+		PolicyPath:      c.Auth.PolicyPath,
+		JWKSPath:        c.Auth.JWKSPath,
+		JWTIssuer:       c.Auth.JWTIssuer,
+		JWTAudience:     c.Auth.JWTAudience,
+		JWTUserClaim:    c.Auth.JWTUserClaim,
+		ProxyUserHeader: c.Auth.ProxyUserHeader,
+		TrustedProxies:  c.Auth.TrustedProxies,
 	}
 }
@@ -4,9 +4,13 @@ package config
 
 import (
 	"fmt"
+	"slices"
 	"strings"
 
 	"go-simpler.org/env"
+
+	"github.com/machadovilaca/prometheus-rag/pkg/embeddings"
+	"github.com/machadovilaca/prometheus-rag/pkg/vectordb"
 )
 
 // Config represents the complete application configuration
@@ -25,18 +29,78 @@ type Config struct {
 
 	// LLM configuration
 	LLM LLMConfig
+
+	// Annotations configuration
+	Annotations AnnotationsConfig
+
+	// Few-shot examples configuration
+	Examples ExamplesConfig
+
+	// User feedback configuration
+	Feedback FeedbackConfig
+
+	// Response cache configuration
+	Cache CacheConfig
+
+	// OpenTelemetry tracing configuration
+	Tracing TracingConfig
+
+	// Query audit log configuration
+	Audit AuditConfig
+
+	// API authentication configuration
+	Auth AuthConfig
+
+	// Rate limiting and LLM token budget configuration
+	Quota QuotaConfig
+
+	// Readiness endpoint configuration
+	Readiness ReadinessConfig
 }
 
 // ServerConfig holds server-specific configuration
 type ServerConfig struct {
 	Host string `env:"PRAG_HOST" default:"0.0.0.0"`
 	Port string `env:"PRAG_PORT" default:"8080"`
+
+	// ReadTimeoutSeconds bounds reading a request, headers and body
+	ReadTimeoutSeconds int `env:"PRAG_SERVER_READ_TIMEOUT_SECONDS" default:"30"`
+
+	// WriteTimeoutSeconds bounds handling a request and writing its response, so it must exceed the query time
+	WriteTimeoutSeconds int `env:"PRAG_SERVER_WRITE_TIMEOUT_SECONDS" default:"120"`
+
+	// IdleTimeoutSeconds is how long idle keep-alive connections are kept open
+	IdleTimeoutSeconds int `env:"PRAG_SERVER_IDLE_TIMEOUT_SECONDS" default:"120"`
+
+	// ShutdownTimeoutSeconds is how long in-flight requests are waited for on shutdown
+	ShutdownTimeoutSeconds int `env:"PRAG_SERVER_SHUTDOWN_TIMEOUT_SECONDS" default:"30"`
+
+	// TLSCertFile and TLSKeyFile are the PEM certificate and key served over TLS, empty serves plaintext HTTP
+	TLSCertFile string `env:"PRAG_SERVER_TLS_CERT_FILE"`
+	TLSKeyFile  string `env:"PRAG_SERVER_TLS_KEY_FILE"`
+
+	// TLSClientCAFile is the PEM CA bundle verifying client certificates, empty disables their verification
+	TLSClientCAFile string `env:"PRAG_SERVER_TLS_CLIENT_CA_FILE"`
+
+	// TLSClientAuth is whether clients must send a certificate (require) or may omit it (optional)
+	TLSClientAuth string `env:"PRAG_SERVER_TLS_CLIENT_AUTH" default:"require"`
+
+	// TLSReloadIntervalSeconds is how often the TLS files are checked for changes
+	TLSReloadIntervalSeconds int `env:"PRAG_SERVER_TLS_RELOAD_INTERVAL_SECONDS" default:"10"`
+
+	// HTTP2 enables HTTP/2 over TLS
+	HTTP2 bool `env:"PRAG_SERVER_HTTP2" default:"true"`
 }
 
 // PrometheusConfig holds Prometheus-specific configuration
 type PrometheusConfig struct {
 	Address            string `env:"PRAG_PROMETHEUS_ADDRESS" default:"http://localhost:9090"`
 	RefreshRateMinutes int    `env:"PRAG_PROMETHEUS_REFRESH_RATE_MINUTES" default:"10"`
+
+	// Label discovery during sync
+	LabelsConcurrency int `env:"PRAG_PROMETHEUS_LABELS_CONCURRENCY" default:"8"`
+	LabelsRateLimit   int `env:"PRAG_PROMETHEUS_LABELS_RATE_LIMIT" default:"20"`
+	LabelsBatchSize   int `env:"PRAG_PROMETHEUS_LABELS_BATCH_SIZE" default:"20"`
 }
 
 // VectorDBConfig holds vector database configuration
@@ -45,6 +109,34 @@ type VectorDBConfig struct {
 	Collection string `env:"PRAG_VECTORDB_COLLECTION" default:"prag-metrics"`
 	EncoderDir string `env:"PRAG_VECTORDB_ENCODER_DIR" default:"./_models"`
 
+	// EncoderProvider runs the model in-process (local) or calls an OpenAI-compatible embeddings API (openai)
+	EncoderProvider       string `env:"PRAG_VECTORDB_ENCODER_PROVIDER" default:"local"`
+	EncoderBaseURL        string `env:"PRAG_VECTORDB_ENCODER_BASE_URL"`
+	EncoderAPIKey         string `env:"PRAG_VECTORDB_ENCODER_API_KEY"`
+	EncoderBatchSize      int    `env:"PRAG_VECTORDB_ENCODER_BATCH_SIZE" default:"64"`
+	EncoderTimeoutSeconds int    `env:"PRAG_VECTORDB_ENCODER_TIMEOUT_SECONDS" default:"30"`
+	EncoderMaxRetries     int    `env:"PRAG_VECTORDB_ENCODER_MAX_RETRIES" default:"3"`
+
+	// Embedding model, see the embeddings package for the supported pooling strategies
+	EncoderModel             string `env:"PRAG_VECTORDB_ENCODER_MODEL" default:"sentence-transformers/LaBSE"`
+	EncoderPooling           string `env:"PRAG_VECTORDB_ENCODER_POOLING" default:"mean"`
+	EncoderMaxSequenceLength int    `env:"PRAG_VECTORDB_ENCODER_MAX_SEQUENCE_LENGTH" default:"0"`
+
+	// EncoderDocument is the text embedded for each metric: plain, tokenized or full
+	EncoderDocument string `env:"PRAG_VECTORDB_ENCODER_DOCUMENT" default:"plain"`
+
+	// OnModelMismatch is what to do when the collection was built with another model: fail or reindex
+	OnModelMismatch string `env:"PRAG_VECTORDB_ON_MODEL_MISMATCH" default:"fail"`
+
+	// EncoderWorkers is the number of texts encoded in parallel, 0 uses all CPUs
+	EncoderWorkers int `env:"PRAG_VECTORDB_ENCODER_WORKERS" default:"0"`
+
+	// EncoderCachePath is the SQLite file caching computed embeddings, empty disables the cache
+	EncoderCachePath string `env:"PRAG_VECTORDB_ENCODER_CACHE_PATH" default:"./_data/embeddings-cache.db"`
+
+	// SearchTimeoutSeconds bounds encoding a question and searching the metrics and examples for it, 0 for no limit
+	SearchTimeoutSeconds int `env:"PRAG_VECTORDB_SEARCH_TIMEOUT_SECONDS" default:"10"`
+
 	// SQLite3 specific
 	Sqlite3DBPath string `env:"PRAG_VECTORDB_SQLITE3_DB_PATH" default:"./_data/metrics.db"`
 
@@ -58,6 +150,154 @@ type LLMConfig struct {
 	BaseURL string `env:"PRAG_LLM_BASE_URL" default:"http://localhost:1234/v1/"`
 	APIKey  string `env:"PRAG_LLM_API_KEY"`
 	Model   string `env:"PRAG_LLM_MODEL" default:"granite-3.1-8b-instruct"`
+
+	// TimeoutSeconds bounds each chat completion request, 0 for no limit
+	TimeoutSeconds int `env:"PRAG_LLM_TIMEOUT_SECONDS" default:"60"`
+
+	// Generation of descriptions for metrics with poor help text during sync
+	EnrichDescriptions bool `env:"PRAG_LLM_ENRICH_DESCRIPTIONS" default:"false"`
+	EnrichMinHelpWords int  `env:"PRAG_LLM_ENRICH_MIN_HELP_WORDS" default:"4"`
+	EnrichMaxPerSync   int  `env:"PRAG_LLM_ENRICH_MAX_PER_SYNC" default:"50"`
+	EnrichConcurrency  int  `env:"PRAG_LLM_ENRICH_CONCURRENCY" default:"4"`
+}
+
+// AnnotationsConfig holds the configuration of the team-curated metric annotations
+type AnnotationsConfig struct {
+	// Path is the YAML file annotations are loaded from and saved to, empty keeps them in memory only
+	Path string `env:"PRAG_ANNOTATIONS_PATH"`
+}
+
+// ExamplesConfig holds the configuration of the few-shot example library
+type ExamplesConfig struct {
+	// Collection is the vector database collection holding the examples
+	Collection string `env:"PRAG_EXAMPLES_COLLECTION" default:"prag-examples"`
+
+	// Path is a YAML file of curated examples added to the library on startup
+	Path string `env:"PRAG_EXAMPLES_PATH"`
+
+	// Limit is the number of examples added to each prompt, 0 disables few-shot examples
+	Limit int `env:"PRAG_EXAMPLES_LIMIT" default:"3"`
+
+	// AutoCapture adds the answers approved by users to the library
+	AutoCapture bool `env:"PRAG_EXAMPLES_AUTO_CAPTURE" default:"false"`
+}
+
+// FeedbackConfig holds the configuration of the user feedback loop
+type FeedbackConfig struct {
+	// DBPath is the SQLite file storing queries and feedback, empty disables feedback
+	DBPath string `env:"PRAG_FEEDBACK_DB_PATH" default:"./_data/feedback.db"`
+
+	// SimilarityThreshold is the minimum similarity of past questions whose negative feedback down-weights metrics
+	SimilarityThreshold float64 `env:"PRAG_FEEDBACK_SIMILARITY_THRESHOLD" default:"0.8"`
+
+	// MinNegative is the number of principals rating down similar questions before a metric is down-weighted
+	MinNegative int `env:"PRAG_FEEDBACK_MIN_NEGATIVE" default:"2"`
+
+	// RetentionDays is how long queries without feedback are kept
+	RetentionDays int `env:"PRAG_FEEDBACK_RETENTION_DAYS" default:"30"`
+}
+
+// CacheConfig holds the configuration of the response cache
+type CacheConfig struct {
+	// TTLMinutes is how long generated answers are reused, 0 disables the cache
+	TTLMinutes int `env:"PRAG_CACHE_TTL_MINUTES" default:"10"`
+
+	// SimilarityThreshold is the minimum similarity of a cached question to answer a new one, 1 only reuses exact matches
+	SimilarityThreshold float64 `env:"PRAG_CACHE_SIMILARITY_THRESHOLD" default:"0.95"`
+
+	// MaxEntries is the number of cached answers, the oldest are evicted first
+	MaxEntries int `env:"PRAG_CACHE_MAX_ENTRIES" default:"1000"`
+}
+
+// TracingConfig holds the configuration of the OpenTelemetry trace export
+type TracingConfig struct {
+	// Enabled exports traces to an OTLP collector
+	Enabled bool `env:"PRAG_TRACING_ENABLED" default:"false"`
+
+	// Endpoint is the host:port of the OTLP gRPC collector
+	Endpoint string `env:"PRAG_TRACING_ENDPOINT" default:"localhost:4317"`
+
+	// Insecure disables TLS towards the collector
+	Insecure bool `env:"PRAG_TRACING_INSECURE" default:"true"`
+
+	// SampleRatio is the fraction of new traces that are sampled
+	SampleRatio float64 `env:"PRAG_TRACING_SAMPLE_RATIO" default:"1"`
+
+	// ServiceName identifies the service in the traces
+	ServiceName string `env:"PRAG_TRACING_SERVICE_NAME" default:"prometheus-rag"`
+}
+
+// AuditConfig holds the configuration of the query audit log
+type AuditConfig struct {
+	// Provider is the audit sink (jsonl, sqlite3), empty disables the audit log
+	Provider string `env:"PRAG_AUDIT_PROVIDER" default:""`
+
+	// JSONLPath is the JSON-lines file of the jsonl provider
+	JSONLPath string `env:"PRAG_AUDIT_JSONL_PATH" default:"./_data/audit.jsonl"`
+
+	// SQLite3DBPath is the SQLite file of the sqlite3 provider
+	SQLite3DBPath string `env:"PRAG_AUDIT_SQLITE3_DB_PATH" default:"./_data/audit.db"`
+}
+
+// AuthConfig holds the configuration of the API authentication
+type AuthConfig struct {
+	// Enabled rejects the API requests without valid credentials
+	Enabled bool `env:"PRAG_AUTH_ENABLED" default:"false"`
+
+	// PolicyPath is the YAML file with the API keys and the restrictions of the principals
+	PolicyPath string `env:"PRAG_AUTH_POLICY_PATH"`
+
+	// JWKSPath is the JSON Web Key Set verifying JWT bearer tokens, empty disables JWT authentication
+	JWKSPath string `env:"PRAG_AUTH_JWKS_PATH"`
+
+	// JWTIssuer is the issuer required in the tokens, empty accepts any
+	JWTIssuer string `env:"PRAG_AUTH_JWT_ISSUER"`
+
+	// JWTAudience is the audience required in the tokens, empty accepts any
+	JWTAudience string `env:"PRAG_AUTH_JWT_AUDIENCE"`
+
+	// JWTUserClaim is the token claim holding the name of the principal
+	JWTUserClaim string `env:"PRAG_AUTH_JWT_USER_CLAIM" default:"sub"`
+
+	// ProxyUserHeader is the header holding the user authenticated by a proxy, empty disables proxy authentication
+	ProxyUserHeader string `env:"PRAG_AUTH_PROXY_USER_HEADER"`
+
+	// TrustedProxies are the space-separated addresses or CIDRs of the proxies allowed to set ProxyUserHeader
+	TrustedProxies []string `env:"PRAG_AUTH_TRUSTED_PROXIES"`
+
+	// FailureRateLimit is the failed authentications per second allowed to each client address, 0 disables the limit
+	FailureRateLimit float64 `env:"PRAG_AUTH_FAILURE_RATE_LIMIT" default:"1"`
+
+	// FailureBurst is the number of failed authentications a client address may make at once
+	FailureBurst int `env:"PRAG_AUTH_FAILURE_BURST" default:"10"`
+}
+
+// QuotaConfig holds the configuration of the rate limiting and LLM token budgets
+type QuotaConfig struct {
+	// RateLimit is the requests per second allowed to each client, 0 disables rate limiting
+	RateLimit float64 `env:"PRAG_RATE_LIMIT" default:"0"`
+
+	// RateLimitBurst is the number of requests a client may send at once
+	RateLimitBurst int `env:"PRAG_RATE_LIMIT_BURST" default:"10"`
+
+	// DailyTokens is the LLM tokens each client may spend per UTC day, 0 for no limit
+	DailyTokens int64 `env:"PRAG_QUOTA_DAILY_TOKENS" default:"0"`
+
+	// MonthlyTokens is the LLM tokens each client may spend per UTC month, 0 for no limit
+	MonthlyTokens int64 `env:"PRAG_QUOTA_MONTHLY_TOKENS" default:"0"`
+
+	// DBPath is the SQLite file persisting the token usage, empty keeps it in memory
+	DBPath string `env:"PRAG_QUOTA_DB_PATH" default:"./_data/quota.db"`
+}
+
+// ReadinessConfig holds the configuration of the readiness endpoint
+type ReadinessConfig struct {
+	// Checks are the space-separated dependency checks (vectordb, prometheus, llm, encoder) that
+	// gate readiness, the others are only reported. Empty reports all checks without gating.
+	Checks []string `env:"PRAG_READINESS_CHECKS" default:"vectordb llm encoder"`
+
+	// TimeoutSeconds bounds each dependency check
+	TimeoutSeconds int `env:"PRAG_READINESS_TIMEOUT_SECONDS" default:"5"`
 }
 
 // Load loads configuration from environment variables
@@ -109,6 +349,28 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("server port cannot be empty")
 	}
 
+	if c.Server.ReadTimeoutSeconds <= 0 || c.Server.WriteTimeoutSeconds <= 0 || c.Server.IdleTimeoutSeconds <= 0 {
+		return fmt.Errorf("server read, write and idle timeouts must be greater than 0")
+	}
+
+	if c.Server.ShutdownTimeoutSeconds < 0 {
+		return fmt.Errorf("server shutdown timeout cannot be negative")
+	}
+
+	if (c.Server.TLSCertFile == "") != (c.Server.TLSKeyFile == "") {
+		return fmt.Errorf("server TLS certificate and key files must be set together")
+	}
+	if c.IsTLSEnabled() {
+		if c.Server.TLSClientAuth != "require" && c.Server.TLSClientAuth != "optional" {
+			return fmt.Errorf("unsupported server TLS client auth: %s", c.Server.TLSClientAuth)
+		}
+		if c.Server.TLSReloadIntervalSeconds <= 0 {
+			return fmt.Errorf("server TLS reload interval must be greater than 0")
+		}
+	} else if c.Server.TLSClientCAFile != "" {
+		return fmt.Errorf("server TLS client CA file requires a TLS certificate and key")
+	}
+
 	if c.Prometheus.Address == "" {
 		return fmt.Errorf("prometheus address cannot be empty")
 	}
@@ -117,6 +379,18 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("prometheus refresh rate must be greater than 0")
 	}
 
+	if c.Prometheus.LabelsConcurrency <= 0 {
+		return fmt.Errorf("prometheus labels concurrency must be greater than 0")
+	}
+
+	if c.Prometheus.LabelsRateLimit < 0 {
+		return fmt.Errorf("prometheus labels rate limit cannot be negative")
+	}
+
+	if c.Prometheus.LabelsBatchSize <= 0 {
+		return fmt.Errorf("prometheus labels batch size must be greater than 0")
+	}
+
 	if c.VectorDB.Provider == "" {
 		return fmt.Errorf("vectordb provider cannot be empty")
 	}
@@ -129,8 +403,57 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("vectordb collection cannot be empty")
 	}
 
-	if c.VectorDB.EncoderDir == "" {
-		return fmt.Errorf("vectordb encoder directory cannot be empty")
+	switch strings.ToLower(c.VectorDB.EncoderProvider) {
+	case embeddings.ProviderLocal:
+		if c.VectorDB.EncoderDir == "" {
+			return fmt.Errorf("vectordb encoder directory cannot be empty")
+		}
+	case embeddings.ProviderOpenAI:
+		if c.VectorDB.EncoderBaseURL == "" {
+			return fmt.Errorf("vectordb encoder base URL cannot be empty when using the openai encoder provider")
+		}
+	default:
+		return fmt.Errorf("vectordb encoder provider must be '%s' or '%s'",
+			embeddings.ProviderLocal, embeddings.ProviderOpenAI)
+	}
+
+	if c.VectorDB.EncoderBatchSize <= 0 {
+		return fmt.Errorf("vectordb encoder batch size must be greater than 0")
+	}
+
+	if c.VectorDB.EncoderTimeoutSeconds <= 0 {
+		return fmt.Errorf("vectordb encoder timeout must be greater than 0")
+	}
+
+	if c.VectorDB.EncoderMaxRetries < 0 {
+		return fmt.Errorf("vectordb encoder max retries cannot be negative")
+	}
+
+	if c.VectorDB.EncoderWorkers < 0 {
+		return fmt.Errorf("vectordb encoder workers cannot be negative")
+	}
+
+	if c.VectorDB.EncoderModel == "" {
+		return fmt.Errorf("vectordb encoder model cannot be empty")
+	}
+
+	if _, err := embeddings.ParsePooling(c.VectorDB.EncoderPooling); err != nil {
+		return fmt.Errorf("invalid vectordb encoder pooling: %w", err)
+	}
+
+	if _, err := embeddings.DocumentPreset(c.VectorDB.EncoderDocument); err != nil {
+		return fmt.Errorf("invalid vectordb encoder document: %w", err)
+	}
+
+	if c.VectorDB.EncoderMaxSequenceLength < 0 {
+		return fmt.Errorf("vectordb encoder max sequence length cannot be negative")
+	}
+
+	switch strings.ToLower(c.VectorDB.OnModelMismatch) {
+	case vectordb.ModelMismatchFail, vectordb.ModelMismatchReindex:
+	default:
+		return fmt.Errorf("vectordb on model mismatch must be '%s' or '%s'",
+			vectordb.ModelMismatchFail, vectordb.ModelMismatchReindex)
 	}
 
 	// Validate provider-specific configurations
@@ -158,6 +481,121 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("llm model cannot be empty")
 	}
 
+	if c.LLM.TimeoutSeconds < 0 {
+		return fmt.Errorf("llm timeout seconds cannot be negative")
+	}
+
+	if c.VectorDB.SearchTimeoutSeconds < 0 {
+		return fmt.Errorf("vectordb search timeout seconds cannot be negative")
+	}
+
+	if c.Examples.Collection == "" {
+		return fmt.Errorf("examples collection cannot be empty")
+	}
+
+	if c.Examples.Collection == c.VectorDB.Collection {
+		return fmt.Errorf("examples collection must differ from the vectordb collection")
+	}
+
+	if c.Examples.Limit < 0 {
+		return fmt.Errorf("examples limit cannot be negative")
+	}
+
+	if c.Feedback.DBPath != "" {
+		if c.Feedback.SimilarityThreshold <= 0 || c.Feedback.SimilarityThreshold > 1 {
+			return fmt.Errorf("feedback similarity threshold must be in (0, 1]")
+		}
+		if c.Feedback.MinNegative <= 0 {
+			return fmt.Errorf("feedback min negative must be greater than 0")
+		}
+		if c.Feedback.RetentionDays <= 0 {
+			return fmt.Errorf("feedback retention days must be greater than 0")
+		}
+	}
+
+	if c.Cache.TTLMinutes < 0 {
+		return fmt.Errorf("cache TTL minutes cannot be negative")
+	}
+
+	if c.Cache.TTLMinutes > 0 {
+		if c.Cache.SimilarityThreshold <= 0 || c.Cache.SimilarityThreshold > 1 {
+			return fmt.Errorf("cache similarity threshold must be in (0, 1]")
+		}
+		if c.Cache.MaxEntries <= 0 {
+			return fmt.Errorf("cache max entries must be greater than 0")
+		}
+	}
+
+	if c.Tracing.Enabled {
+		if c.Tracing.Endpoint == "" {
+			return fmt.Errorf("tracing endpoint cannot be empty when tracing is enabled")
+		}
+		if c.Tracing.SampleRatio < 0 || c.Tracing.SampleRatio > 1 {
+			return fmt.Errorf("tracing sample ratio must be in [0, 1]")
+		}
+		if c.Tracing.ServiceName == "" {
+			return fmt.Errorf("tracing service name cannot be empty when tracing is enabled")
+		}
+	}
+
+	switch strings.ToLower(c.Audit.Provider) {
+	case "":
+	case "jsonl":
+		if c.Audit.JSONLPath == "" {
+			return fmt.Errorf("audit JSON-lines path cannot be empty when using the jsonl provider")
+		}
+	case "sqlite3":
+		if c.Audit.SQLite3DBPath == "" {
+			return fmt.Errorf("audit SQLite3 db path cannot be empty when using the sqlite3 provider")
+		}
+	default:
+		return fmt.Errorf("unsupported audit provider: %s", c.Audit.Provider)
+	}
+
+	if c.Auth.Enabled {
+		if c.Auth.PolicyPath == "" && c.Auth.JWKSPath == "" && c.Auth.ProxyUserHeader == "" {
+			return fmt.Errorf("auth requires a policy file with API keys, a JWKS file or a proxy user header")
+		}
+		if c.Auth.ProxyUserHeader != "" && len(c.Auth.TrustedProxies) == 0 {
+			return fmt.Errorf("auth trusted proxies cannot be empty when a proxy user header is set")
+		}
+		if c.Auth.FailureRateLimit < 0 {
+			return fmt.Errorf("auth failure rate limit cannot be negative")
+		}
+		if c.Auth.FailureRateLimit > 0 && c.Auth.FailureBurst <= 0 {
+			return fmt.Errorf("auth failure burst must be greater than 0")
+		}
+	}
+
+	if c.Quota.RateLimit < 0 {
+		return fmt.Errorf("rate limit cannot be negative")
+	}
+	if c.Quota.RateLimit > 0 && c.Quota.RateLimitBurst <= 0 {
+		return fmt.Errorf("rate limit burst must be greater than 0")
+	}
+	if c.Quota.DailyTokens < 0 || c.Quota.MonthlyTokens < 0 {
+		return fmt.Errorf("token quotas cannot be negative")
+	}
+
+	if err := ValidateReadinessChecks(c.Readiness.Checks); err != nil {
+		return err
+	}
+	if c.Readiness.TimeoutSeconds <= 0 {
+		return fmt.Errorf("readiness timeout must be greater than 0")
+	}
+
+	if c.LLM.EnrichDescriptions {
+		if c.LLM.EnrichMinHelpWords <= 0 {
+			return fmt.Errorf("llm enrich min help words must be greater than 0")
+		}
+		if c.LLM.EnrichMaxPerSync < 0 {
+			return fmt.Errorf("llm enrich max per sync cannot be negative")
+		}
+		if c.LLM.EnrichConcurrency <= 0 {
+			return fmt.Errorf("llm enrich concurrency must be greater than 0")
+		}
+	}
+
 	return nil
 }
 
@@ -175,11 +613,30 @@ func ValidateProvider(provider string) error {
 	return fmt.Errorf("unsupported vectordb provider '%s', supported providers: %v", provider, supportedProviders)
 }
 
+// ValidateReadinessChecks validates if the readiness checks are supported
+func ValidateReadinessChecks(checks []string) error {
+	supportedChecks := []string{"vectordb", "prometheus", "llm", "encoder"}
+
+	for _, check := range checks {
+		// An empty list is read as a single empty check
+		if check != "" && !slices.Contains(supportedChecks, check) {
+			return fmt.Errorf("unsupported readiness check '%s', supported checks: %v", check, supportedChecks)
+		}
+	}
+
+	return nil
+}
+
 // GetServerAddress returns the server address in host:port format
 func (c *Config) GetServerAddress() string {
 	return fmt.Sprintf("%s:%s", c.Server.Host, c.Server.Port)
 }
 
+// IsTLSEnabled returns whether the server is served over TLS
+func (c *Config) IsTLSEnabled() bool {
+	return c.Server.TLSCertFile != "" && c.Server.TLSKeyFile != ""
+}
+
 // IsDebugEnabled returns whether debug mode is enabled
 func (c *Config) IsDebugEnabled() bool {
 	return c.Debug
@@ -23,6 +23,9 @@ var _ = Describe("Config", func() {
 				Expect(cfg.Server.Host).To(Equal("0.0.0.0"))
 				Expect(cfg.Server.Port).To(Equal("8080"))
 				Expect(cfg.VectorDB.Provider).To(Equal("sqlite3"))
+				Expect(cfg.VectorDB.EncoderPooling).To(Equal("mean"))
+				Expect(cfg.VectorDB.OnModelMismatch).To(Equal("fail"))
+				Expect(cfg.Readiness.Checks).To(Equal([]string{"vectordb", "llm", "encoder"}))
 			})
 		})
 
@@ -58,6 +61,35 @@ var _ = Describe("Config", func() {
 				Expect(cfg.Server.Port).To(Equal("9000"))
 				Expect(cfg.VectorDB.Provider).To(Equal("qdrant"))
 			})
+
+			It("should load the readiness checks from a space-separated list", func() {
+				setEnvVar("PRAG_READINESS_CHECKS", "prometheus vectordb")
+
+				cfg, err := Load()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(cfg.Readiness.Checks).To(Equal([]string{"prometheus", "vectordb"}))
+			})
+
+			It("should require the TLS certificate and key together", func() {
+				setEnvVar("PRAG_SERVER_TLS_CERT_FILE", "/etc/prag/tls.crt")
+
+				_, err := Load()
+				Expect(err).To(MatchError(ContainSubstring("certificate and key files must be set together")))
+			})
+
+			It("should require TLS for client certificate verification", func() {
+				setEnvVar("PRAG_SERVER_TLS_CLIENT_CA_FILE", "/etc/prag/ca.crt")
+
+				_, err := Load()
+				Expect(err).To(MatchError(ContainSubstring("client CA file requires a TLS certificate and key")))
+			})
+
+			It("should reject unsupported readiness checks", func() {
+				setEnvVar("PRAG_READINESS_CHECKS", "vectordb cache")
+
+				_, err := Load()
+				Expect(err).To(MatchError(ContainSubstring("unsupported readiness check 'cache'")))
+			})
 		})
 	})
 
@@ -166,6 +198,7 @@ var _ = Describe("Config", func() {
 			It("should convert to embeddings config correctly", func() {
 				embeddingsConfig := cfg.ToEmbeddingsConfig()
 				Expect(embeddingsConfig.ModelsDir).To(Equal("./_models"))
+				Expect(embeddingsConfig.ModelName).To(Equal(cfg.VectorDB.EncoderModel))
 			})
 		})
 	})
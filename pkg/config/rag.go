@@ -13,18 +13,69 @@ type RAGConfig struct {
 	PrometheusRefreshRateMinutes int
 	VectorDBConfig               vectordb.Config
 	LLMConfig                    llm.Config
+
+	// EnrichDescriptions enables generating descriptions for metrics with poor help text
+	EnrichDescriptions bool
+	EnrichmentConfig   llm.EnrichmentConfig
+
+	// AnnotationsPath is the YAML file holding the metric annotations
+	AnnotationsPath string
+
+	// ExamplesPath is the YAML file of curated examples added on startup
+	ExamplesPath string
+	// ExamplesAutoCapture adds the answers approved by users to the example library
+	ExamplesAutoCapture bool
+
+	// FeedbackRetentionDays is how long queries without feedback are kept
+	FeedbackRetentionDays int
+
+	// CacheTTLMinutes is how long generated answers are reused, 0 disables the cache
+	CacheTTLMinutes          int
+	CacheSimilarityThreshold float64
+	CacheMaxEntries          int
+
+	// ReadinessChecks are the dependency checks that gate readiness
+	ReadinessChecks []string
+	// ReadinessTimeoutSeconds bounds each dependency check
+	ReadinessTimeoutSeconds int
 }
 
 // ToRAGConfig converts the application configuration to RAG-specific configuration
-func (c *Config) ToRAGConfig(vectorDBClient vectordb.Client) RAGConfig {
+func (c *Config) ToRAGConfig(vectorDBClient vectordb.Client, exampleStore vectordb.ExampleStore) RAGConfig {
 	return RAGConfig{
 		PrometheusAddress:            c.Prometheus.Address,
 		PrometheusRefreshRateMinutes: c.Prometheus.RefreshRateMinutes,
 		VectorDBConfig:               c.ToVectorDBConfig(),
-		LLMConfig:                    c.ToLLMConfig(vectorDBClient),
+		LLMConfig:                    c.ToLLMConfig(vectorDBClient, exampleStore),
+		EnrichDescriptions:           c.LLM.EnrichDescriptions,
+		EnrichmentConfig:             c.ToEnrichmentConfig(),
+		AnnotationsPath:              c.Annotations.Path,
+		ExamplesPath:                 c.Examples.Path,
+		ExamplesAutoCapture:          c.Examples.AutoCapture,
+		FeedbackRetentionDays:        c.Feedback.RetentionDays,
+		CacheTTLMinutes:              c.Cache.TTLMinutes,
+		CacheSimilarityThreshold:     c.Cache.SimilarityThreshold,
+		CacheMaxEntries:              c.Cache.MaxEntries,
+		ReadinessChecks:              c.Readiness.Checks,
+		ReadinessTimeoutSeconds:      c.Readiness.TimeoutSeconds,
 	}
 }
 
+// GetFeedbackRetention returns the feedback retention as time.Duration
+func (r *RAGConfig) GetFeedbackRetention() time.Duration {
+	return time.Duration(r.FeedbackRetentionDays) * 24 * time.Hour
+}
+
+// GetCacheTTL returns the response cache TTL as time.Duration
+func (r *RAGConfig) GetCacheTTL() time.Duration {
+	return time.Duration(r.CacheTTLMinutes) * time.Minute
+}
+
+// GetReadinessTimeout returns the timeout of each readiness check as time.Duration
+func (r *RAGConfig) GetReadinessTimeout() time.Duration {
+	return time.Duration(r.ReadinessTimeoutSeconds) * time.Second
+}
+
 // GetPrometheusRefreshInterval returns the prometheus refresh interval as time.Duration
 func (r *RAGConfig) GetPrometheusRefreshInterval() time.Duration {
 	return time.Duration(r.PrometheusRefreshRateMinutes) * time.Minute
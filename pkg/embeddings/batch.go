@@ -0,0 +1,97 @@
+package embeddings
+
+import (
+	"context"
+	"runtime"
+	"sync"
+
+	"github.com/rs/zerolog/log"
+	"golang.org/x/sync/errgroup"
+)
+
+// ProgressFunc is called while a batch is encoded with the number of texts
+// encoded so far and the total number of texts in the batch
+type ProgressFunc func(done, total int)
+
+// LogProgress is a ProgressFunc that logs every 10% of a batch
+func LogProgress(done, total int) {
+	if total == 0 {
+		return
+	}
+
+	if done == total || done*10/total > (done-1)*10/total {
+		log.Info().Msgf("encoded %d/%d texts (%d%%)", done, total, done*100/total)
+	}
+}
+
+// parallelEncode encodes texts using at most workers goroutines, keeping the
+// order of the input. It stops at the first error or when ctx is done.
+func parallelEncode(
+	ctx context.Context, texts []string, workers int, encode func(ctx context.Context, text string) ([]float32, error), progress ProgressFunc,
+) ([][]float32, error) {
+	vectors := make([][]float32, len(texts))
+	tracker := newProgressTracker(len(texts), progress)
+
+	err := parallelDo(ctx, len(texts), workers, func(ctx context.Context, i int) error {
+		vector, err := encode(ctx, texts[i])
+		if err != nil {
+			return err
+		}
+		vectors[i] = vector
+		tracker.add(1)
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return vectors, nil
+}
+
+// parallelDo runs task for every index in [0, n) using at most workers
+// goroutines. It stops at the first error or when ctx is done.
+func parallelDo(ctx context.Context, n, workers int, task func(ctx context.Context, i int) error) error {
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+
+	g, ctx := errgroup.WithContext(ctx)
+	g.SetLimit(workers)
+
+	for i := range n {
+		g.Go(func() error {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			return task(ctx, i)
+		})
+	}
+
+	return g.Wait()
+}
+
+// progressTracker reports the progress of concurrent tasks to a ProgressFunc
+type progressTracker struct {
+	mu       sync.Mutex
+	done     int
+	total    int
+	progress ProgressFunc
+}
+
+func newProgressTracker(total int, progress ProgressFunc) *progressTracker {
+	return &progressTracker{total: total, progress: progress}
+}
+
+// add records that n more texts were encoded
+func (t *progressTracker) add(n int) {
+	if t.progress == nil {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.done += n
+	t.progress(t.done, t.total)
+}
@@ -0,0 +1,167 @@
+package embeddings
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/machadovilaca/prometheus-rag/pkg/prometheus"
+)
+
+// CacheStore persists vectors by cache key
+type CacheStore interface {
+	// Get returns the vectors stored for the given keys; missing keys are absent from the result
+	Get(keys []string) (map[string][]float32, error)
+
+	// Put stores the given vectors by key
+	Put(entries map[string][]float32) error
+
+	// Close closes the store
+	Close() error
+}
+
+// cachedEncoder wraps an Encoder and reuses previously computed metric
+// metadata vectors. Entries are keyed by the model name and a hash of the
+// normalized text, so changing models never returns stale vectors. Queries
+// are not cached.
+type cachedEncoder struct {
+	Encoder
+
+	store     CacheStore
+	modelName string
+	documents DocumentBuilder
+}
+
+// NewCachedEncoder wraps an encoder with a content-addressed vector cache.
+// documents must be the document builder used by the encoder, so that keys
+// are computed from the text that is actually embedded; nil uses the plain builder.
+func NewCachedEncoder(encoder Encoder, store CacheStore, modelName string, documents DocumentBuilder) Encoder {
+	if documents == nil {
+		documents = NewDocumentBuilder(DocumentOptions{})
+	}
+
+	return &cachedEncoder{
+		Encoder:   encoder,
+		store:     store,
+		modelName: modelName,
+		documents: documents,
+	}
+}
+
+// Close closes the cache store
+func (c *cachedEncoder) Close() error {
+	return c.store.Close()
+}
+
+func (c *cachedEncoder) EncodeMetricMetadata(ctx context.Context, metadata prometheus.MetricMetadata) ([]float32, error) {
+	vectors, err := c.EncodeMetricMetadataBatch(ctx, []prometheus.MetricMetadata{metadata}, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return vectors[0], nil
+}
+
+func (c *cachedEncoder) EncodeBatch(ctx context.Context, texts []string, progress ProgressFunc) ([][]float32, error) {
+	return c.encodeCached(texts, progress, func(misses []int, progress ProgressFunc) ([][]float32, error) {
+		missTexts := make([]string, len(misses))
+		for i, idx := range misses {
+			missTexts[i] = texts[idx]
+		}
+		return c.Encoder.EncodeBatch(ctx, missTexts, progress)
+	})
+}
+
+func (c *cachedEncoder) EncodeMetricMetadataBatch(ctx context.Context, metadata []prometheus.MetricMetadata, progress ProgressFunc) ([][]float32, error) {
+	texts := make([]string, len(metadata))
+	for i, m := range metadata {
+		if err := m.Validate(); err != nil {
+			return nil, fmt.Errorf("invalid metric metadata '%s': %w", m.Name, err)
+		}
+		texts[i] = c.documents.Build(m)
+	}
+
+	return c.encodeCached(texts, progress, func(misses []int, progress ProgressFunc) ([][]float32, error) {
+		missMetadata := make([]prometheus.MetricMetadata, len(misses))
+		for i, idx := range misses {
+			missMetadata[i] = metadata[idx]
+		}
+		return c.Encoder.EncodeMetricMetadataBatch(ctx, missMetadata, progress)
+	})
+}
+
+// encodeCached looks up the vectors of texts in the store, encodes the
+// missing ones with encodeMisses and stores them
+func (c *cachedEncoder) encodeCached(
+	texts []string, progress ProgressFunc, encodeMisses func(misses []int, progress ProgressFunc) ([][]float32, error),
+) ([][]float32, error) {
+	keys := make([]string, len(texts))
+	for i, text := range texts {
+		keys[i] = c.cacheKey(text)
+	}
+
+	cached, err := c.store.Get(keys)
+	if err != nil {
+		log.Warn().Err(err).Msg("failed to read embedding cache, encoding all texts")
+		cached = map[string][]float32{}
+	}
+
+	vectors := make([][]float32, len(texts))
+	var misses []int
+	for i, key := range keys {
+		if vector, ok := cached[key]; ok {
+			vectors[i] = vector
+			continue
+		}
+		misses = append(misses, i)
+	}
+
+	hits := len(texts) - len(misses)
+	if progress != nil && hits > 0 {
+		progress(hits, len(texts))
+	}
+
+	if len(misses) == 0 {
+		return vectors, nil
+	}
+
+	var missProgress ProgressFunc
+	if progress != nil {
+		missProgress = func(done, _ int) {
+			progress(hits+done, len(texts))
+		}
+	}
+
+	encoded, err := encodeMisses(misses, missProgress)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make(map[string][]float32, len(misses))
+	for i, idx := range misses {
+		vectors[idx] = encoded[i]
+		entries[keys[idx]] = encoded[i]
+	}
+
+	if err := c.store.Put(entries); err != nil {
+		log.Warn().Err(err).Msg("failed to write embedding cache")
+	}
+
+	log.Debug().Msgf("embedding cache: %d hits, %d misses", hits, len(misses))
+	return vectors, nil
+}
+
+func (c *cachedEncoder) cacheKey(text string) string {
+	hash := sha256.Sum256([]byte(c.modelName + "\x00" + normalizeText(text)))
+	return hex.EncodeToString(hash[:])
+}
+
+// normalizeText collapses whitespace. Case is kept, as the remote encoders
+// are case-sensitive even though the local one lowercases texts.
+func normalizeText(text string) string {
+	return strings.Join(strings.Fields(text), " ")
+}
@@ -0,0 +1,151 @@
+package embeddings
+
+import (
+	"database/sql"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// sqliteGetBatchSize bounds the number of keys looked up per query
+const sqliteGetBatchSize = 500
+
+type sqliteCacheStore struct {
+	db *sql.DB
+}
+
+// NewSQLiteCacheStore opens (or creates) an embedding cache stored in a SQLite database file
+func NewSQLiteCacheStore(path string) (CacheStore, error) {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create cache directory %s: %w", dir, err)
+	}
+
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open embedding cache: %w", err)
+	}
+
+	_, err = db.Exec(`
+		CREATE TABLE IF NOT EXISTS embedding_cache (
+			key TEXT PRIMARY KEY,
+			vector BLOB NOT NULL,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		)
+	`)
+	if err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("failed to create embedding cache table: %w", err)
+	}
+
+	return &sqliteCacheStore{db: db}, nil
+}
+
+func (s *sqliteCacheStore) Get(keys []string) (map[string][]float32, error) {
+	result := make(map[string][]float32, len(keys))
+
+	for start := 0; start < len(keys); start += sqliteGetBatchSize {
+		batch := keys[start:min(start+sqliteGetBatchSize, len(keys))]
+
+		args := make([]any, len(batch))
+		for i, key := range batch {
+			args[i] = key
+		}
+
+		query := fmt.Sprintf(`SELECT key, vector FROM embedding_cache WHERE key IN (%s)`,
+			strings.TrimSuffix(strings.Repeat("?,", len(batch)), ","))
+
+		if err := s.scanInto(result, query, args); err != nil {
+			return nil, err
+		}
+	}
+
+	return result, nil
+}
+
+func (s *sqliteCacheStore) scanInto(result map[string][]float32, query string, args []any) error {
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return fmt.Errorf("failed to query embedding cache: %w", err)
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	for rows.Next() {
+		var key string
+		var data []byte
+		if err := rows.Scan(&key, &data); err != nil {
+			return fmt.Errorf("failed to scan embedding cache row: %w", err)
+		}
+
+		vector, err := DecodeVector(data)
+		if err != nil {
+			continue
+		}
+		result[key] = vector
+	}
+
+	return rows.Err()
+}
+
+func (s *sqliteCacheStore) Put(entries map[string][]float32) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	stmt, err := tx.Prepare(`INSERT OR REPLACE INTO embedding_cache (key, vector) VALUES (?, ?)`)
+	if err != nil {
+		return fmt.Errorf("failed to prepare statement: %w", err)
+	}
+	defer func() {
+		_ = stmt.Close()
+	}()
+
+	for key, vector := range entries {
+		if _, err := stmt.Exec(key, EncodeVector(vector)); err != nil {
+			return fmt.Errorf("failed to store embedding: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+func (s *sqliteCacheStore) Close() error {
+	return s.db.Close()
+}
+
+// EncodeVector serializes a vector as little-endian float32 values
+func EncodeVector(vector []float32) []byte {
+	buf := make([]byte, len(vector)*4)
+	for i, val := range vector {
+		binary.LittleEndian.PutUint32(buf[i*4:(i+1)*4], math.Float32bits(val))
+	}
+	return buf
+}
+
+// DecodeVector deserializes a vector written by EncodeVector
+func DecodeVector(data []byte) ([]float32, error) {
+	if len(data)%4 != 0 {
+		return nil, fmt.Errorf("invalid vector data length")
+	}
+
+	vector := make([]float32, len(data)/4)
+	for i := range vector {
+		vector[i] = math.Float32frombits(binary.LittleEndian.Uint32(data[i*4 : (i+1)*4]))
+	}
+	return vector, nil
+}
@@ -0,0 +1,155 @@
+package embeddings_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/machadovilaca/prometheus-rag/pkg/embeddings"
+	"github.com/machadovilaca/prometheus-rag/pkg/prometheus"
+)
+
+// countingEncoder returns a vector derived from the text length and counts encoded texts
+type countingEncoder struct {
+	encoded int
+}
+
+func (c *countingEncoder) GetDimension() (int, error) {
+	return 2, nil
+}
+
+func (c *countingEncoder) Model() embeddings.ModelInfo {
+	return embeddings.ModelInfo{Name: "mock", Pooling: embeddings.PoolingMean, Dimension: 2}
+}
+
+func (c *countingEncoder) EncodeQuery(ctx context.Context, query string) ([]float32, error) {
+	c.encoded++
+	return []float32{float32(len(query)), 1}, nil
+}
+
+func (c *countingEncoder) EncodeMetricMetadata(ctx context.Context, metadata prometheus.MetricMetadata) ([]float32, error) {
+	return c.EncodeQuery(ctx, metadata.Name+" "+metadata.Help)
+}
+
+func (c *countingEncoder) EncodeBatch(ctx context.Context, texts []string, progress embeddings.ProgressFunc) ([][]float32, error) {
+	vectors := make([][]float32, len(texts))
+	for i, text := range texts {
+		vectors[i], _ = c.EncodeQuery(ctx, text)
+		if progress != nil {
+			progress(i+1, len(texts))
+		}
+	}
+	return vectors, nil
+}
+
+func (c *countingEncoder) EncodeMetricMetadataBatch(ctx context.Context, metadata []prometheus.MetricMetadata, progress embeddings.ProgressFunc) ([][]float32, error) {
+	texts := make([]string, len(metadata))
+	for i, m := range metadata {
+		texts[i] = m.Name + " " + m.Help
+	}
+	return c.EncodeBatch(ctx, texts, progress)
+}
+
+var _ = Describe("Cache", func() {
+	var (
+		tempDir   string
+		cachePath string
+		inner     *countingEncoder
+		store     embeddings.CacheStore
+	)
+
+	BeforeEach(func() {
+		var err error
+		tempDir, err = os.MkdirTemp("", "embeddings_cache_test_*")
+		Expect(err).NotTo(HaveOccurred())
+
+		cachePath = filepath.Join(tempDir, "cache.db")
+		store, err = embeddings.NewSQLiteCacheStore(cachePath)
+		Expect(err).NotTo(HaveOccurred())
+
+		inner = &countingEncoder{}
+	})
+
+	AfterEach(func() {
+		Expect(store.Close()).To(Succeed())
+		Expect(os.RemoveAll(tempDir)).To(Succeed())
+	})
+
+	metadata := []prometheus.MetricMetadata{
+		{Name: "up", Help: "Target is up"},
+		{Name: "kubevirt_vmi_phase_count", Help: "VMIs per phase"},
+	}
+
+	It("should only encode metric metadata once", func() {
+		encoder := embeddings.NewCachedEncoder(inner, store, "model-a", nil)
+
+		first, err := encoder.EncodeMetricMetadataBatch(context.Background(), metadata, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(inner.encoded).To(Equal(2))
+
+		second, err := encoder.EncodeMetricMetadataBatch(context.Background(), metadata, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(inner.encoded).To(Equal(2))
+		Expect(second).To(Equal(first))
+
+		single, err := encoder.EncodeMetricMetadata(context.Background(), metadata[1])
+		Expect(err).NotTo(HaveOccurred())
+		Expect(inner.encoded).To(Equal(2))
+		Expect(single).To(Equal(first[1]))
+	})
+
+	It("should persist vectors across encoders", func() {
+		_, err := embeddings.NewCachedEncoder(inner, store, "model-a", nil).EncodeMetricMetadataBatch(context.Background(), metadata, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(store.Close()).To(Succeed())
+
+		store, err = embeddings.NewSQLiteCacheStore(cachePath)
+		Expect(err).NotTo(HaveOccurred())
+
+		restarted := &countingEncoder{}
+		_, err = embeddings.NewCachedEncoder(restarted, store, "model-a", nil).EncodeMetricMetadataBatch(context.Background(), metadata, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(restarted.encoded).To(BeZero())
+	})
+
+	It("should key vectors by model name", func() {
+		_, err := embeddings.NewCachedEncoder(inner, store, "model-a", nil).EncodeMetricMetadataBatch(context.Background(), metadata, nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = embeddings.NewCachedEncoder(inner, store, "model-b", nil).EncodeMetricMetadataBatch(context.Background(), metadata, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(inner.encoded).To(Equal(4))
+	})
+
+	It("should normalize whitespace but not case in texts", func() {
+		encoder := embeddings.NewCachedEncoder(inner, store, "model-a", nil)
+
+		_, err := encoder.EncodeBatch(context.Background(), []string{"CPU  usage"}, nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		var calls [][2]int
+		vectors, err := encoder.EncodeBatch(context.Background(), []string{"CPU usage", "memory usage"}, func(done, total int) {
+			calls = append(calls, [2]int{done, total})
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(vectors).To(HaveLen(2))
+		Expect(inner.encoded).To(Equal(2))
+		Expect(calls).To(Equal([][2]int{{1, 2}, {2, 2}}))
+
+		// Remote models are case-sensitive, so texts differing in case are encoded separately
+		_, err = encoder.EncodeBatch(context.Background(), []string{"cpu usage"}, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(inner.encoded).To(Equal(3))
+	})
+
+	It("should reject invalid metric metadata", func() {
+		encoder := embeddings.NewCachedEncoder(inner, store, "model-a", nil)
+
+		_, err := encoder.EncodeMetricMetadata(context.Background(), prometheus.MetricMetadata{})
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("name is required"))
+	})
+})
@@ -0,0 +1,270 @@
+package embeddings
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/machadovilaca/prometheus-rag/pkg/prometheus"
+)
+
+// DocumentBuilder builds the text that is embedded for a metric. Builders use
+// the generated description of a metric instead of its help text when present,
+// and always include its curated annotations.
+type DocumentBuilder interface {
+	// Build returns the text embedded for the given metric metadata
+	Build(metadata prometheus.MetricMetadata) string
+}
+
+// Document builder presets
+const (
+	// DocumentPlain embeds the metric name and help as-is
+	DocumentPlain = "plain"
+	// DocumentTokenized splits the metric name into words and expands abbreviations
+	DocumentTokenized = "tokenized"
+	// DocumentFull is DocumentTokenized plus the type, unit and label names
+	DocumentFull = "full"
+)
+
+// DocumentOptions configures what the document of a metric is made of
+type DocumentOptions struct {
+	// SplitName splits snake_case and colon separated names into words
+	SplitName bool
+	// ExpandAbbreviations appends the meaning of known abbreviations found in the name
+	ExpandAbbreviations bool
+
+	IncludeType   bool
+	IncludeUnit   bool
+	IncludeLabels bool
+}
+
+// abbreviations maps name tokens commonly found in metric names to their meaning
+var abbreviations = map[string]string{
+	"vm":     "virtual machine",
+	"vmi":    "virtual machine instance",
+	"vmis":   "virtual machine instances",
+	"cpu":    "processor",
+	"mem":    "memory",
+	"rss":    "resident set size memory",
+	"gc":     "garbage collection",
+	"fs":     "filesystem",
+	"io":     "input output",
+	"net":    "network",
+	"rx":     "received",
+	"tx":     "transmitted",
+	"req":    "requests",
+	"reqs":   "requests",
+	"err":    "errors",
+	"errs":   "errors",
+	"conn":   "connections",
+	"conns":  "connections",
+	"ops":    "operations",
+	"tcp":    "transmission control protocol network",
+	"dns":    "domain name system",
+	"api":    "application programming interface",
+	"pvc":    "persistent volume claim",
+	"k8s":    "kubernetes",
+	"kvm":    "kernel virtual machine",
+	"qps":    "queries per second",
+	"rps":    "requests per second",
+	"avg":    "average",
+	"max":    "maximum",
+	"min":    "minimum",
+	"sec":    "seconds",
+	"ms":     "milliseconds",
+	"p50":    "50th percentile median",
+	"p90":    "90th percentile",
+	"p95":    "95th percentile",
+	"p99":    "99th percentile",
+	"p999":   "99.9th percentile",
+	"oom":    "out of memory",
+	"hpa":    "horizontal pod autoscaler",
+	"etcd":   "key value store",
+	"sched":  "scheduler",
+	"alloc":  "allocated",
+	"allocs": "allocations",
+}
+
+// documentPresetName returns the canonical name of a document builder preset
+func documentPresetName(name string) string {
+	if name == "" {
+		return DocumentPlain
+	}
+	return strings.ToLower(name)
+}
+
+// DocumentPreset returns the options of a document builder preset
+func DocumentPreset(name string) (DocumentOptions, error) {
+	switch strings.ToLower(name) {
+	case "", DocumentPlain:
+		return DocumentOptions{}, nil
+	case DocumentTokenized:
+		return DocumentOptions{SplitName: true, ExpandAbbreviations: true}, nil
+	case DocumentFull:
+		return DocumentOptions{
+			SplitName:           true,
+			ExpandAbbreviations: true,
+			IncludeType:         true,
+			IncludeUnit:         true,
+			IncludeLabels:       true,
+		}, nil
+	default:
+		return DocumentOptions{}, fmt.Errorf("unsupported document builder '%s', supported builders: %s, %s, %s",
+			name, DocumentPlain, DocumentTokenized, DocumentFull)
+	}
+}
+
+type documentBuilder struct {
+	options DocumentOptions
+}
+
+// NewDocumentBuilder creates a document builder with the given options. The
+// zero options build the plain "name help" document.
+func NewDocumentBuilder(options DocumentOptions) DocumentBuilder {
+	return &documentBuilder{options: options}
+}
+
+func (b *documentBuilder) Build(metadata prometheus.MetricMetadata) string {
+	help := metadata.Help
+	if metadata.Description != "" {
+		help = metadata.Description
+	}
+
+	if b.options == (DocumentOptions{}) {
+		document := fmt.Sprintf("%s %s", metadata.Name, help)
+		for _, sentence := range annotationSentences(metadata.Annotations) {
+			document += " " + sentence + "."
+		}
+		return document
+	}
+
+	var sb strings.Builder
+	sb.WriteString(metadata.Name)
+
+	if b.options.SplitName || b.options.ExpandAbbreviations {
+		words := nameWords(metadata.Name)
+		if b.options.SplitName {
+			sb.WriteString(" (")
+			sb.WriteString(strings.Join(words, " "))
+			sb.WriteString(")")
+		}
+		if b.options.ExpandAbbreviations {
+			if expanded := expandAbbreviations(words); len(expanded) > 0 {
+				sb.WriteString(" [")
+				sb.WriteString(strings.Join(expanded, ", "))
+				sb.WriteString("]")
+			}
+		}
+	}
+
+	if help != "" {
+		sb.WriteString(". ")
+		sb.WriteString(strings.TrimSuffix(help, "."))
+	}
+
+	if b.options.IncludeType && metadata.Type != "" && metadata.Type != prometheus.MetricTypeUnknown {
+		sb.WriteString(". Type: ")
+		sb.WriteString(metadata.Type)
+	}
+
+	if b.options.IncludeUnit && metadata.Unit != "" {
+		sb.WriteString(". Unit: ")
+		sb.WriteString(metadata.Unit)
+	}
+
+	if b.options.IncludeLabels {
+		if labels := documentLabels(metadata.Labels); len(labels) > 0 {
+			sb.WriteString(". Labels: ")
+			sb.WriteString(strings.Join(labels, ", "))
+		}
+	}
+
+	for _, sentence := range annotationSentences(metadata.Annotations) {
+		sb.WriteString(". ")
+		sb.WriteString(sentence)
+	}
+
+	return sb.String()
+}
+
+// annotationSentences returns the curated description and synonyms of a
+// metric as sentences, without final periods, to append to its document
+func annotationSentences(annotations *prometheus.Annotations) []string {
+	if annotations == nil {
+		return nil
+	}
+
+	var sentences []string
+	if annotations.Description != "" {
+		sentences = append(sentences, strings.TrimSuffix(annotations.Description, "."))
+	}
+
+	if len(annotations.Synonyms) > 0 {
+		sentences = append(sentences, "Also known as: "+strings.Join(annotations.Synonyms, ", "))
+	}
+
+	return sentences
+}
+
+// nameWords splits a metric name on underscores, colons and camelCase boundaries
+func nameWords(name string) []string {
+	var (
+		words   []string
+		current []rune
+	)
+
+	flush := func() {
+		if len(current) > 0 {
+			words = append(words, strings.ToLower(string(current)))
+			current = current[:0]
+		}
+	}
+
+	runes := []rune(name)
+	for i, r := range runes {
+		switch {
+		case r == '_' || r == ':' || r == '.' || r == '-':
+			flush()
+		case unicode.IsUpper(r) && i > 0 && unicode.IsLower(runes[i-1]):
+			flush()
+			current = append(current, r)
+		default:
+			current = append(current, r)
+		}
+	}
+	flush()
+
+	return words
+}
+
+// expandAbbreviations returns the meaning of the known abbreviations in words, without duplicates
+func expandAbbreviations(words []string) []string {
+	seen := make(map[string]struct{})
+	var expanded []string
+
+	for _, word := range words {
+		meaning, ok := abbreviations[word]
+		if !ok {
+			continue
+		}
+		if _, ok := seen[meaning]; ok {
+			continue
+		}
+		seen[meaning] = struct{}{}
+		expanded = append(expanded, word+": "+meaning)
+	}
+
+	return expanded
+}
+
+// documentLabels returns the label names worth embedding, leaving out internal ones
+func documentLabels(labels []string) []string {
+	var result []string
+	for _, label := range labels {
+		if strings.HasPrefix(label, "__") {
+			continue
+		}
+		result = append(result, label)
+	}
+	return result
+}
@@ -0,0 +1,169 @@
+package embeddings_test
+
+import (
+	"context"
+	"hash/fnv"
+	"strings"
+	"unicode"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/machadovilaca/prometheus-rag/pkg/embeddings"
+	"github.com/machadovilaca/prometheus-rag/pkg/prometheus"
+)
+
+// bagOfWordsEncoder embeds texts as hashed word counts, keeping snake_case
+// names as a single word like a tokenizer unaware of metric naming would
+type bagOfWordsEncoder struct{}
+
+const bagOfWordsDimension = 4096
+
+func (b *bagOfWordsEncoder) GetDimension() (int, error) {
+	return bagOfWordsDimension, nil
+}
+
+func (b *bagOfWordsEncoder) Model() embeddings.ModelInfo {
+	return embeddings.ModelInfo{Name: "bag-of-words", Dimension: bagOfWordsDimension}
+}
+
+func (b *bagOfWordsEncoder) EncodeQuery(ctx context.Context, query string) ([]float32, error) {
+	vector := make([]float32, bagOfWordsDimension)
+	words := strings.FieldsFunc(strings.ToLower(query), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_'
+	})
+	for _, word := range words {
+		h := fnv.New32a()
+		_, _ = h.Write([]byte(word))
+		vector[h.Sum32()%bagOfWordsDimension]++
+	}
+	return vector, nil
+}
+
+func (b *bagOfWordsEncoder) EncodeMetricMetadata(ctx context.Context, metadata prometheus.MetricMetadata) ([]float32, error) {
+	return b.EncodeQuery(ctx, metadata.Name+" "+metadata.Help)
+}
+
+func (b *bagOfWordsEncoder) EncodeBatch(ctx context.Context, texts []string, _ embeddings.ProgressFunc) ([][]float32, error) {
+	vectors := make([][]float32, len(texts))
+	for i, text := range texts {
+		vectors[i], _ = b.EncodeQuery(ctx, text)
+	}
+	return vectors, nil
+}
+
+func (b *bagOfWordsEncoder) EncodeMetricMetadataBatch(ctx context.Context, metadata []prometheus.MetricMetadata, progress embeddings.ProgressFunc) ([][]float32, error) {
+	texts := make([]string, len(metadata))
+	for i, m := range metadata {
+		texts[i] = m.Name + " " + m.Help
+	}
+	return b.EncodeBatch(ctx, texts, progress)
+}
+
+var _ = Describe("Documents", func() {
+	metric := prometheus.MetricMetadata{
+		Name:   "kubevirt_vmi_memory_resident_bytes",
+		Help:   "Resident memory of the VMI process.",
+		Type:   prometheus.MetricTypeGauge,
+		Unit:   "bytes",
+		Labels: []string{"__name__", "namespace", "node"},
+	}
+
+	build := func(preset string) string {
+		options, err := embeddings.DocumentPreset(preset)
+		Expect(err).NotTo(HaveOccurred())
+		return embeddings.NewDocumentBuilder(options).Build(metric)
+	}
+
+	It("should keep the plain document as name and help", func() {
+		Expect(build(embeddings.DocumentPlain)).To(Equal("kubevirt_vmi_memory_resident_bytes Resident memory of the VMI process."))
+	})
+
+	It("should split the name and expand abbreviations", func() {
+		Expect(build(embeddings.DocumentTokenized)).To(Equal(
+			"kubevirt_vmi_memory_resident_bytes (kubevirt vmi memory resident bytes) " +
+				"[vmi: virtual machine instance]. Resident memory of the VMI process",
+		))
+	})
+
+	It("should include the type, unit and labels", func() {
+		Expect(build(embeddings.DocumentFull)).To(HaveSuffix(
+			". Type: gauge. Unit: bytes. Labels: namespace, node",
+		))
+	})
+
+	It("should split recording rule and camelCase names", func() {
+		document := embeddings.NewDocumentBuilder(embeddings.DocumentOptions{SplitName: true}).Build(
+			prometheus.MetricMetadata{Name: "job:cpuUsage:p99"},
+		)
+		Expect(document).To(Equal("job:cpuUsage:p99 (job cpu usage p99)"))
+	})
+
+	It("should not expand ambiguous unit suffixes", func() {
+		document := embeddings.NewDocumentBuilder(embeddings.DocumentOptions{SplitName: true, ExpandAbbreviations: true}).Build(
+			prometheus.MetricMetadata{Name: "request_duration_ns"},
+		)
+		Expect(document).To(Equal("request_duration_ns (request duration ns)"))
+	})
+
+	It("should embed the generated description instead of the help", func() {
+		described := metric
+		described.Description = "Memory used by the virtual machine instance."
+		document := embeddings.NewDocumentBuilder(embeddings.DocumentOptions{}).Build(described)
+		Expect(document).To(Equal("kubevirt_vmi_memory_resident_bytes Memory used by the virtual machine instance."))
+	})
+
+	It("should include curated annotations", func() {
+		annotated := metric
+		annotated.Annotations = &prometheus.Annotations{
+			Synonyms:    []string{"guest memory"},
+			Description: "Memory as seen by the guest.",
+		}
+		document := embeddings.NewDocumentBuilder(embeddings.DocumentOptions{}).Build(annotated)
+		Expect(document).To(HaveSuffix("process. Memory as seen by the guest. Also known as: guest memory."))
+	})
+
+	It("should reject unknown presets", func() {
+		_, err := embeddings.DocumentPreset("verbose")
+		Expect(err).To(HaveOccurred())
+	})
+
+	Context("EvaluateDocuments", func() {
+		metrics := []prometheus.MetricMetadata{
+			{Name: "process_open_fds", Help: "Number of open file descriptors."},
+			{Name: "go_gc_duration_seconds", Help: "A summary of the pause duration of GC cycles."},
+			{Name: "kubevirt_vmi_phase_count", Help: "Sum of VMIs per phase and node."},
+		}
+
+		cases := []embeddings.EvalCase{
+			{Query: "garbage collection pauses", Expected: []string{"go_gc_duration_seconds"}},
+			{Query: "open file descriptors", Expected: []string{"process_open_fds"}},
+		}
+
+		It("should compare document variants", func() {
+			tokenized, _ := embeddings.DocumentPreset(embeddings.DocumentTokenized)
+
+			results, err := embeddings.EvaluateDocuments(context.Background(), &bagOfWordsEncoder{}, map[string]embeddings.DocumentBuilder{
+				embeddings.DocumentPlain:     embeddings.NewDocumentBuilder(embeddings.DocumentOptions{}),
+				embeddings.DocumentTokenized: embeddings.NewDocumentBuilder(tokenized),
+			}, metrics, cases, 1)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(results).To(HaveLen(2))
+
+			plain, expanded := results[0], results[1]
+			Expect(plain.Variant).To(Equal(embeddings.DocumentPlain))
+			Expect(plain.RecallAtK).To(Equal(0.5))
+			Expect(plain.Misses).To(ConsistOf("garbage collection pauses"))
+
+			Expect(expanded.Variant).To(Equal(embeddings.DocumentTokenized))
+			Expect(expanded.RecallAtK).To(Equal(1.0))
+			Expect(expanded.MRR).To(Equal(1.0))
+			Expect(expanded.Misses).To(BeEmpty())
+		})
+
+		It("should require a positive k", func() {
+			_, err := embeddings.EvaluateDocuments(context.Background(), &bagOfWordsEncoder{}, nil, metrics, cases, 0)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+})
@@ -4,10 +4,12 @@ import (
 	"context"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/nlpodyssey/cybertron/pkg/models/bert"
 	"github.com/nlpodyssey/cybertron/pkg/tasks"
 	"github.com/nlpodyssey/cybertron/pkg/tasks/textencoding"
+	bertencoding "github.com/nlpodyssey/cybertron/pkg/tasks/textencoding/bert"
 
 	"github.com/machadovilaca/prometheus-rag/pkg/prometheus"
 )
@@ -17,31 +19,123 @@ const (
 	modelName = textencoding.DefaultModelMulti
 )
 
+// Encoder providers
+const (
+	// ProviderLocal runs the model in-process from the models directory
+	ProviderLocal = "local"
+	// ProviderOpenAI calls an OpenAI-compatible /embeddings endpoint
+	ProviderOpenAI = "openai"
+)
+
 // Encoder is an interface for encoding queries and metric metadata
 type Encoder interface {
 	// GetDimension returns the dimension of the encoded vector
 	GetDimension() (int, error)
 
+	// Model returns the identity of the model configuration used to encode
+	Model() ModelInfo
+
 	// EncodeQuery encodes a query into a vector
-	EncodeQuery(query string) ([]float32, error)
+	EncodeQuery(ctx context.Context, query string) ([]float32, error)
 
 	// EncodeMetricMetadata encodes a metric metadata into a vector
-	EncodeMetricMetadata(metadata prometheus.MetricMetadata) ([]float32, error)
+	EncodeMetricMetadata(ctx context.Context, metadata prometheus.MetricMetadata) ([]float32, error)
+
+	// EncodeBatch encodes texts in parallel, returning the vectors in the same
+	// order. It stops at the first error or when ctx is done.
+	EncodeBatch(ctx context.Context, texts []string, progress ProgressFunc) ([][]float32, error)
+
+	// EncodeMetricMetadataBatch encodes metric metadata entries in parallel,
+	// returning the vectors in the same order
+	EncodeMetricMetadataBatch(ctx context.Context, metadata []prometheus.MetricMetadata, progress ProgressFunc) ([][]float32, error)
 }
 
 // Config is the configuration for the encoder
 type Config struct {
+	// Provider is the encoder implementation, ProviderLocal or ProviderOpenAI, defaults to ProviderLocal
+	Provider string
+
 	ModelsDir string
 	ModelName string
+
+	// BaseURL and APIKey address the embeddings API of the ProviderOpenAI provider
+	BaseURL string
+	APIKey  string
+	// BatchSize is the number of texts sent per embeddings API request
+	BatchSize int
+	// Timeout is the timeout of each embeddings API request
+	Timeout time.Duration
+	// MaxRetries is the number of times a failed embeddings API request is retried
+	MaxRetries int
+
+	// Pooling is the pooling strategy (mean, cls, max or mean-max), defaults to mean
+	Pooling string
+
+	// MaxSequenceLength truncates longer inputs to this many tokens, defaults
+	// to (and is capped at) the maximum supported by the model
+	MaxSequenceLength int
+
+	// Document is the document builder preset (plain, tokenized or full), defaults to plain
+	Document string
+
+	// Workers is the maximum number of texts encoded in parallel, defaults to the number of CPUs
+	Workers int
+
+	// CachePath is the SQLite file used to cache metric metadata vectors, empty disables the cache
+	CachePath string
 }
 
 type encoder struct {
-	config *tasks.Config
-	model  textencoding.Interface
+	config  *tasks.Config
+	model   textencoding.Interface
+	workers int
+
+	pooling           bert.PoolingStrategyType
+	maxSequenceLength int
+	info              ModelInfo
+	documents         DocumentBuilder
 }
 
 // NewEncoder creates a new encoder
 func NewEncoder(config Config) (Encoder, error) {
+	documentOptions, err := DocumentPreset(config.Document)
+	if err != nil {
+		return nil, err
+	}
+	documents := NewDocumentBuilder(documentOptions)
+
+	var (
+		e        Encoder
+		provider = strings.ToLower(config.Provider)
+	)
+
+	switch provider {
+	case "", ProviderLocal:
+		provider = ProviderLocal
+		e, err = newLocalEncoder(config, documents)
+	case ProviderOpenAI:
+		e, err = newRemoteEncoder(config, documents)
+	default:
+		return nil, fmt.Errorf("unsupported encoder provider '%s'", config.Provider)
+	}
+	if err != nil {
+		return nil, err
+	}
+	e = newMemoizingEncoder(newInstrumentedEncoder(e, provider))
+
+	if config.CachePath != "" {
+		store, err := NewSQLiteCacheStore(config.CachePath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open embedding cache: %w", err)
+		}
+		e = NewCachedEncoder(e, store, e.Model().ID(), documents)
+	}
+
+	return e, nil
+}
+
+// newLocalEncoder creates an encoder running the model in-process
+func newLocalEncoder(config Config, documents DocumentBuilder) (*encoder, error) {
 	if config.ModelsDir == "" {
 		config.ModelsDir = modelsDir
 	}
@@ -50,6 +144,15 @@ func NewEncoder(config Config) (Encoder, error) {
 		config.ModelName = modelName
 	}
 
+	if config.Pooling == "" {
+		config.Pooling = PoolingMean
+	}
+
+	pooling, err := ParsePooling(config.Pooling)
+	if err != nil {
+		return nil, err
+	}
+
 	tasksConfig := &tasks.Config{
 		ModelsDir: config.ModelsDir,
 		ModelName: config.ModelName,
@@ -60,11 +163,58 @@ func NewEncoder(config Config) (Encoder, error) {
 		return nil, err
 	}
 
-	return &encoder{config: tasksConfig, model: m}, nil
+	enc := &encoder{
+		config:            tasksConfig,
+		model:             m,
+		workers:           config.Workers,
+		pooling:           pooling,
+		maxSequenceLength: config.MaxSequenceLength,
+		documents:         documents,
+	}
+
+	dimension, err := enc.dimension()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get encoding dimension: %w", err)
+	}
+
+	if bertModel, ok := m.(*bertencoding.TextEncoding); ok {
+		modelMax := bertModel.Model.Bert.Config.MaxPositionEmbeddings
+		if enc.maxSequenceLength <= 0 || enc.maxSequenceLength > modelMax {
+			enc.maxSequenceLength = modelMax
+		}
+	}
+
+	enc.info = ModelInfo{
+		Name:              config.ModelName,
+		Pooling:           strings.ToLower(config.Pooling),
+		Dimension:         dimension,
+		MaxSequenceLength: enc.maxSequenceLength,
+		Document:          documentPresetName(config.Document),
+	}
+
+	return enc, nil
 }
 
 func (e *encoder) GetDimension() (int, error) {
-	result, err := e.model.Encode(context.Background(), "", int(bert.MeanPooling))
+	return e.info.Dimension, nil
+}
+
+func (e *encoder) Model() ModelInfo {
+	return e.info
+}
+
+// dimension reads the size of the encoded vectors from the model configuration,
+// falling back to encoding an empty text for models of unknown layout
+func (e *encoder) dimension() (int, error) {
+	if bertModel, ok := e.model.(*bertencoding.TextEncoding); ok {
+		size := bertModel.Model.Bert.Config.HiddenSize
+		if e.pooling == bert.MeanMaxPooling {
+			size *= 2
+		}
+		return size, nil
+	}
+
+	result, err := e.model.Encode(context.Background(), "", int(e.pooling))
 	if err != nil {
 		return 0, err
 	}
@@ -72,23 +222,41 @@ func (e *encoder) GetDimension() (int, error) {
 	return len(result.Vector.Data().F32()), nil
 }
 
-func (e *encoder) EncodeQuery(query string) ([]float32, error) {
-	result, err := e.model.Encode(context.Background(), lowercase(query), int(bert.MeanPooling))
-	if err != nil {
-		return nil, err
-	}
-
-	return result.Vector.Data().F32(), nil
+func (e *encoder) EncodeQuery(ctx context.Context, query string) ([]float32, error) {
+	return e.encode(ctx, query)
 }
 
-func (e *encoder) EncodeMetricMetadata(metadata prometheus.MetricMetadata) ([]float32, error) {
+func (e *encoder) EncodeMetricMetadata(ctx context.Context, metadata prometheus.MetricMetadata) ([]float32, error) {
 	if err := metadata.Validate(); err != nil {
 		return nil, fmt.Errorf("invalid metric metadata: %w", err)
 	}
 
-	text := fmt.Sprintf("%s %s", metadata.Name, metadata.Help)
+	return e.encode(ctx, e.documents.Build(metadata))
+}
+
+func (e *encoder) EncodeBatch(ctx context.Context, texts []string, progress ProgressFunc) ([][]float32, error) {
+	return parallelEncode(ctx, texts, e.workers, e.encode, progress)
+}
+
+func (e *encoder) EncodeMetricMetadataBatch(ctx context.Context, metadata []prometheus.MetricMetadata, progress ProgressFunc) ([][]float32, error) {
+	texts := make([]string, len(metadata))
+	for i, m := range metadata {
+		if err := m.Validate(); err != nil {
+			return nil, fmt.Errorf("invalid metric metadata '%s': %w", m.Name, err)
+		}
+		texts[i] = e.documents.Build(m)
+	}
 
-	result, err := e.model.Encode(context.Background(), lowercase(text), int(bert.MeanPooling))
+	return e.EncodeBatch(ctx, texts, progress)
+}
+
+func (e *encoder) encode(ctx context.Context, text string) ([]float32, error) {
+	// The model does not check the context, so a cancelled request is not encoded
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	result, err := e.model.Encode(ctx, e.truncate(lowercase(text)), int(e.pooling))
 	if err != nil {
 		return nil, err
 	}
@@ -96,6 +264,28 @@ func (e *encoder) EncodeMetricMetadata(metadata prometheus.MetricMetadata) ([]fl
 	return result.Vector.Data().F32(), nil
 }
 
+// truncate shortens text so that it fits in the maximum sequence length,
+// accounting for the [CLS] and [SEP] tokens added by the model
+func (e *encoder) truncate(text string) string {
+	bertModel, ok := e.model.(*bertencoding.TextEncoding)
+	if !ok || e.maxSequenceLength <= 2 {
+		return text
+	}
+
+	tokens := bertModel.Tokenizer.Tokenize(text)
+	maxTokens := e.maxSequenceLength - 2
+	if len(tokens) <= maxTokens {
+		return text
+	}
+
+	runes := []rune(text)
+	end := tokens[maxTokens-1].Offsets.End
+	if end > len(runes) {
+		return text
+	}
+	return string(runes[:end])
+}
+
 func lowercase(s string) string {
 	return strings.ToLower(s)
 }
@@ -1,6 +1,8 @@
 package embeddings_test
 
 import (
+	"context"
+
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 
@@ -36,7 +38,7 @@ var _ = Describe("Encoder", func() {
 		It("should encode query", func() {
 			query := "test query"
 
-			vector, err := encoder.EncodeQuery(query)
+			vector, err := encoder.EncodeQuery(context.Background(), query)
 			Expect(err).NotTo(HaveOccurred())
 			Expect(vector).NotTo(BeNil())
 			Expect(len(vector)).To(Equal(bertDimension))
@@ -50,9 +52,51 @@ var _ = Describe("Encoder", func() {
 				Labels: []string{"label1", "label2"},
 			}
 
-			vector, err := encoder.EncodeMetricMetadata(metadata)
+			vector, err := encoder.EncodeMetricMetadata(context.Background(), metadata)
 			Expect(err).NotTo(HaveOccurred())
 			Expect(vector).NotTo(BeNil())
 		})
 	})
+
+	Context("EncodeBatch", func() {
+		It("should encode texts in order and report progress", func() {
+			texts := []string{"cpu usage", "memory usage", "network traffic", "disk io"}
+
+			var calls []int
+			vectors, err := encoder.EncodeBatch(context.Background(), texts, func(done, total int) {
+				Expect(total).To(Equal(len(texts)))
+				calls = append(calls, done)
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(vectors).To(HaveLen(len(texts)))
+			Expect(calls).To(Equal([]int{1, 2, 3, 4}))
+
+			for i, text := range texts {
+				expected, err := encoder.EncodeQuery(context.Background(), text)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(vectors[i]).To(Equal(expected))
+			}
+		})
+
+		It("should encode a batch of metric metadata", func() {
+			metadata := []prometheus.MetricMetadata{
+				{Name: "test_metric_1", Help: "test help 1"},
+				{Name: "test_metric_2", Help: "test help 2"},
+			}
+
+			vectors, err := encoder.EncodeMetricMetadataBatch(context.Background(), metadata, nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(vectors).To(HaveLen(2))
+
+			expected, err := encoder.EncodeMetricMetadata(context.Background(), metadata[1])
+			Expect(err).NotTo(HaveOccurred())
+			Expect(vectors[1]).To(Equal(expected))
+		})
+
+		It("should fail a batch with invalid metric metadata", func() {
+			_, err := encoder.EncodeMetricMetadataBatch(context.Background(), []prometheus.MetricMetadata{{Name: ""}}, nil)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("name is required"))
+		})
+	})
 })
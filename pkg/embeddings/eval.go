@@ -0,0 +1,153 @@
+package embeddings
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/machadovilaca/prometheus-rag/pkg/prometheus"
+)
+
+// EvalCase is a labelled retrieval query
+type EvalCase struct {
+	// Query is the natural language question
+	Query string `json:"query" yaml:"query"`
+	// Expected lists the names of the metrics relevant to the query
+	Expected []string `json:"expected" yaml:"expected"`
+}
+
+// EvalResult summarizes how well a document builder variant retrieves the expected metrics
+type EvalResult struct {
+	Variant string `json:"variant"`
+	K       int    `json:"k"`
+
+	// RecallAtK is the mean fraction of expected metrics found in the top K results
+	RecallAtK float64 `json:"recall_at_k"`
+	// MRR is the mean reciprocal rank of the first expected metric
+	MRR float64 `json:"mrr"`
+
+	// Misses lists the queries where no expected metric made it to the top K
+	Misses []string `json:"misses,omitempty"`
+}
+
+// EvaluateDocuments compares document builder variants by embedding the
+// metrics with each of them and ranking the metrics for every case query by
+// cosine similarity. Results are sorted by variant name.
+func EvaluateDocuments(
+	ctx context.Context, encoder Encoder, variants map[string]DocumentBuilder, metrics []prometheus.MetricMetadata, cases []EvalCase, k int,
+) ([]EvalResult, error) {
+	if k <= 0 {
+		return nil, fmt.Errorf("k must be greater than 0")
+	}
+
+	if len(metrics) == 0 || len(cases) == 0 {
+		return nil, fmt.Errorf("metrics and cases cannot be empty")
+	}
+
+	queryVectors := make([][]float32, len(cases))
+	for i, c := range cases {
+		vector, err := encoder.EncodeQuery(ctx, c.Query)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode query '%s': %w", c.Query, err)
+		}
+		queryVectors[i] = vector
+	}
+
+	results := make([]EvalResult, 0, len(variants))
+	for name, builder := range variants {
+		documents := make([]string, len(metrics))
+		for i, m := range metrics {
+			documents[i] = builder.Build(m)
+		}
+
+		documentVectors, err := encoder.EncodeBatch(ctx, documents, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode documents of variant '%s': %w", name, err)
+		}
+
+		result := EvalResult{Variant: name, K: k}
+		for i, c := range cases {
+			ranking := rankBySimilarity(queryVectors[i], documentVectors)
+
+			recall, reciprocalRank := scoreRanking(ranking, metrics, c.Expected, k)
+			result.RecallAtK += recall
+			result.MRR += reciprocalRank
+			if recall == 0 {
+				result.Misses = append(result.Misses, c.Query)
+			}
+		}
+
+		result.RecallAtK /= float64(len(cases))
+		result.MRR /= float64(len(cases))
+		results = append(results, result)
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].Variant < results[j].Variant
+	})
+
+	return results, nil
+}
+
+// scoreRanking returns the recall at k and the reciprocal rank of the first expected metric
+func scoreRanking(ranking []int, metrics []prometheus.MetricMetadata, expected []string, k int) (float64, float64) {
+	if len(expected) == 0 {
+		return 0, 0
+	}
+
+	expectedSet := make(map[string]struct{}, len(expected))
+	for _, name := range expected {
+		expectedSet[name] = struct{}{}
+	}
+
+	var (
+		found          int
+		reciprocalRank float64
+	)
+	for rank, idx := range ranking {
+		if _, ok := expectedSet[metrics[idx].Name]; !ok {
+			continue
+		}
+		if reciprocalRank == 0 {
+			reciprocalRank = 1 / float64(rank+1)
+		}
+		if rank < k {
+			found++
+		}
+	}
+
+	return float64(found) / float64(len(expectedSet)), reciprocalRank
+}
+
+// rankBySimilarity returns the indexes of vectors sorted by decreasing cosine similarity to query
+func rankBySimilarity(query []float32, vectors [][]float32) []int {
+	scores := make([]float64, len(vectors))
+	ranking := make([]int, len(vectors))
+	for i, vector := range vectors {
+		scores[i] = CosineSimilarity(query, vector)
+		ranking[i] = i
+	}
+
+	sort.SliceStable(ranking, func(i, j int) bool {
+		return scores[ranking[i]] > scores[ranking[j]]
+	})
+
+	return ranking
+}
+
+// CosineSimilarity returns the cosine similarity of two vectors, 0 if either is zero
+func CosineSimilarity(a, b []float32) float64 {
+	var dot, normA, normB float64
+	for i := range min(len(a), len(b)) {
+		dot += float64(a[i]) * float64(b[i])
+		normA += float64(a[i]) * float64(a[i])
+		normB += float64(b[i]) * float64(b[i])
+	}
+
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+
+	return dot / (math.Sqrt(normA) * math.Sqrt(normB))
+}
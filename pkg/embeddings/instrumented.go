@@ -0,0 +1,75 @@
+package embeddings
+
+import (
+	"context"
+	"time"
+
+	"github.com/machadovilaca/prometheus-rag/pkg/prometheus"
+	"github.com/machadovilaca/prometheus-rag/pkg/telemetry"
+)
+
+// Operations reported in the embedding metrics
+const (
+	operationQuery = "query"
+	operationBatch = "batch"
+)
+
+// instrumentedEncoder records the latency of the calls to an Encoder and
+// traces them. It wraps the encoder below the cache, so cache hits are not
+// reported.
+type instrumentedEncoder struct {
+	Encoder
+
+	provider string
+}
+
+func newInstrumentedEncoder(encoder Encoder, provider string) Encoder {
+	return &instrumentedEncoder{Encoder: encoder, provider: provider}
+}
+
+func (e *instrumentedEncoder) EncodeQuery(ctx context.Context, query string) ([]float32, error) {
+	ctx, end := e.observe(ctx, "embeddings.EncodeQuery", operationQuery, 1)
+	vector, err := e.Encoder.EncodeQuery(ctx, query)
+	end(err)
+
+	return vector, err
+}
+
+func (e *instrumentedEncoder) EncodeMetricMetadata(ctx context.Context, metadata prometheus.MetricMetadata) ([]float32, error) {
+	ctx, end := e.observe(ctx, "embeddings.EncodeMetricMetadata", operationBatch, 1)
+	vector, err := e.Encoder.EncodeMetricMetadata(ctx, metadata)
+	end(err)
+
+	return vector, err
+}
+
+func (e *instrumentedEncoder) EncodeBatch(ctx context.Context, texts []string, progress ProgressFunc) ([][]float32, error) {
+	ctx, end := e.observe(ctx, "embeddings.EncodeBatch", operationBatch, len(texts))
+	vectors, err := e.Encoder.EncodeBatch(ctx, texts, progress)
+	end(err)
+
+	return vectors, err
+}
+
+func (e *instrumentedEncoder) EncodeMetricMetadataBatch(ctx context.Context, metadata []prometheus.MetricMetadata, progress ProgressFunc) ([][]float32, error) {
+	ctx, end := e.observe(ctx, "embeddings.EncodeMetricMetadataBatch", operationBatch, len(metadata))
+	vectors, err := e.Encoder.EncodeMetricMetadataBatch(ctx, metadata, progress)
+	end(err)
+
+	return vectors, err
+}
+
+// observe starts the span of an encoder call of texts, returning the function
+// recording its outcome once it returns
+func (e *instrumentedEncoder) observe(ctx context.Context, name, operation string, texts int) (context.Context, func(error)) {
+	ctx, span := telemetry.StartSpan(ctx, name,
+		telemetry.AttributeEncoderProvider.String(e.provider),
+		telemetry.AttributeTexts.Int(texts),
+	)
+	start := time.Now()
+
+	return ctx, func(err error) {
+		telemetry.ObserveEmbedding(e.provider, operation, texts, start, err)
+		telemetry.EndSpan(span, err)
+	}
+}
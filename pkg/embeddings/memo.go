@@ -0,0 +1,58 @@
+package embeddings
+
+import (
+	"context"
+	"sync"
+)
+
+// queryEmbeddings holds the embeddings of the queries encoded while handling
+// a request, by model and query
+type queryEmbeddings struct {
+	mu      sync.Mutex
+	vectors map[string][]float32
+}
+
+type queryEmbeddingsKey struct{}
+
+// WithQueryEmbeddings returns a copy of ctx under which each query is only
+// encoded once per model, so the response cache, the vector search and the
+// feedback store of a request reuse the embedding of its question
+func WithQueryEmbeddings(ctx context.Context) context.Context {
+	return context.WithValue(ctx, queryEmbeddingsKey{}, &queryEmbeddings{vectors: make(map[string][]float32)})
+}
+
+// memoizingEncoder reuses the query embeddings carried by the context
+type memoizingEncoder struct {
+	Encoder
+}
+
+func newMemoizingEncoder(encoder Encoder) Encoder {
+	return &memoizingEncoder{Encoder: encoder}
+}
+
+func (e *memoizingEncoder) EncodeQuery(ctx context.Context, query string) ([]float32, error) {
+	memo, ok := ctx.Value(queryEmbeddingsKey{}).(*queryEmbeddings)
+	if !ok {
+		return e.Encoder.EncodeQuery(ctx, query)
+	}
+
+	key := e.Model().ID() + "\x00" + query
+
+	memo.mu.Lock()
+	vector, ok := memo.vectors[key]
+	memo.mu.Unlock()
+	if ok {
+		return vector, nil
+	}
+
+	vector, err := e.Encoder.EncodeQuery(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	memo.mu.Lock()
+	memo.vectors[key] = vector
+	memo.mu.Unlock()
+
+	return vector, nil
+}
@@ -0,0 +1,69 @@
+package embeddings
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/nlpodyssey/cybertron/pkg/models/bert"
+)
+
+// Pooling strategies supported by the encoder
+const (
+	PoolingMean    = "mean"
+	PoolingCLS     = "cls"
+	PoolingMax     = "max"
+	PoolingMeanMax = "mean-max"
+)
+
+var poolingStrategies = map[string]bert.PoolingStrategyType{
+	PoolingMean:    bert.MeanPooling,
+	PoolingCLS:     bert.ClsTokenPooling,
+	PoolingMax:     bert.MaxPooling,
+	PoolingMeanMax: bert.MeanMaxPooling,
+}
+
+// ParsePooling returns the BERT pooling strategy for the given name
+func ParsePooling(name string) (bert.PoolingStrategyType, error) {
+	pooling, ok := poolingStrategies[strings.ToLower(name)]
+	if !ok {
+		return 0, fmt.Errorf("unsupported pooling strategy '%s', supported strategies: %s, %s, %s, %s",
+			name, PoolingMean, PoolingCLS, PoolingMax, PoolingMeanMax)
+	}
+	return pooling, nil
+}
+
+// ModelInfo identifies the model configuration that produced a set of vectors.
+// Vectors are only comparable when they were produced with the same model info.
+type ModelInfo struct {
+	Name      string `json:"name"`
+	Pooling   string `json:"pooling"`
+	Dimension int    `json:"dimension"`
+
+	// MaxSequenceLength is the number of tokens inputs are truncated to, 0 when
+	// the encoder does not truncate them
+	MaxSequenceLength int `json:"max_sequence_length"`
+	// Document is the document builder preset turning metrics into texts
+	Document string `json:"document"`
+	// Endpoint is the base URL, without credentials, of the embeddings API
+	// serving a remote model; empty for local models
+	Endpoint string `json:"endpoint,omitempty"`
+}
+
+// ID returns a string uniquely identifying the model configuration
+func (m ModelInfo) ID() string {
+	return fmt.Sprintf("%s#%s#%d#%d#%s#%s", m.Name, m.Pooling, m.Dimension, m.MaxSequenceLength, m.Document, m.Endpoint)
+}
+
+// Matches returns true if vectors produced with both model infos are comparable
+func (m ModelInfo) Matches(other ModelInfo) bool {
+	return m == other
+}
+
+func (m ModelInfo) String() string {
+	if m.Endpoint != "" {
+		return fmt.Sprintf("%s (pooling %s, dimension %d, max sequence length %d, document %s, endpoint %s)",
+			m.Name, m.Pooling, m.Dimension, m.MaxSequenceLength, m.Document, m.Endpoint)
+	}
+	return fmt.Sprintf("%s (pooling %s, dimension %d, max sequence length %d, document %s)",
+		m.Name, m.Pooling, m.Dimension, m.MaxSequenceLength, m.Document)
+}
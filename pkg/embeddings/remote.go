@@ -0,0 +1,214 @@
+package embeddings
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/openai/openai-go"
+	"github.com/openai/openai-go/option"
+
+	"github.com/machadovilaca/prometheus-rag/pkg/prometheus"
+)
+
+const (
+	defaultRemoteBatchSize  = 64
+	defaultRemoteTimeout    = 30 * time.Second
+	defaultRemoteMaxRetries = 3
+
+	// remotePooling is recorded as the pooling of remote models, since pooling
+	// is done by the embeddings server
+	remotePooling = "remote"
+)
+
+// remoteEncoder encodes texts with an OpenAI-compatible /embeddings endpoint
+type remoteEncoder struct {
+	client    *openai.Client
+	model     string
+	batchSize int
+	workers   int
+	info      ModelInfo
+	documents DocumentBuilder
+}
+
+// newRemoteEncoder creates an encoder backed by an OpenAI-compatible embeddings API
+func newRemoteEncoder(config Config, documents DocumentBuilder) (*remoteEncoder, error) {
+	if config.BaseURL == "" {
+		return nil, fmt.Errorf("base URL is required for the %s encoder provider", ProviderOpenAI)
+	} else if !strings.HasSuffix(config.BaseURL, "/") {
+		config.BaseURL = config.BaseURL + "/"
+	}
+
+	if config.ModelName == "" {
+		return nil, fmt.Errorf("model name is required for the %s encoder provider", ProviderOpenAI)
+	}
+
+	if config.BatchSize <= 0 {
+		config.BatchSize = defaultRemoteBatchSize
+	}
+
+	if config.Timeout <= 0 {
+		config.Timeout = defaultRemoteTimeout
+	}
+
+	if config.MaxRetries < 0 {
+		config.MaxRetries = defaultRemoteMaxRetries
+	}
+
+	options := []option.RequestOption{
+		option.WithBaseURL(config.BaseURL),
+		option.WithRequestTimeout(config.Timeout),
+		option.WithMaxRetries(config.MaxRetries),
+	}
+
+	if config.APIKey != "" {
+		options = append(options, option.WithAPIKey(config.APIKey))
+	}
+
+	enc := &remoteEncoder{
+		client:    openai.NewClient(options...),
+		model:     config.ModelName,
+		batchSize: config.BatchSize,
+		workers:   config.Workers,
+		documents: documents,
+	}
+
+	// The dimension is not advertised by the API, so it is read from a probe request
+	vectors, err := enc.encodeChunk(context.Background(), []string{"dimension probe"})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get encoding dimension: %w", err)
+	}
+
+	enc.info = ModelInfo{
+		Name:      config.ModelName,
+		Pooling:   remotePooling,
+		Dimension: len(vectors[0]),
+		Document:  documentPresetName(config.Document),
+		Endpoint:  redactEndpoint(config.BaseURL),
+	}
+
+	return enc, nil
+}
+
+// redactEndpoint returns the base URL without the user info, query and
+// fragment, where credentials may be passed
+func redactEndpoint(baseURL string) string {
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return ""
+	}
+
+	u.User = nil
+	u.RawQuery = ""
+	u.Fragment = ""
+	return u.String()
+}
+
+func (e *remoteEncoder) GetDimension() (int, error) {
+	return e.info.Dimension, nil
+}
+
+func (e *remoteEncoder) Model() ModelInfo {
+	return e.info
+}
+
+func (e *remoteEncoder) EncodeQuery(ctx context.Context, query string) ([]float32, error) {
+	vectors, err := e.encodeChunk(ctx, []string{query})
+	if err != nil {
+		return nil, err
+	}
+
+	return vectors[0], nil
+}
+
+func (e *remoteEncoder) EncodeMetricMetadata(ctx context.Context, metadata prometheus.MetricMetadata) ([]float32, error) {
+	if err := metadata.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid metric metadata: %w", err)
+	}
+
+	return e.EncodeQuery(ctx, e.documents.Build(metadata))
+}
+
+// EncodeBatch sends the texts in chunks of the configured batch size, with up
+// to the configured number of requests in flight
+func (e *remoteEncoder) EncodeBatch(ctx context.Context, texts []string, progress ProgressFunc) ([][]float32, error) {
+	var chunks [][]string
+	for start := 0; start < len(texts); start += e.batchSize {
+		chunks = append(chunks, texts[start:min(start+e.batchSize, len(texts))])
+	}
+
+	tracker := newProgressTracker(len(texts), progress)
+
+	results := make([][][]float32, len(chunks))
+	err := parallelDo(ctx, len(chunks), e.workers, func(ctx context.Context, i int) error {
+		vectors, err := e.encodeChunk(ctx, chunks[i])
+		if err != nil {
+			return err
+		}
+		results[i] = vectors
+		tracker.add(len(chunks[i]))
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	vectors := make([][]float32, 0, len(texts))
+	for _, chunk := range results {
+		vectors = append(vectors, chunk...)
+	}
+
+	return vectors, nil
+}
+
+func (e *remoteEncoder) EncodeMetricMetadataBatch(ctx context.Context, metadata []prometheus.MetricMetadata, progress ProgressFunc) ([][]float32, error) {
+	texts := make([]string, len(metadata))
+	for i, m := range metadata {
+		if err := m.Validate(); err != nil {
+			return nil, fmt.Errorf("invalid metric metadata '%s': %w", m.Name, err)
+		}
+		texts[i] = e.documents.Build(m)
+	}
+
+	return e.EncodeBatch(ctx, texts, progress)
+}
+
+// encodeChunk encodes texts in a single request, returning the vectors in the order of texts
+func (e *remoteEncoder) encodeChunk(ctx context.Context, texts []string) ([][]float32, error) {
+	response, err := e.client.Embeddings.New(ctx, openai.EmbeddingNewParams{
+		Input:          openai.F[openai.EmbeddingNewParamsInputUnion](openai.EmbeddingNewParamsInputArrayOfStrings(texts)),
+		Model:          openai.F(openai.EmbeddingModel(e.model)),
+		EncodingFormat: openai.F(openai.EmbeddingNewParamsEncodingFormatFloat),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to request embeddings: %w", err)
+	}
+
+	if len(response.Data) != len(texts) {
+		return nil, fmt.Errorf("expected %d embeddings, got %d", len(texts), len(response.Data))
+	}
+
+	vectors := make([][]float32, len(texts))
+	for _, embedding := range response.Data {
+		if embedding.Index < 0 || int(embedding.Index) >= len(texts) {
+			return nil, fmt.Errorf("embedding index %d out of range", embedding.Index)
+		}
+
+		vector := make([]float32, len(embedding.Embedding))
+		for i, value := range embedding.Embedding {
+			vector[i] = float32(value)
+		}
+		vectors[embedding.Index] = vector
+	}
+
+	for i, vector := range vectors {
+		if vector == nil {
+			return nil, fmt.Errorf("missing embedding for input %d", i)
+		}
+	}
+
+	return vectors, nil
+}
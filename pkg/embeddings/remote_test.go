@@ -0,0 +1,208 @@
+package embeddings_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/machadovilaca/prometheus-rag/pkg/embeddings"
+	"github.com/machadovilaca/prometheus-rag/pkg/prometheus"
+)
+
+// fakeEmbeddingsAPI serves an OpenAI-compatible /embeddings endpoint returning
+// vectors derived from the input length, in reverse order to exercise indexes
+type fakeEmbeddingsAPI struct {
+	mu       sync.Mutex
+	requests int
+	inputs   [][]string
+
+	// failures is the number of requests answered with an error before succeeding
+	failures int
+	// delay is how long each request takes
+	delay time.Duration
+}
+
+func (f *fakeEmbeddingsAPI) start() *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/embeddings", func(w http.ResponseWriter, r *http.Request) {
+		var request struct {
+			Input []string `json:"input"`
+			Model string   `json:"model"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		f.mu.Lock()
+		f.requests++
+		failing := f.failures > 0
+		if failing {
+			f.failures--
+		} else {
+			f.inputs = append(f.inputs, request.Input)
+		}
+		f.mu.Unlock()
+
+		time.Sleep(f.delay)
+
+		if failing {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+
+		data := []map[string]any{}
+		for i := len(request.Input) - 1; i >= 0; i-- {
+			data = append(data, map[string]any{
+				"object":    "embedding",
+				"index":     i,
+				"embedding": []float64{float64(len(request.Input[i])), 1, 0},
+			})
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"object": "list",
+			"model":  request.Model,
+			"data":   data,
+			"usage":  map[string]int{"prompt_tokens": 0, "total_tokens": 0},
+		})
+	})
+
+	return httptest.NewServer(mux)
+}
+
+var _ = Describe("Remote encoder", func() {
+	var (
+		api    *fakeEmbeddingsAPI
+		server *httptest.Server
+	)
+
+	BeforeEach(func() {
+		api = &fakeEmbeddingsAPI{}
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	newEncoder := func(cfg embeddings.Config) embeddings.Encoder {
+		server = api.start()
+
+		cfg.Provider = embeddings.ProviderOpenAI
+		cfg.BaseURL = server.URL
+		cfg.ModelName = "test-embeddings"
+		encoder, err := embeddings.NewEncoder(cfg)
+		Expect(err).NotTo(HaveOccurred())
+		return encoder
+	}
+
+	It("should read the dimension from the API", func() {
+		encoder := newEncoder(embeddings.Config{})
+
+		dimension, err := encoder.GetDimension()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(dimension).To(Equal(3))
+		Expect(encoder.Model().Name).To(Equal("test-embeddings"))
+	})
+
+	It("should identify the model by its endpoint, without credentials", func() {
+		server = api.start()
+
+		encoder, err := embeddings.NewEncoder(embeddings.Config{
+			Provider:  embeddings.ProviderOpenAI,
+			BaseURL:   strings.Replace(server.URL, "http://", "http://user:secret@", 1),
+			ModelName: "test-embeddings",
+		})
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(encoder.Model().Endpoint).To(Equal(server.URL + "/"))
+		Expect(encoder.Model().ID()).To(ContainSubstring(server.URL))
+		Expect(encoder.Model().ID()).NotTo(ContainSubstring("secret"))
+	})
+
+	It("should encode queries", func() {
+		encoder := newEncoder(embeddings.Config{})
+
+		vector, err := encoder.EncodeQuery(context.Background(), "cpu usage")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(vector).To(Equal([]float32{9, 1, 0}))
+	})
+
+	It("should encode each query once under a context carrying query embeddings", func() {
+		encoder := newEncoder(embeddings.Config{})
+		requests := api.requests
+
+		ctx := embeddings.WithQueryEmbeddings(context.Background())
+		for range 3 {
+			vector, err := encoder.EncodeQuery(ctx, "cpu usage")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(vector).To(Equal([]float32{9, 1, 0}))
+		}
+		Expect(api.requests).To(Equal(requests + 1))
+
+		_, err := encoder.EncodeQuery(ctx, "memory usage")
+		Expect(err).NotTo(HaveOccurred())
+		_, err = encoder.EncodeQuery(context.Background(), "cpu usage")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(api.requests).To(Equal(requests + 3))
+	})
+
+	It("should send batches and keep the order of the input", func() {
+		encoder := newEncoder(embeddings.Config{BatchSize: 2, Workers: 2})
+
+		var progress []int
+		vectors, err := encoder.EncodeMetricMetadataBatch(context.Background(), []prometheus.MetricMetadata{
+			{Name: "a", Help: "x", Type: "gauge"},
+			{Name: "bb", Help: "x", Type: "gauge"},
+			{Name: "ccc", Help: "x", Type: "gauge"},
+		}, func(done, total int) {
+			progress = append(progress, done)
+		})
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(vectors).To(Equal([][]float32{{3, 1, 0}, {4, 1, 0}, {5, 1, 0}}))
+		Expect(api.inputs[1:]).To(ConsistOf([]string{"a x", "bb x"}, []string{"ccc x"}))
+		Expect(progress).To(HaveLen(2))
+		Expect(progress[1]).To(Equal(3))
+	})
+
+	It("should retry failed requests", func() {
+		encoder := newEncoder(embeddings.Config{MaxRetries: 2})
+		api.failures = 2
+
+		_, err := encoder.EncodeQuery(context.Background(), "up")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(api.requests).To(Equal(4))
+	})
+
+	It("should fail once retries are exhausted", func() {
+		encoder := newEncoder(embeddings.Config{MaxRetries: 1})
+		api.failures = 2
+
+		_, err := encoder.EncodeQuery(context.Background(), "up")
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should time out slow requests", func() {
+		encoder := newEncoder(embeddings.Config{Timeout: time.Second})
+		api.delay = 2 * time.Second
+
+		_, err := encoder.EncodeQuery(context.Background(), "up")
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should require a base URL", func() {
+		server = api.start()
+
+		_, err := embeddings.NewEncoder(embeddings.Config{Provider: embeddings.ProviderOpenAI, ModelName: "m"})
+		Expect(err).To(HaveOccurred())
+	})
+})
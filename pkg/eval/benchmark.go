@@ -0,0 +1,263 @@
+package eval
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"gopkg.in/yaml.v3"
+
+	"github.com/machadovilaca/prometheus-rag/pkg/embeddings"
+	"github.com/machadovilaca/prometheus-rag/pkg/prometheus"
+	"github.com/machadovilaca/prometheus-rag/pkg/vectordb"
+)
+
+// Target is a vector database and encoder combination to benchmark
+type Target struct {
+	Name   string
+	Config vectordb.Config
+}
+
+// BenchmarkResult is the retrieval quality and cost of a target
+type BenchmarkResult struct {
+	Target   string `json:"target"`
+	Provider string `json:"provider"`
+	Model    string `json:"model"`
+
+	K       int `json:"k"`
+	Metrics int `json:"metrics"`
+	Queries int `json:"queries"`
+
+	// RecallAtK is the mean fraction of expected metrics found in the top K results
+	RecallAtK float64 `json:"recall_at_k"`
+	// MRR is the mean reciprocal rank of the first expected metric in the top K results
+	MRR float64 `json:"mrr"`
+
+	// IndexDuration is how long encoding and storing the catalog took
+	IndexDuration time.Duration `json:"index_duration"`
+	LatencyP50    time.Duration `json:"latency_p50"`
+	LatencyP99    time.Duration `json:"latency_p99"`
+
+	// MemoryBytes is the growth of the Go heap after creating the encoder and
+	// indexing the catalog; memory used by a remote vector database or
+	// embeddings API is not included
+	MemoryBytes uint64 `json:"memory_bytes"`
+
+	// Misses lists the queries where no expected metric made it to the top K
+	Misses []string `json:"misses,omitempty"`
+
+	// Error is set when the target could not be benchmarked
+	Error string `json:"error,omitempty"`
+}
+
+// queriesFile is the layout of the labelled queries file
+type queriesFile struct {
+	Queries []embeddings.EvalCase `yaml:"queries"`
+}
+
+// LoadQueries loads labelled retrieval queries from a YAML or JSON file
+func LoadQueries(filePath string) ([]embeddings.EvalCase, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read queries file: %w", err)
+	}
+
+	var f queriesFile
+	if err := yaml.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("failed to parse queries file: %w", err)
+	}
+
+	if len(f.Queries) == 0 {
+		return nil, errors.New("queries cannot be empty")
+	}
+
+	for i, c := range f.Queries {
+		if strings.TrimSpace(c.Query) == "" {
+			return nil, fmt.Errorf("query %d: query is required", i)
+		}
+		if len(c.Expected) == 0 {
+			return nil, fmt.Errorf("query %d: expected cannot be empty", i)
+		}
+	}
+
+	return f.Queries, nil
+}
+
+// LoadCatalog loads the metrics of a text exposition file
+func LoadCatalog(filePath string) ([]*prometheus.MetricMetadata, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open catalog file: %w", err)
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+
+	return prometheus.ParseExposition(file)
+}
+
+// Benchmark indexes the catalog into a fresh collection of the target, runs
+// the queries through SearchMetrics and deletes the collection. Failures,
+// including ctx being done, are reported in the Error field of the result.
+func Benchmark(ctx context.Context, target Target, metrics []*prometheus.MetricMetadata, cases []embeddings.EvalCase, k int) *BenchmarkResult {
+	result := &BenchmarkResult{
+		Target:   target.Name,
+		Provider: target.Config.Provider,
+		Model:    target.Config.EncoderModelName,
+		K:        k,
+		Metrics:  len(metrics),
+		Queries:  len(cases),
+	}
+
+	if err := benchmark(ctx, target, metrics, cases, result); err != nil {
+		log.Error().Err(err).Msgf("failed to benchmark %s", target.Name)
+		result.Error = err.Error()
+	}
+
+	return result
+}
+
+func benchmark(ctx context.Context, target Target, metrics []*prometheus.MetricMetadata, cases []embeddings.EvalCase, result *BenchmarkResult) error {
+	if result.K <= 0 {
+		return errors.New("k must be greater than 0")
+	}
+
+	// The collection is recreated anyway, so a model mismatch must not fail the benchmark
+	cfg := target.Config
+	cfg.OnModelMismatch = vectordb.ModelMismatchReindex
+
+	before := heapAlloc()
+
+	encoder, err := vectordb.NewEncoder(cfg)
+	if err != nil {
+		return err
+	}
+
+	client, err := vectordb.NewWithEncoder(cfg, encoder)
+	if err != nil {
+		return fmt.Errorf("failed to create vectordb client: %w", err)
+	}
+	defer func() {
+		// The collection is cleaned up even when the benchmark was cancelled
+		if err := client.DeleteCollection(context.WithoutCancel(ctx)); err != nil {
+			log.Warn().Err(err).Msgf("failed to delete benchmark collection of %s", target.Name)
+		}
+		_ = client.Close()
+	}()
+
+	if err := client.DeleteCollection(ctx); err != nil {
+		return fmt.Errorf("failed to delete collection: %w", err)
+	}
+	if err := client.CreateCollection(ctx); err != nil {
+		return fmt.Errorf("failed to create collection: %w", err)
+	}
+
+	start := time.Now()
+	if err := client.BatchAddMetricMetadata(ctx, metrics); err != nil {
+		return fmt.Errorf("failed to index catalog: %w", err)
+	}
+	result.IndexDuration = time.Since(start)
+
+	if after := heapAlloc(); after > before {
+		result.MemoryBytes = after - before
+	}
+
+	// Warm up lazily initialized state so it does not count towards latency
+	if _, err := client.SearchMetrics(ctx, cases[0].Query, uint64(result.K)); err != nil {
+		return fmt.Errorf("failed to search metrics: %w", err)
+	}
+
+	latencies := make([]time.Duration, len(cases))
+	for i, c := range cases {
+		start := time.Now()
+		found, err := client.SearchMetrics(ctx, c.Query, uint64(result.K))
+		latencies[i] = time.Since(start)
+		if err != nil {
+			return fmt.Errorf("failed to search metrics for '%s': %w", c.Query, err)
+		}
+
+		names := make([]string, len(found))
+		for j, metric := range found {
+			names[j] = metric.Name
+		}
+
+		recall, reciprocalRank := scoreRetrieval(names, c.Expected, result.K)
+		result.RecallAtK += recall
+		result.MRR += reciprocalRank
+		if recall == 0 {
+			result.Misses = append(result.Misses, c.Query)
+		}
+	}
+
+	result.RecallAtK /= float64(len(cases))
+	result.MRR /= float64(len(cases))
+	result.LatencyP50 = percentile(latencies, 0.50)
+	result.LatencyP99 = percentile(latencies, 0.99)
+
+	runtime.KeepAlive(encoder)
+	return nil
+}
+
+// heapAlloc returns the bytes allocated on the heap after a garbage collection
+func heapAlloc() uint64 {
+	runtime.GC()
+
+	var stats runtime.MemStats
+	runtime.ReadMemStats(&stats)
+	return stats.HeapAlloc
+}
+
+// percentile returns the nearest-rank percentile of the durations
+func percentile(durations []time.Duration, p float64) time.Duration {
+	if len(durations) == 0 {
+		return 0
+	}
+
+	sorted := append([]time.Duration(nil), durations...)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i] < sorted[j]
+	})
+
+	rank := int(math.Ceil(p * float64(len(sorted))))
+	return sorted[max(rank, 1)-1]
+}
+
+// WriteBenchmark writes the benchmark results in the given format
+func WriteBenchmark(w io.Writer, results []*BenchmarkResult, format string) error {
+	switch format {
+	case FormatJSON:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(results)
+	case FormatMarkdown:
+		_, err := io.WriteString(w, benchmarkMarkdown(results))
+		return err
+	default:
+		return fmt.Errorf("unsupported report format '%s', supported formats: %s, %s", format, FormatJSON, FormatMarkdown)
+	}
+}
+
+func benchmarkMarkdown(results []*BenchmarkResult) string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "# Retrieval benchmark\n\n")
+	fmt.Fprintf(&b, "| Target | Metrics | Queries | Recall@k | MRR | p50 | p99 | Index time | Memory | Error |\n")
+	fmt.Fprintf(&b, "|---|---|---|---|---|---|---|---|---|---|\n")
+	for _, r := range results {
+		fmt.Fprintf(&b, "| %s | %d | %d | %.3f (k=%d) | %.3f | %s | %s | %s | %.1f MiB | %s |\n",
+			markdownCell(r.Target), r.Metrics, r.Queries, r.RecallAtK, r.K, r.MRR,
+			r.LatencyP50.Round(time.Microsecond), r.LatencyP99.Round(time.Microsecond),
+			r.IndexDuration.Round(time.Millisecond), float64(r.MemoryBytes)/(1<<20), markdownCell(r.Error))
+	}
+
+	return b.String()
+}
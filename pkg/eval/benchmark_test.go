@@ -0,0 +1,150 @@
+package eval_test
+
+import (
+	"bytes"
+	"context"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/machadovilaca/prometheus-rag/pkg/embeddings"
+	"github.com/machadovilaca/prometheus-rag/pkg/eval"
+	"github.com/machadovilaca/prometheus-rag/pkg/prometheus"
+	"github.com/machadovilaca/prometheus-rag/pkg/vectordb"
+)
+
+const catalog = `
+# HELP up Whether the target is up.
+# TYPE up gauge
+up{job="api"} 1
+# HELP http_requests_total Total number of HTTP requests.
+# TYPE http_requests_total counter
+http_requests_total{code="200"} 10
+# HELP node_memory_MemAvailable_bytes Available memory in bytes.
+# TYPE node_memory_MemAvailable_bytes gauge
+node_memory_MemAvailable_bytes 1000
+`
+
+var _ = Describe("Benchmark", func() {
+	var (
+		tempDir       string
+		embeddingsAPI *httptest.Server
+		metrics       []*prometheus.MetricMetadata
+		target        eval.Target
+	)
+
+	BeforeEach(func() {
+		var err error
+		tempDir, err = os.MkdirTemp("", "benchmark_test")
+		Expect(err).NotTo(HaveOccurred())
+
+		embeddingsAPI = startFakeEmbeddingsAPI()
+
+		metrics, err = prometheus.ParseExposition(strings.NewReader(catalog))
+		Expect(err).NotTo(HaveOccurred())
+
+		target = eval.Target{
+			Name: "sqlite3/keywords",
+			Config: vectordb.Config{
+				Provider:         "sqlite3",
+				Sqlite3DBPath:    filepath.Join(tempDir, "benchmark.db"),
+				CollectionName:   "benchmark",
+				EncoderProvider:  "openai",
+				EncoderBaseURL:   embeddingsAPI.URL,
+				EncoderModelName: "keywords",
+			},
+		}
+	})
+
+	AfterEach(func() {
+		embeddingsAPI.Close()
+		Expect(os.RemoveAll(tempDir)).To(Succeed())
+	})
+
+	It("should measure recall, MRR and latency", func() {
+		cases := []embeddings.EvalCase{
+			{Query: "Which targets are down?", Expected: []string{"up"}},
+			{Query: "How many HTTP requests failed?", Expected: []string{"http_requests_total"}},
+			{Query: "Is memory running out?", Expected: []string{"node_memory_MemAvailable_bytes"}},
+			{Query: "What is the disk usage?", Expected: []string{"node_filesystem_avail_bytes"}},
+		}
+
+		result := eval.Benchmark(context.Background(), target, metrics, cases, 1)
+		Expect(result.Error).To(BeEmpty())
+
+		Expect(result.Metrics).To(Equal(3))
+		Expect(result.Queries).To(Equal(4))
+		Expect(result.RecallAtK).To(Equal(0.75))
+		Expect(result.MRR).To(Equal(0.75))
+		Expect(result.Misses).To(Equal([]string{"What is the disk usage?"}))
+		Expect(result.LatencyP50).To(BeNumerically(">", 0))
+		Expect(result.LatencyP99).To(BeNumerically(">=", result.LatencyP50))
+		Expect(result.IndexDuration).To(BeNumerically(">", 0))
+	})
+
+	It("should report targets that cannot be benchmarked", func() {
+		target.Config.Provider = "elasticsearch"
+
+		result := eval.Benchmark(context.Background(), target, metrics, []embeddings.EvalCase{{Query: "up", Expected: []string{"up"}}}, 1)
+		Expect(result.Error).To(ContainSubstring("unsupported provider"))
+	})
+
+	It("should write the results", func() {
+		results := []*eval.BenchmarkResult{{Target: "sqlite3/keywords", K: 5, Metrics: 3, Queries: 4, RecallAtK: 0.75}}
+
+		var b bytes.Buffer
+		Expect(eval.WriteBenchmark(&b, results, eval.FormatMarkdown)).To(Succeed())
+		Expect(b.String()).To(ContainSubstring("| sqlite3/keywords | 3 | 4 | 0.750 (k=5) |"))
+
+		b.Reset()
+		Expect(eval.WriteBenchmark(&b, results, eval.FormatJSON)).To(Succeed())
+		Expect(b.String()).To(ContainSubstring(`"recall_at_k": 0.75`))
+	})
+
+	It("should compare document builders", func() {
+		cases := []embeddings.EvalCase{
+			{Query: "Which targets are down?", Expected: []string{"up"}},
+			{Query: "Is memory running out?", Expected: []string{"node_memory_MemAvailable_bytes"}},
+		}
+
+		comparison := eval.CompareDocuments(context.Background(), target.Config,
+			[]string{embeddings.DocumentTokenized, embeddings.DocumentPlain}, metrics, cases, 1)
+		Expect(comparison.Error).To(BeEmpty())
+		Expect(comparison.Model).To(Equal("keywords"))
+		Expect(comparison.Results).To(HaveLen(2))
+		Expect(comparison.Results[0].Variant).To(Equal(embeddings.DocumentPlain))
+		Expect(comparison.Results[1].Variant).To(Equal(embeddings.DocumentTokenized))
+
+		var b bytes.Buffer
+		Expect(eval.WriteDocumentComparison(&b, []*eval.DocumentComparison{comparison}, eval.FormatMarkdown)).To(Succeed())
+		Expect(b.String()).To(ContainSubstring("| keywords | plain | 3 | 2 |"))
+	})
+
+	It("should report unknown document builders", func() {
+		comparison := eval.CompareDocuments(context.Background(), target.Config, []string{"verbose"}, metrics,
+			[]embeddings.EvalCase{{Query: "up", Expected: []string{"up"}}}, 1)
+		Expect(comparison.Error).To(ContainSubstring("unsupported document builder"))
+	})
+
+	It("should load the sample catalog and queries", func() {
+		metrics, err := eval.LoadCatalog("../../hack/metrics.txt")
+		Expect(err).NotTo(HaveOccurred())
+
+		cases, err := eval.LoadQueries("../../hack/benchmark.yaml")
+		Expect(err).NotTo(HaveOccurred())
+
+		names := make(map[string]bool, len(metrics))
+		for _, metric := range metrics {
+			names[metric.Name] = true
+		}
+		for _, c := range cases {
+			for _, expected := range c.Expected {
+				Expect(names).To(HaveKey(expected), "query '%s'", c.Query)
+			}
+		}
+	})
+})
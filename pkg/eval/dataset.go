@@ -0,0 +1,123 @@
+// Package eval measures the accuracy of the natural language to PromQL
+// pipeline offline, by running the RAG against a dataset of questions served by
+// a stub Prometheus and scoring the answers against reference queries, and
+// benchmarks metric retrieval across vector databases and encoders.
+package eval
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/prometheus/prometheus/promql/parser"
+)
+
+// defaultInterval is the time between the samples of the dataset series
+const defaultInterval = time.Minute
+
+// Dataset is a set of questions along with the metrics and series the stub
+// Prometheus serves while answering them
+type Dataset struct {
+	// Interval is the time between the samples of each series, 1m by default
+	Interval time.Duration `json:"interval,omitempty" yaml:"interval,omitempty"`
+
+	// Metrics is the metadata served by the stub Prometheus
+	Metrics []Metric `json:"metrics" yaml:"metrics"`
+
+	// Series are the samples served by the stub Prometheus and used to
+	// compare the results of the generated and reference queries, in the
+	// notation of the PromQL test files, e.g. `up{job="api"} 1 1 0 1`
+	Series []string `json:"series" yaml:"series"`
+
+	Cases []Case `json:"cases" yaml:"cases"`
+}
+
+// Metric is the metadata of a metric family
+type Metric struct {
+	Name string `json:"name" yaml:"name"`
+	Type string `json:"type" yaml:"type"`
+	Help string `json:"help" yaml:"help"`
+	Unit string `json:"unit,omitempty" yaml:"unit,omitempty"`
+}
+
+// Case is a question along with the metrics needed to answer it and a
+// reference PromQL expression answering it
+type Case struct {
+	Question        string   `json:"question" yaml:"question"`
+	ExpectedMetrics []string `json:"expected_metrics" yaml:"expected_metrics"`
+	ReferencePromQL string   `json:"reference_promql" yaml:"reference_promql"`
+}
+
+// Validate validates the dataset
+func (d *Dataset) Validate() error {
+	if len(d.Metrics) == 0 {
+		return errors.New("metrics cannot be empty")
+	}
+
+	if len(d.Cases) == 0 {
+		return errors.New("cases cannot be empty")
+	}
+
+	if d.Interval < 0 {
+		return errors.New("interval cannot be negative")
+	}
+
+	for i, metric := range d.Metrics {
+		if strings.TrimSpace(metric.Name) == "" {
+			return fmt.Errorf("metric %d: name is required", i)
+		}
+	}
+
+	for i, series := range d.Series {
+		if _, _, err := parser.ParseSeriesDesc(series); err != nil {
+			return fmt.Errorf("series %d: %w", i, err)
+		}
+	}
+
+	for i, c := range d.Cases {
+		if strings.TrimSpace(c.Question) == "" {
+			return fmt.Errorf("case %d: question is required", i)
+		}
+
+		if len(c.ExpectedMetrics) == 0 {
+			return fmt.Errorf("case %d: expected_metrics cannot be empty", i)
+		}
+
+		if _, err := parser.ParseExpr(c.ReferencePromQL); err != nil {
+			return fmt.Errorf("case %d: invalid reference_promql: %w", i, err)
+		}
+	}
+
+	return nil
+}
+
+// GetInterval returns the time between the samples of each series
+func (d *Dataset) GetInterval() time.Duration {
+	if d.Interval == 0 {
+		return defaultInterval
+	}
+	return d.Interval
+}
+
+// LoadDataset loads a dataset from a YAML or JSON file
+func LoadDataset(filePath string) (*Dataset, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read dataset file: %w", err)
+	}
+
+	var dataset Dataset
+	if err := yaml.Unmarshal(data, &dataset); err != nil {
+		return nil, fmt.Errorf("failed to parse dataset file: %w", err)
+	}
+
+	if err := dataset.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid dataset: %w", err)
+	}
+
+	return &dataset, nil
+}
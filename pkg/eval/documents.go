@@ -0,0 +1,117 @@
+package eval
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/machadovilaca/prometheus-rag/pkg/embeddings"
+	"github.com/machadovilaca/prometheus-rag/pkg/prometheus"
+	"github.com/machadovilaca/prometheus-rag/pkg/vectordb"
+)
+
+// DocumentComparison is the retrieval quality of the document builders of a model
+type DocumentComparison struct {
+	Model   string `json:"model"`
+	Metrics int    `json:"metrics"`
+	Queries int    `json:"queries"`
+
+	// Results holds one entry per document builder, sorted by name
+	Results []embeddings.EvalResult `json:"results,omitempty"`
+
+	// Error is set when the document builders could not be compared
+	Error string `json:"error,omitempty"`
+}
+
+// CompareDocuments embeds the catalog with each of the document builder
+// presets using the encoder of cfg and ranks it for the queries, see
+// embeddings.EvaluateDocuments. No vector database is involved. Failures,
+// including ctx being done, are reported in the Error field of the result.
+func CompareDocuments(
+	ctx context.Context, cfg vectordb.Config, documents []string, metrics []*prometheus.MetricMetadata, cases []embeddings.EvalCase, k int,
+) *DocumentComparison {
+	comparison := &DocumentComparison{
+		Model:   cfg.EncoderModelName,
+		Metrics: len(metrics),
+		Queries: len(cases),
+	}
+
+	results, err := compareDocuments(ctx, cfg, documents, metrics, cases, k)
+	if err != nil {
+		log.Error().Err(err).Msgf("failed to compare the documents of %s", cfg.EncoderModelName)
+		comparison.Error = err.Error()
+	}
+	comparison.Results = results
+
+	return comparison
+}
+
+func compareDocuments(
+	ctx context.Context, cfg vectordb.Config, documents []string, metrics []*prometheus.MetricMetadata, cases []embeddings.EvalCase, k int,
+) ([]embeddings.EvalResult, error) {
+	variants := make(map[string]embeddings.DocumentBuilder, len(documents))
+	for _, document := range documents {
+		options, err := embeddings.DocumentPreset(document)
+		if err != nil {
+			return nil, err
+		}
+		variants[strings.ToLower(document)] = embeddings.NewDocumentBuilder(options)
+	}
+
+	encoder, err := vectordb.NewEncoder(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if closer, ok := encoder.(io.Closer); ok {
+		defer func() {
+			_ = closer.Close()
+		}()
+	}
+
+	catalog := make([]prometheus.MetricMetadata, len(metrics))
+	for i, metric := range metrics {
+		catalog[i] = *metric
+	}
+
+	return embeddings.EvaluateDocuments(ctx, encoder, variants, catalog, cases, k)
+}
+
+// WriteDocumentComparison writes the document builder comparisons in the given format
+func WriteDocumentComparison(w io.Writer, comparisons []*DocumentComparison, format string) error {
+	switch format {
+	case FormatJSON:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(comparisons)
+	case FormatMarkdown:
+		_, err := io.WriteString(w, documentComparisonMarkdown(comparisons))
+		return err
+	default:
+		return fmt.Errorf("unsupported report format '%s', supported formats: %s, %s", format, FormatJSON, FormatMarkdown)
+	}
+}
+
+func documentComparisonMarkdown(comparisons []*DocumentComparison) string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "# Document builder comparison\n\n")
+	fmt.Fprintf(&b, "| Model | Document | Metrics | Queries | Recall@k | MRR | Misses | Error |\n")
+	fmt.Fprintf(&b, "|---|---|---|---|---|---|---|---|\n")
+	for _, c := range comparisons {
+		if c.Error != "" {
+			fmt.Fprintf(&b, "| %s | | %d | %d | | | | %s |\n", markdownCell(c.Model), c.Metrics, c.Queries, markdownCell(c.Error))
+			continue
+		}
+
+		for _, r := range c.Results {
+			fmt.Fprintf(&b, "| %s | %s | %d | %d | %.3f (k=%d) | %.3f | %d | |\n",
+				markdownCell(c.Model), markdownCell(r.Variant), c.Metrics, c.Queries, r.RecallAtK, r.K, r.MRR, len(r.Misses))
+		}
+	}
+
+	return b.String()
+}
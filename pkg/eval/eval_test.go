@@ -0,0 +1,338 @@
+package eval_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/machadovilaca/prometheus-rag/pkg/config"
+	"github.com/machadovilaca/prometheus-rag/pkg/eval"
+)
+
+// keywords are the dimensions of the vectors returned by the fake embeddings API
+var keywords = []string{"target", "http", "memory"}
+
+// startFakeEmbeddingsAPI serves an OpenAI-compatible /embeddings endpoint
+// returning vectors that count the keywords in each input
+func startFakeEmbeddingsAPI() *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/embeddings", func(w http.ResponseWriter, r *http.Request) {
+		var request struct {
+			Input []string `json:"input"`
+			Model string   `json:"model"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		data := make([]map[string]any, len(request.Input))
+		for i, input := range request.Input {
+			vector := []float64{0.01}
+			for _, keyword := range keywords {
+				vector = append(vector, float64(strings.Count(strings.ToLower(input), keyword)))
+			}
+			data[i] = map[string]any{"object": "embedding", "index": i, "embedding": vector}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]any{"object": "list", "model": request.Model, "data": data})
+	})
+
+	return httptest.NewServer(mux)
+}
+
+// startFakeLLM serves an OpenAI-compatible /chat/completions endpoint always
+// answering with the given content
+func startFakeLLM(content string, requests *int) *httptest.Server {
+	var mu sync.Mutex
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/chat/completions", func(w http.ResponseWriter, _ *http.Request) {
+		mu.Lock()
+		*requests++
+		mu.Unlock()
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"id":     "fake",
+			"object": "chat.completion",
+			"model":  "fake",
+			"choices": []map[string]any{{
+				"index":         0,
+				"finish_reason": "stop",
+				"message":       map[string]string{"role": "assistant", "content": content},
+			}},
+		})
+	})
+
+	return httptest.NewServer(mux)
+}
+
+func newDataset() *eval.Dataset {
+	return &eval.Dataset{
+		Metrics: []eval.Metric{
+			{Name: "up", Type: "gauge", Help: "Whether the target is up."},
+			{Name: "http_requests_total", Type: "counter", Help: "Total number of HTTP requests."},
+			{Name: "node_memory_MemAvailable_bytes", Type: "gauge", Help: "Available memory in bytes."},
+		},
+		Series: []string{
+			`up{job="api",instance="a"} 1x20`,
+			`up{job="api",instance="b"} 1x5 0x15`,
+			`http_requests_total{code="200"} 0+60x20`,
+			`http_requests_total{code="500"} 0+6x20`,
+			`node_memory_MemAvailable_bytes{instance="a"} 1000-10x20`,
+		},
+		Cases: []eval.Case{
+			{
+				Question:        "Which targets are down?",
+				ExpectedMetrics: []string{"up"},
+				ReferencePromQL: "up == 0",
+			},
+			{
+				Question:        "What is the HTTP request rate per code?",
+				ExpectedMetrics: []string{"http_requests_total"},
+				ReferencePromQL: "sum by (code) (rate(http_requests_total[5m]))",
+			},
+			{
+				Question:        "How much memory is available?",
+				ExpectedMetrics: []string{"node_memory_MemAvailable_bytes"},
+				ReferencePromQL: "sum(node_memory_MemAvailable_bytes)",
+			},
+		},
+	}
+}
+
+var _ = Describe("Eval", func() {
+	Context("LoadDataset", func() {
+		var tempDir string
+
+		BeforeEach(func() {
+			var err error
+			tempDir, err = os.MkdirTemp("", "eval_test")
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		AfterEach(func() {
+			Expect(os.RemoveAll(tempDir)).To(Succeed())
+		})
+
+		write := func(content string) string {
+			filePath := filepath.Join(tempDir, "dataset.yaml")
+			Expect(os.WriteFile(filePath, []byte(content), 0600)).To(Succeed())
+			return filePath
+		}
+
+		It("should load a dataset", func() {
+			dataset, err := eval.LoadDataset(write(`
+interval: 30s
+metrics:
+  - name: up
+    type: gauge
+    help: Whether the target is up.
+series:
+  - up{job="api"} 1 1 0
+cases:
+  - question: Which targets are down?
+    expected_metrics: [up]
+    reference_promql: up == 0
+`))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(dataset.GetInterval()).To(Equal(30 * time.Second))
+			Expect(dataset.Cases).To(HaveLen(1))
+			Expect(dataset.Cases[0].ExpectedMetrics).To(Equal([]string{"up"}))
+		})
+
+		It("should load the sample dataset", func() {
+			dataset, err := eval.LoadDataset("../../hack/eval.yaml")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(dataset.GetInterval()).To(Equal(time.Minute))
+		})
+
+		It("should reject invalid series and reference queries", func() {
+			_, err := eval.LoadDataset(write(`
+metrics:
+  - name: up
+series:
+  - up{job="api" 1 1
+cases:
+  - question: Which targets are down?
+    expected_metrics: [up]
+    reference_promql: up == 0
+`))
+			Expect(err).To(MatchError(ContainSubstring("series 0")))
+
+			_, err = eval.LoadDataset(write(`
+metrics:
+  - name: up
+cases:
+  - question: Which targets are down?
+    expected_metrics: [up]
+    reference_promql: up ==
+`))
+			Expect(err).To(MatchError(ContainSubstring("invalid reference_promql")))
+		})
+	})
+
+	Context("Run", func() {
+		var (
+			tempDir    string
+			embeddings *httptest.Server
+			cfg        config.Config
+		)
+
+		BeforeEach(func() {
+			var err error
+			tempDir, err = os.MkdirTemp("", "eval_test")
+			Expect(err).NotTo(HaveOccurred())
+
+			embeddings = startFakeEmbeddingsAPI()
+
+			loaded, err := config.Load()
+			Expect(err).NotTo(HaveOccurred())
+			cfg = *loaded
+
+			cfg.VectorDB.Provider = "sqlite3"
+			cfg.VectorDB.Sqlite3DBPath = filepath.Join(tempDir, "metrics.db")
+			cfg.VectorDB.EncoderProvider = "openai"
+			cfg.VectorDB.EncoderBaseURL = embeddings.URL
+			cfg.VectorDB.EncoderModel = "keywords"
+			cfg.VectorDB.EncoderCachePath = ""
+			cfg.VectorDB.Collection = "eval_metrics"
+			cfg.Examples.Collection = "eval_examples"
+			cfg.Examples.Limit = 0
+		})
+
+		AfterEach(func() {
+			embeddings.Close()
+			Expect(os.RemoveAll(tempDir)).To(Succeed())
+		})
+
+		It("should score the reference queries as correct", func() {
+			report, err := eval.Run(context.Background(), cfg, newDataset(), eval.Options{LLMMode: eval.LLMModeReference, K: 1})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(report.Summary.Cases).To(Equal(3))
+			Expect(report.Summary.RecallAtK).To(Equal(1.0))
+			Expect(report.Summary.MRR).To(Equal(1.0))
+			Expect(report.Summary.ParseSuccessRate).To(Equal(1.0))
+			Expect(report.Summary.EquivalenceRate).To(Equal(1.0))
+			Expect(report.Summary.Errors).To(BeZero())
+
+			Expect(report.Cases[0].RetrievedMetrics[0]).To(Equal("up"))
+			Expect(report.Cases[0].PromQL).To(Equal("up == 0"))
+		})
+
+		It("should score replayed answers", func() {
+			recordings := &eval.Recordings{Responses: map[string]string{
+				// Equivalent to the reference query
+				"Which targets are down?": "<root><query><promql>up{job=\"api\"} == 0</promql></query></root>",
+				// Valid but returning other results
+				"What is the HTTP request rate per code?": "<root><query><promql>sum(rate(http_requests_total[5m]))</promql></query></root>",
+				// Not valid PromQL
+				"How much memory is available?": "<root><query><promql>sum(node_memory_MemAvailable_bytes</promql></query></root>",
+			}}
+
+			report, err := eval.Run(context.Background(), cfg, newDataset(), eval.Options{LLMMode: eval.LLMModeReplay, Recordings: recordings})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(report.Cases[0].Equivalent).To(BeTrue())
+			Expect(report.Cases[1].Parsed).To(BeTrue())
+			Expect(report.Cases[1].Equivalent).To(BeFalse())
+			Expect(report.Cases[2].Parsed).To(BeFalse())
+			Expect(report.Cases[2].Error).To(ContainSubstring("failed to parse query"))
+
+			Expect(report.Summary.ParseSuccessRate).To(BeNumerically("~", 2.0/3, 1e-9))
+			Expect(report.Summary.EquivalenceRate).To(BeNumerically("~", 1.0/3, 1e-9))
+			Expect(report.Summary.Errors).To(BeZero())
+		})
+
+		It("should report questions without a recorded answer as errors", func() {
+			recordings := &eval.Recordings{Responses: map[string]string{}}
+
+			report, err := eval.Run(context.Background(), cfg, newDataset(), eval.Options{LLMMode: eval.LLMModeReplay, Recordings: recordings})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(report.Summary.Errors).To(Equal(3))
+			Expect(report.Cases[0].Answered).To(BeFalse())
+			Expect(report.Cases[0].Error).NotTo(BeEmpty())
+		})
+
+		It("should record the answers of the LLM", func() {
+			var requests int
+			llm := startFakeLLM("<root><query><promql>up == 0</promql></query></root>", &requests)
+			defer llm.Close()
+			cfg.LLM.BaseURL = llm.URL
+
+			recordings := &eval.Recordings{}
+			report, err := eval.Run(context.Background(), cfg, newDataset(), eval.Options{LLMMode: eval.LLMModeRecord, Recordings: recordings})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(requests).To(Equal(3))
+			Expect(recordings.Responses).To(HaveLen(3))
+			Expect(recordings.Responses).To(HaveKeyWithValue("Which targets are down?",
+				"<root><query><promql>up == 0</promql></query></root>"))
+			Expect(report.Summary.EquivalenceRate).To(BeNumerically("~", 1.0/3, 1e-9))
+
+			filePath := filepath.Join(tempDir, "recordings.yaml")
+			Expect(recordings.Save(filePath)).To(Succeed())
+
+			loaded, err := eval.LoadRecordings(filePath)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(loaded.Responses).To(Equal(recordings.Responses))
+		})
+
+		It("should require recordings to replay", func() {
+			_, err := eval.Run(context.Background(), cfg, newDataset(), eval.Options{LLMMode: eval.LLMModeReplay})
+			Expect(err).To(MatchError(ContainSubstring("recordings are required")))
+		})
+	})
+
+	Context("Report", func() {
+		report := &eval.Report{
+			LLMMode: eval.LLMModeReplay,
+			Summary: eval.Summary{Cases: 1, K: 5, RecallAtK: 1, ParseSuccessRate: 1},
+			Cases: []eval.CaseResult{{
+				Question:        "Which targets are down?",
+				PromQL:          "up == 0 or absent(up)",
+				ReferencePromQL: "up == 0",
+				Answered:        true,
+				Parsed:          true,
+				RecallAtK:       1,
+			}},
+		}
+
+		It("should write a Markdown report", func() {
+			var b bytes.Buffer
+			Expect(report.Write(&b, eval.FormatMarkdown)).To(Succeed())
+
+			Expect(b.String()).To(ContainSubstring("| Recall@5 | 1.000 |"))
+			Expect(b.String()).To(ContainSubstring("| Parse success rate | 100.0% |"))
+			Expect(b.String()).To(ContainSubstring("| Which targets are down? | 1.00 | yes | no | `up == 0 or absent(up)` | `up == 0` |  |"))
+		})
+
+		It("should write a JSON report", func() {
+			var b bytes.Buffer
+			Expect(report.Write(&b, eval.FormatJSON)).To(Succeed())
+
+			var decoded eval.Report
+			Expect(json.Unmarshal(b.Bytes(), &decoded)).To(Succeed())
+			Expect(decoded.Summary).To(Equal(report.Summary))
+			Expect(decoded.Cases).To(Equal(report.Cases))
+		})
+
+		It("should reject unknown formats", func() {
+			Expect(report.Write(&bytes.Buffer{}, "html")).To(HaveOccurred())
+		})
+	})
+})
@@ -0,0 +1,282 @@
+package eval
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"gopkg.in/yaml.v3"
+)
+
+// LLM modes
+const (
+	// LLMModeLive sends the prompts to the configured LLM
+	LLMModeLive = "live"
+	// LLMModeRecord sends the prompts to the configured LLM and records its answers
+	LLMModeRecord = "record"
+	// LLMModeReplay answers with the recorded answers, without calling an LLM
+	LLMModeReplay = "replay"
+	// LLMModeReference answers with the reference query of each case, to
+	// check the dataset and measure retrieval on its own
+	LLMModeReference = "reference"
+)
+
+// upstreamTimeout is the timeout of the requests forwarded to the LLM when recording
+const upstreamTimeout = 2 * time.Minute
+
+// Recordings are the answers of an LLM to the dataset questions
+type Recordings struct {
+	// Responses maps each question to the content of the LLM answer
+	Responses map[string]string `json:"responses" yaml:"responses"`
+}
+
+// LoadRecordings loads recordings from a YAML or JSON file
+func LoadRecordings(filePath string) (*Recordings, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read recordings file: %w", err)
+	}
+
+	var recordings Recordings
+	if err := yaml.Unmarshal(data, &recordings); err != nil {
+		return nil, fmt.Errorf("failed to parse recordings file: %w", err)
+	}
+
+	if recordings.Responses == nil {
+		recordings.Responses = make(map[string]string)
+	}
+
+	return &recordings, nil
+}
+
+// Save writes the recordings to a YAML file
+func (r *Recordings) Save(filePath string) error {
+	data, err := yaml.Marshal(r)
+	if err != nil {
+		return fmt.Errorf("failed to marshal recordings: %w", err)
+	}
+
+	if dir := filepath.Dir(filePath); dir != "" {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return fmt.Errorf("failed to create recordings directory %s: %w", dir, err)
+		}
+	}
+
+	if err := os.WriteFile(filePath, data, 0600); err != nil {
+		return fmt.Errorf("failed to write recordings file: %w", err)
+	}
+
+	return nil
+}
+
+// stubLLM serves an OpenAI-compatible chat completions endpoint answering
+// from recordings or reference queries, or forwarding to an upstream LLM
+type stubLLM struct {
+	server *httptest.Server
+	mode   string
+
+	mu         sync.Mutex
+	recordings *Recordings
+	references map[string]string
+
+	upstreamURL    string
+	upstreamAPIKey string
+	client         *http.Client
+}
+
+func newStubLLM(mode string, dataset *Dataset, recordings *Recordings, upstreamURL, upstreamAPIKey string) *stubLLM {
+	l := &stubLLM{
+		mode:           mode,
+		recordings:     recordings,
+		references:     make(map[string]string, len(dataset.Cases)),
+		upstreamURL:    strings.TrimSuffix(upstreamURL, "/") + "/",
+		upstreamAPIKey: upstreamAPIKey,
+		client:         &http.Client{Timeout: upstreamTimeout},
+	}
+
+	for _, c := range dataset.Cases {
+		l.references[c.Question] = c.ReferencePromQL
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/chat/completions", l.handleChatCompletions)
+	l.server = httptest.NewServer(mux)
+
+	return l
+}
+
+// URL returns the base URL of the stub
+func (l *stubLLM) URL() string {
+	return l.server.URL + "/"
+}
+
+// Close stops the stub
+func (l *stubLLM) Close() {
+	l.server.Close()
+}
+
+func (l *stubLLM) handleChatCompletions(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	question, err := userMessage(body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var content string
+	switch l.mode {
+	case LLMModeReference:
+		reference, ok := l.references[question]
+		if !ok {
+			http.Error(w, fmt.Sprintf("no reference query for question '%s'", question), http.StatusNotFound)
+			return
+		}
+		content, err = referenceResponse(reference)
+	case LLMModeReplay:
+		var ok bool
+		l.mu.Lock()
+		content, ok = l.recordings.Responses[question]
+		l.mu.Unlock()
+		if !ok {
+			http.Error(w, fmt.Sprintf("no recorded response for question '%s'", question), http.StatusNotFound)
+			return
+		}
+	case LLMModeRecord:
+		content, err = l.forward(r, body)
+		if err == nil {
+			l.mu.Lock()
+			l.recordings.Responses[question] = content
+			l.mu.Unlock()
+		}
+	default:
+		err = fmt.Errorf("unsupported LLM mode '%s'", l.mode)
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(map[string]any{
+		"id":      "eval",
+		"object":  "chat.completion",
+		"created": time.Now().Unix(),
+		"model":   "eval",
+		"choices": []map[string]any{{
+			"index":         0,
+			"finish_reason": "stop",
+			"message":       map[string]string{"role": "assistant", "content": content},
+		}},
+	}); err != nil {
+		log.Error().Err(err).Msg("failed to encode stub LLM response")
+	}
+}
+
+// forward sends the request to the upstream LLM and returns the content of its answer
+func (l *stubLLM) forward(r *http.Request, body []byte) (string, error) {
+	request, err := http.NewRequestWithContext(r.Context(), http.MethodPost, l.upstreamURL+"chat/completions", bytes.NewReader(body))
+	if err != nil {
+		return "", fmt.Errorf("failed to create upstream request: %w", err)
+	}
+	request.Header.Set("Content-Type", "application/json")
+	if l.upstreamAPIKey != "" {
+		request.Header.Set("Authorization", "Bearer "+l.upstreamAPIKey)
+	}
+
+	response, err := l.client.Do(request)
+	if err != nil {
+		return "", fmt.Errorf("failed to call upstream LLM: %w", err)
+	}
+	defer func() {
+		_ = response.Body.Close()
+	}()
+
+	if response.StatusCode != http.StatusOK {
+		data, _ := io.ReadAll(response.Body)
+		return "", fmt.Errorf("upstream LLM returned %s: %s", response.Status, strings.TrimSpace(string(data)))
+	}
+
+	var completion struct {
+		Choices []struct {
+			Message struct {
+				Content string `json:"content"`
+			} `json:"message"`
+		} `json:"choices"`
+	}
+	if err := json.NewDecoder(response.Body).Decode(&completion); err != nil {
+		return "", fmt.Errorf("failed to decode upstream response: %w", err)
+	}
+
+	if len(completion.Choices) == 0 {
+		return "", errors.New("upstream LLM returned no choices")
+	}
+
+	return completion.Choices[0].Message.Content, nil
+}
+
+// userMessage returns the text of the last user message of a chat completion request
+func userMessage(body []byte) (string, error) {
+	var request struct {
+		Messages []struct {
+			Role    string          `json:"role"`
+			Content json.RawMessage `json:"content"`
+		} `json:"messages"`
+	}
+	if err := json.Unmarshal(body, &request); err != nil {
+		return "", fmt.Errorf("failed to decode request: %w", err)
+	}
+
+	for i := len(request.Messages) - 1; i >= 0; i-- {
+		message := request.Messages[i]
+		if message.Role != "user" {
+			continue
+		}
+
+		// The content is either a string or a list of parts
+		var text string
+		if err := json.Unmarshal(message.Content, &text); err == nil {
+			return text, nil
+		}
+
+		var parts []struct {
+			Text string `json:"text"`
+		}
+		if err := json.Unmarshal(message.Content, &parts); err != nil {
+			return "", fmt.Errorf("failed to decode user message: %w", err)
+		}
+
+		texts := make([]string, len(parts))
+		for j, part := range parts {
+			texts[j] = part.Text
+		}
+		return strings.Join(texts, ""), nil
+	}
+
+	return "", errors.New("request has no user message")
+}
+
+// referenceResponse wraps a query in the XML answer format the prompt asks for
+func referenceResponse(promql string) (string, error) {
+	var escaped strings.Builder
+	if err := xml.EscapeText(&escaped, []byte(promql)); err != nil {
+		return "", fmt.Errorf("failed to escape reference query: %w", err)
+	}
+
+	return fmt.Sprintf("<root><query><promql>%s</promql></query></root>", escaped.String()), nil
+}
@@ -0,0 +1,126 @@
+package eval
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/rs/zerolog/log"
+)
+
+// stubPrometheus serves the metadata and label discovery endpoints of the
+// Prometheus HTTP API from a dataset
+type stubPrometheus struct {
+	server   *httptest.Server
+	metadata map[string][]map[string]string
+	storage  *seriesStorage
+}
+
+func newStubPrometheus(dataset *Dataset, storage *seriesStorage) *stubPrometheus {
+	p := &stubPrometheus{
+		metadata: make(map[string][]map[string]string, len(dataset.Metrics)),
+		storage:  storage,
+	}
+
+	for _, metric := range dataset.Metrics {
+		p.metadata[metric.Name] = append(p.metadata[metric.Name], map[string]string{
+			"type": metric.Type,
+			"help": metric.Help,
+			"unit": metric.Unit,
+		})
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v1/metadata", p.handleMetadata)
+	mux.HandleFunc("/api/v1/series", p.handleSeries)
+	mux.HandleFunc("/api/v1/labels", p.handleLabels)
+	p.server = httptest.NewServer(mux)
+
+	return p
+}
+
+// URL returns the address of the stub
+func (p *stubPrometheus) URL() string {
+	return p.server.URL
+}
+
+// Close stops the stub
+func (p *stubPrometheus) Close() {
+	p.server.Close()
+}
+
+func (p *stubPrometheus) handleMetadata(w http.ResponseWriter, _ *http.Request) {
+	writeAPIResponse(w, p.metadata)
+}
+
+func (p *stubPrometheus) handleSeries(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		writeAPIError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	series, err := p.storage.series(r.Context(), r.Form["match[]"])
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	data := make([]map[string]string, len(series))
+	for i, lbls := range series {
+		data[i] = lbls.Map()
+	}
+
+	writeAPIResponse(w, data)
+}
+
+func (p *stubPrometheus) handleLabels(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		writeAPIError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	selectors := r.Form["match[]"]
+	if len(selectors) == 0 {
+		selectors = []string{`{__name__=~".+"}`}
+	}
+
+	series, err := p.storage.series(r.Context(), selectors)
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	names := make(map[string]struct{})
+	for _, lbls := range series {
+		lbls.Range(func(l labels.Label) {
+			names[l.Name] = struct{}{}
+		})
+	}
+
+	data := make([]string, 0, len(names))
+	for name := range names {
+		data = append(data, name)
+	}
+	sort.Strings(data)
+
+	writeAPIResponse(w, data)
+}
+
+func writeAPIResponse(w http.ResponseWriter, data any) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(map[string]any{"status": "success", "data": data}); err != nil {
+		log.Error().Err(err).Msg("failed to encode stub prometheus response")
+	}
+}
+
+func writeAPIError(w http.ResponseWriter, status int, err error) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(map[string]any{
+		"status": "error", "errorType": "bad_data", "error": err.Error(),
+	}); err != nil {
+		log.Error().Err(err).Msg("failed to encode stub prometheus response")
+	}
+}
@@ -0,0 +1,169 @@
+package eval
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// Report formats
+const (
+	FormatJSON     = "json"
+	FormatMarkdown = "markdown"
+)
+
+// Report is the outcome of an evaluation run
+type Report struct {
+	LLMMode   string    `json:"llm_mode"`
+	StartedAt time.Time `json:"started_at"`
+
+	Summary Summary      `json:"summary"`
+	Cases   []CaseResult `json:"cases"`
+}
+
+// Summary aggregates the scores of all cases
+type Summary struct {
+	Cases int `json:"cases"`
+	// K is the number of retrieved metrics recall is measured on, 0 for all
+	// the metrics added to the prompt
+	K int `json:"k"`
+
+	// RecallAtK is the mean fraction of expected metrics retrieved in the top K
+	RecallAtK float64 `json:"recall_at_k"`
+	// MRR is the mean reciprocal rank of the first expected metric
+	MRR float64 `json:"mrr"`
+	// ParseSuccessRate is the fraction of answers that are valid PromQL
+	ParseSuccessRate float64 `json:"parse_success_rate"`
+	// EquivalenceRate is the fraction of answers returning the same results
+	// as the reference query over the dataset series
+	EquivalenceRate float64 `json:"equivalence_rate"`
+
+	// Errors is the number of questions the RAG failed to answer
+	Errors int `json:"errors"`
+	// MeanLatency is the mean time taken to answer a question
+	MeanLatency time.Duration `json:"mean_latency"`
+}
+
+// CaseResult is the answer to a case and its scores
+type CaseResult struct {
+	Question         string   `json:"question"`
+	ExpectedMetrics  []string `json:"expected_metrics"`
+	RetrievedMetrics []string `json:"retrieved_metrics"`
+	ReferencePromQL  string   `json:"reference_promql"`
+	PromQL           string   `json:"promql"`
+
+	// Answered is false when the RAG failed to answer the question
+	Answered       bool    `json:"answered"`
+	RecallAtK      float64 `json:"recall_at_k"`
+	ReciprocalRank float64 `json:"reciprocal_rank"`
+	Parsed         bool    `json:"parsed"`
+	Equivalent     bool    `json:"equivalent"`
+
+	Latency time.Duration `json:"latency"`
+	// Error is set when the question could not be answered or the answer
+	// could not be compared with the reference query
+	Error string `json:"error,omitempty"`
+}
+
+func summarize(cases []CaseResult, k int) Summary {
+	summary := Summary{Cases: len(cases), K: k}
+	if len(cases) == 0 {
+		return summary
+	}
+
+	var latency time.Duration
+	for _, c := range cases {
+		summary.RecallAtK += c.RecallAtK
+		summary.MRR += c.ReciprocalRank
+		if c.Parsed {
+			summary.ParseSuccessRate++
+		}
+		if c.Equivalent {
+			summary.EquivalenceRate++
+		}
+		if !c.Answered {
+			summary.Errors++
+		}
+		latency += c.Latency
+	}
+
+	total := float64(len(cases))
+	summary.RecallAtK /= total
+	summary.MRR /= total
+	summary.ParseSuccessRate /= total
+	summary.EquivalenceRate /= total
+	summary.MeanLatency = latency / time.Duration(len(cases))
+
+	return summary
+}
+
+// Write writes the report in the given format
+func (r *Report) Write(w io.Writer, format string) error {
+	switch format {
+	case FormatJSON:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(r)
+	case FormatMarkdown:
+		_, err := io.WriteString(w, r.Markdown())
+		return err
+	default:
+		return fmt.Errorf("unsupported report format '%s', supported formats: %s, %s", format, FormatJSON, FormatMarkdown)
+	}
+}
+
+// Markdown renders the report as a Markdown document
+func (r *Report) Markdown() string {
+	var b strings.Builder
+
+	k := "all"
+	if r.Summary.K > 0 {
+		k = fmt.Sprint(r.Summary.K)
+	}
+
+	fmt.Fprintf(&b, "# Evaluation report\n\n")
+	fmt.Fprintf(&b, "Started at %s with the %s LLM mode.\n\n", r.StartedAt.Format(time.RFC3339), r.LLMMode)
+
+	fmt.Fprintf(&b, "## Summary\n\n")
+	fmt.Fprintf(&b, "| Metric | Value |\n|---|---|\n")
+	fmt.Fprintf(&b, "| Cases | %d |\n", r.Summary.Cases)
+	fmt.Fprintf(&b, "| Recall@%s | %.3f |\n", k, r.Summary.RecallAtK)
+	fmt.Fprintf(&b, "| MRR | %.3f |\n", r.Summary.MRR)
+	fmt.Fprintf(&b, "| Parse success rate | %.1f%% |\n", 100*r.Summary.ParseSuccessRate)
+	fmt.Fprintf(&b, "| Equivalence rate | %.1f%% |\n", 100*r.Summary.EquivalenceRate)
+	fmt.Fprintf(&b, "| Errors | %d |\n", r.Summary.Errors)
+	fmt.Fprintf(&b, "| Mean latency | %s |\n\n", r.Summary.MeanLatency.Round(time.Millisecond))
+
+	fmt.Fprintf(&b, "## Cases\n\n")
+	fmt.Fprintf(&b, "| Question | Recall@%s | Parsed | Equivalent | PromQL | Reference | Error |\n", k)
+	fmt.Fprintf(&b, "|---|---|---|---|---|---|---|\n")
+	for _, c := range r.Cases {
+		fmt.Fprintf(&b, "| %s | %.2f | %s | %s | %s | %s | %s |\n",
+			markdownCell(c.Question), c.RecallAtK, checkmark(c.Parsed), checkmark(c.Equivalent),
+			markdownCode(c.PromQL), markdownCode(c.ReferencePromQL), markdownCell(c.Error))
+	}
+
+	return b.String()
+}
+
+func checkmark(ok bool) string {
+	if ok {
+		return "yes"
+	}
+	return "no"
+}
+
+// markdownCell escapes the characters that would break a table cell
+func markdownCell(s string) string {
+	s = strings.ReplaceAll(s, "|", `\|`)
+	return strings.Join(strings.Fields(s), " ")
+}
+
+func markdownCode(s string) string {
+	if s == "" {
+		return ""
+	}
+	return "`" + markdownCell(s) + "`"
+}
@@ -0,0 +1,262 @@
+package eval
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/prometheus/prometheus/promql/parser"
+	"github.com/rs/zerolog/log"
+
+	"github.com/machadovilaca/prometheus-rag/pkg/config"
+	"github.com/machadovilaca/prometheus-rag/pkg/rag"
+	"github.com/machadovilaca/prometheus-rag/pkg/vectordb"
+)
+
+const (
+	defaultSyncTimeout = 5 * time.Minute
+	syncPollInterval   = 100 * time.Millisecond
+)
+
+// Options configures an evaluation run
+type Options struct {
+	// LLMMode is how the questions are answered, LLMModeReplay by default
+	LLMMode string
+
+	// Recordings are the answers replayed in LLMModeReplay, and are filled
+	// with the answers of the LLM in LLMModeRecord
+	Recordings *Recordings
+
+	// K is the number of retrieved metrics recall is measured on, 0 uses all
+	// the metrics added to the prompt
+	K int
+
+	// ResetCollections deletes the metrics and examples collections before
+	// the run, so metrics of earlier runs are not retrieved
+	ResetCollections bool
+
+	// SyncTimeout is how long to wait for the dataset metrics to be indexed
+	SyncTimeout time.Duration
+}
+
+// Run answers the dataset questions with a RAG built from cfg, pointed at a
+// stub Prometheus serving the dataset and, unless in LLMModeLive, at a stub
+// LLM, and scores the answers. Description enrichment, feedback and the
+// capture of approved answers are disabled so runs are reproducible. The
+// remaining cases fail once ctx is done.
+func Run(ctx context.Context, cfg config.Config, dataset *Dataset, options Options) (*Report, error) {
+	if options.LLMMode == "" {
+		options.LLMMode = LLMModeReplay
+	}
+
+	switch options.LLMMode {
+	case LLMModeLive, LLMModeReference:
+	case LLMModeReplay:
+		if options.Recordings == nil {
+			return nil, errors.New("recordings are required to replay LLM answers")
+		}
+	case LLMModeRecord:
+		if options.Recordings == nil {
+			return nil, errors.New("recordings are required to record LLM answers")
+		}
+		if options.Recordings.Responses == nil {
+			options.Recordings.Responses = make(map[string]string)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported LLM mode '%s', supported modes: %s, %s, %s, %s",
+			options.LLMMode, LLMModeLive, LLMModeRecord, LLMModeReplay, LLMModeReference)
+	}
+
+	if options.SyncTimeout <= 0 {
+		options.SyncTimeout = defaultSyncTimeout
+	}
+
+	storage, err := newSeriesStorage(dataset)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = storage.Close()
+	}()
+
+	stubPrometheus := newStubPrometheus(dataset, storage)
+	defer stubPrometheus.Close()
+
+	cfg.Prometheus.Address = stubPrometheus.URL()
+	cfg.Prometheus.LabelsRateLimit = 0
+	cfg.LLM.EnrichDescriptions = false
+	cfg.Feedback.DBPath = ""
+	cfg.Quota.DBPath = ""
+	cfg.Examples.AutoCapture = false
+	// Similar questions of a dataset must each reach the LLM
+	cfg.Cache.TTLMinutes = 0
+
+	if options.LLMMode != LLMModeLive {
+		stubLLM := newStubLLM(options.LLMMode, dataset, options.Recordings, cfg.LLM.BaseURL, cfg.LLM.APIKey)
+		defer stubLLM.Close()
+
+		cfg.LLM.BaseURL = stubLLM.URL()
+	}
+
+	if options.ResetCollections {
+		if err := resetCollections(ctx, cfg.ToVectorDBConfig()); err != nil {
+			return nil, err
+		}
+	}
+
+	client, err := rag.New(&cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create RAG client: %w", err)
+	}
+
+	if err := waitForSync(ctx, client, options.SyncTimeout); err != nil {
+		return nil, err
+	}
+
+	report := &Report{
+		LLMMode:   options.LLMMode,
+		StartedAt: time.Now(),
+		Cases:     make([]CaseResult, len(dataset.Cases)),
+	}
+
+	for i, c := range dataset.Cases {
+		log.Info().Msgf("evaluating case %d/%d: %s", i+1, len(dataset.Cases), c.Question)
+		report.Cases[i] = evaluateCase(ctx, client, storage, c, options.K)
+	}
+
+	report.Summary = summarize(report.Cases, options.K)
+	return report, nil
+}
+
+// evaluateCase answers a question and scores the answer against the case
+func evaluateCase(ctx context.Context, client *rag.Client, storage *seriesStorage, c Case, k int) CaseResult {
+	result := CaseResult{
+		Question:        c.Question,
+		ExpectedMetrics: c.ExpectedMetrics,
+		ReferencePromQL: c.ReferencePromQL,
+	}
+
+	start := time.Now()
+	answer, err := client.Query(ctx, c.Question)
+	result.Latency = time.Since(start)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+
+	result.Answered = true
+	result.PromQL = answer.PromQL
+	result.RetrievedMetrics = answer.Metrics
+	result.RecallAtK, result.ReciprocalRank = scoreRetrieval(answer.Metrics, c.ExpectedMetrics, k)
+
+	if _, err := parser.ParseExpr(answer.PromQL); err != nil {
+		result.Error = fmt.Sprintf("failed to parse query: %s", err)
+		return result
+	}
+	result.Parsed = true
+
+	result.Equivalent, err = storage.equivalent(ctx, answer.PromQL, c.ReferencePromQL)
+	if err != nil {
+		result.Error = err.Error()
+	}
+
+	return result
+}
+
+// scoreRetrieval returns the fraction of expected metrics in the top k
+// retrieved ones and the reciprocal rank of the first expected metric
+func scoreRetrieval(retrieved, expected []string, k int) (float64, float64) {
+	if len(expected) == 0 {
+		return 0, 0
+	}
+
+	if k <= 0 || k > len(retrieved) {
+		k = len(retrieved)
+	}
+
+	expectedSet := make(map[string]struct{}, len(expected))
+	for _, name := range expected {
+		expectedSet[name] = struct{}{}
+	}
+
+	var (
+		found          int
+		reciprocalRank float64
+	)
+	for rank, name := range retrieved[:k] {
+		if _, ok := expectedSet[name]; !ok {
+			continue
+		}
+		if reciprocalRank == 0 {
+			reciprocalRank = 1 / float64(rank+1)
+		}
+		found++
+	}
+
+	return float64(found) / float64(len(expectedSet)), reciprocalRank
+}
+
+// waitForSync waits for the first synchronization of the dataset metrics
+func waitForSync(ctx context.Context, client *rag.Client, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+
+	for time.Now().Before(deadline) {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		if report := client.LastSyncReport(); report != nil {
+			if report.Error != "" {
+				return fmt.Errorf("failed to sync dataset metrics: %s", report.Error)
+			}
+			if report.HasFailures() {
+				log.Warn().Msgf("labels of %d dataset metrics could not be discovered", len(report.Failures))
+			}
+			return nil
+		}
+
+		time.Sleep(syncPollInterval)
+	}
+
+	return fmt.Errorf("dataset metrics were not synced after %s", timeout)
+}
+
+// resetCollections deletes the metrics and examples collections
+func resetCollections(ctx context.Context, cfg vectordb.Config) error {
+	log.Info().Msgf("resetting collections %s and %s", cfg.CollectionName, cfg.ExamplesCollectionName)
+
+	// The collections are deleted anyway, so a model mismatch must not fail the reset
+	cfg.OnModelMismatch = vectordb.ModelMismatchReindex
+
+	encoder, err := vectordb.NewEncoder(cfg)
+	if err != nil {
+		return err
+	}
+
+	client, err := vectordb.NewWithEncoder(cfg, encoder)
+	if err != nil {
+		return fmt.Errorf("failed to create vectordb client: %w", err)
+	}
+	defer func() {
+		_ = client.Close()
+	}()
+
+	if err := client.DeleteCollection(ctx); err != nil {
+		return fmt.Errorf("failed to delete metrics collection: %w", err)
+	}
+
+	exampleStore, err := vectordb.NewExampleStore(cfg, encoder)
+	if err != nil {
+		return fmt.Errorf("failed to create examples vectordb client: %w", err)
+	}
+	defer func() {
+		_ = exampleStore.Close()
+	}()
+
+	if err := exampleStore.DeleteCollection(ctx); err != nil {
+		return fmt.Errorf("failed to delete examples collection: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,234 @@
+package eval
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/promql"
+	"github.com/prometheus/prometheus/promql/parser"
+	"github.com/prometheus/prometheus/tsdb"
+)
+
+const (
+	queryTimeout    = 30 * time.Second
+	queryMaxSamples = 10000000
+
+	// equivalenceTolerance is the maximum relative difference of two samples
+	// considered equal, absorbing floating point differences of equivalent queries
+	equivalenceTolerance = 1e-9
+)
+
+// seriesStorage holds the series of a dataset in a temporary TSDB and
+// evaluates queries over them
+type seriesStorage struct {
+	dir    string
+	db     *tsdb.DB
+	engine *promql.Engine
+
+	start    time.Time
+	end      time.Time
+	interval time.Duration
+}
+
+// newSeriesStorage loads the dataset series, sampled every interval from the Unix epoch
+func newSeriesStorage(dataset *Dataset) (*seriesStorage, error) {
+	dir, err := os.MkdirTemp("", "prag-eval-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create storage directory: %w", err)
+	}
+
+	db, err := tsdb.Open(dir, nil, nil, tsdb.DefaultOptions(), nil)
+	if err != nil {
+		_ = os.RemoveAll(dir)
+		return nil, fmt.Errorf("failed to open storage: %w", err)
+	}
+
+	s := &seriesStorage{
+		dir: dir,
+		db:  db,
+		engine: promql.NewEngine(promql.EngineOpts{
+			MaxSamples: queryMaxSamples,
+			Timeout:    queryTimeout,
+		}),
+		start:    time.Unix(0, 0),
+		interval: dataset.GetInterval(),
+	}
+	s.end = s.start
+
+	if err := s.load(dataset.Series); err != nil {
+		_ = s.Close()
+		return nil, err
+	}
+
+	return s, nil
+}
+
+func (s *seriesStorage) load(series []string) error {
+	app := s.db.Appender(context.Background())
+
+	for _, desc := range series {
+		lbls, values, err := parser.ParseSeriesDesc(desc)
+		if err != nil {
+			_ = app.Rollback()
+			return fmt.Errorf("failed to parse series '%s': %w", desc, err)
+		}
+
+		for i, value := range values {
+			if value.Omitted {
+				continue
+			}
+			if value.Histogram != nil {
+				_ = app.Rollback()
+				return fmt.Errorf("series '%s': native histograms are not supported", desc)
+			}
+
+			ts := s.start.Add(time.Duration(i) * s.interval)
+			if _, err := app.Append(0, lbls, ts.UnixMilli(), value.Value); err != nil {
+				_ = app.Rollback()
+				return fmt.Errorf("failed to append sample of series '%s': %w", desc, err)
+			}
+
+			if ts.After(s.end) {
+				s.end = ts
+			}
+		}
+	}
+
+	if err := app.Commit(); err != nil {
+		return fmt.Errorf("failed to commit series: %w", err)
+	}
+
+	return nil
+}
+
+// series returns the label sets of the series matching any of the selectors
+func (s *seriesStorage) series(ctx context.Context, selectors []string) ([]labels.Labels, error) {
+	querier, err := s.db.Querier(math.MinInt64, math.MaxInt64)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create querier: %w", err)
+	}
+	defer func() {
+		_ = querier.Close()
+	}()
+
+	seen := make(map[string]bool)
+	var results []labels.Labels
+
+	for _, selector := range selectors {
+		matchers, err := parser.ParseMetricSelector(selector)
+		if err != nil {
+			return nil, fmt.Errorf("invalid selector '%s': %w", selector, err)
+		}
+
+		set := querier.Select(ctx, false, nil, matchers...)
+		for set.Next() {
+			lbls := set.At().Labels().Copy()
+			if key := lbls.String(); !seen[key] {
+				seen[key] = true
+				results = append(results, lbls)
+			}
+		}
+		if err := set.Err(); err != nil {
+			return nil, fmt.Errorf("failed to select series: %w", err)
+		}
+	}
+
+	return results, nil
+}
+
+// evaluate runs a query at every sample of the dataset
+func (s *seriesStorage) evaluate(ctx context.Context, query string) (promql.Matrix, error) {
+	q, err := s.engine.NewRangeQuery(ctx, s.db, nil, query, s.start, s.end, s.interval)
+	if err != nil {
+		return nil, err
+	}
+	defer q.Close()
+
+	result := q.Exec(ctx)
+	if result.Err != nil {
+		return nil, result.Err
+	}
+
+	matrix, err := result.Matrix()
+	if err != nil {
+		return nil, err
+	}
+
+	// The samples are returned to a pool when the query is closed
+	copied := make(promql.Matrix, len(matrix))
+	for i, series := range matrix {
+		copied[i] = promql.Series{
+			Metric: series.Metric.Copy(),
+			Floats: append([]promql.FPoint(nil), series.Floats...),
+		}
+	}
+
+	return copied, nil
+}
+
+// equivalent reports whether two queries return the same series and samples
+// over the dataset, ignoring the order of the series
+func (s *seriesStorage) equivalent(ctx context.Context, query, reference string) (bool, error) {
+	expected, err := s.evaluate(ctx, reference)
+	if err != nil {
+		return false, fmt.Errorf("failed to evaluate reference query: %w", err)
+	}
+	if len(expected) == 0 {
+		return false, fmt.Errorf("reference query returned no data")
+	}
+
+	actual, err := s.evaluate(ctx, query)
+	if err != nil {
+		return false, fmt.Errorf("failed to evaluate query: %w", err)
+	}
+
+	return matricesEqual(actual, expected), nil
+}
+
+// Close closes and removes the storage
+func (s *seriesStorage) Close() error {
+	err := s.db.Close()
+	if removeErr := os.RemoveAll(s.dir); err == nil {
+		err = removeErr
+	}
+	return err
+}
+
+func matricesEqual(a, b promql.Matrix) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	sort.Sort(a)
+	sort.Sort(b)
+
+	for i := range a {
+		if labels.Compare(a[i].Metric, b[i].Metric) != 0 || len(a[i].Floats) != len(b[i].Floats) {
+			return false
+		}
+
+		for j := range a[i].Floats {
+			if a[i].Floats[j].T != b[i].Floats[j].T || !floatsEqual(a[i].Floats[j].F, b[i].Floats[j].F) {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+func floatsEqual(a, b float64) bool {
+	if math.IsNaN(a) || math.IsNaN(b) {
+		return math.IsNaN(a) && math.IsNaN(b)
+	}
+	if a == b {
+		return true
+	}
+
+	return math.Abs(a-b) <= equivalenceTolerance*math.Max(math.Abs(a), math.Abs(b))
+}
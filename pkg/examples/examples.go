@@ -0,0 +1,97 @@
+// Package examples provides curated pairs of natural language questions and
+// the PromQL answering them, used as few-shot examples in the LLM prompt.
+package examples
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Example sources
+const (
+	// SourceCurated marks examples written by the team
+	SourceCurated = "curated"
+	// SourceApproved marks examples captured from answers approved by users
+	SourceApproved = "approved"
+)
+
+// Example is a natural language question and the PromQL expression answering it
+type Example struct {
+	ID       string `json:"id" yaml:"id,omitempty"`
+	Question string `json:"question" yaml:"question"`
+	PromQL   string `json:"promql" yaml:"promql"`
+	Source   string `json:"source,omitempty" yaml:"source,omitempty"`
+}
+
+// Validate validates the example
+func (e *Example) Validate() error {
+	if strings.TrimSpace(e.Question) == "" {
+		return errors.New("question is required")
+	}
+
+	if strings.TrimSpace(e.PromQL) == "" {
+		return errors.New("promql is required")
+	}
+
+	switch e.Source {
+	case "", SourceCurated, SourceApproved:
+	default:
+		return fmt.Errorf("unsupported source '%s', supported sources: %s, %s", e.Source, SourceCurated, SourceApproved)
+	}
+
+	return nil
+}
+
+// Normalize trims the example fields and fills in its ID and source
+func (e *Example) Normalize() {
+	e.Question = strings.TrimSpace(e.Question)
+	e.PromQL = strings.TrimSpace(e.PromQL)
+
+	if e.ID == "" {
+		e.ID = ID(e.Question)
+	}
+
+	if e.Source == "" {
+		e.Source = SourceCurated
+	}
+}
+
+// ID returns the deterministic ID of the example for a question, so adding
+// an example for a question already in the library replaces it
+func ID(question string) string {
+	normalized := strings.ToLower(strings.Join(strings.Fields(question), " "))
+	hash := sha256.Sum256([]byte(normalized))
+	return fmt.Sprintf("%x", hash[:16])
+}
+
+// file is the layout of the examples YAML file
+type file struct {
+	Examples []*Example `yaml:"examples"`
+}
+
+// LoadFile loads the examples from a YAML file
+func LoadFile(filePath string) ([]*Example, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read examples file: %w", err)
+	}
+
+	var f file
+	if err := yaml.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("failed to parse examples file: %w", err)
+	}
+
+	for i, example := range f.Examples {
+		if err := example.Validate(); err != nil {
+			return nil, fmt.Errorf("invalid example %d: %w", i, err)
+		}
+		example.Normalize()
+	}
+
+	return f.Examples, nil
+}
@@ -0,0 +1,90 @@
+package examples_test
+
+import (
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/machadovilaca/prometheus-rag/pkg/examples"
+)
+
+var _ = Describe("Examples", func() {
+	Context("Validate", func() {
+		It("should require a question and a promql expression", func() {
+			Expect((&examples.Example{PromQL: "up"}).Validate()).To(HaveOccurred())
+			Expect((&examples.Example{Question: "Which targets are up?"}).Validate()).To(HaveOccurred())
+			Expect((&examples.Example{Question: "Which targets are up?", PromQL: "up"}).Validate()).To(Succeed())
+		})
+
+		It("should reject unknown sources", func() {
+			example := &examples.Example{Question: "Which targets are up?", PromQL: "up", Source: "imported"}
+			Expect(example.Validate()).To(HaveOccurred())
+		})
+	})
+
+	Context("Normalize", func() {
+		It("should fill in the ID and source", func() {
+			example := &examples.Example{Question: "  Which targets are up? ", PromQL: " up == 1 "}
+			example.Normalize()
+
+			Expect(example.Question).To(Equal("Which targets are up?"))
+			Expect(example.PromQL).To(Equal("up == 1"))
+			Expect(example.ID).To(Equal(examples.ID("Which targets are up?")))
+			Expect(example.Source).To(Equal(examples.SourceCurated))
+		})
+
+		It("should give the same ID to questions differing in case and spacing", func() {
+			Expect(examples.ID("Which  targets are UP?")).To(Equal(examples.ID("which targets are up?")))
+			Expect(examples.ID("Which targets are up?")).NotTo(Equal(examples.ID("Which targets are down?")))
+		})
+	})
+
+	Context("LoadFile", func() {
+		var tempDir string
+
+		BeforeEach(func() {
+			var err error
+			tempDir, err = os.MkdirTemp("", "examples_test")
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		AfterEach(func() {
+			Expect(os.RemoveAll(tempDir)).To(Succeed())
+		})
+
+		write := func(content string) string {
+			filePath := filepath.Join(tempDir, "examples.yaml")
+			Expect(os.WriteFile(filePath, []byte(content), 0600)).To(Succeed())
+			return filePath
+		}
+
+		It("should load and normalize the examples", func() {
+			loaded, err := examples.LoadFile(write(`
+examples:
+  - question: What is the HTTP request rate per status code?
+    promql: sum by (code) (rate(http_requests_total[5m]))
+`))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(loaded).To(HaveLen(1))
+			Expect(loaded[0].PromQL).To(Equal("sum by (code) (rate(http_requests_total[5m]))"))
+			Expect(loaded[0].ID).NotTo(BeEmpty())
+			Expect(loaded[0].Source).To(Equal(examples.SourceCurated))
+		})
+
+		It("should reject invalid examples", func() {
+			_, err := examples.LoadFile(write(`
+examples:
+  - question: What is the HTTP request rate per status code?
+`))
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("should load the example library in hack", func() {
+			loaded, err := examples.LoadFile("../../hack/examples.yaml")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(loaded).NotTo(BeEmpty())
+		})
+	})
+})
@@ -0,0 +1,417 @@
+// Package feedback records the queries answered by the RAG and the feedback
+// users give on them, and uses negative feedback to down-weight metrics that
+// keep being retrieved for similar questions without being useful.
+package feedback
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/prometheus/prometheus/promql/parser"
+	"github.com/rs/zerolog/log"
+
+	"github.com/machadovilaca/prometheus-rag/pkg/embeddings"
+	"github.com/machadovilaca/prometheus-rag/pkg/prometheus"
+)
+
+// Feedback ratings
+const (
+	RatingUp   = "up"
+	RatingDown = "down"
+)
+
+const (
+	defaultSimilarityThreshold = 0.8
+	defaultMinNegative         = 2
+
+	// maxFeedbackScanned is the number of most recent feedback entries
+	// compared with a question to down-weight its metrics
+	maxFeedbackScanned = 1000
+)
+
+// ErrQueryNotFound is returned when feedback is given for an unknown query
+var ErrQueryNotFound = errors.New("query not found")
+
+// Query is a question answered by the RAG
+type Query struct {
+	ID string `json:"id"`
+	// Owner identifies the principal that asked the question, the only one
+	// that may give feedback on it; empty when authentication is disabled
+	Owner    string `json:"owner,omitempty"`
+	Question string `json:"question"`
+	PromQL   string `json:"promql"`
+	// Metrics are the names of the metrics added to the prompt
+	Metrics   []string  `json:"metrics"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Feedback is the rating a user gave to the answer of a query
+type Feedback struct {
+	QueryID string `json:"query_id"`
+	Rating  string `json:"rating"`
+	// CorrectedPromQL is the query the user expected instead, if given
+	CorrectedPromQL string `json:"corrected_promql,omitempty"`
+}
+
+// Validate validates the feedback, trimming the corrected PromQL so a blank
+// correction counts as absent
+func (f *Feedback) Validate() error {
+	if f.QueryID == "" {
+		return errors.New("query_id is required")
+	}
+
+	switch f.Rating {
+	case RatingUp, RatingDown:
+	default:
+		return fmt.Errorf("rating must be '%s' or '%s'", RatingUp, RatingDown)
+	}
+
+	// Corrections may be captured as examples, so they must be valid PromQL
+	f.CorrectedPromQL = strings.TrimSpace(f.CorrectedPromQL)
+	if f.CorrectedPromQL != "" {
+		if _, err := parser.ParseExpr(f.CorrectedPromQL); err != nil {
+			return fmt.Errorf("corrected_promql is not a valid PromQL expression: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// Config is the configuration of the feedback store
+type Config struct {
+	DBPath  string
+	Encoder embeddings.Encoder
+
+	// SimilarityThreshold is the minimum similarity for a past question to
+	// count towards the down-weighting of metrics for a new question
+	SimilarityThreshold float64
+	// MinNegative is the number of owners whose similar questions with
+	// negative feedback a metric must have been retrieved for before it is
+	// down-weighted, so a single user cannot skew retrieval for everyone
+	MinNegative int
+}
+
+// Store persists queries and feedback in a SQLite database
+type Store struct {
+	db     *sql.DB
+	config Config
+}
+
+// NewStore opens (or creates) the feedback database
+func NewStore(config Config) (*Store, error) {
+	if config.Encoder == nil {
+		return nil, errors.New("encoder is required")
+	}
+
+	if config.SimilarityThreshold <= 0 {
+		config.SimilarityThreshold = defaultSimilarityThreshold
+	}
+
+	if config.MinNegative <= 0 {
+		config.MinNegative = defaultMinNegative
+	}
+
+	dir := filepath.Dir(config.DBPath)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create feedback directory %s: %w", dir, err)
+	}
+
+	db, err := sql.Open("sqlite3", config.DBPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open feedback db: %w", err)
+	}
+
+	_, err = db.Exec(`
+		CREATE TABLE IF NOT EXISTS queries (
+			id TEXT PRIMARY KEY,
+			owner TEXT NOT NULL DEFAULT '',
+			question TEXT NOT NULL,
+			promql TEXT,
+			metrics TEXT,
+			embedding BLOB,
+			created_at TIMESTAMP NOT NULL
+		);
+		CREATE TABLE IF NOT EXISTS feedback (
+			query_id TEXT PRIMARY KEY,
+			rating TEXT NOT NULL,
+			corrected_promql TEXT,
+			created_at TIMESTAMP NOT NULL
+		);
+	`)
+	if err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("failed to create feedback tables: %w", err)
+	}
+
+	if err := migrateOwner(db); err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+
+	return &Store{db: db, config: config}, nil
+}
+
+// migrateOwner adds the owner column to a queries table created by an older
+// version; the queries recorded before have no owner
+func migrateOwner(db *sql.DB) error {
+	var count int
+	err := db.QueryRow(`SELECT COUNT(*) FROM pragma_table_info('queries') WHERE name = 'owner'`).Scan(&count)
+	if err != nil {
+		return fmt.Errorf("failed to read table info: %w", err)
+	}
+	if count > 0 {
+		return nil
+	}
+
+	if _, err := db.Exec(`ALTER TABLE queries ADD COLUMN owner TEXT NOT NULL DEFAULT ''`); err != nil {
+		return fmt.Errorf("failed to add column owner: %w", err)
+	}
+	log.Info().Msg("added column owner to feedback queries table")
+	return nil
+}
+
+// RecordQuery stores a query answered for owner so feedback can be given on it
+func (s *Store) RecordQuery(ctx context.Context, owner, question, promql string, metrics []string) (*Query, error) {
+	embedding, err := s.config.Encoder.EncodeQuery(ctx, question)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode question: %w", err)
+	}
+
+	query := &Query{
+		ID:        uuid.NewString(),
+		Owner:     owner,
+		Question:  question,
+		PromQL:    promql,
+		Metrics:   metrics,
+		CreatedAt: time.Now().UTC(),
+	}
+
+	_, err = s.db.ExecContext(ctx, `
+		INSERT INTO queries (id, owner, question, promql, metrics, embedding, created_at) VALUES (?, ?, ?, ?, ?, ?, ?)
+	`, query.ID, query.Owner, query.Question, query.PromQL, strings.Join(query.Metrics, ","),
+		embeddings.EncodeVector(embedding), query.CreatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to record query: %w", err)
+	}
+
+	return query, nil
+}
+
+// GetQuery returns the query with the given ID, or ErrQueryNotFound
+func (s *Store) GetQuery(ctx context.Context, id string) (*Query, error) {
+	var (
+		query   Query
+		promql  sql.NullString
+		metrics sql.NullString
+	)
+
+	err := s.db.QueryRowContext(ctx, `
+		SELECT id, owner, question, promql, metrics, created_at FROM queries WHERE id = ?
+	`, id).Scan(&query.ID, &query.Owner, &query.Question, &promql, &metrics, &query.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrQueryNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read query: %w", err)
+	}
+
+	query.PromQL = promql.String
+	query.Metrics = splitMetrics(metrics.String)
+	return &query, nil
+}
+
+// AddFeedback stores the feedback of owner on a query, replacing earlier
+// feedback on the same query, and returns the query. Queries of other owners
+// are reported as ErrQueryNotFound, so their IDs cannot be probed.
+func (s *Store) AddFeedback(ctx context.Context, owner string, feedback Feedback) (*Query, error) {
+	if err := feedback.Validate(); err != nil {
+		return nil, err
+	}
+
+	query, err := s.GetQuery(ctx, feedback.QueryID)
+	if err != nil {
+		return nil, err
+	}
+	if query.Owner != owner {
+		return nil, ErrQueryNotFound
+	}
+
+	_, err = s.db.ExecContext(ctx, `
+		INSERT OR REPLACE INTO feedback (query_id, rating, corrected_promql, created_at) VALUES (?, ?, ?, ?)
+	`, feedback.QueryID, feedback.Rating, feedback.CorrectedPromQL, time.Now().UTC())
+	if err != nil {
+		return nil, fmt.Errorf("failed to store feedback: %w", err)
+	}
+
+	return query, nil
+}
+
+// Prune deletes the queries older than the given time that received no
+// feedback, returning how many were deleted
+func (s *Store) Prune(ctx context.Context, before time.Time) (int64, error) {
+	result, err := s.db.ExecContext(ctx, `
+		DELETE FROM queries WHERE created_at < ? AND id NOT IN (SELECT query_id FROM feedback)
+	`, before.UTC())
+	if err != nil {
+		return 0, fmt.Errorf("failed to prune queries: %w", err)
+	}
+
+	return result.RowsAffected()
+}
+
+// Close closes the feedback database
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+// Rerank moves the metrics that were repeatedly retrieved for similar
+// questions with negative feedback, and never useful for them, after the
+// other metrics. Failures are logged and leave the order unchanged.
+func (s *Store) Rerank(ctx context.Context, question string, metrics []*prometheus.MetricMetadata) []*prometheus.MetricMetadata {
+	penalized, err := s.penalizedMetrics(ctx, question)
+	if err != nil {
+		log.Warn().Err(err).Msg("failed to compute feedback penalties")
+		return metrics
+	}
+	if len(penalized) == 0 {
+		return metrics
+	}
+
+	reranked := make([]*prometheus.MetricMetadata, 0, len(metrics))
+	var demoted []*prometheus.MetricMetadata
+	for _, metric := range metrics {
+		if penalized[metric.Name] {
+			demoted = append(demoted, metric)
+			continue
+		}
+		reranked = append(reranked, metric)
+	}
+
+	if len(demoted) > 0 {
+		log.Debug().Msgf("down-weighted %d metrics after negative feedback on similar questions", len(demoted))
+	}
+
+	return append(reranked, demoted...)
+}
+
+// penalizedMetrics returns the metrics retrieved for similar questions with
+// negative feedback from at least MinNegative owners that were not referenced
+// by any approved or corrected answer to a similar question. Queries without
+// an owner, asked while authentication was disabled, each count as one owner.
+// Only the most recent feedback is compared, so the cost of a query does not
+// grow with the history.
+func (s *Store) penalizedMetrics(ctx context.Context, question string) (map[string]bool, error) {
+	embedding, err := s.config.Encoder.EncodeQuery(ctx, question)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode question: %w", err)
+	}
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT q.id, q.owner, q.promql, q.metrics, q.embedding, f.rating, f.corrected_promql
+		FROM queries q JOIN feedback f ON f.query_id = q.id
+		ORDER BY f.created_at DESC LIMIT ?
+	`, maxFeedbackScanned)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query feedback: %w", err)
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	// negative holds the owners that rated down a similar question, by metric
+	negative := make(map[string]map[string]struct{})
+	useful := make(map[string]bool)
+
+	for rows.Next() {
+		var (
+			id, owner, rating          string
+			promql, metrics, corrected sql.NullString
+			data                       []byte
+		)
+		if err := rows.Scan(&id, &owner, &promql, &metrics, &data, &rating, &corrected); err != nil {
+			return nil, fmt.Errorf("failed to scan feedback: %w", err)
+		}
+
+		vector, err := embeddings.DecodeVector(data)
+		if err != nil || embeddings.CosineSimilarity(embedding, vector) < s.config.SimilarityThreshold {
+			continue
+		}
+
+		retrieved := splitMetrics(metrics.String)
+		answer := corrected.String
+		if answer == "" && rating == RatingUp {
+			answer = promql.String
+		}
+
+		referenced := ReferencedMetrics(answer, retrieved)
+		for _, name := range referenced {
+			useful[name] = true
+		}
+
+		if rating == RatingDown {
+			if owner == "" {
+				owner = "query:" + id
+			}
+			for _, name := range retrieved {
+				if negative[name] == nil {
+					negative[name] = make(map[string]struct{})
+				}
+				negative[name][owner] = struct{}{}
+			}
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating feedback: %w", err)
+	}
+
+	penalized := make(map[string]bool)
+	for name, owners := range negative {
+		if len(owners) >= s.config.MinNegative && !useful[name] {
+			penalized[name] = true
+		}
+	}
+
+	return penalized, nil
+}
+
+// ReferencedMetrics returns the metric names referenced by a PromQL
+// expression, including through the _bucket, _sum, _count and _total series
+func ReferencedMetrics(promql string, names []string) []string {
+	if promql == "" {
+		return nil
+	}
+
+	identifiers := make(map[string]bool)
+	for _, identifier := range strings.FieldsFunc(promql, func(r rune) bool {
+		return !(r == '_' || r == ':' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9')
+	}) {
+		identifiers[identifier] = true
+	}
+
+	var referenced []string
+	for _, name := range names {
+		for _, series := range []string{name, name + "_bucket", name + "_sum", name + "_count", name + "_total"} {
+			if identifiers[series] {
+				referenced = append(referenced, name)
+				break
+			}
+		}
+	}
+
+	return referenced
+}
+
+func splitMetrics(metrics string) []string {
+	if metrics == "" {
+		return nil
+	}
+	return strings.Split(metrics, ",")
+}
@@ -0,0 +1,235 @@
+package feedback_test
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/machadovilaca/prometheus-rag/pkg/embeddings"
+	"github.com/machadovilaca/prometheus-rag/pkg/feedback"
+	"github.com/machadovilaca/prometheus-rag/pkg/prometheus"
+)
+
+// topicEncoder embeds questions by the topics they mention
+type topicEncoder struct{}
+
+var topics = []string{"memory", "cpu", "disk"}
+
+func (t *topicEncoder) GetDimension() (int, error) {
+	return len(topics), nil
+}
+
+func (t *topicEncoder) Model() embeddings.ModelInfo {
+	return embeddings.ModelInfo{Name: "topics", Dimension: len(topics)}
+}
+
+func (t *topicEncoder) EncodeQuery(ctx context.Context, query string) ([]float32, error) {
+	vector := make([]float32, len(topics))
+	for i, topic := range topics {
+		if strings.Contains(strings.ToLower(query), topic) {
+			vector[i] = 1
+		}
+	}
+	return vector, nil
+}
+
+func (t *topicEncoder) EncodeMetricMetadata(ctx context.Context, metadata prometheus.MetricMetadata) ([]float32, error) {
+	return t.EncodeQuery(ctx, metadata.Name+" "+metadata.Help)
+}
+
+func (t *topicEncoder) EncodeBatch(ctx context.Context, texts []string, _ embeddings.ProgressFunc) ([][]float32, error) {
+	vectors := make([][]float32, len(texts))
+	for i, text := range texts {
+		vectors[i], _ = t.EncodeQuery(ctx, text)
+	}
+	return vectors, nil
+}
+
+func (t *topicEncoder) EncodeMetricMetadataBatch(ctx context.Context, metadata []prometheus.MetricMetadata, progress embeddings.ProgressFunc) ([][]float32, error) {
+	texts := make([]string, len(metadata))
+	for i, m := range metadata {
+		texts[i] = m.Name + " " + m.Help
+	}
+	return t.EncodeBatch(ctx, texts, progress)
+}
+
+var _ = Describe("Feedback", func() {
+	var (
+		store   *feedback.Store
+		tempDir string
+	)
+
+	retrieved := []string{"go_memstats_alloc_bytes", "process_resident_memory_bytes", "node_memory_MemAvailable_bytes"}
+
+	metrics := func() []*prometheus.MetricMetadata {
+		result := make([]*prometheus.MetricMetadata, len(retrieved))
+		for i, name := range retrieved {
+			result[i] = &prometheus.MetricMetadata{Name: name}
+		}
+		return result
+	}
+
+	names := func(metrics []*prometheus.MetricMetadata) []string {
+		result := make([]string, len(metrics))
+		for i, metric := range metrics {
+			result[i] = metric.Name
+		}
+		return result
+	}
+
+	rateAs := func(owner, question, promql, rating, corrected string) {
+		query, err := store.RecordQuery(context.Background(), owner, question, promql, retrieved)
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = store.AddFeedback(context.Background(), owner, feedback.Feedback{QueryID: query.ID, Rating: rating, CorrectedPromQL: corrected})
+		Expect(err).NotTo(HaveOccurred())
+	}
+
+	rate := func(question, promql, rating, corrected string) {
+		rateAs("", question, promql, rating, corrected)
+	}
+
+	BeforeEach(func() {
+		var err error
+		tempDir, err = os.MkdirTemp("", "feedback_test")
+		Expect(err).NotTo(HaveOccurred())
+
+		store, err = feedback.NewStore(feedback.Config{
+			DBPath:  filepath.Join(tempDir, "feedback.db"),
+			Encoder: &topicEncoder{},
+		})
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		Expect(store.Close()).To(Succeed())
+		Expect(os.RemoveAll(tempDir)).To(Succeed())
+	})
+
+	It("should record queries and their feedback", func() {
+		query, err := store.RecordQuery(context.Background(), "api_key:grafana", "How much memory is free?", "node_memory_MemAvailable_bytes", retrieved)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(query.ID).NotTo(BeEmpty())
+
+		rated, err := store.AddFeedback(context.Background(), "api_key:grafana", feedback.Feedback{QueryID: query.ID, Rating: feedback.RatingUp})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(rated.Question).To(Equal("How much memory is free?"))
+		Expect(rated.Metrics).To(Equal(retrieved))
+		Expect(rated.Owner).To(Equal("api_key:grafana"))
+	})
+
+	It("should reject feedback on the queries of other owners", func() {
+		query, err := store.RecordQuery(context.Background(), "api_key:grafana", "How much memory is free?", "node_memory_MemAvailable_bytes", retrieved)
+		Expect(err).NotTo(HaveOccurred())
+
+		for _, owner := range []string{"jwt:grafana", "api_key:alice", ""} {
+			_, err = store.AddFeedback(context.Background(), owner, feedback.Feedback{QueryID: query.ID, Rating: feedback.RatingDown})
+			Expect(err).To(MatchError(feedback.ErrQueryNotFound), owner)
+		}
+	})
+
+	It("should add the owner column to databases of older versions", func() {
+		path := filepath.Join(tempDir, "old.db")
+		db, err := sql.Open("sqlite3", path)
+		Expect(err).NotTo(HaveOccurred())
+		_, err = db.Exec(`
+			CREATE TABLE queries (id TEXT PRIMARY KEY, question TEXT NOT NULL, promql TEXT, metrics TEXT, embedding BLOB, created_at TIMESTAMP NOT NULL);
+			INSERT INTO queries (id, question, created_at) VALUES ('old', 'Free memory', CURRENT_TIMESTAMP);
+		`)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(db.Close()).To(Succeed())
+
+		old, err := feedback.NewStore(feedback.Config{DBPath: path, Encoder: &topicEncoder{}})
+		Expect(err).NotTo(HaveOccurred())
+		defer func() {
+			Expect(old.Close()).To(Succeed())
+		}()
+
+		query, err := old.AddFeedback(context.Background(), "", feedback.Feedback{QueryID: "old", Rating: feedback.RatingUp})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(query.Owner).To(BeEmpty())
+	})
+
+	It("should reject feedback on unknown queries", func() {
+		_, err := store.AddFeedback(context.Background(), "", feedback.Feedback{QueryID: "unknown", Rating: feedback.RatingDown})
+		Expect(err).To(MatchError(feedback.ErrQueryNotFound))
+	})
+
+	It("should reject invalid ratings", func() {
+		Expect((&feedback.Feedback{QueryID: "id", Rating: "meh"}).Validate()).To(HaveOccurred())
+	})
+
+	It("should reject corrections that are not valid PromQL", func() {
+		Expect((&feedback.Feedback{QueryID: "id", Rating: feedback.RatingDown, CorrectedPromQL: "sum(rate(up[5m])"}).Validate()).
+			To(MatchError(ContainSubstring("corrected_promql")))
+		Expect((&feedback.Feedback{QueryID: "id", Rating: feedback.RatingDown, CorrectedPromQL: "sum(rate(up[5m]))"}).Validate()).
+			To(Succeed())
+	})
+
+	It("should treat blank corrections as absent", func() {
+		fb := &feedback.Feedback{QueryID: "id", Rating: feedback.RatingDown, CorrectedPromQL: "  \n "}
+		Expect(fb.Validate()).To(Succeed())
+		Expect(fb.CorrectedPromQL).To(BeEmpty())
+	})
+
+	It("should down-weight metrics repeatedly retrieved without being useful", func() {
+		rate("How much memory is free?", "go_memstats_alloc_bytes", feedback.RatingDown, "node_memory_MemAvailable_bytes")
+		rate("Free memory on nodes", "go_memstats_alloc_bytes", feedback.RatingDown, "")
+
+		reranked := store.Rerank(context.Background(), "Which node has the least free memory?", metrics())
+		Expect(names(reranked)).To(Equal([]string{
+			"node_memory_MemAvailable_bytes", "go_memstats_alloc_bytes", "process_resident_memory_bytes",
+		}))
+	})
+
+	It("should not down-weight metrics after a single negative feedback", func() {
+		rate("How much memory is free?", "go_memstats_alloc_bytes", feedback.RatingDown, "")
+
+		Expect(names(store.Rerank(context.Background(), "Free memory", metrics()))).To(Equal(retrieved))
+	})
+
+	It("should only down-weight metrics after negative feedback from several owners", func() {
+		rateAs("api_key:alice", "How much memory is free?", "go_memstats_alloc_bytes", feedback.RatingDown, "")
+		rateAs("api_key:alice", "Free memory on nodes", "go_memstats_alloc_bytes", feedback.RatingDown, "")
+		rateAs("api_key:alice", "Free memory", "go_memstats_alloc_bytes", feedback.RatingDown, "")
+
+		Expect(names(store.Rerank(context.Background(), "Free memory", metrics()))).To(Equal(retrieved))
+
+		rateAs("jwt:alice", "Memory left on nodes", "go_memstats_alloc_bytes", feedback.RatingDown, "node_memory_MemAvailable_bytes")
+
+		Expect(names(store.Rerank(context.Background(), "Free memory", metrics()))).To(Equal([]string{
+			"node_memory_MemAvailable_bytes", "go_memstats_alloc_bytes", "process_resident_memory_bytes",
+		}))
+	})
+
+	It("should ignore feedback on dissimilar questions", func() {
+		rate("How busy is the cpu?", "go_memstats_alloc_bytes", feedback.RatingDown, "")
+		rate("Which cpu is busiest?", "go_memstats_alloc_bytes", feedback.RatingDown, "")
+
+		Expect(names(store.Rerank(context.Background(), "Free memory", metrics()))).To(Equal(retrieved))
+	})
+
+	It("should prune old queries without feedback", func() {
+		_, err := store.RecordQuery(context.Background(), "", "Free memory", "", retrieved)
+		Expect(err).NotTo(HaveOccurred())
+		rate("How much memory is free?", "go_memstats_alloc_bytes", feedback.RatingUp, "")
+
+		deleted, err := store.Prune(context.Background(), time.Now().Add(time.Minute))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(deleted).To(Equal(int64(1)))
+	})
+
+	It("should find the metrics referenced by a query", func() {
+		referenced := feedback.ReferencedMetrics(
+			"histogram_quantile(0.9, sum by (le) (rate(http_request_duration_seconds_bucket[5m]))) / up",
+			[]string{"http_request_duration_seconds", "up", "http_requests"},
+		)
+		Expect(referenced).To(Equal([]string{"http_request_duration_seconds", "up"}))
+	})
+})
@@ -0,0 +1,146 @@
+package llm
+
+import (
+	"context"
+	"strings"
+	"sync"
+
+	"github.com/rs/zerolog/log"
+	"golang.org/x/sync/errgroup"
+
+	"github.com/machadovilaca/prometheus-rag/pkg/prometheus"
+)
+
+const (
+	defaultEnrichMinHelpWords = 4
+	defaultEnrichConcurrency  = 4
+)
+
+// EnrichmentConfig configures the generation of descriptions for metrics with poor help text
+type EnrichmentConfig struct {
+	// MinHelpWords is the number of words below which a help text is considered poor
+	MinHelpWords int
+	// MaxPerSync is the maximum number of descriptions generated per sync, 0 for no limit
+	MaxPerSync int
+	// Concurrency is the number of descriptions generated in parallel
+	Concurrency int
+}
+
+// EnrichmentStats summarizes an enrichment pass
+type EnrichmentStats struct {
+	// Generated is the number of descriptions generated by the LLM
+	Generated int `json:"generated"`
+	// Reused is the number of descriptions reused from previous passes
+	Reused int `json:"reused"`
+	// Failed is the number of metrics whose description could not be generated
+	Failed int `json:"failed"`
+	// Deferred is the number of metrics left for later passes because of MaxPerSync
+	Deferred int `json:"deferred"`
+}
+
+// Enricher generates richer descriptions for metrics with poor help text.
+// Descriptions are remembered by metric name, type and help, so each metric
+// is only described once while its metadata does not change.
+type Enricher struct {
+	client Client
+	config EnrichmentConfig
+
+	mu           sync.Mutex
+	descriptions map[string]string
+}
+
+// NewEnricher creates a new enricher using the given LLM client
+func NewEnricher(client Client, config EnrichmentConfig) *Enricher {
+	if config.MinHelpWords <= 0 {
+		config.MinHelpWords = defaultEnrichMinHelpWords
+	}
+
+	if config.Concurrency <= 0 {
+		config.Concurrency = defaultEnrichConcurrency
+	}
+
+	return &Enricher{
+		client:       client,
+		config:       config,
+		descriptions: make(map[string]string),
+	}
+}
+
+// NeedsEnrichment returns true if the help text of the metric is too short to be useful
+func (e *Enricher) NeedsEnrichment(metric *prometheus.MetricMetadata) bool {
+	return len(strings.Fields(metric.Help)) < e.config.MinHelpWords
+}
+
+// Enrich sets the Description of the metrics that need enrichment. Failures
+// are logged and leave the metric without a description, as do the pending
+// metrics once ctx is done.
+func (e *Enricher) Enrich(ctx context.Context, metrics []*prometheus.MetricMetadata) EnrichmentStats {
+	var (
+		stats   EnrichmentStats
+		pending []*prometheus.MetricMetadata
+	)
+
+	for _, metric := range metrics {
+		if !e.NeedsEnrichment(metric) {
+			continue
+		}
+
+		if description, ok := e.cached(metric); ok {
+			metric.Description = description
+			stats.Reused++
+			continue
+		}
+
+		if e.config.MaxPerSync > 0 && len(pending) >= e.config.MaxPerSync {
+			stats.Deferred++
+			continue
+		}
+		pending = append(pending, metric)
+	}
+
+	var mu sync.Mutex
+	g := errgroup.Group{}
+	g.SetLimit(e.config.Concurrency)
+
+	for _, metric := range pending {
+		g.Go(func() error {
+			description, err := e.client.DescribeMetric(ctx, metric)
+
+			mu.Lock()
+			defer mu.Unlock()
+
+			if err != nil {
+				log.Warn().Err(err).Msgf("failed to generate description for metric %s", metric.Name)
+				stats.Failed++
+				return nil
+			}
+
+			metric.Description = description
+			e.store(metric, description)
+			stats.Generated++
+			return nil
+		})
+	}
+	_ = g.Wait()
+
+	return stats
+}
+
+func (e *Enricher) cached(metric *prometheus.MetricMetadata) (string, bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	description, ok := e.descriptions[enrichmentKey(metric)]
+	return description, ok
+}
+
+func (e *Enricher) store(metric *prometheus.MetricMetadata, description string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.descriptions[enrichmentKey(metric)] = description
+}
+
+func enrichmentKey(metric *prometheus.MetricMetadata) string {
+	return metric.Name + "\x00" + metric.Type + "\x00" + metric.Help
+}
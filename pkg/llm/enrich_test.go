@@ -0,0 +1,90 @@
+package llm_test
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/machadovilaca/prometheus-rag/pkg/llm"
+	"github.com/machadovilaca/prometheus-rag/pkg/prometheus"
+	"github.com/machadovilaca/prometheus-rag/tests/mocks"
+)
+
+var _ = Describe("Enricher", func() {
+	var (
+		mockLLM *mocks.LLMMock
+		calls   atomic.Int32
+	)
+
+	BeforeEach(func() {
+		calls.Store(0)
+		mockLLM = mocks.NewLLMMock()
+		mockLLM.DescribeMetricFunc = func(_ context.Context, metric *prometheus.MetricMetadata) (string, error) {
+			calls.Add(1)
+			if metric.Name == "broken" {
+				return "", errors.New("llm unavailable")
+			}
+			return "Generated description of " + metric.Name + ".", nil
+		}
+	})
+
+	newMetrics := func() []*prometheus.MetricMetadata {
+		return []*prometheus.MetricMetadata{
+			{Name: "http_requests_total", Help: "Total number of HTTP requests.", Type: "counter"},
+			{Name: "kubevirt_vmi_phase_count", Help: "VMI phase.", Type: "gauge"},
+			{Name: "node_load1", Help: "", Type: "gauge"},
+		}
+	}
+
+	It("should describe metrics with short help texts only", func() {
+		enricher := llm.NewEnricher(mockLLM, llm.EnrichmentConfig{MinHelpWords: 4})
+
+		metrics := newMetrics()
+		stats := enricher.Enrich(context.Background(), metrics)
+
+		Expect(stats.Generated).To(Equal(2))
+		Expect(metrics[0].Description).To(BeEmpty())
+		Expect(metrics[1].Description).To(Equal("Generated description of kubevirt_vmi_phase_count."))
+		Expect(metrics[2].Description).To(Equal("Generated description of node_load1."))
+	})
+
+	It("should reuse descriptions while the metadata does not change", func() {
+		enricher := llm.NewEnricher(mockLLM, llm.EnrichmentConfig{})
+
+		enricher.Enrich(context.Background(), newMetrics())
+		metrics := newMetrics()
+		metrics[2].Help = "Load."
+		stats := enricher.Enrich(context.Background(), metrics)
+
+		Expect(stats.Reused).To(Equal(1))
+		Expect(stats.Generated).To(Equal(1))
+		Expect(calls.Load()).To(BeEquivalentTo(3))
+		Expect(metrics[1].Description).NotTo(BeEmpty())
+	})
+
+	It("should defer metrics over the per sync limit", func() {
+		enricher := llm.NewEnricher(mockLLM, llm.EnrichmentConfig{MaxPerSync: 1})
+
+		stats := enricher.Enrich(context.Background(), newMetrics())
+		Expect(stats.Generated).To(Equal(1))
+		Expect(stats.Deferred).To(Equal(1))
+
+		stats = enricher.Enrich(context.Background(), newMetrics())
+		Expect(stats.Reused).To(Equal(1))
+		Expect(stats.Generated).To(Equal(1))
+		Expect(stats.Deferred).To(BeZero())
+	})
+
+	It("should leave metrics without a description when generation fails", func() {
+		enricher := llm.NewEnricher(mockLLM, llm.EnrichmentConfig{})
+
+		metrics := []*prometheus.MetricMetadata{{Name: "broken", Type: "gauge"}}
+		stats := enricher.Enrich(context.Background(), metrics)
+
+		Expect(stats.Failed).To(Equal(1))
+		Expect(metrics[0].Description).To(BeEmpty())
+	})
+})
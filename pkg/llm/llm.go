@@ -5,17 +5,92 @@ import (
 	"encoding/xml"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/openai/openai-go"
 	"github.com/openai/openai-go/option"
+	"github.com/rs/zerolog/log"
 
+	"github.com/machadovilaca/prometheus-rag/pkg/auth"
+	"github.com/machadovilaca/prometheus-rag/pkg/examples"
+	"github.com/machadovilaca/prometheus-rag/pkg/prometheus"
+	"github.com/machadovilaca/prometheus-rag/pkg/telemetry"
 	"github.com/machadovilaca/prometheus-rag/pkg/vectordb"
 )
 
-// Client interface for interacting with the LLM
+// metricsLimit is the number of metrics added to the prompt
+const metricsLimit = 10
+
+// Operations reported in the LLM metrics
+const (
+	operationGenerate = "generate"
+	operationDescribe = "describe"
+)
+
+// rerankCandidates is the number of metrics retrieved when a Reranker is
+// configured, so demoted metrics can be replaced by the next best ones
+const rerankCandidates = 20
+
+// restrictedCandidates is the number of metrics first retrieved for
+// principals restricted to metric prefixes, so enough remain once the others
+// are dropped; the search is widened up to maxRestrictedCandidates otherwise
+const (
+	restrictedCandidates    = 100
+	maxRestrictedCandidates = 10000
+)
+
+// Client interface for interacting with the LLM. Calls stop when the given
+// context is cancelled or its deadline expires.
 type Client interface {
 	// Run runs a query against the LLM
-	Run(query string) (string, error)
+	Run(ctx context.Context, query string) (string, error)
+
+	// Generate runs a query against the LLM, returning the PromQL along with
+	// the metrics that were added to the prompt
+	Generate(ctx context.Context, query string) (*Result, error)
+
+	// DescribeMetric asks the LLM for a richer description of a metric
+	DescribeMetric(ctx context.Context, metric *prometheus.MetricMetadata) (string, error)
+
+	// Ping checks that the models endpoint of the LLM server answers
+	Ping(ctx context.Context) error
+}
+
+// Result is the answer to a query
+type Result struct {
+	PromQL  string
+	Metrics []*prometheus.MetricMetadata
+
+	// Prompt is the system prompt sent to the model
+	Prompt string
+	// Model is the model that answered
+	Model string
+	// Response is the answer of the model before it was parsed
+	Response string
+
+	// PromptTokens and CompletionTokens are the tokens billed for the answer
+	PromptTokens     int64
+	CompletionTokens int64
+}
+
+// InvalidResponseError is returned when the answer of the model cannot be parsed
+type InvalidResponseError struct {
+	Result *Result
+	Err    error
+}
+
+func (e *InvalidResponseError) Error() string {
+	return fmt.Sprintf("invalid LLM response: %v", e.Err)
+}
+
+func (e *InvalidResponseError) Unwrap() error {
+	return e.Err
+}
+
+// Reranker reorders the metrics retrieved for a query before the best ones
+// are added to the prompt
+type Reranker interface {
+	Rerank(ctx context.Context, query string, metrics []*prometheus.MetricMetadata) []*prometheus.MetricMetadata
 }
 
 // Config represents the configuration for the LLM
@@ -25,6 +100,20 @@ type Config struct {
 	Model   string
 
 	VectorDBClient vectordb.Client
+
+	// ExampleStore holds the few-shot examples added to the prompt, nil disables them
+	ExampleStore vectordb.ExampleStore
+	// ExamplesLimit is the number of examples most similar to the query added to the prompt
+	ExamplesLimit int
+
+	// Reranker reorders the retrieved metrics, nil keeps the vector database order
+	Reranker Reranker
+
+	// RetrievalTimeout bounds the search of the metrics and examples added to
+	// the prompt, 0 for no limit
+	RetrievalTimeout time.Duration
+	// GenerationTimeout bounds each chat completion request, 0 for no limit
+	GenerationTimeout time.Duration
 }
 
 type llm struct {
@@ -32,6 +121,7 @@ type llm struct {
 	config Config
 
 	vectorDBClient vectordb.Client
+	exampleStore   vectordb.ExampleStore
 }
 
 // New creates a new LLM client
@@ -62,27 +152,178 @@ func New(config Config) (Client, error) {
 		client:         openai.NewClient(options...),
 		config:         config,
 		vectorDBClient: config.VectorDBClient,
+		exampleStore:   config.ExampleStore,
 	}, nil
 }
 
-func (l *llm) Run(query string) (string, error) {
-	metrics, err := l.vectorDBClient.SearchMetrics(query, 10)
+func (l *llm) Run(ctx context.Context, query string) (string, error) {
+	result, err := l.Generate(ctx, query)
 	if err != nil {
-		return "", fmt.Errorf("failed to search metrics: %w", err)
+		return "", err
 	}
 
-	prompt, err := BuildPrompt(metrics)
+	return result.PromQL, nil
+}
+
+func (l *llm) Generate(ctx context.Context, query string) (result *Result, err error) {
+	ctx, span := telemetry.StartSpan(ctx, "llm.Generate", telemetry.AttributeModel.String(l.config.Model))
+	defer func() {
+		telemetry.EndSpan(span, err)
+	}()
+
+	metrics, found, err := l.retrieve(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	prompt, err := BuildPromptWithExamples(metrics, found)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build prompt: %w", err)
+	}
+	span.SetAttributes(telemetry.AttributePromptBytes.Int(len(prompt)))
+
+	generationCtx, cancel := withTimeout(ctx, l.config.GenerationTimeout)
+	defer cancel()
+
+	chatCompletion, err := l.complete(generationCtx, operationGenerate, prompt, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to run llm: %w", err)
+	}
+
+	if len(chatCompletion.Choices) == 0 {
+		return nil, fmt.Errorf("no choices returned")
+	}
+
+	result = &Result{
+		Metrics:          metrics,
+		Prompt:           prompt,
+		Model:            l.config.Model,
+		Response:         chatCompletion.Choices[0].Message.Content,
+		PromptTokens:     chatCompletion.Usage.PromptTokens,
+		CompletionTokens: chatCompletion.Usage.CompletionTokens,
+	}
+
+	result.PromQL, err = parseXMLExtract(result.Response)
+	if err != nil {
+		telemetry.IncLLMInvalidResponse(telemetry.InvalidResponseParse)
+		return nil, &InvalidResponseError{Result: result, Err: err}
+	}
+
+	return result, nil
+}
+
+// retrieve returns the metrics and few-shot examples added to the prompt for the query
+func (l *llm) retrieve(ctx context.Context, query string) (metrics []*prometheus.MetricMetadata, found []*examples.Example, err error) {
+	ctx, span := telemetry.StartSpan(ctx, "llm.Retrieve")
+	defer func() {
+		telemetry.EndSpan(span, err)
+	}()
+
+	ctx, cancel := withTimeout(ctx, l.config.RetrievalTimeout)
+	defer cancel()
+
+	metrics, err = l.searchMetrics(ctx, query)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to search metrics: %w", err)
+	}
+
+	names := make([]string, len(metrics))
+	for i, metric := range metrics {
+		names[i] = metric.Name
+	}
+	span.SetAttributes(telemetry.AttributeMetrics.StringSlice(names))
+
+	return metrics, l.searchExamples(ctx, query), nil
+}
+
+// searchMetrics returns the metrics added to the prompt for the query,
+// leaving out those the principal may not query
+func (l *llm) searchMetrics(ctx context.Context, query string) ([]*prometheus.MetricMetadata, error) {
+	principal := auth.PrincipalFromContext(ctx)
+
+	wanted := uint64(metricsLimit)
+	if l.config.Reranker != nil {
+		wanted = rerankCandidates
+	}
+
+	if !principal.RestrictsMetrics() {
+		metrics, err := l.vectorDBClient.SearchMetrics(ctx, query, wanted)
+		if err != nil {
+			return nil, err
+		}
+		return l.rerank(ctx, query, metrics), nil
+	}
+
+	// The vector databases cannot filter by metric prefix, so the search is
+	// widened until enough allowed metrics are found or the catalog is exhausted
+	var allowed []*prometheus.MetricMetadata
+	for limit := uint64(restrictedCandidates); ; limit = min(limit*4, maxRestrictedCandidates) {
+		metrics, err := l.vectorDBClient.SearchMetrics(ctx, query, limit)
+		if err != nil {
+			return nil, err
+		}
+
+		allowed = allowed[:0]
+		for _, metric := range metrics {
+			if principal.AllowsMetric(metric.Name) {
+				allowed = append(allowed, metric)
+			}
+		}
+
+		if uint64(len(allowed)) >= wanted || uint64(len(metrics)) < limit || limit >= maxRestrictedCandidates {
+			break
+		}
+	}
+
+	return l.rerank(ctx, query, allowed), nil
+}
+
+// rerank applies the Reranker, if any, and keeps the metrics added to the prompt
+func (l *llm) rerank(ctx context.Context, query string, metrics []*prometheus.MetricMetadata) []*prometheus.MetricMetadata {
+	if l.config.Reranker != nil {
+		metrics = l.config.Reranker.Rerank(ctx, query, metrics)
+	}
+	return metrics[:min(metricsLimit, len(metrics))]
+}
+
+// searchExamples returns the few-shot examples most similar to the query.
+// Examples only improve the prompt, so failures are logged and ignored.
+func (l *llm) searchExamples(ctx context.Context, query string) []*examples.Example {
+	if l.exampleStore == nil || l.config.ExamplesLimit <= 0 {
+		return nil
+	}
+
+	found, err := l.exampleStore.SearchExamples(ctx, query, uint64(l.config.ExamplesLimit))
+	if err != nil {
+		log.Warn().Ctx(ctx).Err(err).Msg("failed to search few-shot examples")
+		return nil
+	}
+
+	// Examples would reveal the metrics the principal may not query
+	principal := auth.PrincipalFromContext(ctx)
+	if principal.RestrictsMetrics() {
+		allowed := found[:0]
+		for _, example := range found {
+			if principal.CheckPromQL(example.PromQL) == nil {
+				allowed = append(allowed, example)
+			}
+		}
+		found = allowed
+	}
+
+	return found
+}
+
+func (l *llm) DescribeMetric(ctx context.Context, metric *prometheus.MetricMetadata) (string, error) {
+	systemPrompt, userPrompt, err := BuildDescribePrompt(metric)
 	if err != nil {
 		return "", fmt.Errorf("failed to build prompt: %w", err)
 	}
 
-	chatCompletion, err := l.client.Chat.Completions.New(context.TODO(), openai.ChatCompletionNewParams{
-		Messages: openai.F([]openai.ChatCompletionMessageParamUnion{
-			openai.SystemMessage(prompt),
-			openai.UserMessage(query),
-		}),
-		Model: openai.F(l.config.Model),
-	})
+	ctx, cancel := withTimeout(ctx, l.config.GenerationTimeout)
+	defer cancel()
+
+	chatCompletion, err := l.complete(ctx, operationDescribe, systemPrompt, userPrompt)
 	if err != nil {
 		return "", fmt.Errorf("failed to run llm: %w", err)
 	}
@@ -91,12 +332,59 @@ func (l *llm) Run(query string) (string, error) {
 		return "", fmt.Errorf("no choices returned")
 	}
 
-	parsed, err := parseXMLExtract(chatCompletion.Choices[0].Message.Content)
+	description := strings.Join(strings.Fields(chatCompletion.Choices[0].Message.Content), " ")
+	if description == "" {
+		return "", fmt.Errorf("empty description returned")
+	}
+
+	return description, nil
+}
+
+// Ping lists the models served, without retries so an unreachable server is reported quickly
+func (l *llm) Ping(ctx context.Context) error {
+	if _, err := l.client.Models.List(ctx, option.WithMaxRetries(0)); err != nil {
+		return fmt.Errorf("failed to list models: %w", err)
+	}
+
+	return nil
+}
+
+// complete sends a chat completion request, recording its latency and token usage
+func (l *llm) complete(ctx context.Context, operation, systemPrompt, userPrompt string) (*openai.ChatCompletion, error) {
+	ctx, span := telemetry.StartSpan(ctx, "llm.ChatCompletion",
+		telemetry.AttributeModel.String(l.config.Model),
+		telemetry.AttributePromptBytes.Int(len(systemPrompt)+len(userPrompt)),
+	)
+	start := time.Now()
+	chatCompletion, err := l.client.Chat.Completions.New(ctx, openai.ChatCompletionNewParams{
+		Messages: openai.F([]openai.ChatCompletionMessageParamUnion{
+			openai.SystemMessage(systemPrompt),
+			openai.UserMessage(userPrompt),
+		}),
+		Model: openai.F(l.config.Model),
+	})
+	telemetry.ObserveLLMRequest(operation, start, err)
 	if err != nil {
-		return "", fmt.Errorf("failed to parse XML response: %w", err)
+		telemetry.EndSpan(span, err)
+		return nil, err
 	}
 
-	return parsed, nil
+	telemetry.AddLLMTokens(operation, chatCompletion.Usage.PromptTokens, chatCompletion.Usage.CompletionTokens)
+	span.SetAttributes(
+		telemetry.AttributeInputTokens.Int64(chatCompletion.Usage.PromptTokens),
+		telemetry.AttributeOutputTokens.Int64(chatCompletion.Usage.CompletionTokens),
+	)
+	telemetry.EndSpan(span, nil)
+
+	return chatCompletion, nil
+}
+
+// withTimeout returns a context bounded by timeout, or only cancellable when timeout is 0
+func withTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, timeout)
 }
 
 type xmlResponse struct {
@@ -1,9 +1,16 @@
 package llm_test
 
 import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 
+	"github.com/machadovilaca/prometheus-rag/pkg/auth"
 	"github.com/machadovilaca/prometheus-rag/pkg/llm"
 	"github.com/machadovilaca/prometheus-rag/pkg/prometheus"
 	"github.com/machadovilaca/prometheus-rag/pkg/vectordb"
@@ -25,7 +32,7 @@ var _ = Describe("LLM", func() {
 
 	BeforeEach(func() {
 		mockDB := mocks.NewVectorDBMock()
-		mockDB.SearchMetricsFunc = func(query string, limit uint64) ([]*prometheus.MetricMetadata, error) {
+		mockDB.SearchMetricsFunc = func(_ context.Context, query string, limit uint64) ([]*prometheus.MetricMetadata, error) {
 			return []*prometheus.MetricMetadata{
 				{Name: "up", Help: "Whether the instance is up", Type: "gauge", Labels: []string{"instance", "job"}},
 				{Name: "kube_pod_status_phase", Help: "Pod status phase", Type: "gauge", Labels: []string{"pod", "namespace", "phase"}},
@@ -58,7 +65,7 @@ var _ = Describe("LLM", func() {
 		It("should successfully run query", func() {
 			query := "Number of up pods"
 
-			response, err := llmClient.Run(query)
+			response, err := llmClient.Run(context.Background(), query)
 			Expect(err).NotTo(HaveOccurred())
 			Expect(response).NotTo(BeEmpty())
 		})
@@ -72,9 +79,90 @@ var _ = Describe("LLM", func() {
 			})
 			Expect(err).NotTo(HaveOccurred())
 
-			response, err := llmClient.Run("test query")
+			response, err := llmClient.Run(context.Background(), "test query")
 			Expect(err).To(HaveOccurred())
 			Expect(response).To(BeEmpty())
 		})
+
+		It("should stop searching metrics after the retrieval timeout", func() {
+			mockDB := mocks.NewVectorDBMock()
+			mockDB.SearchMetricsFunc = func(ctx context.Context, _ string, _ uint64) ([]*prometheus.MetricMetadata, error) {
+				<-ctx.Done()
+				return nil, ctx.Err()
+			}
+
+			llmClient, err = llm.New(llm.Config{
+				BaseURL:          baseURL,
+				VectorDBClient:   mockDB,
+				RetrievalTimeout: 10 * time.Millisecond,
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = llmClient.Run(context.Background(), "test query")
+			Expect(err).To(MatchError(context.DeadlineExceeded))
+		})
+
+		It("should widen the metrics search until enough allowed metrics are found", func() {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				_, _ = w.Write([]byte(`{"choices": [{"index": 0, "finish_reason": "stop", "message": {"role": "assistant",
+					"content": "<query><promql>kubevirt_metric_0</promql></query>"}}]}`))
+			}))
+			defer server.Close()
+
+			// The allowed metrics rank after a thousand others
+			var limits []uint64
+			mockDB := mocks.NewVectorDBMock()
+			mockDB.SearchMetricsFunc = func(_ context.Context, _ string, limit uint64) ([]*prometheus.MetricMetadata, error) {
+				limits = append(limits, limit)
+
+				var metrics []*prometheus.MetricMetadata
+				for i := range min(limit, 1020) {
+					name := fmt.Sprintf("node_metric_%d", i)
+					if i >= 1000 {
+						name = fmt.Sprintf("kubevirt_metric_%d", i-1000)
+					}
+					metrics = append(metrics, &prometheus.MetricMetadata{Name: name, Type: "gauge"})
+				}
+				return metrics, nil
+			}
+
+			llmClient, err = llm.New(llm.Config{BaseURL: server.URL + "/v1/", VectorDBClient: mockDB})
+			Expect(err).NotTo(HaveOccurred())
+
+			ctx := auth.WithPrincipal(context.Background(), &auth.Principal{Name: "kubevirt", Policy: auth.Policy{MetricPrefixes: []string{"kubevirt_"}}})
+			result, err := llmClient.Generate(ctx, "test query")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.Metrics).To(HaveLen(10))
+			Expect(result.Metrics[0].Name).To(Equal("kubevirt_metric_0"))
+			Expect(limits).To(Equal([]uint64{100, 400, 1600}))
+		})
+	})
+
+	Context("Ping", func() {
+		It("should succeed when the models endpoint answers", func() {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				Expect(r.URL.Path).To(Equal("/v1/models"))
+				w.Header().Set("Content-Type", "application/json")
+				_, _ = w.Write([]byte(`{"object": "list", "data": [{"id": "gemma-3-27b-it", "object": "model"}]}`))
+			}))
+			defer server.Close()
+
+			llmClient, err = llm.New(llm.Config{
+				BaseURL:        server.URL + "/v1/",
+				VectorDBClient: dbClient,
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(llmClient.Ping(context.Background())).To(Succeed())
+		})
+
+		It("should fail when the server is unreachable", func() {
+			llmClient, err = llm.New(llm.Config{
+				BaseURL:        "http://127.0.0.1:9999/v1/",
+				VectorDBClient: dbClient,
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(llmClient.Ping(context.Background())).To(MatchError(ContainSubstring("failed to list models")))
+		})
 	})
 })
@@ -4,31 +4,44 @@ import (
 	"bytes"
 	_ "embed"
 	"fmt"
-	"html/template"
+	"strings"
+	"text/template"
 
 	"github.com/rs/zerolog/log"
 
+	"github.com/machadovilaca/prometheus-rag/pkg/examples"
 	"github.com/machadovilaca/prometheus-rag/pkg/prometheus"
 )
 
 //go:embed promql_prompt.tmpl
 var promptTemplate string
 
-// PromptData is the wrapper for metrics metadata to be used in the prompt
+// PromptData is the wrapper for metrics metadata and few-shot examples to be used in the prompt
 type PromptData struct {
-	Metrics []*prometheus.MetricMetadata
+	Metrics  []*prometheus.MetricMetadata
+	Examples []*examples.Example
 }
 
 // BuildPrompt builds a prompt for the LLM using the metrics metadata
 func BuildPrompt(metrics []*prometheus.MetricMetadata) (string, error) {
-	tmpl, err := template.New("promql_prompt").Parse(promptTemplate)
+	return BuildPromptWithExamples(metrics, nil)
+}
+
+// BuildPromptWithExamples builds a prompt for the LLM using the metrics
+// metadata and worked examples of questions answered in PromQL. The prompt is
+// plain text, so PromQL in examples and annotations is not HTML escaped.
+func BuildPromptWithExamples(metrics []*prometheus.MetricMetadata, fewShot []*examples.Example) (string, error) {
+	tmpl, err := template.New("promql_prompt").Funcs(template.FuncMap{
+		"queryHint": queryHint,
+	}).Parse(promptTemplate)
 	if err != nil {
 		return "", fmt.Errorf("failed to parse prompt template: %w", err)
 	}
 
 	var promptBuf bytes.Buffer
 	err = tmpl.ExecuteTemplate(&promptBuf, "PromqlSystemPrompt", PromptData{
-		Metrics: metrics,
+		Metrics:  metrics,
+		Examples: fewShot,
 	})
 	if err != nil {
 		return "", fmt.Errorf("failed to execute template: %w", err)
@@ -37,3 +50,44 @@ func BuildPrompt(metrics []*prometheus.MetricMetadata) (string, error) {
 	log.Debug().Msgf("prompt: %s", promptBuf.String())
 	return promptBuf.String(), nil
 }
+
+//go:embed describe_prompt.tmpl
+var describeTemplate string
+
+// BuildDescribePrompt builds the system and user prompts asking the LLM to describe a metric
+func BuildDescribePrompt(metric *prometheus.MetricMetadata) (string, string, error) {
+	tmpl, err := template.New("describe_prompt").Parse(describeTemplate)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to parse describe template: %w", err)
+	}
+
+	var systemBuf, userBuf bytes.Buffer
+	if err := tmpl.ExecuteTemplate(&systemBuf, "DescribeSystemPrompt", nil); err != nil {
+		return "", "", fmt.Errorf("failed to execute template: %w", err)
+	}
+	if err := tmpl.ExecuteTemplate(&userBuf, "DescribeUserPrompt", metric); err != nil {
+		return "", "", fmt.Errorf("failed to execute template: %w", err)
+	}
+
+	return strings.TrimSpace(systemBuf.String()), strings.TrimSpace(userBuf.String()), nil
+}
+
+// queryHint returns usage guidance for metric families that need special
+// handling in PromQL, such as histograms and summaries
+func queryHint(metric *prometheus.MetricMetadata) string {
+	switch {
+	case metric.IsHistogram() && metric.HasClassicBuckets():
+		return fmt.Sprintf("classic histogram; for quantiles use histogram_quantile(0.99, sum by (le) (rate(%[1]s_bucket[5m]))), "+
+			"for averages divide rate(%[1]s_sum[5m]) by rate(%[1]s_count[5m])", metric.Name)
+	case metric.IsHistogram() && metric.NativeHistogram:
+		return fmt.Sprintf("native histogram; for quantiles use histogram_quantile(0.99, sum(rate(%[1]s[5m]))), "+
+			"for averages use histogram_avg(rate(%[1]s[5m])), there is no le label", metric.Name)
+	case metric.IsSummary():
+		return fmt.Sprintf("summary; precomputed quantiles are in %[1]s with the quantile label and cannot be aggregated, "+
+			"for averages divide rate(%[1]s_sum[5m]) by rate(%[1]s_count[5m])", metric.Name)
+	case metric.Type == prometheus.MetricTypeCounter:
+		return "counter; use rate() or increase() instead of the raw value"
+	default:
+		return ""
+	}
+}
@@ -4,6 +4,7 @@ import (
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 
+	"github.com/machadovilaca/prometheus-rag/pkg/examples"
 	"github.com/machadovilaca/prometheus-rag/pkg/llm"
 	"github.com/machadovilaca/prometheus-rag/pkg/prometheus"
 )
@@ -34,6 +35,79 @@ var _ = Describe("Prompt", func() {
 			Expect(prompt).To(ContainSubstring("Current memory usage in bytes"))
 		})
 
+		It("should include unit and histogram usage hints", func() {
+			metrics := []*prometheus.MetricMetadata{
+				{
+					Name:   "http_request_duration_seconds",
+					Help:   "Request latency",
+					Type:   "histogram",
+					Unit:   "seconds",
+					Labels: []string{"handler", "le"},
+					Series: []string{
+						"http_request_duration_seconds_bucket",
+						"http_request_duration_seconds_sum",
+						"http_request_duration_seconds_count",
+					},
+				},
+			}
+
+			prompt, err := llm.BuildPrompt(metrics)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(prompt).To(ContainSubstring("Unit: seconds"))
+			Expect(prompt).To(ContainSubstring("http_request_duration_seconds_bucket"))
+			Expect(prompt).To(ContainSubstring("histogram_quantile(0.99, sum by (le) (rate(http_request_duration_seconds_bucket[5m])))"))
+		})
+
+		It("should include generated descriptions", func() {
+			metrics := []*prometheus.MetricMetadata{
+				{Name: "kubevirt_vmi_phase_count", Help: "VMI phase.", Description: "Number of virtual machine instances per phase.", Type: "gauge"},
+			}
+
+			prompt, err := llm.BuildPrompt(metrics)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(prompt).To(ContainSubstring("Description: Number of virtual machine instances per phase."))
+		})
+
+		It("should include curated annotations", func() {
+			metrics := []*prometheus.MetricMetadata{
+				{
+					Name: "kubevirt_vmi_phase_count",
+					Help: "VMI phase.",
+					Type: "gauge",
+					Annotations: &prometheus.Annotations{
+						Synonyms:   []string{"VM status"},
+						Examples:   []string{"sum by (phase) (kubevirt_vmi_phase_count)"},
+						Deprecated: "Use kubevirt_vmi_info instead.",
+					},
+				},
+			}
+
+			prompt, err := llm.BuildPrompt(metrics)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(prompt).To(ContainSubstring("Also known as: [VM status]"))
+			Expect(prompt).To(ContainSubstring("Example: sum by (phase) (kubevirt_vmi_phase_count)"))
+			Expect(prompt).To(ContainSubstring("Deprecated: Use kubevirt_vmi_info instead."))
+		})
+
+		It("should include few-shot examples without escaping them", func() {
+			metrics := []*prometheus.MetricMetadata{
+				{Name: "up", Help: "Whether the target is up.", Type: "gauge"},
+			}
+
+			prompt, err := llm.BuildPromptWithExamples(metrics, []*examples.Example{
+				{Question: "Which targets of the api job are down?", PromQL: `up{job="api"} == 0`},
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(prompt).To(ContainSubstring("Question: Which targets of the api job are down?"))
+			Expect(prompt).To(ContainSubstring(`<promql>up{job="api"} == 0</promql>`))
+		})
+
+		It("should leave out the examples section without examples", func() {
+			prompt, err := llm.BuildPrompt(nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(prompt).NotTo(ContainSubstring("Worked examples"))
+		})
+
 		It("should build prompt with empty metrics", func() {
 			metrics := []*prometheus.MetricMetadata{}
 
@@ -50,4 +124,19 @@ var _ = Describe("Prompt", func() {
 			Expect(prompt).NotTo(BeEmpty())
 		})
 	})
+
+	Context("BuildDescribePrompt", func() {
+		It("should describe the metric to the LLM", func() {
+			system, user, err := llm.BuildDescribePrompt(&prometheus.MetricMetadata{
+				Name:   "kubevirt_vmi_phase_count",
+				Help:   "VMI phase.",
+				Type:   "gauge",
+				Labels: []string{"namespace", "phase"},
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(system).To(ContainSubstring("example PromQL query"))
+			Expect(user).To(ContainSubstring("Name: kubevirt_vmi_phase_count"))
+			Expect(user).To(ContainSubstring("Labels: [namespace, phase]"))
+		})
+	})
 })
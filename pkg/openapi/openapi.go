@@ -0,0 +1,328 @@
+// Package openapi generates OpenAPI 3 documents describing HTTP APIs, with the
+// schemas of the request and response bodies derived from their Go types, so
+// the document cannot drift from the types the handlers encode.
+package openapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"path"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Version is the version of the OpenAPI specification of the documents
+const Version = "3.0.3"
+
+// Document is an OpenAPI document
+type Document struct {
+	OpenAPI    string               `json:"openapi"`
+	Info       Info                 `json:"info"`
+	Paths      map[string]*PathItem `json:"paths"`
+	Components Components           `json:"components"`
+
+	// errorBody is the schema of the body of every error response
+	errorBody *Schema
+	// types maps the names of the component schemas to their Go types
+	types map[string]reflect.Type
+}
+
+// Info describes the API
+type Info struct {
+	Title       string `json:"title"`
+	Version     string `json:"version"`
+	Description string `json:"description,omitempty"`
+}
+
+// PathItem holds the operations of a path, by lowercase method
+type PathItem map[string]*Operation
+
+// Operation is an API operation on a path
+type Operation struct {
+	Summary     string                `json:"summary,omitempty"`
+	OperationID string                `json:"operationId,omitempty"`
+	Parameters  []Parameter           `json:"parameters,omitempty"`
+	RequestBody *RequestBody          `json:"requestBody,omitempty"`
+	Responses   map[string]*Response  `json:"responses"`
+	Security    []map[string][]string `json:"security,omitempty"`
+}
+
+// Parameter is a path or query parameter of an operation
+type Parameter struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"`
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+// RequestBody is the body of the requests of an operation
+type RequestBody struct {
+	Required bool                 `json:"required"`
+	Content  map[string]MediaType `json:"content"`
+}
+
+// Response is a response of an operation
+type Response struct {
+	Description string               `json:"description"`
+	Content     map[string]MediaType `json:"content,omitempty"`
+}
+
+// MediaType is the schema of a body of a given content type
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+// Components holds the schemas referenced by the operations
+type Components struct {
+	Schemas         map[string]*Schema         `json:"schemas"`
+	SecuritySchemes map[string]*SecurityScheme `json:"securitySchemes,omitempty"`
+}
+
+// SecurityScheme is a way to authenticate the requests
+type SecurityScheme struct {
+	Type         string `json:"type"`
+	Description  string `json:"description,omitempty"`
+	Scheme       string `json:"scheme,omitempty"`
+	BearerFormat string `json:"bearerFormat,omitempty"`
+	Name         string `json:"name,omitempty"`
+	In           string `json:"in,omitempty"`
+}
+
+// Schema is the JSON schema of a value
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Minimum              *float64           `json:"minimum,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+}
+
+// Endpoint describes an operation to add to a document
+type Endpoint struct {
+	Method      string
+	Path        string
+	Summary     string
+	OperationID string
+	Parameters  []Parameter
+
+	// Request is a value of the type of the request body, nil for none
+	Request any
+
+	// Status is the status of a success, and Response a value of the type of
+	// its body, nil for none
+	Status   int
+	Response any
+
+	// Errors are the statuses of the errors the operation may return
+	Errors []int
+
+	// Security lists the names of the security schemes accepted, empty for a public operation
+	Security []string
+}
+
+var (
+	timeType     = reflect.TypeFor[time.Time]()
+	durationType = reflect.TypeFor[time.Duration]()
+	rawType      = reflect.TypeFor[json.RawMessage]()
+)
+
+// New creates a document without operations, where errors have the body of
+// the type of errorBody
+func New(info Info, errorBody any) *Document {
+	d := &Document{
+		OpenAPI: Version,
+		Info:    info,
+		Paths:   make(map[string]*PathItem),
+		Components: Components{
+			Schemas: make(map[string]*Schema),
+		},
+		types: make(map[string]reflect.Type),
+	}
+	d.errorBody = d.Schema(errorBody)
+	return d
+}
+
+// AddSecurityScheme adds a security scheme that endpoints may accept
+func (d *Document) AddSecurityScheme(name string, scheme *SecurityScheme) {
+	if d.Components.SecuritySchemes == nil {
+		d.Components.SecuritySchemes = make(map[string]*SecurityScheme)
+	}
+	d.Components.SecuritySchemes[name] = scheme
+}
+
+// Add adds the operation of an endpoint to the document
+func (d *Document) Add(endpoint Endpoint) {
+	operation := &Operation{
+		Summary:     endpoint.Summary,
+		OperationID: endpoint.OperationID,
+		Parameters:  endpoint.Parameters,
+		Responses:   make(map[string]*Response),
+	}
+
+	if endpoint.Request != nil {
+		operation.RequestBody = &RequestBody{
+			Required: true,
+			Content:  jsonContent(d.Schema(endpoint.Request)),
+		}
+	}
+
+	success := &Response{Description: http.StatusText(endpoint.Status)}
+	if endpoint.Response != nil {
+		success.Content = jsonContent(d.Schema(endpoint.Response))
+	}
+	operation.Responses[strconv.Itoa(endpoint.Status)] = success
+
+	for _, status := range endpoint.Errors {
+		operation.Responses[strconv.Itoa(status)] = &Response{
+			Description: http.StatusText(status),
+			Content:     jsonContent(d.errorBody),
+		}
+	}
+
+	for _, name := range endpoint.Security {
+		operation.Security = append(operation.Security, map[string][]string{name: {}})
+	}
+
+	item, ok := d.Paths[endpoint.Path]
+	if !ok {
+		item = &PathItem{}
+		d.Paths[endpoint.Path] = item
+	}
+	(*item)[strings.ToLower(endpoint.Method)] = operation
+}
+
+// Schema returns the schema of the type of value, adding the schemas of the
+// named structs it holds to the components and referencing them
+func (d *Document) Schema(value any) *Schema {
+	return d.schema(reflect.TypeOf(value))
+}
+
+func (d *Document) schema(t reflect.Type) *Schema {
+	switch t {
+	case timeType:
+		return &Schema{Type: "string", Format: "date-time"}
+	case durationType:
+		return &Schema{Type: "integer", Format: "int64", Description: "Duration in nanoseconds"}
+	case rawType:
+		return &Schema{}
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		return d.schema(t.Elem())
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Int, reflect.Int64:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Int8, reflect.Int16, reflect.Int32:
+		return &Schema{Type: "integer", Format: "int32"}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		minimum := 0.0
+		return &Schema{Type: "integer", Minimum: &minimum}
+	case reflect.Float32:
+		return &Schema{Type: "number", Format: "float"}
+	case reflect.Float64:
+		return &Schema{Type: "number", Format: "double"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte"}
+		}
+		return &Schema{Type: "array", Items: d.schema(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: d.schema(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return d.structSchema(t)
+		}
+		return &Schema{Ref: "#/components/schemas/" + d.component(t)}
+	default:
+		// Interfaces hold any value
+		return &Schema{}
+	}
+}
+
+// component adds the schema of a named struct to the components, returning its name
+func (d *Document) component(t reflect.Type) string {
+	name := t.Name()
+	if existing, ok := d.types[name]; ok && existing != t {
+		// Structs of different packages may share a name
+		name = exportedName(path.Base(t.PkgPath())) + name
+	}
+
+	if _, ok := d.types[name]; ok {
+		return name
+	}
+
+	// Registered before its fields, so recursive types reference it
+	d.types[name] = t
+	d.Components.Schemas[name] = d.structSchema(t)
+	return name
+}
+
+// structSchema returns the schema of the JSON object a struct is encoded to
+func (d *Document) structSchema(t reflect.Type) *Schema {
+	schema := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+	d.addFields(schema, t)
+	return schema
+}
+
+// addFields adds the properties of the exported fields of a struct, inlining
+// the fields of embedded structs without a JSON name as encoding/json does
+func (d *Document) addFields(schema *Schema, t reflect.Type) {
+	for i := range t.NumField() {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, options, _ := strings.Cut(tag, ",")
+
+		fieldType := field.Type
+		if field.Anonymous && name == "" {
+			if fieldType.Kind() == reflect.Pointer {
+				fieldType = fieldType.Elem()
+			}
+			if fieldType.Kind() == reflect.Struct {
+				d.addFields(schema, fieldType)
+				continue
+			}
+		}
+
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		schema.Properties[name] = d.schema(fieldType)
+		if !strings.Contains(options, "omitempty") {
+			schema.Required = append(schema.Required, name)
+		}
+	}
+}
+
+// exportedName capitalizes the first letter of a name
+func exportedName(name string) string {
+	runes := []rune(name)
+	if len(runes) == 0 {
+		return name
+	}
+	runes[0] = unicode.ToUpper(runes[0])
+	return string(runes)
+}
+
+// jsonContent returns the content of a JSON body with the given schema
+func jsonContent(schema *Schema) map[string]MediaType {
+	return map[string]MediaType{"application/json": {Schema: schema}}
+}
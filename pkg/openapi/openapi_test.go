@@ -0,0 +1,130 @@
+package openapi_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/machadovilaca/prometheus-rag/pkg/openapi"
+)
+
+type apiError struct {
+	Code    string         `json:"code"`
+	Message string         `json:"message"`
+	Details map[string]any `json:"details,omitempty"`
+}
+
+type Target struct {
+	Job string `json:"job"`
+}
+
+type metric struct {
+	Target
+
+	Name     string            `json:"name"`
+	Help     string            `json:"help,omitempty"`
+	Samples  uint64            `json:"samples"`
+	Values   []float64         `json:"values"`
+	Extra    map[string]string `json:"extra,omitempty"`
+	Parent   *metric           `json:"parent,omitempty"`
+	SyncedAt time.Time         `json:"synced_at"`
+	Duration time.Duration     `json:"duration"`
+	Ignored  string            `json:"-"`
+	internal string
+}
+
+var _ = Describe("Document", func() {
+	var document *openapi.Document
+
+	BeforeEach(func() {
+		document = openapi.New(openapi.Info{Title: "Test API", Version: "v1"}, apiError{})
+	})
+
+	It("should generate the schemas of structs from their JSON encoding", func() {
+		Expect(document.Schema(&metric{})).To(Equal(&openapi.Schema{Ref: "#/components/schemas/metric"}))
+
+		schema := document.Components.Schemas["metric"]
+		Expect(schema.Type).To(Equal("object"))
+		Expect(schema.Properties).To(HaveKey("job"))
+		Expect(schema.Properties).NotTo(HaveKey("Target"))
+		Expect(schema.Properties).NotTo(HaveKey("Ignored"))
+		Expect(schema.Properties).NotTo(HaveKey("internal"))
+		Expect(schema.Required).To(ConsistOf("job", "name", "samples", "values", "synced_at", "duration"))
+
+		Expect(schema.Properties["samples"].Type).To(Equal("integer"))
+		Expect(*schema.Properties["samples"].Minimum).To(BeZero())
+		Expect(schema.Properties["values"]).To(Equal(&openapi.Schema{
+			Type:  "array",
+			Items: &openapi.Schema{Type: "number", Format: "double"},
+		}))
+		Expect(schema.Properties["extra"]).To(Equal(&openapi.Schema{
+			Type:                 "object",
+			AdditionalProperties: &openapi.Schema{Type: "string"},
+		}))
+		Expect(schema.Properties["parent"]).To(Equal(&openapi.Schema{Ref: "#/components/schemas/metric"}))
+		Expect(schema.Properties["synced_at"]).To(Equal(&openapi.Schema{Type: "string", Format: "date-time"}))
+		Expect(schema.Properties["duration"].Type).To(Equal("integer"))
+	})
+
+	It("should add the operations of endpoints", func() {
+		document.AddSecurityScheme("apiKey", &openapi.SecurityScheme{Type: "apiKey", In: "header", Name: "X-API-Key"})
+		document.Add(openapi.Endpoint{
+			Method:      http.MethodPost,
+			Path:        "/metrics",
+			OperationID: "addMetric",
+			Request:     metric{},
+			Status:      http.StatusCreated,
+			Response:    metric{},
+			Errors:      []int{http.StatusBadRequest},
+			Security:    []string{"apiKey"},
+		})
+		document.Add(openapi.Endpoint{
+			Method: http.MethodDelete,
+			Path:   "/metrics",
+			Status: http.StatusNoContent,
+		})
+
+		item := *document.Paths["/metrics"]
+		Expect(item).To(HaveKey("post"))
+		Expect(item).To(HaveKey("delete"))
+
+		operation := item["post"]
+		Expect(operation.OperationID).To(Equal("addMetric"))
+		Expect(operation.RequestBody.Content["application/json"].Schema.Ref).To(Equal("#/components/schemas/metric"))
+		Expect(operation.Responses["201"].Content["application/json"].Schema.Ref).To(Equal("#/components/schemas/metric"))
+		Expect(operation.Responses["400"].Description).To(Equal("Bad Request"))
+		Expect(operation.Responses["400"].Content["application/json"].Schema.Ref).To(Equal("#/components/schemas/apiError"))
+		Expect(operation.Security).To(Equal([]map[string][]string{{"apiKey": {}}}))
+
+		Expect(item["delete"].Responses["204"].Content).To(BeEmpty())
+		Expect(item["delete"].Security).To(BeEmpty())
+	})
+
+	It("should prefix the schemas of structs sharing a name with their package", func() {
+		document.Schema(openapi.Info{})
+		type Info struct {
+			Name string `json:"name"`
+		}
+
+		Expect(document.Schema(Info{}).Ref).To(Equal("#/components/schemas/Openapi_testInfo"))
+		Expect(document.Components.Schemas).To(HaveKey("Info"))
+		Expect(document.Components.Schemas).To(HaveKey("Openapi_testInfo"))
+	})
+
+	It("should encode to an OpenAPI document", func() {
+		document.Add(openapi.Endpoint{Method: http.MethodGet, Path: "/metrics", Status: http.StatusOK, Response: metric{}})
+
+		data, err := json.Marshal(document)
+		Expect(err).NotTo(HaveOccurred())
+
+		var decoded map[string]any
+		Expect(json.Unmarshal(data, &decoded)).To(Succeed())
+		Expect(decoded).To(HaveKeyWithValue("openapi", openapi.Version))
+		Expect(decoded).To(HaveKey("paths"))
+		Expect(decoded["components"]).To(HaveKey("schemas"))
+		Expect(string(data)).To(ContainSubstring(`"$ref":"#/components/schemas/metric"`))
+	})
+})
@@ -0,0 +1,77 @@
+package prometheus
+
+import (
+	"encoding/json"
+	"slices"
+)
+
+// Annotations holds team-curated knowledge about a metric that is not part
+// of the Prometheus metadata
+type Annotations struct {
+	// Synonyms lists other names users refer to the metric by
+	Synonyms []string `json:"synonyms,omitempty" yaml:"synonyms,omitempty"`
+
+	// Description explains the metric in the team's own terms
+	Description string `json:"description,omitempty" yaml:"description,omitempty"`
+
+	// Examples lists preferred PromQL queries using the metric
+	Examples []string `json:"examples,omitempty" yaml:"examples,omitempty"`
+
+	// Deprecated explains why the metric should no longer be used and what replaces it
+	Deprecated string `json:"deprecated,omitempty" yaml:"deprecated,omitempty"`
+}
+
+// IsEmpty returns true if no annotation is set
+func (a *Annotations) IsEmpty() bool {
+	return len(a.Synonyms) == 0 && a.Description == "" && len(a.Examples) == 0 && a.Deprecated == ""
+}
+
+// Merge adds the annotations of other, keeping the existing description and
+// deprecation note and appending synonyms and examples not yet present
+func (a *Annotations) Merge(other Annotations) {
+	for _, synonym := range other.Synonyms {
+		if !slices.Contains(a.Synonyms, synonym) {
+			a.Synonyms = append(a.Synonyms, synonym)
+		}
+	}
+
+	for _, example := range other.Examples {
+		if !slices.Contains(a.Examples, example) {
+			a.Examples = append(a.Examples, example)
+		}
+	}
+
+	if a.Description == "" {
+		a.Description = other.Description
+	}
+
+	if a.Deprecated == "" {
+		a.Deprecated = other.Deprecated
+	}
+}
+
+// EncodeAnnotations serializes annotations for storage
+func EncodeAnnotations(annotations *Annotations) string {
+	if annotations == nil || annotations.IsEmpty() {
+		return ""
+	}
+
+	data, err := json.Marshal(annotations)
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
+
+// DecodeAnnotations parses annotations serialized with EncodeAnnotations
+func DecodeAnnotations(s string) *Annotations {
+	if s == "" {
+		return nil
+	}
+
+	var annotations Annotations
+	if err := json.Unmarshal([]byte(s), &annotations); err != nil {
+		return nil
+	}
+	return &annotations
+}
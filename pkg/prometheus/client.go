@@ -13,17 +13,32 @@ import (
 
 // Client interface for interacting with Prometheus
 type Client interface {
-	// ListMetricsMetadata lists all metrics metadata from Prometheus
-	ListMetricsMetadata() ([]*MetricMetadata, error)
+	// ListMetricsMetadata lists all metrics metadata from Prometheus, along with
+	// a report of the metrics that could not be listed
+	ListMetricsMetadata(ctx context.Context) ([]*MetricMetadata, *SyncReport, error)
+
+	// Ping checks that Prometheus answers metadata requests
+	Ping(ctx context.Context) error
 }
 
 // Config represents the configuration for the Prometheus API
 type Config struct {
 	Address string
+
+	// LabelsConcurrency is the number of concurrent label discovery requests
+	LabelsConcurrency int
+	// LabelsRateLimit is the maximum number of label discovery requests per second, 0 for no limit
+	LabelsRateLimit int
+	// LabelsBatchSize is the number of metrics looked up per label discovery request
+	LabelsBatchSize int
+	// LabelsLookback is how far back the series are looked up to discover the
+	// labels, so the lookups only touch the recent blocks of the TSDB
+	LabelsLookback time.Duration
 }
 
 type api struct {
 	client promAPI.Client
+	config Config
 }
 
 // New creates a new Prometheus client
@@ -37,48 +52,111 @@ func New(cfg Config) (Client, error) {
 
 	return &api{
 		client: client,
+		config: cfg,
 	}, nil
 }
 
 // ListMetricsMetadata lists all metrics metadata from Prometheus
-func (p *api) ListMetricsMetadata() ([]*MetricMetadata, error) {
+func (p *api) ListMetricsMetadata(ctx context.Context) ([]*MetricMetadata, *SyncReport, error) {
+	report := &SyncReport{StartedAt: time.Now()}
+
 	v1api := promv1.NewAPI(p.client)
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	metadataCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
 	defer cancel()
 
-	results, err := v1api.Metadata(ctx, "", "")
+	results, err := v1api.Metadata(metadataCtx, "", "")
 	if err != nil {
-		return nil, fmt.Errorf("failed to list metrics metadata: %w", err)
+		return nil, nil, fmt.Errorf("failed to list metrics metadata: %w", err)
 	}
 
-	return p.convertMetadata(results), nil
+	metrics := p.convertMetadata(ctx, results, report)
+	report.Duration = time.Since(report.StartedAt)
+
+	return metrics, report, nil
 }
 
-func (p *api) convertMetadata(results map[string][]promv1.Metadata) []*MetricMetadata {
-	metrics := make([]*MetricMetadata, 0, len(results))
+// Ping requests the metadata of a single metric, the API the synchronization depends on
+func (p *api) Ping(ctx context.Context) error {
+	if _, err := promv1.NewAPI(p.client).Metadata(ctx, "", "1"); err != nil {
+		return fmt.Errorf("failed to reach prometheus: %w", err)
+	}
+
+	return nil
+}
+
+func (p *api) convertMetadata(ctx context.Context, results map[string][]promv1.Metadata, report *SyncReport) []*MetricMetadata {
+	families := groupFamilies(results)
+
+	var selectors []string
+	for _, family := range families {
+		selectors = append(selectors, labelSelectors(family)...)
+	}
 
-	for metric, metadata := range results {
-		metrics = append(metrics, &MetricMetadata{
-			Name:   metric,
-			Help:   metadata[0].Help,
-			Type:   string(metadata[0].Type),
-			Labels: p.getMetricLabels(metric),
-		})
+	fetcher := newLabelFetcher(promv1.NewAPI(p.client), p.config)
+	labels, failures := fetcher.fetch(ctx, selectors)
+	report.LabelRequests = fetcher.requests
+
+	metrics := make([]*MetricMetadata, 0, len(families))
+	for _, family := range families {
+		// Metrics whose labels could not be discovered are kept without labels,
+		// so a transient failure does not remove them from the catalog
+		if err := familyFailure(family, failures); err != nil {
+			log.Error().Err(err).Msgf("failed to get labels for metric %s", family.Name)
+			report.addFailure(family.Name, err)
+		}
+
+		discoverSeries(family, labels)
+		metrics = append(metrics, family)
 	}
 
+	report.Metrics = len(metrics)
+	report.sortFailures()
+
 	return metrics
 }
 
-func (p *api) getMetricLabels(metric string) []string {
-	v1api := promv1.NewAPI(p.client)
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
+// labelSelectors returns the series names whose labels are needed to describe a metric family
+func labelSelectors(metric *MetricMetadata) []string {
+	switch {
+	case metric.IsHistogram():
+		return []string{metric.Name + "_bucket", metric.Name}
+	case metric.IsSummary():
+		return []string{metric.Name, metric.Name + "_count"}
+	default:
+		return []string{metric.Name}
+	}
+}
 
-	results, _, err := v1api.LabelNames(ctx, []string{metric}, time.Time{}, time.Time{})
-	if err != nil {
-		log.Error().Err(err).Msg("failed to get metric labels")
-		return []string{}
+func familyFailure(metric *MetricMetadata, failures map[string]error) error {
+	for _, selector := range labelSelectors(metric) {
+		if err, ok := failures[selector]; ok {
+			return err
+		}
 	}
+	return nil
+}
 
-	return results
+// discoverSeries fills in the labels and component series of a metric family.
+// Histograms are checked for both classic (_bucket) and native series.
+func discoverSeries(metric *MetricMetadata, labels map[string][]string) {
+	switch {
+	case metric.IsHistogram():
+		classicLabels := labels[metric.Name+"_bucket"]
+		nativeLabels := labels[metric.Name]
+
+		if len(classicLabels) > 0 || len(nativeLabels) == 0 {
+			metric.Series = componentSeries(metric.Name, metric.Type)
+		}
+		if len(nativeLabels) > 0 {
+			metric.NativeHistogram = true
+			metric.Series = append(metric.Series, metric.Name)
+		}
+
+		metric.Labels = mergeLabels(classicLabels, nativeLabels)
+	case metric.IsSummary():
+		metric.Series = componentSeries(metric.Name, metric.Type)
+		metric.Labels = mergeLabels(labels[metric.Name], labels[metric.Name+"_count"])
+	default:
+		metric.Labels = mergeLabels(labels[metric.Name])
+	}
 }
@@ -0,0 +1,379 @@
+package prometheus_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/machadovilaca/prometheus-rag/pkg/prometheus"
+)
+
+type fakeMetadata struct {
+	Type string `json:"type"`
+	Help string `json:"help"`
+	Unit string `json:"unit"`
+}
+
+type fakePrometheus struct {
+	metadata map[string][]fakeMetadata
+	labels   map[string][]string
+
+	// failing lists the metrics whose label lookups fail
+	failing map[string]bool
+	// series is the number of series of each metric, 1 if unset
+	series map[string]int
+
+	seriesRequests int
+	labelsRequests int
+
+	// windows are the time ranges of the label discovery requests
+	windows []time.Duration
+}
+
+func newFakePrometheus(metadata map[string][]fakeMetadata, labels map[string][]string) *httptest.Server {
+	return (&fakePrometheus{metadata: metadata, labels: labels}).start()
+}
+
+func (f *fakePrometheus) start() *httptest.Server {
+	writeData := func(w http.ResponseWriter, data any) {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"status": "success",
+			"data":   data,
+		})
+	}
+
+	writeError := func(w http.ResponseWriter) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusServiceUnavailable)
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"status":    "error",
+			"errorType": "unavailable",
+			"error":     "unavailable",
+		})
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v1/metadata", func(w http.ResponseWriter, r *http.Request) {
+		writeData(w, f.metadata)
+	})
+	mux.HandleFunc("/api/v1/labels", func(w http.ResponseWriter, r *http.Request) {
+		_ = r.ParseForm()
+		f.labelsRequests++
+		f.recordWindow(r)
+
+		result := []string{}
+		for _, match := range r.Form["match[]"] {
+			if f.failing[match] {
+				writeError(w)
+				return
+			}
+			result = append(result, f.labels[match]...)
+		}
+		writeData(w, result)
+	})
+	mux.HandleFunc("/api/v1/series", func(w http.ResponseWriter, r *http.Request) {
+		_ = r.ParseForm()
+		f.seriesRequests++
+		f.recordWindow(r)
+
+		result := []map[string]string{}
+		for _, match := range r.Form["match[]"] {
+			name := strings.TrimSuffix(strings.TrimPrefix(match, `{__name__="`), `"}`)
+			if f.failing[name] {
+				writeError(w)
+				return
+			}
+			if len(f.labels[name]) == 0 {
+				continue
+			}
+
+			for i := range max(f.series[name], 1) {
+				series := map[string]string{}
+				for _, label := range f.labels[name] {
+					series[label] = strconv.Itoa(i)
+				}
+				series["__name__"] = name
+				result = append(result, series)
+			}
+		}
+		if limit, err := strconv.Atoi(r.Form.Get("limit")); err == nil && limit > 0 && len(result) > limit {
+			result = result[:limit]
+		}
+		writeData(w, result)
+	})
+
+	return httptest.NewServer(mux)
+}
+
+// recordWindow records the time range of a request, given in Unix seconds
+func (f *fakePrometheus) recordWindow(r *http.Request) {
+	start, errStart := strconv.ParseFloat(r.Form.Get("start"), 64)
+	end, errEnd := strconv.ParseFloat(r.Form.Get("end"), 64)
+	if errStart != nil || errEnd != nil {
+		f.windows = append(f.windows, 0)
+		return
+	}
+	f.windows = append(f.windows, time.Duration((end-start)*float64(time.Second)).Round(time.Second))
+}
+
+var _ = Describe("Client", func() {
+	var server *httptest.Server
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	listMetricsWithReport := func(cfg prometheus.Config) ([]*prometheus.MetricMetadata, *prometheus.SyncReport) {
+		cfg.Address = server.URL
+		client, err := prometheus.New(cfg)
+		Expect(err).NotTo(HaveOccurred())
+
+		metrics, report, err := client.ListMetricsMetadata(context.Background())
+		Expect(err).NotTo(HaveOccurred())
+		return metrics, report
+	}
+
+	listMetrics := func() []*prometheus.MetricMetadata {
+		metrics, _ := listMetricsWithReport(prometheus.Config{})
+		return metrics
+	}
+
+	Context("ListMetricsMetadata", func() {
+		It("should keep the unit of the metric", func() {
+			server = newFakePrometheus(map[string][]fakeMetadata{
+				"process_resident_memory_bytes": {{Type: "gauge", Help: "Resident memory size in bytes.", Unit: "bytes"}},
+			}, map[string][]string{
+				"process_resident_memory_bytes": {"__name__", "instance", "job"},
+			})
+
+			metrics := listMetrics()
+			Expect(metrics).To(HaveLen(1))
+			Expect(metrics[0].Unit).To(Equal("bytes"))
+			Expect(metrics[0].Labels).To(Equal([]string{"__name__", "instance", "job"}))
+			Expect(metrics[0].Series).To(BeEmpty())
+		})
+
+		It("should group classic histogram series under their family", func() {
+			server = newFakePrometheus(map[string][]fakeMetadata{
+				"http_request_duration_seconds":        {{Type: "histogram", Help: "Request latency."}},
+				"http_request_duration_seconds_bucket": {{Type: "unknown"}},
+				"http_request_duration_seconds_sum":    {{Type: "unknown"}},
+				"http_request_duration_seconds_count":  {{Type: "unknown"}},
+			}, map[string][]string{
+				"http_request_duration_seconds_bucket": {"__name__", "handler", "le"},
+			})
+
+			metrics := listMetrics()
+			Expect(metrics).To(HaveLen(1))
+			Expect(metrics[0].Name).To(Equal("http_request_duration_seconds"))
+			Expect(metrics[0].Type).To(Equal(prometheus.MetricTypeHistogram))
+			Expect(metrics[0].Series).To(Equal([]string{
+				"http_request_duration_seconds_bucket",
+				"http_request_duration_seconds_sum",
+				"http_request_duration_seconds_count",
+			}))
+			Expect(metrics[0].Labels).To(ContainElement("le"))
+			Expect(metrics[0].NativeHistogram).To(BeFalse())
+			Expect(metrics[0].HasClassicBuckets()).To(BeTrue())
+		})
+
+		It("should identify native histograms", func() {
+			server = newFakePrometheus(map[string][]fakeMetadata{
+				"rpc_duration_seconds": {{Type: "histogram", Help: "RPC latency."}},
+			}, map[string][]string{
+				"rpc_duration_seconds": {"__name__", "service"},
+			})
+
+			metrics := listMetrics()
+			Expect(metrics).To(HaveLen(1))
+			Expect(metrics[0].NativeHistogram).To(BeTrue())
+			Expect(metrics[0].Series).To(Equal([]string{"rpc_duration_seconds"}))
+			Expect(metrics[0].HasClassicBuckets()).To(BeFalse())
+		})
+
+		It("should group summary series under their family", func() {
+			server = newFakePrometheus(map[string][]fakeMetadata{
+				"go_gc_duration_seconds_sum":   {{Type: "summary", Help: "GC pause durations."}},
+				"go_gc_duration_seconds_count": {{Type: "summary", Help: "GC pause durations."}},
+			}, map[string][]string{
+				"go_gc_duration_seconds":       {"__name__", "instance", "quantile"},
+				"go_gc_duration_seconds_count": {"__name__", "instance"},
+			})
+
+			metrics := listMetrics()
+			Expect(metrics).To(HaveLen(1))
+			Expect(metrics[0].Name).To(Equal("go_gc_duration_seconds"))
+			Expect(metrics[0].Type).To(Equal(prometheus.MetricTypeSummary))
+			Expect(metrics[0].Labels).To(Equal([]string{"__name__", "instance", "quantile"}))
+			Expect(metrics[0].Series).To(ContainElement("go_gc_duration_seconds_sum"))
+		})
+
+		It("should merge metadata reported by different targets", func() {
+			server = newFakePrometheus(map[string][]fakeMetadata{
+				"up": {{Type: "gauge", Help: "Target is up."}},
+				"requests": {
+					{Type: "gauge", Help: "Requests in flight."},
+					{Type: "counter", Help: "Total requests."},
+					{Type: "counter", Help: "Total requests."},
+				},
+			}, map[string][]string{})
+
+			metrics := listMetrics()
+			Expect(metrics).To(HaveLen(2))
+
+			requests := metrics[0]
+			Expect(requests.Name).To(Equal("requests"))
+			Expect(requests.Type).To(Equal(prometheus.MetricTypeCounter))
+			Expect(requests.TypeConflict).To(BeTrue())
+			Expect(requests.Help).To(Equal("Total requests. | Requests in flight."))
+			Expect(requests.HasConflicts()).To(BeTrue())
+			Expect(requests.Variants).To(Equal([]prometheus.MetadataVariant{
+				{Type: "counter", Help: "Total requests."},
+				{Type: "gauge", Help: "Requests in flight."},
+			}))
+
+			up := metrics[1]
+			Expect(up.TypeConflict).To(BeFalse())
+			Expect(up.HasConflicts()).To(BeFalse())
+			Expect(up.Variants).To(BeEmpty())
+		})
+
+		It("should flag differing help texts without a type conflict", func() {
+			server = newFakePrometheus(map[string][]fakeMetadata{
+				"build_info": {
+					{Type: "unknown", Help: "Build information."},
+					{Type: "gauge", Help: "Build info."},
+				},
+			}, map[string][]string{})
+
+			metrics := listMetrics()
+			Expect(metrics).To(HaveLen(1))
+			Expect(metrics[0].Type).To(Equal(prometheus.MetricTypeGauge))
+			Expect(metrics[0].TypeConflict).To(BeFalse())
+			Expect(metrics[0].HasConflicts()).To(BeTrue())
+			Expect(metrics[0].Help).To(Equal("Build info. | Build information."))
+		})
+
+		It("should not group series that only share a suffix", func() {
+			server = newFakePrometheus(map[string][]fakeMetadata{
+				"kubevirt_vmi_phase":       {{Type: "gauge", Help: "VMI phase."}},
+				"kubevirt_vmi_phase_count": {{Type: "gauge", Help: "Number of VMIs per phase."}},
+			}, map[string][]string{})
+
+			metrics := listMetrics()
+			Expect(metrics).To(HaveLen(2))
+			Expect(metrics[0].Name).To(Equal("kubevirt_vmi_phase"))
+			Expect(metrics[1].Name).To(Equal("kubevirt_vmi_phase_count"))
+		})
+	})
+	Context("label discovery", func() {
+		var fake *fakePrometheus
+
+		BeforeEach(func() {
+			fake = &fakePrometheus{
+				metadata: map[string][]fakeMetadata{
+					"metric_a": {{Type: "gauge"}},
+					"metric_b": {{Type: "gauge"}},
+					"metric_c": {{Type: "gauge"}},
+				},
+				labels: map[string][]string{
+					"metric_a": {"__name__", "job"},
+					"metric_b": {"__name__", "instance"},
+					"metric_c": {"__name__", "pod"},
+				},
+			}
+		})
+
+		It("should look up several metrics per request", func() {
+			server = fake.start()
+
+			metrics, report := listMetricsWithReport(prometheus.Config{LabelsBatchSize: 3, LabelsConcurrency: 1})
+			Expect(metrics).To(HaveLen(3))
+			Expect(metrics[0].Labels).To(Equal([]string{"__name__", "job"}))
+			Expect(metrics[1].Labels).To(Equal([]string{"__name__", "instance"}))
+			Expect(metrics[2].Labels).To(Equal([]string{"__name__", "pod"}))
+
+			Expect(fake.seriesRequests).To(Equal(1))
+			Expect(fake.labelsRequests).To(BeZero())
+			Expect(report.LabelRequests).To(Equal(1))
+			Expect(report.Metrics).To(Equal(3))
+			Expect(report.HasFailures()).To(BeFalse())
+		})
+
+		It("should look up the metrics one by one when a batch has too many series", func() {
+			fake.series = map[string]int{"metric_a": 1000}
+			server = fake.start()
+
+			metrics, report := listMetricsWithReport(prometheus.Config{LabelsBatchSize: 3, LabelsConcurrency: 1})
+			Expect(metrics).To(HaveLen(3))
+			Expect(metrics[0].Labels).To(Equal([]string{"__name__", "job"}))
+			Expect(metrics[1].Labels).To(Equal([]string{"__name__", "instance"}))
+			Expect(metrics[2].Labels).To(Equal([]string{"__name__", "pod"}))
+
+			Expect(fake.seriesRequests).To(Equal(1))
+			Expect(fake.labelsRequests).To(Equal(3))
+			Expect(report.HasFailures()).To(BeFalse())
+		})
+
+		It("should keep and report metrics whose labels could not be discovered", func() {
+			fake.failing = map[string]bool{"metric_b": true}
+			server = fake.start()
+
+			metrics, report := listMetricsWithReport(prometheus.Config{LabelsBatchSize: 3, LabelsConcurrency: 2})
+			Expect(metrics).To(HaveLen(3))
+			Expect(metrics[0].Labels).To(Equal([]string{"__name__", "job"}))
+			Expect(metrics[1].Name).To(Equal("metric_b"))
+			Expect(metrics[1].Labels).To(BeEmpty())
+			Expect(metrics[2].Labels).To(Equal([]string{"__name__", "pod"}))
+
+			Expect(report.Metrics).To(Equal(3))
+			Expect(report.Failures).To(HaveLen(1))
+			Expect(report.Failures[0].Metric).To(Equal("metric_b"))
+			Expect(fake.labelsRequests).To(Equal(3))
+		})
+
+		It("should only look up the series of the lookback window", func() {
+			fake.failing = map[string]bool{"metric_b": true}
+			server = fake.start()
+
+			_, report := listMetricsWithReport(prometheus.Config{LabelsBatchSize: 3, LabelsConcurrency: 1, LabelsLookback: 5 * time.Minute})
+			Expect(report.LabelRequests).To(Equal(4))
+			Expect(fake.windows).To(HaveLen(4))
+			Expect(fake.windows).To(HaveEach(Equal(5 * time.Minute)))
+		})
+
+		It("should limit the request rate", func() {
+			server = fake.start()
+
+			_, report := listMetricsWithReport(prometheus.Config{LabelsBatchSize: 1, LabelsRateLimit: 10})
+			Expect(report.LabelRequests).To(Equal(3))
+			Expect(report.Duration).To(BeNumerically(">=", 250*time.Millisecond))
+		})
+	})
+
+	Context("Ping", func() {
+		It("should succeed when the metadata API answers", func() {
+			server = newFakePrometheus(map[string][]fakeMetadata{}, nil)
+
+			client, err := prometheus.New(prometheus.Config{Address: server.URL})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(client.Ping(context.Background())).To(Succeed())
+		})
+
+		It("should fail when the metadata API does not answer", func() {
+			server = httptest.NewServer(http.NotFoundHandler())
+
+			client, err := prometheus.New(prometheus.Config{Address: server.URL})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(client.Ping(context.Background())).To(MatchError(ContainSubstring("failed to reach prometheus")))
+		})
+	})
+})
@@ -0,0 +1,151 @@
+package prometheus
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+
+	promv1 "github.com/prometheus/client_golang/api/prometheus/v1"
+)
+
+// helpSeparator separates the distinct help texts merged into a single entry
+const helpSeparator = " | "
+
+// MetadataVariant is one of the metadata entries reported for a metric by
+// different targets
+type MetadataVariant struct {
+	Type string `json:"type"`
+	Help string `json:"help,omitempty"`
+	Unit string `json:"unit,omitempty"`
+}
+
+// mergeMetadata collapses the metadata entries reported for a metric into a
+// single entry. The most common type wins (unknown only if nothing else is
+// reported), distinct help texts are merged, and when the entries disagree
+// the distinct variants are kept. Ties are broken lexically so the result
+// does not depend on the order returned by Prometheus.
+func mergeMetadata(name string, metadata []promv1.Metadata) *MetricMetadata {
+	variants := distinctVariants(metadata)
+
+	typeCounts := make(map[string]int)
+	helpCounts := make(map[string]int)
+	for _, m := range metadata {
+		typeCounts[string(m.Type)]++
+		if m.Help != "" {
+			helpCounts[m.Help]++
+		}
+	}
+
+	metric := &MetricMetadata{
+		Name: name,
+		Type: resolveType(typeCounts),
+		Help: strings.Join(rankByCount(helpCounts), helpSeparator),
+	}
+
+	for _, v := range variants {
+		if v.Unit != "" {
+			metric.Unit = v.Unit
+			break
+		}
+	}
+
+	if len(variants) > 1 {
+		metric.Variants = variants
+	}
+
+	knownTypes := 0
+	for t := range typeCounts {
+		if t != MetricTypeUnknown && t != "" {
+			knownTypes++
+		}
+	}
+	metric.TypeConflict = knownTypes > 1
+
+	return metric
+}
+
+func distinctVariants(metadata []promv1.Metadata) []MetadataVariant {
+	seen := make(map[MetadataVariant]struct{})
+	variants := []MetadataVariant{}
+
+	for _, m := range metadata {
+		v := MetadataVariant{Type: string(m.Type), Help: m.Help, Unit: m.Unit}
+		if _, ok := seen[v]; ok {
+			continue
+		}
+		seen[v] = struct{}{}
+		variants = append(variants, v)
+	}
+
+	sort.Slice(variants, func(i, j int) bool {
+		if variants[i].Type != variants[j].Type {
+			return variants[i].Type < variants[j].Type
+		}
+		if variants[i].Help != variants[j].Help {
+			return variants[i].Help < variants[j].Help
+		}
+		return variants[i].Unit < variants[j].Unit
+	})
+
+	return variants
+}
+
+func resolveType(typeCounts map[string]int) string {
+	known := make(map[string]int)
+	for t, count := range typeCounts {
+		if t != MetricTypeUnknown && t != "" {
+			known[t] = count
+		}
+	}
+
+	if ranked := rankByCount(known); len(ranked) > 0 {
+		return ranked[0]
+	}
+	if _, ok := typeCounts[MetricTypeUnknown]; ok {
+		return MetricTypeUnknown
+	}
+	return ""
+}
+
+// rankByCount returns the keys sorted by descending count, then lexically
+func rankByCount(counts map[string]int) []string {
+	keys := make([]string, 0, len(counts))
+	for k := range counts {
+		keys = append(keys, k)
+	}
+
+	sort.Slice(keys, func(i, j int) bool {
+		if counts[keys[i]] != counts[keys[j]] {
+			return counts[keys[i]] > counts[keys[j]]
+		}
+		return keys[i] < keys[j]
+	})
+
+	return keys
+}
+
+// EncodeVariants serializes metadata variants for storage
+func EncodeVariants(variants []MetadataVariant) string {
+	if len(variants) == 0 {
+		return ""
+	}
+
+	data, err := json.Marshal(variants)
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
+
+// DecodeVariants parses metadata variants serialized with EncodeVariants
+func DecodeVariants(s string) []MetadataVariant {
+	if s == "" {
+		return nil
+	}
+
+	var variants []MetadataVariant
+	if err := json.Unmarshal([]byte(s), &variants); err != nil {
+		return nil
+	}
+	return variants
+}
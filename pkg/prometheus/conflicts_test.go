@@ -0,0 +1,101 @@
+package prometheus_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	promv1 "github.com/prometheus/client_golang/api/prometheus/v1"
+
+	"github.com/machadovilaca/prometheus-rag/pkg/prometheus"
+)
+
+var _ = Describe("MergeMetadata", func() {
+	entry := func(metricType, help string) promv1.Metadata {
+		return promv1.Metadata{Type: promv1.MetricType(metricType), Help: help}
+	}
+
+	It("should pick the most common type", func() {
+		metric := prometheus.MergeMetadata("requests", []promv1.Metadata{
+			entry("gauge", "Requests."),
+			entry("counter", "Requests."),
+			entry("counter", "Requests."),
+		})
+		Expect(metric.Name).To(Equal("requests"))
+		Expect(metric.Type).To(Equal(prometheus.MetricTypeCounter))
+		Expect(metric.TypeConflict).To(BeTrue())
+	})
+
+	It("should only pick unknown when no other type is reported", func() {
+		metric := prometheus.MergeMetadata("build_info", []promv1.Metadata{
+			entry("unknown", "Build information."),
+			entry("unknown", "Build information."),
+			entry("gauge", "Build information."),
+		})
+		Expect(metric.Type).To(Equal(prometheus.MetricTypeGauge))
+		Expect(metric.TypeConflict).To(BeFalse())
+
+		metric = prometheus.MergeMetadata("build_info", []promv1.Metadata{entry("unknown", "Build information.")})
+		Expect(metric.Type).To(Equal(prometheus.MetricTypeUnknown))
+	})
+
+	It("should break ties lexically regardless of the reported order", func() {
+		for _, metadata := range [][]promv1.Metadata{
+			{entry("gauge", "In flight."), entry("counter", "Total.")},
+			{entry("counter", "Total."), entry("gauge", "In flight.")},
+		} {
+			metric := prometheus.MergeMetadata("requests", metadata)
+			Expect(metric.Type).To(Equal(prometheus.MetricTypeCounter))
+			Expect(metric.Help).To(Equal("In flight. | Total."))
+			Expect(metric.Variants).To(Equal([]prometheus.MetadataVariant{
+				{Type: "counter", Help: "Total."},
+				{Type: "gauge", Help: "In flight."},
+			}))
+		}
+	})
+
+	It("should join distinct help texts, most common first", func() {
+		metric := prometheus.MergeMetadata("requests", []promv1.Metadata{
+			entry("counter", "In flight."),
+			entry("counter", "Total."),
+			entry("counter", ""),
+			entry("counter", "Total."),
+		})
+		Expect(metric.Help).To(Equal("Total. | In flight."))
+	})
+
+	It("should not report conflicts for a single variant", func() {
+		metric := prometheus.MergeMetadata("up", []promv1.Metadata{
+			{Type: "gauge", Help: "Target is up.", Unit: "targets"},
+			{Type: "gauge", Help: "Target is up.", Unit: "targets"},
+		})
+		Expect(metric.Unit).To(Equal("targets"))
+		Expect(metric.Variants).To(BeEmpty())
+		Expect(metric.HasConflicts()).To(BeFalse())
+	})
+
+	It("should keep the variants when several are reported", func() {
+		metric := prometheus.MergeMetadata("up", []promv1.Metadata{
+			{Type: "gauge", Help: "Target is up."},
+			{Type: "gauge", Help: "Target is up.", Unit: "targets"},
+		})
+		Expect(metric.Unit).To(Equal("targets"))
+		Expect(metric.TypeConflict).To(BeFalse())
+		Expect(metric.HasConflicts()).To(BeTrue())
+		Expect(metric.Variants).To(HaveLen(2))
+	})
+})
+
+var _ = Describe("Variants", func() {
+	It("should round-trip encoded variants", func() {
+		variants := []prometheus.MetadataVariant{
+			{Type: "counter", Help: "Total requests.", Unit: "requests"},
+			{Type: "gauge"},
+		}
+
+		Expect(prometheus.DecodeVariants(prometheus.EncodeVariants(variants))).To(Equal(variants))
+	})
+
+	It("should encode no variants as an empty string", func() {
+		Expect(prometheus.EncodeVariants(nil)).To(BeEmpty())
+		Expect(prometheus.DecodeVariants("")).To(BeNil())
+	})
+})
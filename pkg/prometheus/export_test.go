@@ -0,0 +1,4 @@
+package prometheus
+
+// MergeMetadata exposes mergeMetadata to the tests of the package
+var MergeMetadata = mergeMetadata
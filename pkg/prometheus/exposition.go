@@ -0,0 +1,72 @@
+package prometheus
+
+import (
+	"fmt"
+	"io"
+	"sort"
+
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+	"github.com/prometheus/common/model"
+)
+
+// ParseExposition reads the metric families of a text exposition, such as the
+// output of a /metrics endpoint, into the metadata a sync would list for them.
+// The result is sorted by name.
+func ParseExposition(r io.Reader) ([]*MetricMetadata, error) {
+	var parser expfmt.TextParser
+	families, err := parser.TextToMetricFamilies(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse exposition: %w", err)
+	}
+
+	metrics := make([]*MetricMetadata, 0, len(families))
+	for name, family := range families {
+		metric := &MetricMetadata{
+			Name: name,
+			Help: family.GetHelp(),
+			Type: expositionType(family.GetType()),
+			Unit: family.GetUnit(),
+		}
+		metric.Series = componentSeries(metric.Name, metric.Type)
+
+		labels := []string{model.MetricNameLabel}
+		switch metric.Type {
+		case MetricTypeHistogram, MetricTypeGaugeHistogram:
+			labels = append(labels, model.BucketLabel)
+		case MetricTypeSummary:
+			labels = append(labels, model.QuantileLabel)
+		}
+		for _, m := range family.GetMetric() {
+			for _, label := range m.GetLabel() {
+				labels = append(labels, label.GetName())
+			}
+		}
+		metric.Labels = mergeLabels(labels)
+
+		metrics = append(metrics, metric)
+	}
+
+	sort.Slice(metrics, func(i, j int) bool {
+		return metrics[i].Name < metrics[j].Name
+	})
+
+	return metrics, nil
+}
+
+func expositionType(metricType dto.MetricType) string {
+	switch metricType {
+	case dto.MetricType_COUNTER:
+		return MetricTypeCounter
+	case dto.MetricType_GAUGE:
+		return MetricTypeGauge
+	case dto.MetricType_HISTOGRAM:
+		return MetricTypeHistogram
+	case dto.MetricType_GAUGE_HISTOGRAM:
+		return MetricTypeGaugeHistogram
+	case dto.MetricType_SUMMARY:
+		return MetricTypeSummary
+	default:
+		return MetricTypeUnknown
+	}
+}
@@ -0,0 +1,58 @@
+package prometheus_test
+
+import (
+	"os"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/machadovilaca/prometheus-rag/pkg/prometheus"
+)
+
+var _ = Describe("ParseExposition", func() {
+	It("should read the metric families of a text exposition", func() {
+		metrics, err := prometheus.ParseExposition(strings.NewReader(`
+# HELP http_requests_total Total number of HTTP requests.
+# TYPE http_requests_total counter
+http_requests_total{code="200",handler="/"} 10
+http_requests_total{code="500",method="GET"} 1
+# HELP http_request_duration_seconds Latency of HTTP requests.
+# TYPE http_request_duration_seconds histogram
+http_request_duration_seconds_bucket{handler="/",le="0.1"} 5
+http_request_duration_seconds_bucket{handler="/",le="+Inf"} 6
+http_request_duration_seconds_sum{handler="/"} 1.5
+http_request_duration_seconds_count{handler="/"} 6
+`))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(metrics).To(HaveLen(2))
+
+		Expect(metrics[0].Name).To(Equal("http_request_duration_seconds"))
+		Expect(metrics[0].Type).To(Equal(prometheus.MetricTypeHistogram))
+		Expect(metrics[0].Labels).To(Equal([]string{"__name__", "handler", "le"}))
+		Expect(metrics[0].Series).To(ConsistOf(
+			"http_request_duration_seconds_bucket", "http_request_duration_seconds_sum", "http_request_duration_seconds_count"))
+
+		Expect(metrics[1].Name).To(Equal("http_requests_total"))
+		Expect(metrics[1].Help).To(Equal("Total number of HTTP requests."))
+		Expect(metrics[1].Type).To(Equal(prometheus.MetricTypeCounter))
+		Expect(metrics[1].Labels).To(Equal([]string{"__name__", "code", "handler", "method"}))
+	})
+
+	It("should read the sample catalog", func() {
+		file, err := os.Open("../../hack/metrics.txt")
+		Expect(err).NotTo(HaveOccurred())
+		defer func() {
+			_ = file.Close()
+		}()
+
+		metrics, err := prometheus.ParseExposition(file)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(metrics).NotTo(BeEmpty())
+	})
+
+	It("should fail on invalid input", func() {
+		_, err := prometheus.ParseExposition(strings.NewReader("http_requests_total{code=200} 1\n"))
+		Expect(err).To(HaveOccurred())
+	})
+})
@@ -0,0 +1,130 @@
+package prometheus
+
+import (
+	"sort"
+	"strings"
+
+	promv1 "github.com/prometheus/client_golang/api/prometheus/v1"
+)
+
+// familySuffixes lists the suffixes of the series that belong to a metric family, by family type
+var familySuffixes = map[string][]string{
+	MetricTypeHistogram:      {"_bucket", "_sum", "_count", "_created"},
+	MetricTypeGaugeHistogram: {"_bucket", "_gsum", "_gcount"},
+	MetricTypeSummary:        {"_sum", "_count", "_created"},
+}
+
+// componentSeries returns the classic series names exposed by a metric family
+func componentSeries(name, metricType string) []string {
+	switch metricType {
+	case MetricTypeHistogram:
+		return []string{name + "_bucket", name + "_sum", name + "_count"}
+	case MetricTypeGaugeHistogram:
+		return []string{name + "_bucket", name + "_gsum", name + "_gcount"}
+	case MetricTypeSummary:
+		return []string{name, name + "_sum", name + "_count"}
+	default:
+		return nil
+	}
+}
+
+// familyName returns the name of the histogram or summary family the given
+// series belongs to. The second return value is false if the series is not a
+// component of a family.
+func familyName(name string, results map[string][]promv1.Metadata) (string, bool) {
+	for familyType, suffixes := range familySuffixes {
+		for _, suffix := range suffixes {
+			if !strings.HasSuffix(name, suffix) {
+				continue
+			}
+
+			base := strings.TrimSuffix(name, suffix)
+			if base == "" {
+				continue
+			}
+
+			if metadata, ok := results[base]; ok && len(metadata) > 0 && metadataType(metadata) == familyType {
+				return base, true
+			}
+
+			if metadata := results[name]; len(metadata) > 0 && metadataType(metadata) == familyType {
+				return base, true
+			}
+		}
+	}
+
+	return "", false
+}
+
+// groupFamilies converts the metadata returned by Prometheus into one entry per
+// metric family, folding histogram and summary component series into their family.
+// The result is sorted by name.
+func groupFamilies(results map[string][]promv1.Metadata) []*MetricMetadata {
+	names := make([]string, 0, len(results))
+	for name, metadata := range results {
+		if len(metadata) == 0 {
+			continue
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	families := make(map[string]*MetricMetadata, len(names))
+
+	for _, name := range names {
+		if _, ok := familyName(name, results); ok {
+			continue
+		}
+		families[name] = mergeMetadata(name, results[name])
+	}
+
+	// Components whose family has no metadata entry of its own still
+	// produce a single family entry
+	for _, name := range names {
+		base, ok := familyName(name, results)
+		if !ok {
+			continue
+		}
+		if _, exists := families[base]; !exists {
+			families[base] = mergeMetadata(base, results[name])
+		}
+	}
+
+	metrics := make([]*MetricMetadata, 0, len(families))
+	for _, family := range families {
+		metrics = append(metrics, family)
+	}
+	sort.Slice(metrics, func(i, j int) bool {
+		return metrics[i].Name < metrics[j].Name
+	})
+
+	return metrics
+}
+
+// metadataType returns the type resolved from the metadata entries of a metric
+func metadataType(metadata []promv1.Metadata) string {
+	typeCounts := make(map[string]int)
+	for _, m := range metadata {
+		typeCounts[string(m.Type)]++
+	}
+	return resolveType(typeCounts)
+}
+
+// mergeLabels returns the sorted union of the given label name lists
+func mergeLabels(lists ...[]string) []string {
+	seen := make(map[string]struct{})
+	merged := []string{}
+
+	for _, list := range lists {
+		for _, label := range list {
+			if _, ok := seen[label]; ok {
+				continue
+			}
+			seen[label] = struct{}{}
+			merged = append(merged, label)
+		}
+	}
+
+	sort.Strings(merged)
+	return merged
+}
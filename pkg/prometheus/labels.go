@@ -0,0 +1,237 @@
+package prometheus
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/prometheus/common/model"
+	"github.com/rs/zerolog/log"
+
+	promv1 "github.com/prometheus/client_golang/api/prometheus/v1"
+)
+
+const (
+	defaultLabelsConcurrency = 8
+	defaultLabelsBatchSize   = 20
+	defaultLabelsLookback    = 10 * time.Minute
+	labelsRequestTimeout     = 10 * time.Second
+
+	// labelsSeriesPerMetric bounds the series listed per metric of a batch
+	labelsSeriesPerMetric = 100
+)
+
+// errSeriesLimitReached is returned when the series of a batch were truncated,
+// so some label names, or whole metrics, may be missing
+var errSeriesLimitReached = errors.New("series limit reached")
+
+// labelFetcher discovers the label names of metrics using a bounded pool of
+// workers. Several metrics are looked up per request through the series API,
+// listing a bounded number of series per metric; batches that fail or reach
+// the limit are retried one metric at a time through the labels API. Both
+// only look up the series of the lookback window, as the series of the whole
+// TSDB can be far more expensive to list on high-cardinality metrics.
+type labelFetcher struct {
+	v1api       promv1.API
+	concurrency int
+	batchSize   int
+	lookback    time.Duration
+	limiter     *rateLimiter
+
+	mu       sync.Mutex
+	labels   map[string][]string
+	failures map[string]error
+	requests int
+}
+
+func newLabelFetcher(v1api promv1.API, cfg Config) *labelFetcher {
+	concurrency := cfg.LabelsConcurrency
+	if concurrency <= 0 {
+		concurrency = defaultLabelsConcurrency
+	}
+
+	batchSize := cfg.LabelsBatchSize
+	if batchSize <= 0 {
+		batchSize = defaultLabelsBatchSize
+	}
+
+	lookback := cfg.LabelsLookback
+	if lookback <= 0 {
+		lookback = defaultLabelsLookback
+	}
+
+	return &labelFetcher{
+		v1api:       v1api,
+		concurrency: concurrency,
+		batchSize:   batchSize,
+		lookback:    lookback,
+		limiter:     newRateLimiter(cfg.LabelsRateLimit),
+		labels:      make(map[string][]string),
+		failures:    make(map[string]error),
+	}
+}
+
+// fetch looks up the label names of the given metric names. It returns the
+// labels of every metric that could be looked up and the errors of those that could not.
+func (f *labelFetcher) fetch(ctx context.Context, names []string) (map[string][]string, map[string]error) {
+	defer f.limiter.stop()
+
+	batches := make(chan []string)
+	var wg sync.WaitGroup
+
+	for i := 0; i < f.concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for batch := range batches {
+				f.fetchBatch(ctx, batch)
+			}
+		}()
+	}
+
+	for start := 0; start < len(names); start += f.batchSize {
+		end := min(start+f.batchSize, len(names))
+		batches <- names[start:end]
+	}
+	close(batches)
+	wg.Wait()
+
+	return f.labels, f.failures
+}
+
+func (f *labelFetcher) fetchBatch(ctx context.Context, batch []string) {
+	if len(batch) > 1 {
+		labels, err := f.seriesLabels(ctx, batch)
+		if err == nil {
+			f.mu.Lock()
+			for _, name := range batch {
+				f.labels[name] = labels[name]
+			}
+			f.mu.Unlock()
+			return
+		}
+		if errors.Is(err, errSeriesLimitReached) {
+			log.Debug().Msgf("too many series for a batch of %d metrics, looking them up one by one", len(batch))
+		} else {
+			log.Warn().Err(err).Msgf("failed to get labels for a batch of %d metrics, retrying one by one", len(batch))
+		}
+	}
+
+	for _, name := range batch {
+		labels, err := f.labelNames(ctx, name)
+
+		f.mu.Lock()
+		if err != nil {
+			f.failures[name] = err
+		} else {
+			f.labels[name] = labels
+		}
+		f.mu.Unlock()
+	}
+}
+
+// seriesLabels returns the label names of each metric in the batch, using one match[] selector per metric
+func (f *labelFetcher) seriesLabels(ctx context.Context, batch []string) (map[string][]string, error) {
+	if err := f.wait(ctx); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, labelsRequestTimeout)
+	defer cancel()
+
+	matches := make([]string, len(batch))
+	for i, name := range batch {
+		matches[i] = fmt.Sprintf("{%s=%q}", model.MetricNameLabel, name)
+	}
+
+	limit := uint64(len(batch) * labelsSeriesPerMetric)
+
+	end := time.Now()
+	series, _, err := f.v1api.Series(ctx, matches, end.Add(-f.lookback), end, promv1.WithLimit(limit))
+	if err != nil {
+		return nil, err
+	}
+	if uint64(len(series)) >= limit {
+		return nil, errSeriesLimitReached
+	}
+
+	sets := make(map[string]map[string]struct{}, len(batch))
+	for _, labelSet := range series {
+		name := string(labelSet[model.MetricNameLabel])
+		if sets[name] == nil {
+			sets[name] = make(map[string]struct{})
+		}
+		for label := range labelSet {
+			sets[name][string(label)] = struct{}{}
+		}
+	}
+
+	labels := make(map[string][]string, len(batch))
+	for _, name := range batch {
+		names := make([]string, 0, len(sets[name]))
+		for label := range sets[name] {
+			names = append(names, label)
+		}
+		labels[name] = mergeLabels(names)
+	}
+
+	return labels, nil
+}
+
+func (f *labelFetcher) labelNames(ctx context.Context, name string) ([]string, error) {
+	if err := f.wait(ctx); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, labelsRequestTimeout)
+	defer cancel()
+
+	end := time.Now()
+	labels, _, err := f.v1api.LabelNames(ctx, []string{name}, end.Add(-f.lookback), end)
+	if err != nil {
+		return nil, err
+	}
+
+	return mergeLabels(labels), nil
+}
+
+func (f *labelFetcher) wait(ctx context.Context) error {
+	f.mu.Lock()
+	f.requests++
+	f.mu.Unlock()
+
+	return f.limiter.wait(ctx)
+}
+
+// rateLimiter limits the number of requests per second; a zero limit disables it
+type rateLimiter struct {
+	ticker *time.Ticker
+}
+
+func newRateLimiter(perSecond int) *rateLimiter {
+	if perSecond <= 0 {
+		return &rateLimiter{}
+	}
+	return &rateLimiter{ticker: time.NewTicker(time.Second / time.Duration(perSecond))}
+}
+
+func (l *rateLimiter) wait(ctx context.Context) error {
+	if l.ticker == nil {
+		return nil
+	}
+
+	select {
+	case <-l.ticker.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (l *rateLimiter) stop() {
+	if l.ticker != nil {
+		l.ticker.Stop()
+	}
+}
@@ -5,6 +5,18 @@ import (
 	"strings"
 )
 
+// Metric types as reported by the Prometheus metadata API
+const (
+	MetricTypeCounter        = "counter"
+	MetricTypeGauge          = "gauge"
+	MetricTypeHistogram      = "histogram"
+	MetricTypeGaugeHistogram = "gaugehistogram"
+	MetricTypeSummary        = "summary"
+	MetricTypeInfo           = "info"
+	MetricTypeStateset       = "stateset"
+	MetricTypeUnknown        = "unknown"
+)
+
 // MetricMetadata represents metadata associated with a Prometheus metric
 type MetricMetadata struct {
 	// Name is the name of the metric
@@ -13,11 +25,36 @@ type MetricMetadata struct {
 	// Help provides a description of what the metric represents
 	Help string `json:"help"`
 
+	// Description is a richer description generated for metrics with poor help
+	// text; when set it is embedded instead of Help
+	Description string `json:"description,omitempty"`
+
 	// Type indicates the type of metric (counter, gauge, histogram, etc)
 	Type string `json:"type"`
 
+	// Unit is the unit of the metric as exposed by the target (seconds, bytes, etc)
+	Unit string `json:"unit,omitempty"`
+
 	// Labels contains the label names associated with the metric
 	Labels []string `json:"labels,omitempty"`
+
+	// Series contains the names of the series that make up the metric family,
+	// e.g. foo_bucket, foo_sum and foo_count for a classic histogram
+	Series []string `json:"series,omitempty"`
+
+	// NativeHistogram indicates the metric is exposed as a native histogram
+	NativeHistogram bool `json:"native_histogram,omitempty"`
+
+	// TypeConflict indicates that targets disagree on the type of the metric
+	TypeConflict bool `json:"type_conflict,omitempty"`
+
+	// Variants lists the distinct metadata entries reported by different
+	// targets; it is only set when the targets disagree
+	Variants []MetadataVariant `json:"variants,omitempty"`
+
+	// Annotations holds the team-curated synonyms, description, examples and
+	// deprecation note matching the metric, if any
+	Annotations *Annotations `json:"annotations,omitempty"`
 }
 
 // Validate validates the metric metadata
@@ -29,12 +66,44 @@ func (m *MetricMetadata) Validate() error {
 	return nil
 }
 
+// IsHistogram returns true if the metric is a (gauge) histogram family
+func (m *MetricMetadata) IsHistogram() bool {
+	return m.Type == MetricTypeHistogram || m.Type == MetricTypeGaugeHistogram
+}
+
+// IsSummary returns true if the metric is a summary family
+func (m *MetricMetadata) IsSummary() bool {
+	return m.Type == MetricTypeSummary
+}
+
+// HasClassicBuckets returns true if the family exposes a _bucket series
+func (m *MetricMetadata) HasClassicBuckets() bool {
+	for _, s := range m.Series {
+		if s == m.Name+"_bucket" {
+			return true
+		}
+	}
+	return false
+}
+
+// HasConflicts returns true if targets reported differing metadata for the metric
+func (m *MetricMetadata) HasConflicts() bool {
+	return len(m.Variants) > 1
+}
+
 // ToMap converts the metric metadata to a map
 func (m *MetricMetadata) ToMap() map[string]any {
 	return map[string]any{
-		"name":   m.Name,
-		"help":   m.Help,
-		"type":   m.Type,
-		"labels": strings.Join(m.Labels, ", "),
+		"name":             m.Name,
+		"help":             m.Help,
+		"description":      m.Description,
+		"type":             m.Type,
+		"unit":             m.Unit,
+		"labels":           strings.Join(m.Labels, ", "),
+		"series":           strings.Join(m.Series, ", "),
+		"native_histogram": m.NativeHistogram,
+		"type_conflict":    m.TypeConflict,
+		"variants":         EncodeVariants(m.Variants),
+		"annotations":      EncodeAnnotations(m.Annotations),
 	}
 }
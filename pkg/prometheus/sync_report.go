@@ -0,0 +1,56 @@
+package prometheus
+
+import (
+	"sort"
+	"time"
+)
+
+// SyncReport summarizes the outcome of listing metrics metadata from Prometheus
+type SyncReport struct {
+	// StartedAt is the time the listing started
+	StartedAt time.Time `json:"started_at"`
+
+	// Duration is how long the listing took
+	Duration time.Duration `json:"duration"`
+
+	// Metrics is the number of metric families listed
+	Metrics int `json:"metrics"`
+
+	// LabelRequests is the number of requests made to discover label names
+	LabelRequests int `json:"label_requests"`
+
+	// Failures lists the metrics whose labels could not be discovered; they are
+	// listed without labels
+	Failures []SyncFailure `json:"failures,omitempty"`
+
+	// Described is the number of metrics stored with a generated description
+	Described int `json:"described,omitempty"`
+
+	// DescriptionFailures is the number of metrics whose description could not be generated
+	DescriptionFailures int `json:"description_failures,omitempty"`
+
+	// Error is set when the synchronization failed after the listing, e.g.
+	// while storing the metrics
+	Error string `json:"error,omitempty"`
+}
+
+// SyncFailure describes a metric that could not be synchronized
+type SyncFailure struct {
+	Metric string `json:"metric"`
+	Error  string `json:"error"`
+}
+
+// HasFailures returns true if any metric could not be synchronized
+func (r *SyncReport) HasFailures() bool {
+	return len(r.Failures) > 0
+}
+
+func (r *SyncReport) addFailure(metric string, err error) {
+	r.Failures = append(r.Failures, SyncFailure{Metric: metric, Error: err.Error()})
+}
+
+func (r *SyncReport) sortFailures() {
+	sort.Slice(r.Failures, func(i, j int) bool {
+		return r.Failures[i].Metric < r.Failures[j].Metric
+	})
+}
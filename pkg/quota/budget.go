@@ -0,0 +1,270 @@
+package quota
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// Layouts of the day and month periods, which sort chronologically and put
+// the days of a month after the month itself
+const (
+	dayLayout   = "2006-01-02"
+	monthLayout = "2006-01"
+)
+
+// Usage is the LLM tokens spent by a client in the current day and month
+type Usage struct {
+	Key string `json:"key"`
+
+	Day           string `json:"day"`
+	DailyTokens   int64  `json:"daily_tokens"`
+	Month         string `json:"month"`
+	MonthlyTokens int64  `json:"monthly_tokens"`
+
+	// Limits are the limits applied to the last request of the client
+	Limits Limits `json:"limits"`
+}
+
+// roll resets the counters of the periods that ended before now
+func (u *Usage) roll(now time.Time) {
+	if day := now.Format(dayLayout); u.Day != day {
+		u.Day, u.DailyTokens = day, 0
+	}
+	if month := now.Format(monthLayout); u.Month != month {
+		u.Month, u.MonthlyTokens = month, 0
+	}
+}
+
+// Budget tracks the LLM tokens spent by each client against its limits. The
+// usage is optionally persisted to SQLite, so restarts do not renew budgets.
+type Budget struct {
+	mu    sync.Mutex
+	db    *sql.DB
+	usage map[string]*Usage
+	now   func() time.Time
+	// cleanup is when the usage of the clients that spent no tokens this month was last dropped
+	cleanup time.Time
+}
+
+// NewBudget creates a budget tracker persisted to the SQLite file at dbPath,
+// loading the usage of the current day and month. An empty path keeps the
+// usage in memory only.
+func NewBudget(dbPath string) (*Budget, error) {
+	b := &Budget{
+		usage: make(map[string]*Usage),
+		now:   func() time.Time { return time.Now().UTC() },
+	}
+	b.cleanup = b.now()
+
+	if dbPath == "" {
+		return b, nil
+	}
+
+	dir := filepath.Dir(dbPath)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create quota directory %s: %w", dir, err)
+	}
+
+	db, err := sql.Open("sqlite3", dbPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open quota db: %w", err)
+	}
+
+	_, err = db.Exec(`
+		CREATE TABLE IF NOT EXISTS token_usage (
+			key TEXT NOT NULL,
+			period TEXT NOT NULL,
+			tokens INTEGER NOT NULL,
+			PRIMARY KEY (key, period)
+		)
+	`)
+	if err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("failed to create token usage table: %w", err)
+	}
+
+	b.db = db
+	if err := b.load(); err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+
+	return b, nil
+}
+
+// load reads the usage of the current periods, dropping the previous months
+func (b *Budget) load() error {
+	now := b.now()
+	day, month := now.Format(dayLayout), now.Format(monthLayout)
+
+	if _, err := b.db.Exec(`DELETE FROM token_usage WHERE period < ?`, month); err != nil {
+		return fmt.Errorf("failed to prune token usage: %w", err)
+	}
+
+	rows, err := b.db.Query(`SELECT key, period, tokens FROM token_usage WHERE period IN (?, ?)`, day, month)
+	if err != nil {
+		return fmt.Errorf("failed to load token usage: %w", err)
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	for rows.Next() {
+		var (
+			key, period string
+			tokens      int64
+		)
+		if err := rows.Scan(&key, &period, &tokens); err != nil {
+			return fmt.Errorf("failed to read token usage: %w", err)
+		}
+
+		usage := b.get(key, now)
+		if period == day {
+			usage.DailyTokens = tokens
+		} else {
+			usage.MonthlyTokens = tokens
+		}
+	}
+
+	return rows.Err()
+}
+
+// get returns the usage of a client in the current periods. It must be
+// called with mu held.
+func (b *Budget) get(key string, now time.Time) *Usage {
+	b.evictIdle(now)
+
+	usage, ok := b.usage[key]
+	if !ok {
+		usage = &Usage{Key: key}
+		b.usage[key] = usage
+	}
+	usage.roll(now)
+	return usage
+}
+
+// evictIdle drops the usage of the clients that spent no tokens this month,
+// so clients identified by their address do not accumulate. It must be
+// called with mu held.
+func (b *Budget) evictIdle(now time.Time) {
+	if now.Sub(b.cleanup) < cleanupInterval {
+		return
+	}
+
+	for key, usage := range b.usage {
+		usage.roll(now)
+		if usage.MonthlyTokens == 0 {
+			delete(b.usage, key)
+		}
+	}
+	b.cleanup = now
+}
+
+// Check returns an ExceededError if the client has spent its monthly or daily
+// budget. Clients are only tracked once they spend tokens.
+func (b *Budget) Check(client Client) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := b.now()
+	b.evictIdle(now)
+
+	usage, ok := b.usage[client.Key]
+	if !ok {
+		return nil
+	}
+	usage.roll(now)
+	usage.Limits = client.Limits
+
+	if client.Limits.Monthly > 0 && usage.MonthlyTokens >= client.Limits.Monthly {
+		start, _ := time.Parse(monthLayout, usage.Month)
+		return &ExceededError{
+			Key:        client.Key,
+			Period:     PeriodMonthly,
+			Limit:      client.Limits.Monthly,
+			RetryAfter: start.AddDate(0, 1, 0).Sub(now),
+		}
+	}
+
+	if client.Limits.Daily > 0 && usage.DailyTokens >= client.Limits.Daily {
+		start, _ := time.Parse(dayLayout, usage.Day)
+		return &ExceededError{
+			Key:        client.Key,
+			Period:     PeriodDaily,
+			Limit:      client.Limits.Daily,
+			RetryAfter: start.AddDate(0, 0, 1).Sub(now),
+		}
+	}
+
+	return nil
+}
+
+// Add records tokens spent by the client. The request that crosses a limit
+// is not interrupted, the following ones are rejected by Check. The tokens are
+// stored even when ctx is cancelled, since they were already spent.
+func (b *Budget) Add(ctx context.Context, client Client, tokens int64) error {
+	if tokens <= 0 {
+		return nil
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	usage := b.get(client.Key, b.now())
+	usage.Limits = client.Limits
+	usage.DailyTokens += tokens
+	usage.MonthlyTokens += tokens
+
+	if b.db == nil {
+		return nil
+	}
+
+	ctx = context.WithoutCancel(ctx)
+	for _, period := range []string{usage.Day, usage.Month} {
+		_, err := b.db.ExecContext(ctx, `
+			INSERT INTO token_usage (key, period, tokens) VALUES (?, ?, ?)
+			ON CONFLICT (key, period) DO UPDATE SET tokens = tokens + excluded.tokens
+		`, client.Key, period, tokens)
+		if err != nil {
+			return fmt.Errorf("failed to store token usage: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// Usage returns the usage of every client that spent tokens this month, by key
+func (b *Budget) Usage() []Usage {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := b.now()
+	usage := make([]Usage, 0, len(b.usage))
+	for _, u := range b.usage {
+		u.roll(now)
+		if u.MonthlyTokens > 0 {
+			usage = append(usage, *u)
+		}
+	}
+
+	sort.Slice(usage, func(i, j int) bool {
+		return usage[i].Key < usage[j].Key
+	})
+	return usage
+}
+
+// Close closes the budget database, if any
+func (b *Budget) Close() error {
+	if b.db == nil {
+		return nil
+	}
+	return b.db.Close()
+}
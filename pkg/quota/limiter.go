@@ -0,0 +1,105 @@
+package quota
+
+import (
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// cleanupInterval is how often the limiters of idle clients, and the usage
+// of clients that spent no tokens this month, are dropped
+const cleanupInterval = time.Minute
+
+// RateLimiter limits the requests of each client with a token bucket
+type RateLimiter struct {
+	mu       sync.Mutex
+	limit    rate.Limit
+	burst    int
+	clients  map[string]*clientLimiter
+	cleanup  time.Time
+	idleTime time.Duration
+}
+
+type clientLimiter struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+// NewRateLimiter creates a rate limiter allowing each client requestsPerSecond
+// requests on average, and bursts of up to burst requests
+func NewRateLimiter(requestsPerSecond float64, burst int) *RateLimiter {
+	burst = max(burst, 1)
+
+	// A bucket left alone that long is full again, like a new one
+	idleTime := time.Duration(float64(burst) / requestsPerSecond * float64(time.Second))
+
+	return &RateLimiter{
+		limit:    rate.Limit(requestsPerSecond),
+		burst:    burst,
+		clients:  make(map[string]*clientLimiter),
+		cleanup:  time.Now(),
+		idleTime: max(idleTime, cleanupInterval),
+	}
+}
+
+// Allow takes a token from the bucket of the client, returning 0 if the
+// request is allowed or how long the client must wait otherwise
+func (l *RateLimiter) Allow(key string) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	l.evictIdle(now)
+
+	client, ok := l.clients[key]
+	if !ok {
+		client = &clientLimiter{limiter: rate.NewLimiter(l.limit, l.burst)}
+		l.clients[key] = client
+	}
+	client.lastSeen = now
+
+	reservation := client.limiter.ReserveN(now, 1)
+	if delay := reservation.DelayFrom(now); delay > 0 {
+		// Rejected requests do not consume tokens
+		reservation.CancelAt(now)
+		return delay
+	}
+
+	return 0
+}
+
+// Delay returns how long the client must wait for a token, without taking it
+func (l *RateLimiter) Delay(key string) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	l.evictIdle(now)
+
+	client, ok := l.clients[key]
+	if !ok {
+		return 0
+	}
+
+	tokens := client.limiter.TokensAt(now)
+	if tokens >= 1 {
+		return 0
+	}
+	return time.Duration((1 - tokens) / float64(l.limit) * float64(time.Second))
+}
+
+// evictIdle drops the limiters of the clients idle for long enough that their
+// bucket is full again. It must be called with mu held.
+func (l *RateLimiter) evictIdle(now time.Time) {
+	if now.Sub(l.cleanup) < cleanupInterval {
+		return
+	}
+
+	for k, client := range l.clients {
+		if now.Sub(client.lastSeen) >= l.idleTime {
+			delete(l.clients, k)
+		}
+	}
+	l.cleanup = now
+}
@@ -0,0 +1,63 @@
+// Package quota limits how often each client may call the API and how many
+// LLM tokens it may spend per day and per month.
+package quota
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Budget periods
+const (
+	PeriodDaily   = "daily"
+	PeriodMonthly = "monthly"
+)
+
+// ErrQuotaExceeded is matched by the errors returned when a client has spent its token budget
+var ErrQuotaExceeded = errors.New("quota exceeded")
+
+// Limits are the LLM tokens a client may spend per UTC day and month, 0 for no limit
+type Limits struct {
+	Daily   int64 `json:"daily"`
+	Monthly int64 `json:"monthly"`
+}
+
+// Client is a client of the API whose token spending is tracked
+type Client struct {
+	// Key identifies the client, such as its principal as method:name or its address
+	Key    string
+	Limits Limits
+}
+
+type clientKey struct{}
+
+// WithClient returns a copy of ctx carrying the client of the request
+func WithClient(ctx context.Context, client Client) context.Context {
+	return context.WithValue(ctx, clientKey{}, client)
+}
+
+// ClientFromContext returns the client carried by ctx, if any
+func ClientFromContext(ctx context.Context) (Client, bool) {
+	client, ok := ctx.Value(clientKey{}).(Client)
+	return client, ok
+}
+
+// ExceededError is returned when a client has spent its token budget for a period
+type ExceededError struct {
+	Key    string
+	Period string
+	Limit  int64
+	// RetryAfter is how long until the budget is renewed
+	RetryAfter time.Duration
+}
+
+func (e *ExceededError) Error() string {
+	return fmt.Sprintf("%s budget of %d LLM tokens spent by %s", e.Period, e.Limit, e.Key)
+}
+
+// Is makes errors.Is(err, ErrQuotaExceeded) match exceeded budgets
+func (e *ExceededError) Is(target error) bool {
+	return target == ErrQuotaExceeded
+}
@@ -0,0 +1,146 @@
+package quota_test
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/machadovilaca/prometheus-rag/pkg/quota"
+)
+
+var _ = Describe("Budget", func() {
+	var (
+		ctx    context.Context
+		dbPath string
+		budget *quota.Budget
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		dbPath = filepath.Join(GinkgoT().TempDir(), "quota.db")
+
+		var err error
+		budget, err = quota.NewBudget(dbPath)
+		Expect(err).NotTo(HaveOccurred())
+		DeferCleanup(func() {
+			_ = budget.Close()
+		})
+	})
+
+	It("should allow clients under their limits", func() {
+		client := quota.Client{Key: "alice", Limits: quota.Limits{Daily: 100, Monthly: 1000}}
+
+		Expect(budget.Check(client)).To(Succeed())
+		Expect(budget.Add(ctx, client, 99)).To(Succeed())
+		Expect(budget.Check(client)).To(Succeed())
+	})
+
+	It("should reject clients that spent their daily budget until the next day", func() {
+		client := quota.Client{Key: "alice", Limits: quota.Limits{Daily: 100}}
+		Expect(budget.Add(ctx, client, 150)).To(Succeed())
+
+		err := budget.Check(client)
+		Expect(err).To(MatchError(quota.ErrQuotaExceeded))
+
+		var exceeded *quota.ExceededError
+		Expect(errors.As(err, &exceeded)).To(BeTrue())
+		Expect(exceeded.Period).To(Equal(quota.PeriodDaily))
+		Expect(exceeded.RetryAfter).To(BeNumerically(">", 0))
+		Expect(exceeded.RetryAfter).To(BeNumerically("<=", 24*time.Hour))
+
+		Expect(budget.Check(quota.Client{Key: "bob", Limits: client.Limits})).To(Succeed())
+	})
+
+	It("should report the monthly budget first", func() {
+		client := quota.Client{Key: "alice", Limits: quota.Limits{Daily: 100, Monthly: 100}}
+		Expect(budget.Add(ctx, client, 100)).To(Succeed())
+
+		var exceeded *quota.ExceededError
+		Expect(errors.As(budget.Check(client), &exceeded)).To(BeTrue())
+		Expect(exceeded.Period).To(Equal(quota.PeriodMonthly))
+	})
+
+	It("should only track the clients that spent tokens", func() {
+		Expect(budget.Check(quota.Client{Key: "10.0.0.1", Limits: quota.Limits{Daily: 100}})).To(Succeed())
+		Expect(budget.Add(ctx, quota.Client{Key: "alice"}, 10)).To(Succeed())
+
+		usage := budget.Usage()
+		Expect(usage).To(HaveLen(1))
+		Expect(usage[0].Key).To(Equal("alice"))
+	})
+
+	It("should not limit clients without limits", func() {
+		client := quota.Client{Key: "alice"}
+		Expect(budget.Add(ctx, client, 1_000_000)).To(Succeed())
+		Expect(budget.Check(client)).To(Succeed())
+	})
+
+	It("should keep the usage across restarts", func() {
+		client := quota.Client{Key: "alice", Limits: quota.Limits{Daily: 100}}
+		Expect(budget.Add(ctx, client, 60)).To(Succeed())
+		Expect(budget.Add(ctx, client, 40)).To(Succeed())
+		Expect(budget.Close()).To(Succeed())
+
+		var err error
+		budget, err = quota.NewBudget(dbPath)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(budget.Check(client)).To(MatchError(quota.ErrQuotaExceeded))
+
+		usage := budget.Usage()
+		Expect(usage).To(HaveLen(1))
+		Expect(usage[0].Key).To(Equal("alice"))
+		Expect(usage[0].DailyTokens).To(Equal(int64(100)))
+		Expect(usage[0].MonthlyTokens).To(Equal(int64(100)))
+		Expect(usage[0].Limits).To(Equal(client.Limits))
+	})
+
+	It("should store the usage of cancelled requests", func() {
+		cancelled, cancel := context.WithCancel(ctx)
+		cancel()
+
+		client := quota.Client{Key: "alice", Limits: quota.Limits{Daily: 100}}
+		Expect(budget.Add(cancelled, client, 100)).To(Succeed())
+		Expect(budget.Close()).To(Succeed())
+
+		var err error
+		budget, err = quota.NewBudget(dbPath)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(budget.Check(client)).To(MatchError(quota.ErrQuotaExceeded))
+	})
+})
+
+var _ = Describe("RateLimiter", func() {
+	It("should allow bursts and then delay each client", func() {
+		limiter := quota.NewRateLimiter(1, 2)
+
+		Expect(limiter.Allow("alice")).To(BeZero())
+		Expect(limiter.Allow("alice")).To(BeZero())
+
+		delay := limiter.Allow("alice")
+		Expect(delay).To(BeNumerically(">", 0))
+		Expect(delay).To(BeNumerically("<=", time.Second))
+
+		// Rejected requests do not push the wait further
+		Expect(limiter.Allow("alice")).To(BeNumerically("<=", delay))
+
+		Expect(limiter.Allow("bob")).To(BeZero())
+	})
+
+	It("should report the delay without taking a token", func() {
+		limiter := quota.NewRateLimiter(1, 1)
+
+		Expect(limiter.Delay("alice")).To(BeZero())
+		Expect(limiter.Delay("alice")).To(BeZero())
+		Expect(limiter.Allow("alice")).To(BeZero())
+
+		delay := limiter.Delay("alice")
+		Expect(delay).To(BeNumerically(">", 0))
+		Expect(delay).To(BeNumerically("<=", time.Second))
+	})
+})
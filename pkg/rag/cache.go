@@ -0,0 +1,252 @@
+package rag
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/machadovilaca/prometheus-rag/pkg/embeddings"
+	"github.com/machadovilaca/prometheus-rag/pkg/prometheus"
+)
+
+// Cache statuses of a query
+const (
+	// CacheMiss means the answer was generated by the LLM
+	CacheMiss = "miss"
+	// CacheExact means the answer was cached for the same question
+	CacheExact = "exact"
+	// CacheSimilar means the answer was cached for a similar question
+	CacheSimilar = "similar"
+)
+
+const defaultCacheMaxEntries = 1000
+
+// CacheInfo describes whether a query was answered from the response cache
+type CacheInfo struct {
+	Status string `json:"status"`
+
+	// Question is the cached question that answered a similar one, only set
+	// when it was asked by the same owner
+	Question string `json:"question,omitempty"`
+	// Similarity is the similarity of the cached question to the query
+	Similarity float64 `json:"similarity,omitempty"`
+	// CachedAt is when the cached answer was generated
+	CachedAt *time.Time `json:"cached_at,omitempty"`
+}
+
+// CacheConfig is the configuration of the response cache
+type CacheConfig struct {
+	Encoder embeddings.Encoder
+
+	// TTL is how long an answer is reused
+	TTL time.Duration
+	// SimilarityThreshold is the minimum similarity of a cached question to
+	// answer a new one; 1 or more only reuses answers to the same question
+	SimilarityThreshold float64
+	// MaxEntries is the number of answers kept, the oldest are evicted first
+	MaxEntries int
+}
+
+// CachedResponse is an answer found in the response cache
+type CachedResponse struct {
+	PromQL  string
+	Metrics []string
+	Info    CacheInfo
+}
+
+type cacheEntry struct {
+	// owner identifies the principal that asked the question
+	owner     string
+	question  string
+	key       string
+	embedding []float32
+	promql    string
+	metrics   []string
+	createdAt time.Time
+}
+
+// ResponseCache keeps the answers generated for recent questions, so repeated
+// and near-duplicate questions are answered without calling the LLM
+type ResponseCache struct {
+	config CacheConfig
+
+	mu sync.Mutex
+	// entries are ordered from oldest to newest
+	entries []*cacheEntry
+}
+
+// NewResponseCache creates an empty response cache
+func NewResponseCache(config CacheConfig) *ResponseCache {
+	if config.MaxEntries <= 0 {
+		config.MaxEntries = defaultCacheMaxEntries
+	}
+
+	return &ResponseCache{config: config}
+}
+
+// Lookup returns the answer cached for the question of owner, or for the most
+// similar question above the threshold, or nil. Similar questions asked by
+// other owners are not disclosed. It also returns the embedding of the
+// question, nil if it was not needed, so it can be stored without encoding it
+// again.
+func (c *ResponseCache) Lookup(ctx context.Context, owner, question string) (*CachedResponse, []float32, error) {
+	key := cacheKey(question)
+
+	c.mu.Lock()
+	c.expire()
+	for _, entry := range c.entries {
+		if entry.key == key {
+			c.mu.Unlock()
+			return entry.response(CacheInfo{Status: CacheExact}), nil, nil
+		}
+	}
+	c.mu.Unlock()
+
+	if !c.similarityEnabled() {
+		return nil, nil, nil
+	}
+
+	embedding, err := c.config.Encoder.EncodeQuery(ctx, question)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to encode question: %w", err)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var (
+		best           *cacheEntry
+		bestSimilarity float64
+	)
+	for _, entry := range c.entries {
+		if entry.embedding == nil {
+			continue
+		}
+		if similarity := embeddings.CosineSimilarity(embedding, entry.embedding); similarity > bestSimilarity {
+			best, bestSimilarity = entry, similarity
+		}
+	}
+
+	if best == nil || bestSimilarity < c.config.SimilarityThreshold {
+		return nil, embedding, nil
+	}
+
+	info := CacheInfo{Status: CacheSimilar, Similarity: bestSimilarity}
+	if best.owner == owner {
+		info.Question = best.question
+	}
+	return best.response(info), embedding, nil
+}
+
+// Store caches the answer to a question of owner, replacing an earlier one.
+// The embedding may be nil, in which case only the same question is answered.
+func (c *ResponseCache) Store(owner, question string, embedding []float32, promql string, metrics []string) {
+	key := cacheKey(question)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.remove(func(entry *cacheEntry) bool {
+		return entry.key == key
+	})
+
+	c.entries = append(c.entries, &cacheEntry{
+		owner:     owner,
+		question:  strings.TrimSpace(question),
+		key:       key,
+		embedding: embedding,
+		promql:    promql,
+		metrics:   metrics,
+		createdAt: time.Now(),
+	})
+
+	if overflow := len(c.entries) - c.config.MaxEntries; overflow > 0 {
+		c.entries = c.entries[overflow:]
+	}
+}
+
+// Forget removes the cached answers with the given PromQL, returning how many were removed
+func (c *ResponseCache) Forget(promql string) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.remove(func(entry *cacheEntry) bool {
+		return entry.promql == promql
+	})
+}
+
+// Invalidate removes all cached answers
+func (c *ResponseCache) Invalidate() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.entries = nil
+}
+
+// Len returns the number of cached answers, including expired ones not yet removed
+func (c *ResponseCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return len(c.entries)
+}
+
+func (c *ResponseCache) similarityEnabled() bool {
+	return c.config.Encoder != nil && c.config.SimilarityThreshold < 1
+}
+
+// expire removes the entries older than the TTL; must be called with the lock held
+func (c *ResponseCache) expire() {
+	deadline := time.Now().Add(-c.config.TTL)
+	c.remove(func(entry *cacheEntry) bool {
+		return entry.createdAt.Before(deadline)
+	})
+}
+
+// remove deletes the matching entries, keeping the order of the others; must
+// be called with the lock held
+func (c *ResponseCache) remove(match func(entry *cacheEntry) bool) int {
+	kept := c.entries[:0]
+	for _, entry := range c.entries {
+		if !match(entry) {
+			kept = append(kept, entry)
+		}
+	}
+
+	removed := len(c.entries) - len(kept)
+	clear(c.entries[len(kept):])
+	c.entries = kept
+	return removed
+}
+
+func (e *cacheEntry) response(info CacheInfo) *CachedResponse {
+	createdAt := e.createdAt
+	info.CachedAt = &createdAt
+
+	return &CachedResponse{PromQL: e.promql, Metrics: e.metrics, Info: info}
+}
+
+// cacheKey normalizes a question so differences in case and spacing are exact matches
+func cacheKey(question string) string {
+	return strings.ToLower(strings.Join(strings.Fields(question), " "))
+}
+
+// catalogFingerprint identifies the content of the metric catalog, so the
+// cache can be invalidated when a sync changes it
+func catalogFingerprint(metrics []*prometheus.MetricMetadata) string {
+	lines := make([]string, len(metrics))
+	for i, metric := range metrics {
+		lines[i] = strings.Join([]string{
+			metric.Name, metric.Type, metric.Unit, metric.Help, metric.Description,
+			strings.Join(metric.Labels, ","), strings.Join(metric.Series, ","),
+		}, "\x00")
+	}
+	sort.Strings(lines)
+
+	hash := sha256.Sum256([]byte(strings.Join(lines, "\n")))
+	return fmt.Sprintf("%x", hash)
+}
@@ -0,0 +1,221 @@
+package rag_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/machadovilaca/prometheus-rag/pkg/embeddings"
+	"github.com/machadovilaca/prometheus-rag/pkg/prometheus"
+	"github.com/machadovilaca/prometheus-rag/pkg/rag"
+)
+
+// topicEncoder embeds questions by the topics they mention
+type topicEncoder struct {
+	calls int
+	err   error
+}
+
+var topics = []string{"memory", "cpu", "disk"}
+
+func (t *topicEncoder) GetDimension() (int, error) {
+	return len(topics), nil
+}
+
+func (t *topicEncoder) Model() embeddings.ModelInfo {
+	return embeddings.ModelInfo{Name: "topics", Dimension: len(topics)}
+}
+
+func (t *topicEncoder) EncodeQuery(ctx context.Context, query string) ([]float32, error) {
+	t.calls++
+	if t.err != nil {
+		return nil, t.err
+	}
+
+	vector := make([]float32, len(topics))
+	for i, topic := range topics {
+		if strings.Contains(strings.ToLower(query), topic) {
+			vector[i] = 1
+		}
+	}
+	return vector, nil
+}
+
+func (t *topicEncoder) EncodeMetricMetadata(ctx context.Context, metadata prometheus.MetricMetadata) ([]float32, error) {
+	return t.EncodeQuery(ctx, metadata.Name+" "+metadata.Help)
+}
+
+func (t *topicEncoder) EncodeBatch(ctx context.Context, texts []string, _ embeddings.ProgressFunc) ([][]float32, error) {
+	vectors := make([][]float32, len(texts))
+	for i, text := range texts {
+		vectors[i], _ = t.EncodeQuery(ctx, text)
+	}
+	return vectors, nil
+}
+
+func (t *topicEncoder) EncodeMetricMetadataBatch(ctx context.Context, metadata []prometheus.MetricMetadata, progress embeddings.ProgressFunc) ([][]float32, error) {
+	texts := make([]string, len(metadata))
+	for i, m := range metadata {
+		texts[i] = m.Name + " " + m.Help
+	}
+	return t.EncodeBatch(ctx, texts, progress)
+}
+
+var _ = Describe("ResponseCache", func() {
+	const (
+		promql = "node_memory_MemAvailable_bytes"
+		owner  = "api_key:grafana"
+	)
+
+	var (
+		encoder *topicEncoder
+		cache   *rag.ResponseCache
+	)
+
+	store := func(question, answer string) {
+		_, embedding, err := cache.Lookup(context.Background(), owner, question)
+		Expect(err).NotTo(HaveOccurred())
+		cache.Store(owner, question, embedding, answer, []string{answer})
+	}
+
+	BeforeEach(func() {
+		encoder = &topicEncoder{}
+		cache = rag.NewResponseCache(rag.CacheConfig{
+			Encoder:             encoder,
+			TTL:                 time.Minute,
+			SimilarityThreshold: 0.95,
+			MaxEntries:          10,
+		})
+	})
+
+	It("should miss when nothing is cached", func() {
+		cached, embedding, err := cache.Lookup(context.Background(), owner, "How much memory is free?")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(cached).To(BeNil())
+		Expect(embedding).To(Equal([]float32{1, 0, 0}))
+	})
+
+	It("should answer the same question ignoring case and spacing", func() {
+		store("How much memory is free?", promql)
+		encoder.calls = 0
+
+		cached, _, err := cache.Lookup(context.Background(), owner, "  how much   MEMORY is free? ")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(cached).NotTo(BeNil())
+		Expect(cached.PromQL).To(Equal(promql))
+		Expect(cached.Metrics).To(Equal([]string{promql}))
+		Expect(cached.Info.Status).To(Equal(rag.CacheExact))
+		Expect(cached.Info.CachedAt).NotTo(BeNil())
+		Expect(encoder.calls).To(BeZero())
+	})
+
+	It("should answer similar questions above the threshold", func() {
+		store("How much memory is free?", promql)
+
+		cached, _, err := cache.Lookup(context.Background(), owner, "Free memory on the nodes")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(cached).NotTo(BeNil())
+		Expect(cached.PromQL).To(Equal(promql))
+		Expect(cached.Info.Status).To(Equal(rag.CacheSimilar))
+		Expect(cached.Info.Question).To(Equal("How much memory is free?"))
+		Expect(cached.Info.Similarity).To(BeNumerically("~", 1, 1e-6))
+
+		cached, _, err = cache.Lookup(context.Background(), owner, "Memory and cpu usage")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(cached).To(BeNil())
+	})
+
+	It("should not disclose similar questions of other owners", func() {
+		store("How much memory is free?", promql)
+
+		cached, _, err := cache.Lookup(context.Background(), "jwt:grafana", "Free memory on the nodes")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(cached).NotTo(BeNil())
+		Expect(cached.PromQL).To(Equal(promql))
+		Expect(cached.Info.Status).To(Equal(rag.CacheSimilar))
+		Expect(cached.Info.Question).To(BeEmpty())
+	})
+
+	It("should only answer the same question with a threshold of 1", func() {
+		cache = rag.NewResponseCache(rag.CacheConfig{Encoder: encoder, TTL: time.Minute, SimilarityThreshold: 1})
+		store("How much memory is free?", promql)
+
+		cached, embedding, err := cache.Lookup(context.Background(), owner, "Free memory on the nodes")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(cached).To(BeNil())
+		Expect(embedding).To(BeNil())
+		Expect(encoder.calls).To(BeZero())
+	})
+
+	It("should expire answers after the TTL", func() {
+		cache = rag.NewResponseCache(rag.CacheConfig{Encoder: encoder, TTL: 50 * time.Millisecond, SimilarityThreshold: 0.95})
+		store("How much memory is free?", promql)
+
+		Eventually(func() *rag.CachedResponse {
+			cached, _, err := cache.Lookup(context.Background(), owner, "How much memory is free?")
+			Expect(err).NotTo(HaveOccurred())
+			return cached
+		}).Should(BeNil())
+		Expect(cache.Len()).To(BeZero())
+	})
+
+	It("should evict the oldest answers beyond the max entries", func() {
+		cache = rag.NewResponseCache(rag.CacheConfig{Encoder: encoder, TTL: time.Minute, SimilarityThreshold: 1, MaxEntries: 2})
+		store("first", "a")
+		store("second", "b")
+		store("third", "c")
+
+		Expect(cache.Len()).To(Equal(2))
+
+		cached, _, err := cache.Lookup(context.Background(), owner, "first")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(cached).To(BeNil())
+
+		cached, _, err = cache.Lookup(context.Background(), owner, "third")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(cached.PromQL).To(Equal("c"))
+	})
+
+	It("should replace the answer to the same question", func() {
+		store("How much memory is free?", "go_memstats_alloc_bytes")
+		store("How much memory is free?", promql)
+
+		Expect(cache.Len()).To(Equal(1))
+
+		cached, _, err := cache.Lookup(context.Background(), owner, "How much memory is free?")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(cached.PromQL).To(Equal(promql))
+	})
+
+	It("should forget the answers with a given PromQL", func() {
+		store("How much memory is free?", promql)
+		store("Available memory", promql)
+		store("CPU usage", "node_cpu_seconds_total")
+
+		Expect(cache.Forget(promql)).To(Equal(2))
+		Expect(cache.Len()).To(Equal(1))
+	})
+
+	It("should remove every answer when invalidated", func() {
+		store("How much memory is free?", promql)
+		store("CPU usage", "node_cpu_seconds_total")
+
+		cache.Invalidate()
+
+		Expect(cache.Len()).To(BeZero())
+		cached, _, err := cache.Lookup(context.Background(), owner, "How much memory is free?")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(cached).To(BeNil())
+	})
+
+	It("should return encoder errors", func() {
+		encoder.err = errors.New("encoder unavailable")
+
+		_, _, err := cache.Lookup(context.Background(), owner, "How much memory is free?")
+		Expect(err).To(MatchError(ContainSubstring("encoder unavailable")))
+	})
+})
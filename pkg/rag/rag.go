@@ -1,41 +1,132 @@
 package rag
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"io"
+	"sync"
 	"time"
 
+	"github.com/google/uuid"
+	"github.com/prometheus/prometheus/promql/parser"
 	"github.com/rs/zerolog/log"
 
+	"github.com/machadovilaca/prometheus-rag/pkg/annotations"
+	"github.com/machadovilaca/prometheus-rag/pkg/audit"
+	"github.com/machadovilaca/prometheus-rag/pkg/auth"
 	"github.com/machadovilaca/prometheus-rag/pkg/config"
+	"github.com/machadovilaca/prometheus-rag/pkg/embeddings"
+	"github.com/machadovilaca/prometheus-rag/pkg/examples"
+	"github.com/machadovilaca/prometheus-rag/pkg/feedback"
 	"github.com/machadovilaca/prometheus-rag/pkg/llm"
 	"github.com/machadovilaca/prometheus-rag/pkg/prometheus"
+	"github.com/machadovilaca/prometheus-rag/pkg/quota"
+	"github.com/machadovilaca/prometheus-rag/pkg/telemetry"
 	"github.com/machadovilaca/prometheus-rag/pkg/vectordb"
 )
 
+// feedbackPruneInterval is how often queries past the feedback retention are deleted
+const feedbackPruneInterval = 24 * time.Hour
+
+// ErrFeedbackDisabled is returned when feedback is given while the feedback store is disabled
+var ErrFeedbackDisabled = errors.New("feedback is disabled")
+
+// ErrAuditDisabled is returned when audit records are searched while the audit log is disabled
+var ErrAuditDisabled = errors.New("audit log is disabled")
+
 // Client is the main client for the RAG
 type Client struct {
 	cfg config.RAGConfig
 
+	encoder          embeddings.Encoder
 	vectorDBClient   vectordb.Client
+	exampleStore     vectordb.ExampleStore
 	prometheusClient prometheus.Client
 	llmClient        llm.Client
-	metricsMetadata  []*prometheus.MetricMetadata
+	enricher         *llm.Enricher
+	annotations      *annotations.Store
+	feedback         *feedback.Store
+	cache            *ResponseCache
+	audit            audit.Sink
+	budget           *quota.Budget
+	readiness        *ReadinessChecker
+
+	metricsMetadataMu  sync.RWMutex
+	metricsMetadata    []*prometheus.MetricMetadata
+	catalogFingerprint string
+	lastSyncReport     *prometheus.SyncReport
+
+	// stop cancels the background synchronization and pruning, tracked by background
+	stop       context.CancelFunc
+	background sync.WaitGroup
 }
 
 // New creates a new RAG client
-func New(cfg *config.Config) (*Client, error) {
+func New(cfg *config.Config) (_ *Client, err error) {
 	log.Info().Msg("starting RAG")
 
-	var err error
 	r := &Client{}
 
-	r.vectorDBClient, err = r.connectToVectorDB(cfg)
+	ctx, stop := context.WithCancel(context.Background())
+	r.stop = stop
+
+	// Release whatever was opened or started before a failure
+	defer func() {
+		if err == nil {
+			return
+		}
+		if closeErr := r.Close(); closeErr != nil {
+			log.Warn().Err(closeErr).Msg("failed to release RAG resources")
+		}
+	}()
+
+	err = r.connectToVectorDB(cfg)
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to vectorDB: %w", err)
 	}
 
 	// Create RAG-specific configuration
-	r.cfg = cfg.ToRAGConfig(r.vectorDBClient)
+	r.cfg = cfg.ToRAGConfig(r.vectorDBClient, r.exampleStore)
+
+	if r.cfg.ExamplesPath != "" {
+		if err := r.loadExamples(context.Background(), r.cfg.ExamplesPath); err != nil {
+			return nil, fmt.Errorf("failed to load examples: %w", err)
+		}
+	}
+
+	if cfg.Feedback.DBPath != "" {
+		log.Info().Msgf("opening feedback store at %s", cfg.Feedback.DBPath)
+		r.feedback, err = feedback.NewStore(cfg.ToFeedbackConfig(r.encoder))
+		if err != nil {
+			return nil, fmt.Errorf("failed to open feedback store: %w", err)
+		}
+		r.cfg.LLMConfig.Reranker = r.feedback
+		r.startFeedbackPruning(ctx)
+	}
+
+	r.budget, err = quota.NewBudget(cfg.Quota.DBPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open token budget: %w", err)
+	}
+
+	if cfg.Audit.Provider != "" {
+		log.Info().Msgf("enabling %s audit log", cfg.Audit.Provider)
+		r.audit, err = audit.NewSink(cfg.ToAuditConfig())
+		if err != nil {
+			return nil, fmt.Errorf("failed to open audit log: %w", err)
+		}
+	}
+
+	if r.cfg.CacheTTLMinutes > 0 {
+		log.Info().Msgf("enabling response cache with a TTL of %s", r.cfg.GetCacheTTL())
+		r.cache = NewResponseCache(CacheConfig{
+			Encoder:             r.encoder,
+			TTL:                 r.cfg.GetCacheTTL(),
+			SimilarityThreshold: r.cfg.CacheSimilarityThreshold,
+			MaxEntries:          r.cfg.CacheMaxEntries,
+		})
+	}
 
 	log.Info().Msg("starting LLM client")
 	r.llmClient, err = llm.New(r.cfg.LLMConfig)
@@ -43,36 +134,405 @@ func New(cfg *config.Config) (*Client, error) {
 		return nil, fmt.Errorf("failed to create LLM client: %w", err)
 	}
 
-	err = r.startPrometheusSync(cfg)
+	if r.cfg.EnrichDescriptions {
+		log.Info().Msg("enabling metric description enrichment")
+		r.enricher = llm.NewEnricher(r.llmClient, r.cfg.EnrichmentConfig)
+	}
+
+	r.annotations, err = annotations.NewStore(r.cfg.AnnotationsPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load annotations: %w", err)
+	}
+
+	err = r.startPrometheusSync(ctx, cfg)
 	if err != nil {
 		return nil, fmt.Errorf("failed to start prometheus sync: %w", err)
 	}
 
+	r.registerReadinessChecks()
+
 	return r, nil
 }
 
-func (r *Client) Query(query string) (string, error) {
-	response, err := r.llmClient.Run(query)
+// QueryResult is the answer to a query
+type QueryResult struct {
+	// ID identifies the query when giving feedback, empty if it was not recorded
+	ID     string
+	PromQL string
+	// Metrics are the names of the metrics added to the prompt, most relevant first
+	Metrics []string
+	// Cache tells whether the answer came from the response cache, nil if it is disabled
+	Cache *CacheInfo
+}
+
+// Query answers a natural language question with PromQL. Retrieval and
+// generation stop when ctx is cancelled, e.g. when the client disconnects.
+func (r *Client) Query(ctx context.Context, query string) (response *QueryResult, err error) {
+	ctx, span := telemetry.StartSpan(ctx, "rag.Query")
+	// The cache, the vector search and the feedback store all look up the question
+	ctx = embeddings.WithQueryEmbeddings(ctx)
+	record := &audit.Record{Timestamp: time.Now(), Question: query}
+	defer func() {
+		telemetry.EndSpan(span, err)
+		r.recordAudit(ctx, record, err)
+	}()
+
+	if principal := auth.PrincipalFromContext(ctx); !principal.AllowsSource(r.cfg.PrometheusAddress) {
+		return nil, fmt.Errorf("%w: %s may not query %s", auth.ErrForbidden, principal.Name, r.cfg.PrometheusAddress)
+	}
+
+	response, err = r.generate(ctx, query, record)
 	if err != nil {
-		return "", fmt.Errorf("failed to run LLM: %w", err)
+		return nil, err
+	}
+	span.SetAttributes(telemetry.AttributeMetrics.StringSlice(response.Metrics))
+
+	if r.feedback == nil {
+		return response, nil
 	}
 
+	// Recording only enables feedback, so failures do not fail the query
+	owner := auth.PrincipalFromContext(ctx).Key()
+	recorded, recordErr := r.feedback.RecordQuery(ctx, owner, query, response.PromQL, response.Metrics)
+	if recordErr != nil {
+		log.Warn().Ctx(ctx).Err(recordErr).Msg("failed to record query for feedback")
+		return response, nil
+	}
+
+	response.ID = recorded.ID
+	record.QueryID = recorded.ID
 	return response, nil
 }
 
-func (r *Client) connectToVectorDB(cfg *config.Config) (vectordb.Client, error) {
+// generate answers the query from the response cache, falling back to the
+// LLM, and fills the audit record with how the answer was produced
+func (r *Client) generate(ctx context.Context, query string, record *audit.Record) (*QueryResult, error) {
+	principal := auth.PrincipalFromContext(ctx)
+	owner := principal.Key()
+
+	var embedding []float32
+	if r.cache != nil {
+		cached, queryEmbedding := r.lookupCache(ctx, owner, query)
+		// Answers cached for other principals may select metrics this one may
+		// not query, or have been retrieved along with them
+		if cached != nil && principal.CheckPromQL(cached.PromQL) == nil {
+			log.Debug().Ctx(ctx).Msgf("answering query from the response cache (%s)", cached.Info.Status)
+			metrics := visibleMetricNames(principal, cached.Metrics)
+			record.Cache = cached.Info.Status
+			record.Metrics = metrics
+			record.PromQL = cached.PromQL
+			return &QueryResult{PromQL: cached.PromQL, Metrics: metrics, Cache: &cached.Info}, nil
+		}
+		record.Cache = CacheMiss
+		embedding = queryEmbedding
+	}
+
+	// Cached answers are free, so only the LLM calls are subject to the budget
+	client, tracked := quota.ClientFromContext(ctx)
+	if tracked {
+		if err := r.budget.Check(client); err != nil {
+			return nil, err
+		}
+	}
+
+	result, err := r.llmClient.Generate(ctx, query)
+	var invalid *llm.InvalidResponseError
+	if errors.As(err, &invalid) {
+		result = invalid.Result
+	}
+	if result != nil {
+		fillAuditRecord(record, result)
+		if tracked {
+			if err := r.budget.Add(ctx, client, result.PromptTokens+result.CompletionTokens); err != nil {
+				log.Error().Ctx(ctx).Err(err).Msg("failed to record token usage")
+			}
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to run LLM: %w", err)
+	}
+
+	valid := result.PromQL != ""
+	if valid {
+		// The answer is still returned, so the user sees what the LLM produced
+		if _, err := parser.ParseExpr(result.PromQL); err != nil {
+			valid = false
+			record.ValidationError = err.Error()
+			telemetry.IncLLMInvalidResponse(telemetry.InvalidResponsePromQL)
+			log.Warn().Ctx(ctx).Err(err).Msgf("LLM returned an invalid PromQL expression: %s", result.PromQL)
+		}
+	}
+
+	// The prompt only holds allowed metrics, but the LLM may still guess others
+	if valid {
+		if err := principal.CheckPromQL(result.PromQL); err != nil {
+			return nil, err
+		}
+	}
+
+	response := &QueryResult{PromQL: result.PromQL, Metrics: record.Metrics}
+	if r.cache != nil {
+		response.Cache = &CacheInfo{Status: CacheMiss}
+		// Invalid answers are not reused, so asking again gives the LLM another chance
+		if valid {
+			r.cache.Store(owner, query, embedding, result.PromQL, record.Metrics)
+		}
+	}
+
+	return response, nil
+}
+
+// visibleMetricNames returns the metric names the principal may query
+func visibleMetricNames(principal *auth.Principal, names []string) []string {
+	if !principal.RestrictsMetrics() {
+		return names
+	}
+
+	visible := make([]string, 0, len(names))
+	for _, name := range names {
+		if principal.AllowsMetric(name) {
+			visible = append(visible, name)
+		}
+	}
+	return visible
+}
+
+// fillAuditRecord adds the prompt and answer of the LLM to an audit record
+func fillAuditRecord(record *audit.Record, result *llm.Result) {
+	metrics := make([]string, len(result.Metrics))
+	for i, metric := range result.Metrics {
+		metrics[i] = metric.Name
+	}
+
+	record.Metrics = metrics
+	record.PromptHash = audit.HashPrompt(result.Prompt)
+	record.Model = result.Model
+	record.RawOutput = result.Response
+	record.PromQL = result.PromQL
+}
+
+// recordAudit completes the audit record of a query with its outcome and
+// stores it. Auditing must not fail the query, so failures are logged.
+func (r *Client) recordAudit(ctx context.Context, record *audit.Record, err error) {
+	if r.audit == nil {
+		return
+	}
+
+	identity := audit.IdentityFromContext(ctx)
+	record.ID = uuid.NewString()
+	record.User = identity.User
+	record.ClientAddress = identity.Address
+	record.TraceID = telemetry.TraceID(ctx)
+	record.Latency = time.Since(record.Timestamp)
+
+	var invalid *llm.InvalidResponseError
+	switch {
+	case errors.As(err, &invalid):
+		record.Outcome = audit.OutcomeInvalid
+		record.ValidationError = invalid.Err.Error()
+	case err != nil:
+		record.Outcome = audit.OutcomeError
+		record.Error = err.Error()
+	case record.PromQL == "":
+		record.Outcome = audit.OutcomeInvalid
+		record.ValidationError = "empty PromQL expression"
+	case record.ValidationError != "":
+		record.Outcome = audit.OutcomeInvalid
+	default:
+		record.Outcome = audit.OutcomeSuccess
+	}
+
+	// The record is kept even when the client disconnected before the answer
+	if err := r.audit.Record(context.WithoutCancel(ctx), record); err != nil {
+		log.Error().Ctx(ctx).Err(err).Msg("failed to write audit record")
+	}
+}
+
+// TokenUsage returns the LLM tokens spent by each client in the current day and month
+func (r *Client) TokenUsage() []quota.Usage {
+	return r.budget.Usage()
+}
+
+// AuditRecords returns the audit records selected by the filter, newest first
+func (r *Client) AuditRecords(ctx context.Context, filter audit.Filter) ([]*audit.Record, error) {
+	if r.audit == nil {
+		return nil, ErrAuditDisabled
+	}
+
+	return r.audit.Search(ctx, filter)
+}
+
+// lookupCache looks up the answer to the query in the response cache,
+// returning the query embedding computed for the lookup, if any
+func (r *Client) lookupCache(ctx context.Context, owner, query string) (*CachedResponse, []float32) {
+	ctx, span := telemetry.StartSpan(ctx, "rag.cache.Lookup")
+	defer span.End()
+
+	cached, embedding, err := r.cache.Lookup(ctx, owner, query)
+	if err != nil {
+		// The cache only saves LLM calls, so failures do not fail the query
+		log.Warn().Ctx(ctx).Err(err).Msg("failed to look up response cache")
+	}
+
+	status := CacheMiss
+	if cached != nil {
+		status = cached.Info.Status
+	}
+	telemetry.IncCacheLookup(status)
+	span.SetAttributes(telemetry.AttributeCacheResult.String(status))
+
+	return cached, embedding
+}
+
+// Feedback stores the feedback of a user on one of their queries. Approved
+// answers, and corrected ones, are added to the example library when
+// auto-capture is enabled; it returns whether one was captured. Corrections
+// selecting metrics the user may not query are rejected with auth.ErrForbidden.
+func (r *Client) Feedback(ctx context.Context, fb feedback.Feedback) (bool, error) {
+	if r.feedback == nil {
+		return false, ErrFeedbackDisabled
+	}
+
+	// Nothing is stored or forgotten for feedback that cannot be captured
+	if err := fb.Validate(); err != nil {
+		return false, err
+	}
+
+	principal := auth.PrincipalFromContext(ctx)
+	if fb.CorrectedPromQL != "" {
+		if err := principal.CheckPromQL(fb.CorrectedPromQL); err != nil {
+			return false, err
+		}
+	}
+
+	query, err := r.feedback.AddFeedback(ctx, principal.Key(), fb)
+	if err != nil {
+		return false, err
+	}
+
+	// A rejected or corrected answer must not keep being served from the cache
+	if r.cache != nil && (fb.Rating == feedback.RatingDown || fb.CorrectedPromQL != "") {
+		if forgotten := r.cache.Forget(query.PromQL); forgotten > 0 {
+			log.Info().Msgf("removed %d cached answers after negative feedback", forgotten)
+		}
+	}
+
+	approved := fb.CorrectedPromQL
+	if approved == "" && fb.Rating == feedback.RatingUp {
+		approved = query.PromQL
+	}
+	if approved == "" {
+		return false, nil
+	}
+
+	return r.CaptureApprovedAnswer(ctx, query.Question, approved)
+}
+
+func (r *Client) startFeedbackPruning(ctx context.Context) {
+	r.runPeriodically(ctx, feedbackPruneInterval, func(ctx context.Context) {
+		deleted, err := r.feedback.Prune(ctx, time.Now().Add(-r.cfg.GetFeedbackRetention()))
+		if err != nil {
+			log.Error().Err(err).Msg("failed to prune feedback queries")
+			return
+		}
+		if deleted > 0 {
+			log.Info().Msgf("pruned %d queries without feedback", deleted)
+		}
+	})
+}
+
+// runPeriodically calls fn right away and then every interval in the
+// background, until ctx is cancelled
+func (r *Client) runPeriodically(ctx context.Context, interval time.Duration, fn func(context.Context)) {
+	r.background.Add(1)
+	go func() {
+		defer r.background.Done()
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		fn(ctx)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				fn(ctx)
+			}
+		}
+	}()
+}
+
+// Close stops the background synchronization and pruning, waits for them to
+// return, and closes the databases. The client must not be used afterwards.
+// It also releases a partially built client, closing only what was opened.
+func (r *Client) Close() error {
+	r.stop()
+	r.background.Wait()
+
+	var errs []error
+	if r.vectorDBClient != nil {
+		errs = append(errs, r.vectorDBClient.Close())
+	}
+	if r.exampleStore != nil {
+		errs = append(errs, r.exampleStore.Close())
+	}
+	if r.budget != nil {
+		errs = append(errs, r.budget.Close())
+	}
+	if r.feedback != nil {
+		errs = append(errs, r.feedback.Close())
+	}
+	if r.audit != nil {
+		errs = append(errs, r.audit.Close())
+	}
+	if closer, ok := r.encoder.(io.Closer); ok {
+		errs = append(errs, closer.Close())
+	}
+
+	return errors.Join(errs...)
+}
+
+func (r *Client) connectToVectorDB(cfg *config.Config) error {
 	log.Info().Msg("starting VectorDB client")
 	vectordbConfig := cfg.ToVectorDBConfig()
 
-	vectordbAPI, err := vectordb.New(vectordbConfig)
+	var err error
+	r.encoder, err = vectordb.NewEncoder(vectordbConfig)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create vectordb API: %w", err)
+		return fmt.Errorf("failed to create encoder: %w", err)
 	}
 
-	return vectordbAPI, nil
+	r.vectorDBClient, err = vectordb.NewWithEncoder(vectordbConfig, r.encoder)
+	if err != nil {
+		return fmt.Errorf("failed to create vectordb API: %w", err)
+	}
+
+	log.Info().Msg("starting examples VectorDB client")
+	r.exampleStore, err = vectordb.NewExampleStore(vectordbConfig, r.encoder)
+	if err != nil {
+		return fmt.Errorf("failed to create examples vectordb API: %w", err)
+	}
+
+	return nil
 }
 
-func (r *Client) startPrometheusSync(cfg *config.Config) error {
+// loadExamples adds the curated examples of a YAML file to the example library
+func (r *Client) loadExamples(ctx context.Context, filePath string) error {
+	entries, err := examples.LoadFile(filePath)
+	if err != nil {
+		return err
+	}
+
+	if err := r.exampleStore.AddExamples(ctx, entries); err != nil {
+		return err
+	}
+
+	log.Info().Msgf("loaded %d examples from %s", len(entries), filePath)
+	return nil
+}
+
+func (r *Client) startPrometheusSync(ctx context.Context, cfg *config.Config) error {
 	log.Info().Msg("starting Prometheus client")
 	var err error
 
@@ -83,36 +543,225 @@ func (r *Client) startPrometheusSync(cfg *config.Config) error {
 		return fmt.Errorf("failed to create prometheus API: %w", err)
 	}
 
-	ticker := time.NewTicker(r.cfg.GetPrometheusRefreshInterval())
-	go func() {
-		r.listMetricsMetadata()
+	r.runPeriodically(ctx, r.cfg.GetPrometheusRefreshInterval(), r.listMetricsMetadata)
 
-		for range ticker.C {
-			r.listMetricsMetadata()
+	return nil
+}
+
+// MetadataConflicts returns the metrics whose metadata disagrees across targets
+func (r *Client) MetadataConflicts() []*prometheus.MetricMetadata {
+	r.metricsMetadataMu.RLock()
+	defer r.metricsMetadataMu.RUnlock()
+
+	conflicts := []*prometheus.MetricMetadata{}
+	for _, metric := range r.metricsMetadata {
+		if metric.HasConflicts() {
+			conflicts = append(conflicts, metric)
 		}
-	}()
+	}
 
-	return nil
+	return conflicts
 }
 
-func (r *Client) listMetricsMetadata() {
-	var err error
+// LastSyncReport returns the report of the latest Prometheus synchronization,
+// or nil if none has completed yet
+func (r *Client) LastSyncReport() *prometheus.SyncReport {
+	r.metricsMetadataMu.RLock()
+	defer r.metricsMetadataMu.RUnlock()
 
-	log.Info().Msg("listing metrics metadata from Prometheus")
+	return r.lastSyncReport
+}
+
+func (r *Client) listMetricsMetadata(ctx context.Context) {
+	ctx, span := telemetry.StartSpan(ctx, "rag.Sync")
+	log.Info().Ctx(ctx).Msg("listing metrics metadata from Prometheus")
+	start := time.Now()
 
-	r.metricsMetadata, err = r.prometheusClient.ListMetricsMetadata()
+	listCtx, listSpan := telemetry.StartSpan(ctx, "prometheus.ListMetricsMetadata")
+	metricsMetadata, report, err := r.prometheusClient.ListMetricsMetadata(listCtx)
+	telemetry.EndSpan(listSpan, err)
 	if err != nil {
-		log.Error().Err(err).Msg("failed to list metrics metadata")
+		log.Error().Ctx(ctx).Err(err).Msg("failed to list metrics metadata")
+		telemetry.ObserveSync(time.Since(start), 0, 0, err)
+		telemetry.EndSpan(span, err)
+		r.setLastSyncReport(&prometheus.SyncReport{StartedAt: start, Error: err.Error()})
 		return
 	}
 
-	log.Info().Msgf("found %d metrics metadata", len(r.metricsMetadata))
+	r.annotations.Apply(metricsMetadata)
+
+	fingerprint := catalogFingerprint(metricsMetadata)
+
+	r.metricsMetadataMu.Lock()
+	r.metricsMetadata = metricsMetadata
+	catalogChanged := fingerprint != r.catalogFingerprint
+	r.catalogFingerprint = fingerprint
+	r.metricsMetadataMu.Unlock()
+
+	if catalogChanged {
+		r.invalidateCache("the metric catalog changed")
+	}
+
+	span.SetAttributes(
+		telemetry.AttributeSyncedMetrics.Int(len(metricsMetadata)),
+		telemetry.AttributeSkippedMetrics.Int(len(report.Failures)),
+	)
+	log.Info().Ctx(ctx).Msgf("found %d metrics metadata in %s using %d label requests",
+		len(metricsMetadata), report.Duration, report.LabelRequests)
+	if report.HasFailures() {
+		log.Warn().Ctx(ctx).Msgf("stored %d metrics without labels as their labels could not be discovered", len(report.Failures))
+	}
+	if conflicts := r.MetadataConflicts(); len(conflicts) > 0 {
+		log.Warn().Ctx(ctx).Msgf("found %d metrics with conflicting metadata across targets", len(conflicts))
+	}
+
+	if r.enricher != nil {
+		// The published metrics may be read without the lock, so the
+		// descriptions are added to copies that replace them
+		enriched := cloneMetrics(metricsMetadata)
 
-	err = r.vectorDBClient.BatchAddMetricMetadata(r.metricsMetadata)
+		enrichCtx, enrichSpan := telemetry.StartSpan(ctx, "llm.Enrich")
+		stats := r.enricher.Enrich(enrichCtx, enriched)
+		enrichSpan.SetAttributes(telemetry.AttributeDescribedMetrics.Int(stats.Generated + stats.Reused))
+		enrichSpan.End()
+
+		r.metricsMetadataMu.Lock()
+		// The annotations may have changed while the descriptions were generated
+		r.annotations.Apply(enriched)
+		r.metricsMetadata = enriched
+		r.metricsMetadataMu.Unlock()
+		metricsMetadata = enriched
+
+		report.Described = stats.Generated + stats.Reused
+		report.DescriptionFailures = stats.Failed
+		log.Info().Ctx(ctx).Msgf("generated %d metric descriptions, reused %d, %d failed, %d deferred to the next sync",
+			stats.Generated, stats.Reused, stats.Failed, stats.Deferred)
+	}
+
+	err = r.vectorDBClient.BatchAddMetricMetadata(ctx, metricsMetadata)
 	if err != nil {
-		log.Error().Err(err).Msg("failed to add metrics metadata to vectorDB")
+		log.Error().Ctx(ctx).Err(err).Msg("failed to add metrics metadata to vectorDB")
+		telemetry.ObserveSync(time.Since(start), 0, 0, err)
+		telemetry.EndSpan(span, err)
+		report.Error = err.Error()
+		r.setLastSyncReport(report)
 		return
 	}
 
-	log.Info().Msg("metrics metadata added to vectorDB")
+	telemetry.ObserveSync(time.Since(start), len(metricsMetadata), len(report.Failures), nil)
+	telemetry.EndSpan(span, nil)
+	r.setLastSyncReport(report)
+
+	log.Info().Ctx(ctx).Msg("metrics metadata added to vectorDB")
+}
+
+// Annotations returns the team-curated metric annotations
+func (r *Client) Annotations() []annotations.Annotation {
+	return r.annotations.List()
+}
+
+// PutAnnotation adds or replaces an annotation and updates the affected metrics in the vectorDB
+func (r *Client) PutAnnotation(ctx context.Context, annotation annotations.Annotation) error {
+	if err := r.annotations.Put(annotation); err != nil {
+		return err
+	}
+
+	return r.reapplyAnnotations(ctx)
+}
+
+// DeleteAnnotation removes an annotation and updates the affected metrics in
+// the vectorDB, returning false if there is no annotation with the given match
+func (r *Client) DeleteAnnotation(ctx context.Context, match string) (bool, error) {
+	deleted, err := r.annotations.Delete(match)
+	if err != nil || !deleted {
+		return deleted, err
+	}
+
+	return true, r.reapplyAnnotations(ctx)
+}
+
+// reapplyAnnotations applies the annotations to the last synchronized metrics
+// and re-adds the ones that changed, so edits are searchable without waiting for a sync
+func (r *Client) reapplyAnnotations(ctx context.Context) error {
+	// The published metrics may be read without the lock, so the annotations
+	// are applied to copies that replace them
+	r.metricsMetadataMu.Lock()
+	annotated := cloneMetrics(r.metricsMetadata)
+	changed := r.annotations.Apply(annotated)
+	if len(changed) > 0 {
+		r.metricsMetadata = annotated
+	}
+	r.metricsMetadataMu.Unlock()
+
+	if len(changed) == 0 {
+		return nil
+	}
+
+	r.invalidateCache("an annotation changed")
+
+	if err := r.vectorDBClient.BatchAddMetricMetadata(ctx, changed); err != nil {
+		return fmt.Errorf("failed to update annotated metrics in vectorDB: %w", err)
+	}
+
+	log.Info().Msgf("updated %d metrics after an annotation change", len(changed))
+	return nil
+}
+
+// Examples returns the few-shot examples in the library
+func (r *Client) Examples(ctx context.Context) ([]*examples.Example, error) {
+	return r.exampleStore.ListExamples(ctx)
+}
+
+// AddExample adds an example to the library, replacing the one for the same question if any
+func (r *Client) AddExample(ctx context.Context, example *examples.Example) error {
+	return r.exampleStore.AddExamples(ctx, []*examples.Example{example})
+}
+
+// DeleteExample removes an example from the library, returning false if there is none with the given ID
+func (r *Client) DeleteExample(ctx context.Context, id string) (bool, error) {
+	return r.exampleStore.DeleteExample(ctx, id)
+}
+
+// CaptureApprovedAnswer adds an answer approved by a user to the example
+// library when auto-capture is enabled, returning whether it was captured
+func (r *Client) CaptureApprovedAnswer(ctx context.Context, question, promql string) (bool, error) {
+	if !r.cfg.ExamplesAutoCapture {
+		return false, nil
+	}
+
+	example := &examples.Example{Question: question, PromQL: promql, Source: examples.SourceApproved}
+	if err := r.AddExample(ctx, example); err != nil {
+		return false, fmt.Errorf("failed to capture approved answer: %w", err)
+	}
+
+	log.Info().Msgf("captured approved answer as example %s", example.ID)
+	return true, nil
+}
+
+// invalidateCache removes the cached answers, which may refer to metrics that changed
+func (r *Client) invalidateCache(reason string) {
+	if r.cache == nil || r.cache.Len() == 0 {
+		return
+	}
+
+	r.cache.Invalidate()
+	log.Info().Msgf("invalidated the response cache because %s", reason)
+}
+
+// cloneMetrics returns copies of the metrics, sharing their slices, which are
+// never modified once listed
+func cloneMetrics(metrics []*prometheus.MetricMetadata) []*prometheus.MetricMetadata {
+	clones := make([]*prometheus.MetricMetadata, len(metrics))
+	for i, metric := range metrics {
+		clone := *metric
+		clones[i] = &clone
+	}
+	return clones
+}
+
+func (r *Client) setLastSyncReport(report *prometheus.SyncReport) {
+	r.metricsMetadataMu.Lock()
+	defer r.metricsMetadataMu.Unlock()
+
+	r.lastSyncReport = report
 }
@@ -0,0 +1,13 @@
+package rag_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestRAG(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "RAG Suite")
+}
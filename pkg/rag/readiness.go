@@ -0,0 +1,195 @@
+package rag
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/machadovilaca/prometheus-rag/pkg/embeddings"
+)
+
+// Dependency checks run by the readiness checker
+const (
+	CheckVectorDB   = "vectordb"
+	CheckPrometheus = "prometheus"
+	CheckLLM        = "llm"
+	CheckEncoder    = "encoder"
+)
+
+// Statuses of the readiness and of each check
+const (
+	StatusReady    = "ready"
+	StatusNotReady = "not_ready"
+	StatusOK       = "ok"
+	StatusFailed   = "failed"
+)
+
+// encoderProbeTimeout bounds the request of the encoder check to a remote
+// embeddings API, which would otherwise be retried up to its own timeout
+const encoderProbeTimeout = 5 * time.Second
+
+// ErrCollectionEmpty is reported by the vector database check before the
+// first synchronization has stored any metric
+var ErrCollectionEmpty = errors.New("metrics collection is empty")
+
+// Check probes a dependency, returning an error if it cannot be used
+type Check func(ctx context.Context) error
+
+// CheckResult is the outcome of a dependency check
+type CheckResult struct {
+	Status string `json:"status"`
+	// Gating tells whether a failure of the check makes the service not ready
+	Gating  bool          `json:"gating"`
+	Error   string        `json:"error,omitempty"`
+	Latency time.Duration `json:"latency"`
+}
+
+// Readiness is the outcome of all dependency checks
+type Readiness struct {
+	Status string                 `json:"status"`
+	Checks map[string]CheckResult `json:"checks"`
+}
+
+// Ready tells whether all gating checks succeeded
+func (r *Readiness) Ready() bool {
+	return r.Status == StatusReady
+}
+
+// ReadinessChecker runs dependency checks concurrently. All checks are
+// reported, but only the gating ones decide whether the service is ready.
+type ReadinessChecker struct {
+	timeout time.Duration
+	gating  map[string]bool
+	checks  map[string]Check
+}
+
+// NewReadinessChecker creates a readiness checker bounding each check by
+// timeout, 0 for no limit, where failures of the gating checks make the
+// service not ready
+func NewReadinessChecker(timeout time.Duration, gating []string) *ReadinessChecker {
+	c := &ReadinessChecker{
+		timeout: timeout,
+		gating:  make(map[string]bool, len(gating)),
+		checks:  make(map[string]Check),
+	}
+	for _, name := range gating {
+		c.gating[name] = true
+	}
+	return c
+}
+
+// Register adds a dependency check, replacing any with the same name
+func (c *ReadinessChecker) Register(name string, check Check) {
+	c.checks[name] = check
+}
+
+// Check runs all dependency checks and reports their outcome
+func (c *ReadinessChecker) Check(ctx context.Context) *Readiness {
+	readiness := &Readiness{
+		Status: StatusReady,
+		Checks: make(map[string]CheckResult, len(c.checks)),
+	}
+
+	var (
+		mu sync.Mutex
+		wg sync.WaitGroup
+	)
+	for name, check := range c.checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			result := c.run(ctx, name, check)
+
+			mu.Lock()
+			defer mu.Unlock()
+			readiness.Checks[name] = result
+			if result.Gating && result.Status != StatusOK {
+				readiness.Status = StatusNotReady
+			}
+		}()
+	}
+	wg.Wait()
+
+	return readiness
+}
+
+// run runs a single check within the timeout
+func (c *ReadinessChecker) run(ctx context.Context, name string, check Check) CheckResult {
+	ctx, cancel := withTimeout(ctx, c.timeout)
+	defer cancel()
+
+	start := time.Now()
+	err := check(ctx)
+
+	result := CheckResult{
+		Status:  StatusOK,
+		Gating:  c.gating[name],
+		Latency: time.Since(start),
+	}
+	if err != nil {
+		result.Status = StatusFailed
+		result.Error = err.Error()
+	}
+	return result
+}
+
+// withTimeout returns a context bounded by timeout, or only cancellable when timeout is 0
+func withTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, timeout)
+}
+
+// Readiness checks the dependencies of the RAG
+func (r *Client) Readiness(ctx context.Context) *Readiness {
+	return r.readiness.Check(ctx)
+}
+
+// registerReadinessChecks adds the checks of the vector database, Prometheus,
+// the LLM and the encoder to the readiness checker
+func (r *Client) registerReadinessChecks() {
+	r.readiness = NewReadinessChecker(r.cfg.GetReadinessTimeout(), r.cfg.ReadinessChecks)
+
+	r.readiness.Register(CheckVectorDB, func(ctx context.Context) error {
+		count, err := r.vectorDBClient.CountMetrics(ctx)
+		if err != nil {
+			return err
+		}
+		if count == 0 {
+			return ErrCollectionEmpty
+		}
+		return nil
+	})
+
+	r.readiness.Register(CheckPrometheus, r.prometheusClient.Ping)
+	r.readiness.Register(CheckLLM, r.llmClient.Ping)
+
+	r.readiness.Register(CheckEncoder, r.checkEncoder)
+}
+
+// checkEncoder checks the in-process model is loaded, or that the remote
+// embeddings API encodes a short text
+func (r *Client) checkEncoder(ctx context.Context) error {
+	if strings.EqualFold(r.cfg.VectorDBConfig.EncoderProvider, embeddings.ProviderOpenAI) {
+		ctx, cancel := context.WithTimeout(ctx, encoderProbeTimeout)
+		defer cancel()
+
+		if _, err := r.encoder.EncodeQuery(ctx, "readiness probe"); err != nil {
+			return fmt.Errorf("failed to encode with the embeddings API: %w", err)
+		}
+		return nil
+	}
+
+	dimension, err := r.encoder.GetDimension()
+	if err != nil {
+		return err
+	}
+	if dimension <= 0 {
+		return fmt.Errorf("invalid encoder dimension %d", dimension)
+	}
+	return nil
+}
@@ -0,0 +1,75 @@
+package rag_test
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/machadovilaca/prometheus-rag/pkg/rag"
+)
+
+var _ = Describe("ReadinessChecker", func() {
+	var checker *rag.ReadinessChecker
+
+	succeed := func(context.Context) error { return nil }
+	fail := func(context.Context) error { return errors.New("connection refused") }
+
+	BeforeEach(func() {
+		checker = rag.NewReadinessChecker(50*time.Millisecond, []string{rag.CheckVectorDB, rag.CheckLLM})
+	})
+
+	It("should be ready when all checks succeed", func() {
+		checker.Register(rag.CheckVectorDB, succeed)
+		checker.Register(rag.CheckLLM, succeed)
+		checker.Register(rag.CheckPrometheus, succeed)
+
+		readiness := checker.Check(context.Background())
+		Expect(readiness.Ready()).To(BeTrue())
+		Expect(readiness.Status).To(Equal(rag.StatusReady))
+		Expect(readiness.Checks).To(HaveLen(3))
+		Expect(readiness.Checks[rag.CheckVectorDB]).To(And(
+			HaveField("Status", rag.StatusOK),
+			HaveField("Gating", true),
+			HaveField("Error", BeEmpty()),
+		))
+		Expect(readiness.Checks[rag.CheckPrometheus].Gating).To(BeFalse())
+	})
+
+	It("should not be ready when a gating check fails", func() {
+		checker.Register(rag.CheckVectorDB, succeed)
+		checker.Register(rag.CheckLLM, fail)
+
+		readiness := checker.Check(context.Background())
+		Expect(readiness.Ready()).To(BeFalse())
+		Expect(readiness.Status).To(Equal(rag.StatusNotReady))
+		Expect(readiness.Checks[rag.CheckLLM]).To(And(
+			HaveField("Status", rag.StatusFailed),
+			HaveField("Error", "connection refused"),
+		))
+	})
+
+	It("should stay ready when a non-gating check fails", func() {
+		checker.Register(rag.CheckVectorDB, succeed)
+		checker.Register(rag.CheckPrometheus, fail)
+
+		readiness := checker.Check(context.Background())
+		Expect(readiness.Ready()).To(BeTrue())
+		Expect(readiness.Checks[rag.CheckPrometheus].Status).To(Equal(rag.StatusFailed))
+	})
+
+	It("should fail checks that exceed the timeout", func() {
+		checker.Register(rag.CheckVectorDB, func(ctx context.Context) error {
+			<-ctx.Done()
+			return ctx.Err()
+		})
+
+		start := time.Now()
+		readiness := checker.Check(context.Background())
+		Expect(time.Since(start)).To(BeNumerically("<", time.Second))
+		Expect(readiness.Ready()).To(BeFalse())
+		Expect(readiness.Checks[rag.CheckVectorDB].Error).To(Equal(context.DeadlineExceeded.Error()))
+	})
+})
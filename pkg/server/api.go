@@ -0,0 +1,524 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/machadovilaca/prometheus-rag/pkg/annotations"
+	apiv1 "github.com/machadovilaca/prometheus-rag/pkg/api/v1"
+	"github.com/machadovilaca/prometheus-rag/pkg/audit"
+	"github.com/machadovilaca/prometheus-rag/pkg/auth"
+	"github.com/machadovilaca/prometheus-rag/pkg/examples"
+	"github.com/machadovilaca/prometheus-rag/pkg/feedback"
+	"github.com/machadovilaca/prometheus-rag/pkg/openapi"
+	"github.com/machadovilaca/prometheus-rag/pkg/telemetry"
+)
+
+// apiHandler handles a request of the versioned API, returning the body of
+// the response, nil for none, or an error mapped by toAPIError
+type apiHandler func(r *http.Request) (any, error)
+
+// apiRoute is an operation of the versioned API. The routes both register
+// the handlers and generate the OpenAPI document, so it documents every
+// operation served.
+type apiRoute struct {
+	method string
+	// path is relative to apiv1.Prefix, with {name} path parameters
+	path        string
+	operationID string
+	summary     string
+	params      []openapi.Parameter
+
+	// request and response are values of the types of the bodies, nil for none
+	request  any
+	status   int
+	response any
+	// errors are the statuses of the errors the handler returns, besides the
+	// ones of authentication, authorization and rate limiting
+	errors []int
+
+	// public routes are served without authentication nor rate limiting
+	public bool
+	// admin routes are only served to administrators
+	admin bool
+
+	handler apiHandler
+}
+
+// apiRoutes returns the operations of the versioned API
+func (s *Server) apiRoutes() []apiRoute {
+	return []apiRoute{
+		{
+			method: http.MethodPost, path: "/query", operationID: "query",
+			summary: "Answer a natural language question with PromQL",
+			request: apiv1.QueryRequest{}, status: http.StatusOK, response: apiv1.QueryResponse{},
+			errors:  []int{http.StatusBadRequest, http.StatusForbidden, http.StatusTooManyRequests, http.StatusGatewayTimeout},
+			handler: s.apiQuery,
+		},
+		{
+			method: http.MethodGet, path: "/metadata/conflicts", operationID: "listMetadataConflicts",
+			summary: "List the metrics whose metadata differs between targets",
+			status:  http.StatusOK, response: apiv1.MetricsResponse{},
+			handler: s.apiMetadataConflicts,
+		},
+		{
+			method: http.MethodGet, path: "/sync/report", operationID: "getSyncReport",
+			summary: "Report on the last synchronization of the metrics",
+			status:  http.StatusOK, response: apiv1.SyncReport{},
+			errors:  []int{http.StatusNotFound},
+			handler: s.apiSyncReport,
+		},
+		{
+			method: http.MethodGet, path: "/annotations", operationID: "listAnnotations",
+			summary: "List the metric annotations",
+			status:  http.StatusOK, response: apiv1.AnnotationsResponse{},
+			handler: s.apiAnnotations,
+		},
+		{
+			method: http.MethodPut, path: "/annotations", operationID: "putAnnotation",
+			summary: "Add or replace the annotation of a metric name or pattern",
+			request: annotations.Annotation{}, status: http.StatusNoContent,
+			errors: []int{http.StatusBadRequest}, admin: true,
+			handler: s.apiPutAnnotation,
+		},
+		{
+			method: http.MethodDelete, path: "/annotations/{match}", operationID: "deleteAnnotation",
+			summary: "Delete the annotation of a metric name or pattern",
+			params:  []openapi.Parameter{pathParam("match", "Metric name or glob pattern of the annotation")},
+			status:  http.StatusNoContent,
+			errors:  []int{http.StatusNotFound}, admin: true,
+			handler: s.apiDeleteAnnotation,
+		},
+		{
+			method: http.MethodGet, path: "/examples", operationID: "listExamples",
+			summary: "List the examples of the library",
+			status:  http.StatusOK, response: apiv1.ExamplesResponse{},
+			handler: s.apiExamples,
+		},
+		{
+			method: http.MethodPost, path: "/examples", operationID: "addExample",
+			summary: "Add an example to the library",
+			request: apiv1.ExampleRequest{}, status: http.StatusCreated, response: examples.Example{},
+			errors: []int{http.StatusBadRequest}, admin: true,
+			handler: s.apiAddExample,
+		},
+		{
+			method: http.MethodDelete, path: "/examples/{id}", operationID: "deleteExample",
+			summary: "Delete an example from the library",
+			params:  []openapi.Parameter{pathParam("id", "ID of the example")},
+			status:  http.StatusNoContent,
+			errors:  []int{http.StatusNotFound}, admin: true,
+			handler: s.apiDeleteExample,
+		},
+		{
+			method: http.MethodPost, path: "/examples/approve", operationID: "approveExample",
+			summary: "Approve the PromQL answering a question, adding it to the library",
+			request: apiv1.ApproveRequest{}, status: http.StatusOK, response: apiv1.CapturedResponse{},
+			errors: []int{http.StatusBadRequest}, admin: true,
+			handler: s.apiApproveExample,
+		},
+		{
+			method: http.MethodPost, path: "/feedback", operationID: "giveFeedback",
+			summary: "Rate the answer to a query",
+			request: apiv1.FeedbackRequest{}, status: http.StatusOK, response: apiv1.CapturedResponse{},
+			errors:  []int{http.StatusBadRequest, http.StatusForbidden, http.StatusNotFound, http.StatusNotImplemented},
+			handler: s.apiFeedback,
+		},
+		{
+			method: http.MethodGet, path: "/audit", operationID: "searchAudit",
+			summary: "Search the audit records, newest first",
+			params: []openapi.Parameter{
+				queryParam("since", "Only records at or after this RFC 3339 timestamp", &openapi.Schema{Type: "string", Format: "date-time"}),
+				queryParam("until", "Only records before this RFC 3339 timestamp", &openapi.Schema{Type: "string", Format: "date-time"}),
+				queryParam("user", "Only records of this principal, as method:name", &openapi.Schema{Type: "string"}),
+				queryParam("outcome", "Only records with this outcome", &openapi.Schema{Type: "string"}),
+				queryParam("limit", "Maximum number of records", &openapi.Schema{Type: "integer"}),
+			},
+			status: http.StatusOK, response: apiv1.AuditResponse{},
+			errors: []int{http.StatusBadRequest, http.StatusNotImplemented}, admin: true,
+			handler: s.apiAudit,
+		},
+		{
+			method: http.MethodGet, path: "/quota", operationID: "getQuota",
+			summary: "Report the LLM token usage of the clients",
+			params:  []openapi.Parameter{queryParam("key", "Only the usage of this client, a principal as method:name or an address", &openapi.Schema{Type: "string"})},
+			status:  http.StatusOK, response: apiv1.QuotaResponse{},
+			admin:   true,
+			handler: s.apiQuota,
+		},
+		// The document is described as any JSON value, not by the schemas of its types
+		{
+			method: http.MethodGet, path: "/openapi.json", operationID: "getOpenAPI",
+			summary: "Get the OpenAPI document of the API",
+			status:  http.StatusOK, response: json.RawMessage{},
+			public:  true,
+			handler: s.apiOpenAPI,
+		},
+	}
+}
+
+// handleAPI registers the routes of the versioned API. Routes are grouped by
+// path, so requests with another method get the JSON error envelope instead
+// of the plain text response of the mux.
+func (s *Server) handleAPI(mux *http.ServeMux, routes []apiRoute) {
+	var paths []string
+	byPath := make(map[string][]apiRoute)
+	for _, route := range routes {
+		if _, ok := byPath[route.path]; !ok {
+			paths = append(paths, route.path)
+		}
+		byPath[route.path] = append(byPath[route.path], route)
+	}
+
+	for _, path := range paths {
+		pattern := apiv1.Prefix + path
+		mux.HandleFunc(pattern, telemetry.InstrumentHandler(pattern, s.dispatch(byPath[path])))
+	}
+
+	// Unknown paths of the API get the envelope too
+	notFoundPattern := apiv1.Prefix + "/"
+	mux.HandleFunc(notFoundPattern, telemetry.InstrumentHandler(notFoundPattern, s.authenticate(
+		func(w http.ResponseWriter, r *http.Request) {
+			writeError(w, r, http.StatusNotFound, apiv1.CodeNotFound, "Not found", nil)
+		},
+	)))
+}
+
+// dispatch serves the requests of a path with the route of their method
+func (s *Server) dispatch(routes []apiRoute) http.HandlerFunc {
+	handlers := make(map[string]http.HandlerFunc, len(routes))
+	var allowed []string
+	for _, route := range routes {
+		handler := s.serveAPI(route)
+		if route.admin {
+			handler = s.adminOnly(handler)
+		}
+		if !route.public {
+			handler = s.authenticate(s.limit(handler))
+		}
+		handlers[route.method] = handler
+		allowed = append(allowed, route.method)
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		log.Debug().Ctx(r.Context()).Msgf("received request: %s %s from %s", r.Method, r.URL.Path, r.RemoteAddr)
+
+		handler, ok := handlers[r.Method]
+		if !ok {
+			w.Header().Set("Allow", strings.Join(allowed, ", "))
+			writeError(w, r, http.StatusMethodNotAllowed, apiv1.CodeMethodNotAllowed, "Method not allowed", nil)
+			return
+		}
+		handler(w, r)
+	}
+}
+
+// serveAPI writes the response of the handler of a route
+func (s *Server) serveAPI(route apiRoute) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		body, err := route.handler(r)
+		if r.Context().Err() != nil {
+			log.Debug().Ctx(r.Context()).Msgf("client %s disconnected before the request was handled", r.RemoteAddr)
+			return
+		}
+		if err != nil {
+			writeAPIError(w, r, err)
+			return
+		}
+
+		if body == nil {
+			w.WriteHeader(route.status)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(route.status)
+		// The status is already written, so encoding errors can only be logged
+		if err := json.NewEncoder(w).Encode(body); err != nil {
+			log.Error().Err(err).Msg("failed to encode response")
+		}
+	}
+}
+
+// apiDocument generates the OpenAPI document of the routes
+func (s *Server) apiDocument(routes []apiRoute) *openapi.Document {
+	document := openapi.New(openapi.Info{
+		Title:       "Prometheus RAG API",
+		Version:     "v1",
+		Description: "Translates natural language questions into PromQL queries.",
+	}, apiv1.Error{})
+
+	var schemes []string
+	var authErrors, limitErrors []int
+	if s.authenticator != nil {
+		document.AddSecurityScheme("apiKey", &openapi.SecurityScheme{
+			Type: "apiKey", In: "header", Name: auth.APIKeyHeader,
+		})
+		document.AddSecurityScheme("bearer", &openapi.SecurityScheme{
+			Type: "http", Scheme: "bearer", Description: "API key or JWT",
+		})
+		schemes = []string{"apiKey", "bearer"}
+		authErrors = []int{http.StatusUnauthorized}
+	}
+	if s.rateLimiter != nil || s.authLimiter != nil {
+		limitErrors = []int{http.StatusTooManyRequests}
+	}
+
+	for _, route := range routes {
+		endpoint := openapi.Endpoint{
+			Method:      route.method,
+			Path:        apiv1.Prefix + route.path,
+			Summary:     route.summary,
+			OperationID: route.operationID,
+			Parameters:  route.params,
+			Request:     route.request,
+			Status:      route.status,
+			Response:    route.response,
+			Errors:      slices.Clone(route.errors),
+		}
+
+		if !route.public {
+			endpoint.Security = schemes
+			endpoint.Errors = append(endpoint.Errors, authErrors...)
+			endpoint.Errors = append(endpoint.Errors, limitErrors...)
+			if route.admin {
+				endpoint.Errors = append(endpoint.Errors, http.StatusForbidden)
+			}
+		}
+		endpoint.Errors = append(endpoint.Errors, http.StatusInternalServerError)
+
+		document.Add(endpoint)
+	}
+
+	return document
+}
+
+func pathParam(name, description string) openapi.Parameter {
+	return openapi.Parameter{Name: name, In: "path", Description: description, Required: true, Schema: &openapi.Schema{Type: "string"}}
+}
+
+func queryParam(name, description string, schema *openapi.Schema) openapi.Parameter {
+	return openapi.Parameter{Name: name, In: "query", Description: description, Schema: schema}
+}
+
+// decodeBody decodes the JSON body of a request
+func decodeBody(r *http.Request, v any) error {
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		return invalidRequest("Invalid request body: %v", err)
+	}
+	return nil
+}
+
+// withIdentity returns the context of a request with the identity audited
+// for its queries
+func withIdentity(r *http.Request) context.Context {
+	identity := audit.Identity{Address: r.RemoteAddr}
+	if principal := auth.PrincipalFromContext(r.Context()); principal != nil {
+		identity.User = principal.Key()
+	}
+	return audit.WithIdentity(r.Context(), identity)
+}
+
+func (s *Server) apiQuery(r *http.Request) (any, error) {
+	var request apiv1.QueryRequest
+	if err := decodeBody(r, &request); err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(request.Query) == "" {
+		return nil, invalidRequest("query is required")
+	}
+
+	result, err := s.rag.Query(withIdentity(r), request.Query)
+	if err != nil {
+		return nil, err
+	}
+
+	response := apiv1.QueryResponse{
+		ID:      result.ID,
+		PromQL:  result.PromQL,
+		Metrics: result.Metrics,
+		TraceID: telemetry.TraceID(r.Context()),
+	}
+	if response.Metrics == nil {
+		response.Metrics = []string{}
+	}
+	if result.Cache != nil {
+		response.Cache = &apiv1.CacheInfo{
+			Status:     result.Cache.Status,
+			Question:   result.Cache.Question,
+			Similarity: result.Cache.Similarity,
+			CachedAt:   result.Cache.CachedAt,
+		}
+	}
+	return response, nil
+}
+
+func (s *Server) apiMetadataConflicts(r *http.Request) (any, error) {
+	return apiv1.MetricsResponse{Metrics: nonNil(visibleMetrics(r, s.rag.MetadataConflicts()))}, nil
+}
+
+func (s *Server) apiSyncReport(r *http.Request) (any, error) {
+	report := s.rag.LastSyncReport()
+	if report == nil {
+		return nil, notFound("No synchronization has completed yet")
+	}
+
+	report = visibleSyncReport(r, report)
+	return apiv1.SyncReport{
+		StartedAt:           report.StartedAt,
+		DurationSeconds:     report.Duration.Seconds(),
+		Metrics:             report.Metrics,
+		LabelRequests:       report.LabelRequests,
+		Failures:            report.Failures,
+		Described:           report.Described,
+		DescriptionFailures: report.DescriptionFailures,
+		Error:               report.Error,
+	}, nil
+}
+
+func (s *Server) apiAnnotations(r *http.Request) (any, error) {
+	return apiv1.AnnotationsResponse{Annotations: nonNil(visibleAnnotations(r, s.rag.Annotations()))}, nil
+}
+
+func (s *Server) apiPutAnnotation(r *http.Request) (any, error) {
+	var annotation annotations.Annotation
+	if err := decodeBody(r, &annotation); err != nil {
+		return nil, err
+	}
+	if err := annotation.Validate(); err != nil {
+		return nil, invalidRequest("Invalid annotation: %v", err)
+	}
+
+	if err := s.rag.PutAnnotation(r.Context(), annotation); err != nil {
+		return nil, fmt.Errorf("failed to put annotation: %w", err)
+	}
+	return nil, nil
+}
+
+func (s *Server) apiDeleteAnnotation(r *http.Request) (any, error) {
+	deleted, err := s.rag.DeleteAnnotation(r.Context(), r.PathValue("match"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to delete annotation: %w", err)
+	}
+	if !deleted {
+		return nil, notFound("Annotation not found")
+	}
+	return nil, nil
+}
+
+func (s *Server) apiExamples(r *http.Request) (any, error) {
+	list, err := s.rag.Examples(r.Context())
+	if err != nil {
+		return nil, fmt.Errorf("failed to list examples: %w", err)
+	}
+	return apiv1.ExamplesResponse{Examples: nonNil(visibleExamples(r, list))}, nil
+}
+
+func (s *Server) apiAddExample(r *http.Request) (any, error) {
+	var request apiv1.ExampleRequest
+	if err := decodeBody(r, &request); err != nil {
+		return nil, err
+	}
+
+	example := &examples.Example{
+		ID:       request.ID,
+		Question: request.Question,
+		PromQL:   request.PromQL,
+		Source:   request.Source,
+	}
+	if err := example.Validate(); err != nil {
+		return nil, invalidRequest("Invalid example: %v", err)
+	}
+
+	if err := s.rag.AddExample(r.Context(), example); err != nil {
+		return nil, fmt.Errorf("failed to add example: %w", err)
+	}
+	return example, nil
+}
+
+func (s *Server) apiDeleteExample(r *http.Request) (any, error) {
+	deleted, err := s.rag.DeleteExample(r.Context(), r.PathValue("id"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to delete example: %w", err)
+	}
+	if !deleted {
+		return nil, notFound("Example not found")
+	}
+	return nil, nil
+}
+
+func (s *Server) apiApproveExample(r *http.Request) (any, error) {
+	var request apiv1.ApproveRequest
+	if err := decodeBody(r, &request); err != nil {
+		return nil, err
+	}
+	if request.Query == "" || request.PromQL == "" {
+		return nil, invalidRequest("Both query and promql are required")
+	}
+
+	captured, err := s.rag.CaptureApprovedAnswer(r.Context(), request.Query, request.PromQL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to capture approved answer: %w", err)
+	}
+	return apiv1.CapturedResponse{Captured: captured}, nil
+}
+
+func (s *Server) apiFeedback(r *http.Request) (any, error) {
+	var request apiv1.FeedbackRequest
+	if err := decodeBody(r, &request); err != nil {
+		return nil, err
+	}
+
+	fb := feedback.Feedback{
+		QueryID:         request.QueryID,
+		Rating:          request.Rating,
+		CorrectedPromQL: request.CorrectedPromQL,
+	}
+	if err := fb.Validate(); err != nil {
+		return nil, invalidRequest("Invalid feedback: %v", err)
+	}
+
+	captured, err := s.rag.Feedback(r.Context(), fb)
+	if err != nil {
+		return nil, err
+	}
+	return apiv1.CapturedResponse{Captured: captured}, nil
+}
+
+func (s *Server) apiAudit(r *http.Request) (any, error) {
+	filter, err := parseAuditFilter(r)
+	if err != nil {
+		return nil, invalidRequest("Invalid filter: %v", err)
+	}
+
+	records, err := s.rag.AuditRecords(r.Context(), filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search audit records: %w", err)
+	}
+	return apiv1.AuditResponse{Records: nonNil(records)}, nil
+}
+
+func (s *Server) apiQuota(r *http.Request) (any, error) {
+	return apiv1.QuotaResponse{
+		DefaultLimits: s.defaultTokenLimits,
+		Usage:         nonNil(s.tokenUsage(r.URL.Query().Get("key"))),
+	}, nil
+}
+
+func (s *Server) apiOpenAPI(*http.Request) (any, error) {
+	return s.openAPI, nil
+}
+
+// nonNil returns an empty slice instead of nil, so lists are encoded as []
+func nonNil[T any](list []T) []T {
+	if list == nil {
+		return []T{}
+	}
+	return list
+}
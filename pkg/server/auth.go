@@ -0,0 +1,138 @@
+package server
+
+import (
+	"net"
+	"net/http"
+	"net/netip"
+	"slices"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/machadovilaca/prometheus-rag/pkg/annotations"
+	apiv1 "github.com/machadovilaca/prometheus-rag/pkg/api/v1"
+	"github.com/machadovilaca/prometheus-rag/pkg/auth"
+	"github.com/machadovilaca/prometheus-rag/pkg/examples"
+	"github.com/machadovilaca/prometheus-rag/pkg/prometheus"
+)
+
+// authenticate rejects the requests without valid credentials and adds the
+// principal to the context of the others. Requests are not authenticated
+// when authentication is disabled. Addresses over their limit of failed
+// authentications are rejected before their credentials are checked, so keys
+// and tokens cannot be guessed by brute force.
+func (s *Server) authenticate(handler http.HandlerFunc) http.HandlerFunc {
+	if s.authenticator == nil {
+		return handler
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		address := clientAddress(r)
+		if s.authLimiter != nil {
+			if delay := s.authLimiter.Delay(address); delay > 0 {
+				log.Debug().Ctx(r.Context()).Msgf("rate limited authentication from %s", address)
+				tooManyRequests(w, r, delay, apiv1.CodeRateLimited, "Too many failed authentications")
+				return
+			}
+		}
+
+		principal, err := s.authenticator.Authenticate(r)
+		if err != nil {
+			if s.authLimiter != nil {
+				s.authLimiter.Allow(address)
+			}
+			log.Debug().Ctx(r.Context()).Err(err).Msgf("rejected unauthenticated request from %s", r.RemoteAddr)
+			w.Header().Set("WWW-Authenticate", `Bearer realm="prometheus-rag"`)
+			writeError(w, r, http.StatusUnauthorized, apiv1.CodeUnauthorized, "Unauthorized", nil)
+			return
+		}
+
+		handler(w, r.WithContext(auth.WithPrincipal(r.Context(), principal)))
+	}
+}
+
+// adminOnly rejects the requests of principals that are not administrators,
+// except for the exempt methods. When authentication is disabled there are no
+// administrators, so only requests from the loopback interface are allowed.
+func (s *Server) adminOnly(handler http.HandlerFunc, exempt ...string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !slices.Contains(exempt, r.Method) && !s.isAdmin(r) {
+			log.Debug().Ctx(r.Context()).Msgf("rejected %s %s from non-admin %s", r.Method, r.URL.Path, r.RemoteAddr)
+			writeError(w, r, http.StatusForbidden, apiv1.CodeForbidden, "Forbidden", nil)
+			return
+		}
+
+		handler(w, r)
+	}
+}
+
+// isAdmin returns true if the request may use the administration endpoints
+func (s *Server) isAdmin(r *http.Request) bool {
+	if s.authenticator == nil {
+		return isLoopback(r.RemoteAddr)
+	}
+	return auth.PrincipalFromContext(r.Context()).IsAdmin()
+}
+
+// isLoopback returns true if the address is on the loopback interface
+func isLoopback(remoteAddr string) bool {
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		host = remoteAddr
+	}
+
+	addr, err := netip.ParseAddr(host)
+	return err == nil && addr.Unmap().IsLoopback()
+}
+
+// visibleMetrics returns the metrics the principal of the request may query
+func visibleMetrics(r *http.Request, metrics []*prometheus.MetricMetadata) []*prometheus.MetricMetadata {
+	principal := auth.PrincipalFromContext(r.Context())
+	if !principal.RestrictsMetrics() {
+		return metrics
+	}
+
+	return slices.DeleteFunc(slices.Clone(metrics), func(metric *prometheus.MetricMetadata) bool {
+		return !principal.AllowsMetric(metric.Name)
+	})
+}
+
+// visibleSyncReport returns the report without the failures of the metrics
+// the principal of the request may not query
+func visibleSyncReport(r *http.Request, report *prometheus.SyncReport) *prometheus.SyncReport {
+	principal := auth.PrincipalFromContext(r.Context())
+	if !principal.RestrictsMetrics() {
+		return report
+	}
+
+	visible := *report
+	visible.Failures = slices.DeleteFunc(slices.Clone(report.Failures), func(failure prometheus.SyncFailure) bool {
+		return !principal.AllowsMetric(failure.Metric)
+	})
+	return &visible
+}
+
+// visibleAnnotations returns the annotations whose match the principal of the
+// request may query, comparing patterns with the allowed prefixes as names
+func visibleAnnotations(r *http.Request, list []annotations.Annotation) []annotations.Annotation {
+	principal := auth.PrincipalFromContext(r.Context())
+	if !principal.RestrictsMetrics() {
+		return list
+	}
+
+	return slices.DeleteFunc(slices.Clone(list), func(annotation annotations.Annotation) bool {
+		return !principal.AllowsMetric(annotation.Match)
+	})
+}
+
+// visibleExamples returns the examples whose PromQL the principal of the
+// request may query
+func visibleExamples(r *http.Request, list []*examples.Example) []*examples.Example {
+	principal := auth.PrincipalFromContext(r.Context())
+	if !principal.RestrictsMetrics() {
+		return list
+	}
+
+	return slices.DeleteFunc(slices.Clone(list), func(example *examples.Example) bool {
+		return principal.CheckPromQL(example.PromQL) != nil
+	})
+}
@@ -0,0 +1,126 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	apiv1 "github.com/machadovilaca/prometheus-rag/pkg/api/v1"
+	"github.com/machadovilaca/prometheus-rag/pkg/auth"
+	"github.com/machadovilaca/prometheus-rag/pkg/feedback"
+	"github.com/machadovilaca/prometheus-rag/pkg/quota"
+	"github.com/machadovilaca/prometheus-rag/pkg/rag"
+)
+
+// apiError is an error returned by a versioned API handler, with the status
+// and code of its response
+type apiError struct {
+	status  int
+	code    string
+	message string
+	details map[string]any
+	// retryAfter is set in Retry-After when positive
+	retryAfter time.Duration
+}
+
+func (e *apiError) Error() string {
+	return e.message
+}
+
+func invalidRequest(format string, args ...any) error {
+	return &apiError{status: http.StatusBadRequest, code: apiv1.CodeInvalidRequest, message: fmt.Sprintf(format, args...)}
+}
+
+func notFound(message string) error {
+	return &apiError{status: http.StatusNotFound, code: apiv1.CodeNotFound, message: message}
+}
+
+// toAPIError maps an error of the RAG to the response of the versioned API
+func toAPIError(err error) *apiError {
+	var apiErr *apiError
+	if errors.As(err, &apiErr) {
+		return apiErr
+	}
+
+	var exceeded *quota.ExceededError
+	switch {
+	case errors.As(err, &exceeded):
+		return &apiError{
+			status:  http.StatusTooManyRequests,
+			code:    apiv1.CodeQuotaExceeded,
+			message: fmt.Sprintf("Quota exceeded: %v", err),
+			details: map[string]any{
+				"period": exceeded.Period,
+				"limit":  exceeded.Limit,
+			},
+			retryAfter: exceeded.RetryAfter,
+		}
+	case errors.Is(err, auth.ErrForbidden):
+		return &apiError{status: http.StatusForbidden, code: apiv1.CodeForbidden, message: fmt.Sprintf("Forbidden query: %v", err)}
+	case errors.Is(err, context.DeadlineExceeded):
+		return &apiError{status: http.StatusGatewayTimeout, code: apiv1.CodeTimeout, message: fmt.Sprintf("Timed out: %v", err)}
+	case errors.Is(err, feedback.ErrQueryNotFound):
+		return &apiError{status: http.StatusNotFound, code: apiv1.CodeNotFound, message: "Query not found"}
+	case errors.Is(err, rag.ErrFeedbackDisabled):
+		return &apiError{status: http.StatusNotImplemented, code: apiv1.CodeDisabled, message: "Feedback is disabled"}
+	case errors.Is(err, rag.ErrAuditDisabled):
+		return &apiError{status: http.StatusNotImplemented, code: apiv1.CodeDisabled, message: "Audit log is disabled"}
+	default:
+		// The cause is logged by writeAPIError, it may reveal internals to clients
+		return &apiError{status: http.StatusInternalServerError, code: apiv1.CodeInternal, message: "Internal server error"}
+	}
+}
+
+// writeAPIError responds with the error returned by a versioned API handler
+func writeAPIError(w http.ResponseWriter, r *http.Request, err error) {
+	apiErr := toAPIError(err)
+	if apiErr.status >= http.StatusInternalServerError {
+		log.Error().Ctx(r.Context()).Err(err).Msgf("failed to handle %s %s", r.Method, r.URL.Path)
+	} else {
+		log.Debug().Ctx(r.Context()).Err(err).Msgf("rejected %s %s", r.Method, r.URL.Path)
+	}
+
+	if apiErr.retryAfter > 0 {
+		setRetryAfter(w, apiErr.retryAfter)
+	}
+	writeError(w, r, apiErr.status, apiErr.code, apiErr.message, apiErr.details)
+}
+
+// writeError responds with an error: the JSON envelope on the versioned API,
+// and the message as plain text on the legacy endpoints
+func writeError(w http.ResponseWriter, r *http.Request, status int, code, message string, details map[string]any) {
+	if !strings.HasPrefix(r.URL.Path, apiv1.Prefix+"/") {
+		http.Error(w, message, status)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(status)
+
+	// The status is already written, so encoding errors can only be logged
+	err := json.NewEncoder(w).Encode(apiv1.Error{Code: code, Message: message, Details: details})
+	if err != nil {
+		log.Error().Err(err).Msg("failed to encode response")
+	}
+}
+
+// tooManyRequests responds 429 with the seconds to wait in Retry-After
+func tooManyRequests(w http.ResponseWriter, r *http.Request, retryAfter time.Duration, code, message string) {
+	setRetryAfter(w, retryAfter)
+	writeError(w, r, http.StatusTooManyRequests, code, message, nil)
+}
+
+// setRetryAfter sets Retry-After to the seconds to wait, rounded up
+func setRetryAfter(w http.ResponseWriter, retryAfter time.Duration) {
+	seconds := int(math.Ceil(retryAfter.Seconds()))
+	w.Header().Set("Retry-After", strconv.Itoa(max(seconds, 1)))
+}
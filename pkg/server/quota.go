@@ -0,0 +1,105 @@
+package server
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+
+	"github.com/rs/zerolog/log"
+
+	apiv1 "github.com/machadovilaca/prometheus-rag/pkg/api/v1"
+	"github.com/machadovilaca/prometheus-rag/pkg/auth"
+	"github.com/machadovilaca/prometheus-rag/pkg/quota"
+)
+
+// limit rejects the requests of clients over their rate limit, and adds the
+// client to the context of the others so their LLM tokens are budgeted
+func (s *Server) limit(handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		principal := auth.PrincipalFromContext(r.Context())
+		key := clientKey(r, principal)
+
+		if s.rateLimiter != nil {
+			if delay := s.rateLimiter.Allow(key); delay > 0 {
+				log.Debug().Ctx(r.Context()).Msgf("rate limited request from %s", key)
+				tooManyRequests(w, r, delay, apiv1.CodeRateLimited, "Rate limit exceeded")
+				return
+			}
+		}
+
+		client := quota.Client{Key: key, Limits: s.tokenLimits(principal)}
+		handler(w, r.WithContext(quota.WithClient(r.Context(), client)))
+	}
+}
+
+// tokenLimits returns the token quotas of the principal, the defaults unless
+// its policy overrides them
+func (s *Server) tokenLimits(principal *auth.Principal) quota.Limits {
+	limits := s.defaultTokenLimits
+	if principal == nil {
+		return limits
+	}
+
+	if principal.DailyTokens != nil {
+		limits.Daily = *principal.DailyTokens
+	}
+	if principal.MonthlyTokens != nil {
+		limits.Monthly = *principal.MonthlyTokens
+	}
+	return limits
+}
+
+// tokenUsage returns the token usage of the clients, only of the one with the
+// given key unless it is empty
+func (s *Server) tokenUsage(key string) []quota.Usage {
+	usage := s.rag.TokenUsage()
+	if key == "" {
+		return usage
+	}
+
+	filtered := usage[:0]
+	for _, u := range usage {
+		if u.Key == key {
+			filtered = append(filtered, u)
+		}
+	}
+	return filtered
+}
+
+// clientKey identifies the client of a request by its principal, or by its IP
+// address when authentication is disabled
+func clientKey(r *http.Request, principal *auth.Principal) string {
+	if principal != nil {
+		return principal.Key()
+	}
+	return clientAddress(r)
+}
+
+// clientAddress returns the IP address of the client of a request
+func clientAddress(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+func (s *Server) handleQuota(w http.ResponseWriter, r *http.Request) {
+	log.Debug().Ctx(r.Context()).Msgf("received request: %s %s from %s", r.Method, r.URL.Path, r.RemoteAddr)
+
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	err := json.NewEncoder(w).Encode(map[string]any{
+		"default_limits": s.defaultTokenLimits,
+		"usage":          s.tokenUsage(r.URL.Query().Get("key")),
+	})
+	if err != nil {
+		log.Error().Err(err).Msg("failed to encode response")
+		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+		return
+	}
+}
@@ -1,55 +1,216 @@
 package server
 
 import (
+	"context"
+	"crypto/tls"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/rs/zerolog/log"
 
+	"github.com/machadovilaca/prometheus-rag/pkg/annotations"
+	apiv1 "github.com/machadovilaca/prometheus-rag/pkg/api/v1"
+	"github.com/machadovilaca/prometheus-rag/pkg/audit"
+	"github.com/machadovilaca/prometheus-rag/pkg/auth"
 	"github.com/machadovilaca/prometheus-rag/pkg/config"
+	"github.com/machadovilaca/prometheus-rag/pkg/examples"
+	"github.com/machadovilaca/prometheus-rag/pkg/feedback"
+	"github.com/machadovilaca/prometheus-rag/pkg/openapi"
+	"github.com/machadovilaca/prometheus-rag/pkg/quota"
 	"github.com/machadovilaca/prometheus-rag/pkg/rag"
+	"github.com/machadovilaca/prometheus-rag/pkg/telemetry"
+	"github.com/machadovilaca/prometheus-rag/pkg/tlsconfig"
 )
 
 // Server is the HTTP server for the RAG
 type Server struct {
-	host string
-	port string
+	httpServer *http.Server
 
 	rag *rag.Client
+
+	// authenticator is nil when authentication is disabled
+	authenticator *auth.Authenticator
+	// authLimiter limits the failed authentications per client address, nil
+	// when authentication or the limit is disabled
+	authLimiter *quota.RateLimiter
+	// rateLimiter is nil when rate limiting is disabled
+	rateLimiter        *quota.RateLimiter
+	defaultTokenLimits quota.Limits
+
+	// openAPI documents the versioned API
+	openAPI *openapi.Document
 }
 
 // New creates a new Server
 func New(cfg *config.Config) (*Server, error) {
+	var authenticator *auth.Authenticator
+	if cfg.Auth.Enabled {
+		var err error
+		authenticator, err = auth.New(cfg.ToAuthConfig())
+		if err != nil {
+			return nil, fmt.Errorf("failed to set up authentication: %v", err)
+		}
+	}
+
+	var tlsReloader *tlsconfig.Reloader
+	if cfg.IsTLSEnabled() {
+		var err error
+		tlsReloader, err = tlsconfig.New(cfg.ToTLSConfig())
+		if err != nil {
+			return nil, fmt.Errorf("failed to set up TLS: %v", err)
+		}
+	}
+
+	var authLimiter *quota.RateLimiter
+	if authenticator != nil && cfg.Auth.FailureRateLimit > 0 {
+		authLimiter = quota.NewRateLimiter(cfg.Auth.FailureRateLimit, cfg.Auth.FailureBurst)
+	}
+
+	var rateLimiter *quota.RateLimiter
+	if cfg.Quota.RateLimit > 0 {
+		rateLimiter = quota.NewRateLimiter(cfg.Quota.RateLimit, cfg.Quota.RateLimitBurst)
+	}
+
 	rag, err := rag.New(cfg)
 	if err != nil {
 		return nil, fmt.Errorf("failed to run RAG: %v", err)
 	}
 
-	return &Server{
-		host: cfg.Server.Host,
-		port: cfg.Server.Port,
+	s := &Server{
+		rag:           rag,
+		authenticator: authenticator,
+		authLimiter:   authLimiter,
+		rateLimiter:   rateLimiter,
+		defaultTokenLimits: quota.Limits{
+			Daily:   cfg.Quota.DailyTokens,
+			Monthly: cfg.Quota.MonthlyTokens,
+		},
+	}
+
+	s.httpServer = &http.Server{
+		Addr:              fmt.Sprintf("%s:%s", cfg.Server.Host, cfg.Server.Port),
+		Handler:           s.routes(),
+		ReadHeaderTimeout: time.Duration(cfg.Server.ReadTimeoutSeconds) * time.Second,
+		ReadTimeout:       time.Duration(cfg.Server.ReadTimeoutSeconds) * time.Second,
+		WriteTimeout:      time.Duration(cfg.Server.WriteTimeoutSeconds) * time.Second,
+		IdleTimeout:       time.Duration(cfg.Server.IdleTimeoutSeconds) * time.Second,
+	}
+
+	if tlsReloader != nil {
+		s.httpServer.TLSConfig = tlsReloader.TLSConfig()
+		if !cfg.Server.HTTP2 {
+			// A non-nil empty map keeps the server from configuring HTTP/2
+			s.httpServer.TLSNextProto = map[string]func(*http.Server, *tls.Conn, http.Handler){}
+		}
+	}
 
-		rag: rag,
-	}, nil
+	return s, nil
 }
 
-// Start starts the HTTP server
+// Start serves HTTP requests, over TLS when it is configured, until the server is shut down
 func (s *Server) Start() error {
-	http.HandleFunc("/healthz", s.handleHealthz)
-	http.HandleFunc("/query", s.handleQuery)
+	var err error
+	if s.httpServer.TLSConfig != nil {
+		log.Info().Msgf("starting HTTPS server on %s", s.httpServer.Addr)
+		// The certificates come from the TLS configuration, reloaded on change
+		err = s.httpServer.ListenAndServeTLS("", "")
+	} else {
+		log.Info().Msgf("starting HTTP server on %s", s.httpServer.Addr)
+		err = s.httpServer.ListenAndServe()
+	}
+
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+	return err
+}
+
+// Shutdown stops accepting requests, waits for the in-flight ones until ctx
+// is done, and then stops the RAG synchronization and closes its databases
+func (s *Server) Shutdown(ctx context.Context) error {
+	log.Info().Msg("shutting down HTTP server")
+
+	var errs []error
+	if err := s.httpServer.Shutdown(ctx); err != nil {
+		// The remaining requests are cut short, so the RAG can be closed under them
+		errs = append(errs, fmt.Errorf("failed to drain requests: %w", err), s.httpServer.Close())
+	}
+
+	log.Info().Msg("stopping RAG")
+	if err := s.rag.Close(); err != nil {
+		errs = append(errs, fmt.Errorf("failed to close RAG: %w", err))
+	}
 
-	log.Info().Msgf("starting HTTP server on %s:%s", s.host, s.port)
-	return http.ListenAndServe(fmt.Sprintf("%s:%s", s.host, s.port), nil)
+	return errors.Join(errs...)
+}
+
+// routes returns the handler of the API endpoints: the versioned API under
+// apiv1.Prefix, and the legacy unversioned endpoints
+func (s *Server) routes() http.Handler {
+	mux := http.NewServeMux()
+	handle := func(pattern string, handler http.HandlerFunc) {
+		mux.HandleFunc(pattern, telemetry.InstrumentHandler(pattern, s.authenticate(s.limit(handler))))
+	}
+
+	mux.HandleFunc("/healthz", telemetry.InstrumentHandler("/healthz", s.handleHealthz))
+	mux.HandleFunc("/readyz", telemetry.InstrumentHandler("/readyz", s.handleReadyz))
+	handle("/query", s.handleQuery)
+	handle("/metadata/conflicts", s.handleMetadataConflicts)
+	handle("/sync/report", s.handleSyncReport)
+	handle("/annotations", s.adminOnly(s.handleAnnotations, http.MethodGet))
+	handle("/examples", s.adminOnly(s.handleExamples, http.MethodGet))
+	handle("/examples/approve", s.adminOnly(s.handleApproveExample))
+	handle("/feedback", s.handleFeedback)
+	handle("/audit", s.adminOnly(s.handleAudit))
+	handle("/quota", s.adminOnly(s.handleQuota))
+	mux.Handle("/metrics", telemetry.Handler())
+
+	apiRoutes := s.apiRoutes()
+	s.openAPI = s.apiDocument(apiRoutes)
+	s.handleAPI(mux, apiRoutes)
+
+	return mux
 }
 
 func (s *Server) handleHealthz(w http.ResponseWriter, r *http.Request) {
-	log.Debug().Msgf("received request: %s %s from %s", r.Method, r.URL.Path, r.RemoteAddr)
+	log.Debug().Ctx(r.Context()).Msgf("received request: %s %s from %s", r.Method, r.URL.Path, r.RemoteAddr)
 	w.WriteHeader(http.StatusOK)
 }
 
+// handleReadyz reports the dependency checks, with a 503 status while a
+// gating check fails
+func (s *Server) handleReadyz(w http.ResponseWriter, r *http.Request) {
+	log.Debug().Ctx(r.Context()).Msgf("received request: %s %s from %s", r.Method, r.URL.Path, r.RemoteAddr)
+
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	readiness := s.rag.Readiness(r.Context())
+	for name, check := range readiness.Checks {
+		if check.Status != rag.StatusOK {
+			log.Warn().Ctx(r.Context()).Msgf("readiness check %s failed: %s", name, check.Error)
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if !readiness.Ready() {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+
+	// The status is already written, so encoding errors can only be logged
+	if err := json.NewEncoder(w).Encode(readiness); err != nil {
+		log.Error().Err(err).Msg("failed to encode response")
+	}
+}
+
 func (s *Server) handleQuery(w http.ResponseWriter, r *http.Request) {
-	log.Debug().Msgf("received request: %s %s from %s", r.Method, r.URL.Path, r.RemoteAddr)
+	log.Debug().Ctx(r.Context()).Msgf("received request: %s %s from %s", r.Method, r.URL.Path, r.RemoteAddr)
 
 	if r.Method != http.MethodPost {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
@@ -65,16 +226,336 @@ func (s *Server) handleQuery(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	response, err := s.rag.Query(request.Query)
+	response, err := s.rag.Query(withIdentity(r), request.Query)
+	if r.Context().Err() != nil {
+		log.Debug().Ctx(r.Context()).Msgf("client %s disconnected before the query was answered", r.RemoteAddr)
+		return
+	}
+	var exceeded *quota.ExceededError
+	if errors.As(err, &exceeded) {
+		log.Warn().Ctx(r.Context()).Err(err).Msg("rejected query over token budget")
+		tooManyRequests(w, r, exceeded.RetryAfter, apiv1.CodeQuotaExceeded, fmt.Sprintf("Quota exceeded: %v", err))
+		return
+	}
+	if errors.Is(err, auth.ErrForbidden) {
+		log.Warn().Ctx(r.Context()).Err(err).Msg("rejected forbidden query")
+		http.Error(w, fmt.Sprintf("Forbidden query: %v", err), http.StatusForbidden)
+		return
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		log.Error().Ctx(r.Context()).Err(err).Msg("query timed out")
+		http.Error(w, fmt.Sprintf("Query timed out: %v", err), http.StatusGatewayTimeout)
+		return
+	}
 	if err != nil {
-		log.Error().Err(err).Msg("failed to process query")
+		log.Error().Ctx(r.Context()).Err(err).Msg("failed to process query")
 		http.Error(w, fmt.Sprintf("Failed to process query: %v", err), http.StatusInternalServerError)
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	err = json.NewEncoder(w).Encode(map[string]string{
-		"response": response,
+	err = json.NewEncoder(w).Encode(struct {
+		ID       string         `json:"id,omitempty"`
+		Response string         `json:"response"`
+		Cache    *rag.CacheInfo `json:"cache,omitempty"`
+		TraceID  string         `json:"trace_id,omitempty"`
+	}{
+		ID:       response.ID,
+		Response: response.PromQL,
+		Cache:    response.Cache,
+		TraceID:  telemetry.TraceID(r.Context()),
+	})
+	if err != nil {
+		log.Error().Err(err).Msg("failed to encode response")
+		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+		return
+	}
+}
+
+func (s *Server) handleMetadataConflicts(w http.ResponseWriter, r *http.Request) {
+	log.Debug().Ctx(r.Context()).Msgf("received request: %s %s from %s", r.Method, r.URL.Path, r.RemoteAddr)
+
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	err := json.NewEncoder(w).Encode(map[string]any{
+		"metrics": visibleMetrics(r, s.rag.MetadataConflicts()),
+	})
+	if err != nil {
+		log.Error().Err(err).Msg("failed to encode response")
+		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+		return
+	}
+}
+
+func (s *Server) handleSyncReport(w http.ResponseWriter, r *http.Request) {
+	log.Debug().Ctx(r.Context()).Msgf("received request: %s %s from %s", r.Method, r.URL.Path, r.RemoteAddr)
+
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	report := s.rag.LastSyncReport()
+	if report == nil {
+		http.Error(w, "No synchronization has completed yet", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	err := json.NewEncoder(w).Encode(visibleSyncReport(r, report))
+	if err != nil {
+		log.Error().Err(err).Msg("failed to encode response")
+		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+		return
+	}
+}
+
+func (s *Server) handleAnnotations(w http.ResponseWriter, r *http.Request) {
+	log.Debug().Ctx(r.Context()).Msgf("received request: %s %s from %s", r.Method, r.URL.Path, r.RemoteAddr)
+
+	switch r.Method {
+	case http.MethodGet:
+		w.Header().Set("Content-Type", "application/json")
+		err := json.NewEncoder(w).Encode(map[string]any{
+			"annotations": visibleAnnotations(r, s.rag.Annotations()),
+		})
+		if err != nil {
+			log.Error().Err(err).Msg("failed to encode response")
+			http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+			return
+		}
+	case http.MethodPut:
+		var annotation annotations.Annotation
+		if err := json.NewDecoder(r.Body).Decode(&annotation); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		if err := annotation.Validate(); err != nil {
+			http.Error(w, fmt.Sprintf("Invalid annotation: %v", err), http.StatusBadRequest)
+			return
+		}
+
+		if err := s.rag.PutAnnotation(r.Context(), annotation); err != nil {
+			log.Error().Err(err).Msg("failed to put annotation")
+			http.Error(w, fmt.Sprintf("Failed to put annotation: %v", err), http.StatusInternalServerError)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	case http.MethodDelete:
+		match := r.URL.Query().Get("match")
+		if match == "" {
+			http.Error(w, "Missing match parameter", http.StatusBadRequest)
+			return
+		}
+
+		deleted, err := s.rag.DeleteAnnotation(r.Context(), match)
+		if err != nil {
+			log.Error().Err(err).Msg("failed to delete annotation")
+			http.Error(w, fmt.Sprintf("Failed to delete annotation: %v", err), http.StatusInternalServerError)
+			return
+		}
+		if !deleted {
+			http.Error(w, "Annotation not found", http.StatusNotFound)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (s *Server) handleExamples(w http.ResponseWriter, r *http.Request) {
+	log.Debug().Ctx(r.Context()).Msgf("received request: %s %s from %s", r.Method, r.URL.Path, r.RemoteAddr)
+
+	switch r.Method {
+	case http.MethodGet:
+		list, err := s.rag.Examples(r.Context())
+		if err != nil {
+			log.Error().Err(err).Msg("failed to list examples")
+			http.Error(w, fmt.Sprintf("Failed to list examples: %v", err), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		err = json.NewEncoder(w).Encode(map[string]any{
+			"examples": visibleExamples(r, list),
+		})
+		if err != nil {
+			log.Error().Err(err).Msg("failed to encode response")
+			http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+			return
+		}
+	case http.MethodPost:
+		var example examples.Example
+		if err := json.NewDecoder(r.Body).Decode(&example); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		if err := example.Validate(); err != nil {
+			http.Error(w, fmt.Sprintf("Invalid example: %v", err), http.StatusBadRequest)
+			return
+		}
+
+		if err := s.rag.AddExample(r.Context(), &example); err != nil {
+			log.Error().Err(err).Msg("failed to add example")
+			http.Error(w, fmt.Sprintf("Failed to add example: %v", err), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		if err := json.NewEncoder(w).Encode(example); err != nil {
+			log.Error().Err(err).Msg("failed to encode response")
+			return
+		}
+	case http.MethodDelete:
+		id := r.URL.Query().Get("id")
+		if id == "" {
+			http.Error(w, "Missing id parameter", http.StatusBadRequest)
+			return
+		}
+
+		deleted, err := s.rag.DeleteExample(r.Context(), id)
+		if err != nil {
+			log.Error().Err(err).Msg("failed to delete example")
+			http.Error(w, fmt.Sprintf("Failed to delete example: %v", err), http.StatusInternalServerError)
+			return
+		}
+		if !deleted {
+			http.Error(w, "Example not found", http.StatusNotFound)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (s *Server) handleApproveExample(w http.ResponseWriter, r *http.Request) {
+	log.Debug().Ctx(r.Context()).Msgf("received request: %s %s from %s", r.Method, r.URL.Path, r.RemoteAddr)
+
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var request struct {
+		Query  string `json:"query"`
+		PromQL string `json:"promql"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if request.Query == "" || request.PromQL == "" {
+		http.Error(w, "Both query and promql are required", http.StatusBadRequest)
+		return
+	}
+
+	captured, err := s.rag.CaptureApprovedAnswer(r.Context(), request.Query, request.PromQL)
+	if err != nil {
+		log.Error().Err(err).Msg("failed to capture approved answer")
+		http.Error(w, fmt.Sprintf("Failed to capture approved answer: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	err = json.NewEncoder(w).Encode(map[string]bool{
+		"captured": captured,
+	})
+	if err != nil {
+		log.Error().Err(err).Msg("failed to encode response")
+		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+		return
+	}
+}
+
+func (s *Server) handleFeedback(w http.ResponseWriter, r *http.Request) {
+	log.Debug().Ctx(r.Context()).Msgf("received request: %s %s from %s", r.Method, r.URL.Path, r.RemoteAddr)
+
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var request feedback.Feedback
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := request.Validate(); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid feedback: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	captured, err := s.rag.Feedback(r.Context(), request)
+	switch {
+	case errors.Is(err, feedback.ErrQueryNotFound):
+		http.Error(w, "Query not found", http.StatusNotFound)
+		return
+	case errors.Is(err, rag.ErrFeedbackDisabled):
+		http.Error(w, "Feedback is disabled", http.StatusNotImplemented)
+		return
+	case errors.Is(err, auth.ErrForbidden):
+		http.Error(w, fmt.Sprintf("Forbidden correction: %v", err), http.StatusForbidden)
+		return
+	case err != nil:
+		log.Error().Err(err).Msg("failed to store feedback")
+		http.Error(w, fmt.Sprintf("Failed to store feedback: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	err = json.NewEncoder(w).Encode(map[string]bool{
+		"captured": captured,
+	})
+	if err != nil {
+		log.Error().Err(err).Msg("failed to encode response")
+		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+		return
+	}
+}
+
+func (s *Server) handleAudit(w http.ResponseWriter, r *http.Request) {
+	log.Debug().Ctx(r.Context()).Msgf("received request: %s %s from %s", r.Method, r.URL.Path, r.RemoteAddr)
+
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	filter, err := parseAuditFilter(r)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid filter: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	records, err := s.rag.AuditRecords(r.Context(), filter)
+	switch {
+	case errors.Is(err, rag.ErrAuditDisabled):
+		http.Error(w, "Audit log is disabled", http.StatusNotImplemented)
+		return
+	case err != nil:
+		log.Error().Ctx(r.Context()).Err(err).Msg("failed to search audit records")
+		http.Error(w, fmt.Sprintf("Failed to search audit records: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	err = json.NewEncoder(w).Encode(map[string]any{
+		"records": records,
 	})
 	if err != nil {
 		log.Error().Err(err).Msg("failed to encode response")
@@ -82,3 +563,32 @@ func (s *Server) handleQuery(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 }
+
+// parseAuditFilter reads the audit search filter from the since, until
+// (RFC 3339), user, outcome and limit query parameters
+func parseAuditFilter(r *http.Request) (audit.Filter, error) {
+	params := r.URL.Query()
+	filter := audit.Filter{
+		User:    params.Get("user"),
+		Outcome: params.Get("outcome"),
+	}
+
+	var err error
+	if since := params.Get("since"); since != "" {
+		if filter.Since, err = time.Parse(time.RFC3339, since); err != nil {
+			return filter, fmt.Errorf("since must be an RFC 3339 timestamp: %w", err)
+		}
+	}
+	if until := params.Get("until"); until != "" {
+		if filter.Until, err = time.Parse(time.RFC3339, until); err != nil {
+			return filter, fmt.Errorf("until must be an RFC 3339 timestamp: %w", err)
+		}
+	}
+	if limit := params.Get("limit"); limit != "" {
+		if filter.Limit, err = strconv.Atoi(limit); err != nil {
+			return filter, fmt.Errorf("limit must be an integer: %w", err)
+		}
+	}
+
+	return filter, filter.Validate()
+}
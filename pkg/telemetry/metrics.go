@@ -0,0 +1,202 @@
+package telemetry
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "prag"
+
+// Outcomes of an instrumented operation
+const (
+	OutcomeSuccess = "success"
+	OutcomeError   = "error"
+)
+
+// Reasons an LLM answer is invalid
+const (
+	// InvalidResponseParse is an answer that is not the requested XML structure
+	InvalidResponseParse = "parse"
+	// InvalidResponsePromQL is an answer whose PromQL expression does not parse
+	InvalidResponsePromQL = "promql"
+)
+
+// registry holds the metrics of the service, so they are not mixed with the
+// ones other libraries register on the default registry
+var registry = prometheus.NewRegistry()
+
+var (
+	httpRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "Total number of HTTP requests by handler, method and status code.",
+	}, []string{"handler", "method", "code"})
+
+	httpRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "Duration of HTTP requests by handler and method.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"handler", "method"})
+
+	llmRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "llm_request_duration_seconds",
+		Help:      "Duration of LLM chat completion requests by operation and outcome.",
+		Buckets:   prometheus.ExponentialBuckets(0.1, 2, 11),
+	}, []string{"operation", "outcome"})
+
+	llmTokens = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "llm_tokens_total",
+		Help:      "Total number of tokens reported by the LLM by operation and type (prompt or completion).",
+	}, []string{"operation", "type"})
+
+	llmInvalidResponses = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "llm_invalid_responses_total",
+		Help:      "Total number of LLM answers that failed to parse or validate, by reason.",
+	}, []string{"reason"})
+
+	retrievalDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "retrieval_duration_seconds",
+		Help:      "Duration of vector database searches by backend, operation and outcome.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"backend", "operation", "outcome"})
+
+	embeddingDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "embedding_duration_seconds",
+		Help:      "Duration of encoder calls by provider, operation and outcome.",
+		Buckets:   prometheus.ExponentialBuckets(0.005, 2, 14),
+	}, []string{"provider", "operation", "outcome"})
+
+	embeddingTexts = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "embedding_texts_total",
+		Help:      "Total number of texts encoded by provider.",
+	}, []string{"provider"})
+
+	syncDuration = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "sync_duration_seconds",
+		Help:      "Duration of the synchronizations of the Prometheus metrics metadata.",
+		Buckets:   prometheus.ExponentialBuckets(1, 2, 11),
+	})
+
+	syncs = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "syncs_total",
+		Help:      "Total number of synchronizations of the Prometheus metrics metadata by outcome.",
+	}, []string{"outcome"})
+
+	syncMetrics = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "sync_metrics",
+		Help:      "Number of metrics found by the latest synchronization.",
+	})
+
+	syncSkippedMetrics = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "sync_skipped_metrics",
+		Help:      "Number of metrics stored without labels by the latest synchronization because their labels could not be discovered.",
+	})
+
+	syncLastSuccess = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "sync_last_success_timestamp_seconds",
+		Help:      "Unix timestamp of the latest successful synchronization.",
+	})
+
+	cacheLookups = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "cache_lookups_total",
+		Help:      "Total number of response cache lookups by result (miss, exact or similar).",
+	}, []string{"result"})
+)
+
+func init() {
+	registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		httpRequests,
+		httpRequestDuration,
+		llmRequestDuration,
+		llmTokens,
+		llmInvalidResponses,
+		retrievalDuration,
+		embeddingDuration,
+		embeddingTexts,
+		syncDuration,
+		syncs,
+		syncMetrics,
+		syncSkippedMetrics,
+		syncLastSuccess,
+		cacheLookups,
+	)
+}
+
+// Handler serves the metrics of the service in the Prometheus exposition format
+func Handler() http.Handler {
+	return promhttp.HandlerFor(registry, promhttp.HandlerOpts{Registry: registry})
+}
+
+// ObserveLLMRequest records the duration of an LLM request started at start
+func ObserveLLMRequest(operation string, start time.Time, err error) {
+	llmRequestDuration.WithLabelValues(operation, outcome(err)).Observe(time.Since(start).Seconds())
+}
+
+// AddLLMTokens records the tokens an LLM request used
+func AddLLMTokens(operation string, promptTokens, completionTokens int64) {
+	llmTokens.WithLabelValues(operation, "prompt").Add(float64(promptTokens))
+	llmTokens.WithLabelValues(operation, "completion").Add(float64(completionTokens))
+}
+
+// IncLLMInvalidResponse records an LLM answer that failed to parse or validate
+func IncLLMInvalidResponse(reason string) {
+	llmInvalidResponses.WithLabelValues(reason).Inc()
+}
+
+// ObserveRetrieval records the duration of a vector database search started at start
+func ObserveRetrieval(backend, operation string, start time.Time, err error) {
+	retrievalDuration.WithLabelValues(backend, operation, outcome(err)).Observe(time.Since(start).Seconds())
+}
+
+// ObserveEmbedding records the duration of an encoder call of texts started at start
+func ObserveEmbedding(provider, operation string, texts int, start time.Time, err error) {
+	embeddingDuration.WithLabelValues(provider, operation, outcome(err)).Observe(time.Since(start).Seconds())
+	if err == nil {
+		embeddingTexts.WithLabelValues(provider).Add(float64(texts))
+	}
+}
+
+// ObserveSync records a synchronization of the metrics metadata that found
+// metrics and skipped the ones whose labels could not be discovered
+func ObserveSync(duration time.Duration, metrics, skipped int, err error) {
+	syncDuration.Observe(duration.Seconds())
+	syncs.WithLabelValues(outcome(err)).Inc()
+	if err != nil {
+		return
+	}
+
+	syncMetrics.Set(float64(metrics))
+	syncSkippedMetrics.Set(float64(skipped))
+	syncLastSuccess.SetToCurrentTime()
+}
+
+// IncCacheLookup records a response cache lookup with the given result
+func IncCacheLookup(result string) {
+	cacheLookups.WithLabelValues(result).Inc()
+}
+
+func outcome(err error) string {
+	if err != nil {
+		return OutcomeError
+	}
+	return OutcomeSuccess
+}
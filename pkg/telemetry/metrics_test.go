@@ -0,0 +1,58 @@
+package telemetry_test
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/machadovilaca/prometheus-rag/pkg/telemetry"
+)
+
+var _ = Describe("Metrics", func() {
+	scrape := func() string {
+		recorder := httptest.NewRecorder()
+		telemetry.Handler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+		Expect(recorder.Code).To(Equal(http.StatusOK))
+
+		body, err := io.ReadAll(recorder.Body)
+		Expect(err).NotTo(HaveOccurred())
+		return string(body)
+	}
+
+	It("should record the requests served by an instrumented handler", func() {
+		handler := telemetry.InstrumentHandler("/teapot", func(w http.ResponseWriter, _ *http.Request) {
+			w.WriteHeader(http.StatusTeapot)
+		})
+		handler(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/teapot", nil))
+
+		body := scrape()
+		Expect(body).To(ContainSubstring(`prag_http_requests_total{code="418",handler="/teapot",method="get"} 1`))
+		Expect(body).To(ContainSubstring(`prag_http_request_duration_seconds_count{handler="/teapot",method="get"} 1`))
+	})
+
+	It("should record LLM latency and token usage", func() {
+		telemetry.ObserveLLMRequest("test", time.Now(), nil)
+		telemetry.AddLLMTokens("test", 120, 30)
+
+		body := scrape()
+		Expect(body).To(ContainSubstring(`prag_llm_request_duration_seconds_count{operation="test",outcome="success"} 1`))
+		Expect(body).To(ContainSubstring(`prag_llm_tokens_total{operation="test",type="prompt"} 120`))
+		Expect(body).To(ContainSubstring(`prag_llm_tokens_total{operation="test",type="completion"} 30`))
+	})
+
+	It("should only update the sync gauges on successful synchronizations", func() {
+		telemetry.ObserveSync(time.Second, 42, 2, nil)
+		telemetry.ObserveSync(time.Second, 0, 0, errors.New("prometheus is down"))
+
+		body := scrape()
+		Expect(body).To(ContainSubstring(`prag_syncs_total{outcome="success"} 1`))
+		Expect(body).To(ContainSubstring(`prag_syncs_total{outcome="error"} 1`))
+		Expect(body).To(ContainSubstring("prag_sync_metrics 42"))
+		Expect(body).To(ContainSubstring("prag_sync_skipped_metrics 2"))
+	})
+})
@@ -0,0 +1,135 @@
+package telemetry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const tracerName = "github.com/machadovilaca/prometheus-rag"
+
+// TraceIDHeader is the response header carrying the ID of the request trace
+const TraceIDHeader = "X-Trace-Id"
+
+// Span attributes shared across packages
+const (
+	AttributeMetrics          = attribute.Key("prag.metrics")
+	AttributeModel            = attribute.Key("gen_ai.request.model")
+	AttributePromptBytes      = attribute.Key("prag.prompt.bytes")
+	AttributeInputTokens      = attribute.Key("gen_ai.usage.input_tokens")
+	AttributeOutputTokens     = attribute.Key("gen_ai.usage.output_tokens")
+	AttributeCacheResult      = attribute.Key("prag.cache.result")
+	AttributeTexts            = attribute.Key("prag.texts")
+	AttributeBackend          = attribute.Key("prag.vectordb.backend")
+	AttributeEncoderProvider  = attribute.Key("prag.encoder.provider")
+	AttributeSyncedMetrics    = attribute.Key("prag.sync.metrics")
+	AttributeSkippedMetrics   = attribute.Key("prag.sync.skipped_metrics")
+	AttributeDescribedMetrics = attribute.Key("prag.sync.described_metrics")
+)
+
+// TracingConfig configures the export of traces
+type TracingConfig struct {
+	// Endpoint is the host:port of the OTLP gRPC collector
+	Endpoint string
+	// Insecure disables TLS towards the collector
+	Insecure bool
+	// SampleRatio is the fraction of traces sampled, unless the caller already sampled them
+	SampleRatio float64
+	// ServiceName identifies the service in the traces
+	ServiceName string
+}
+
+// SetupTracing installs a tracer provider exporting spans to an OTLP
+// collector, and a log hook adding the trace ID to the events logged with a
+// traced context. It returns a function flushing the pending spans and
+// stopping the export. Spans are not recorded unless it is called.
+func SetupTracing(ctx context.Context, cfg TracingConfig) (func(context.Context) error, error) {
+	if cfg.Endpoint == "" {
+		return nil, errors.New("tracing endpoint is required")
+	}
+
+	options := []otlptracegrpc.Option{otlptracegrpc.WithEndpoint(cfg.Endpoint)}
+	if cfg.Insecure {
+		options = append(options, otlptracegrpc.WithInsecure())
+	}
+
+	exporter, err := otlptracegrpc.New(ctx, options...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create OTLP trace exporter: %w", err)
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(
+		semconv.SchemaURL,
+		semconv.ServiceName(cfg.ServiceName),
+	))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create trace resource: %w", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.SampleRatio))),
+	)
+
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+	log.Logger = log.Logger.Hook(traceHook{})
+
+	return provider.Shutdown, nil
+}
+
+// StartSpan starts a span named name as a child of the span in ctx
+func StartSpan(ctx context.Context, name string, attributes ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(tracerName).Start(ctx, name, trace.WithAttributes(attributes...))
+}
+
+// EndSpan records err on span, if any, and ends it
+func EndSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// TraceID returns the ID of the trace in ctx, empty if it is not sampled
+func TraceID(ctx context.Context) string {
+	spanContext := trace.SpanContextFromContext(ctx)
+	if !spanContext.IsSampled() {
+		return ""
+	}
+	return spanContext.TraceID().String()
+}
+
+// traceHook adds the IDs of the span in the event context to the event
+type traceHook struct{}
+
+func (traceHook) Run(e *zerolog.Event, _ zerolog.Level, _ string) {
+	ctx := e.GetCtx()
+	if ctx == nil {
+		return
+	}
+
+	spanContext := trace.SpanContextFromContext(ctx)
+	if !spanContext.IsValid() {
+		return
+	}
+
+	e.Str("trace_id", spanContext.TraceID().String()).Str("span_id", spanContext.SpanID().String())
+}
@@ -0,0 +1,71 @@
+package telemetry_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/machadovilaca/prometheus-rag/pkg/telemetry"
+)
+
+var _ = Describe("Tracing", func() {
+	var recorder *tracetest.SpanRecorder
+
+	BeforeEach(func() {
+		previous := otel.GetTracerProvider()
+		recorder = tracetest.NewSpanRecorder()
+		otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+
+		DeferCleanup(func() {
+			otel.SetTracerProvider(previous)
+		})
+	})
+
+	It("should return no trace ID without a sampled span", func() {
+		Expect(telemetry.TraceID(context.Background())).To(BeEmpty())
+	})
+
+	It("should trace requests and return their trace ID", func() {
+		var handlerTraceID string
+		handler := telemetry.InstrumentHandler("/traced", func(w http.ResponseWriter, r *http.Request) {
+			handlerTraceID = telemetry.TraceID(r.Context())
+			w.WriteHeader(http.StatusInternalServerError)
+		})
+
+		response := httptest.NewRecorder()
+		handler(response, httptest.NewRequest(http.MethodPost, "/traced", nil))
+
+		Expect(handlerTraceID).NotTo(BeEmpty())
+		Expect(response.Header().Get(telemetry.TraceIDHeader)).To(Equal(handlerTraceID))
+
+		spans := recorder.Ended()
+		Expect(spans).To(HaveLen(1))
+		Expect(spans[0].Name()).To(Equal("POST /traced"))
+		Expect(spans[0].SpanKind()).To(Equal(trace.SpanKindServer))
+		Expect(spans[0].Status().Code).To(Equal(codes.Error))
+	})
+
+	It("should record errors on child spans", func() {
+		ctx, parent := telemetry.StartSpan(context.Background(), "parent")
+		_, child := telemetry.StartSpan(ctx, "child", telemetry.AttributeBackend.String("sqlite3"))
+		telemetry.EndSpan(child, errors.New("search failed"))
+		telemetry.EndSpan(parent, nil)
+
+		spans := recorder.Ended()
+		Expect(spans).To(HaveLen(2))
+		Expect(spans[0].Name()).To(Equal("child"))
+		Expect(spans[0].Parent().SpanID()).To(Equal(parent.SpanContext().SpanID()))
+		Expect(spans[0].Status().Description).To(Equal("search failed"))
+		Expect(spans[0].Attributes()).To(ContainElement(telemetry.AttributeBackend.String("sqlite3")))
+		Expect(spans[1].Status().Code).To(Equal(codes.Unset))
+	})
+})
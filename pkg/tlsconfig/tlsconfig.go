@@ -0,0 +1,234 @@
+// Package tlsconfig builds the TLS configuration of the HTTP server from
+// certificate files, reloading them when they change so renewed certificates
+// are served without a restart.
+package tlsconfig
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// DefaultCheckInterval is how often the files are checked for changes when
+// the configuration does not set it
+const DefaultCheckInterval = 10 * time.Second
+
+// Client certificate verification modes
+const (
+	// ClientAuthRequire rejects clients without a certificate signed by the client CA
+	ClientAuthRequire = "require"
+	// ClientAuthOptional verifies the certificates sent, but accepts clients without one
+	ClientAuthOptional = "optional"
+)
+
+// Config represents the TLS configuration of the server
+type Config struct {
+	CertFile string
+	KeyFile  string
+
+	// ClientCAFile is the CA bundle verifying client certificates, empty
+	// disables client certificate verification
+	ClientCAFile string
+	// ClientAuth is ClientAuthRequire or ClientAuthOptional
+	ClientAuth string
+
+	// HTTP2 offers HTTP/2 to clients during the TLS handshake
+	HTTP2 bool
+
+	// CheckInterval is how often the files are checked for changes, on handshakes
+	CheckInterval time.Duration
+}
+
+// Reloader serves the TLS configuration built from the files of a Config,
+// rebuilding it when their modification time or size changes. A file that
+// fails to load keeps the previous configuration until it is fixed.
+type Reloader struct {
+	config Config
+
+	mu      sync.Mutex
+	current *tls.Config
+	stamps  []fileStamp
+	checked time.Time
+}
+
+// fileStamp identifies a version of a file
+type fileStamp struct {
+	modTime time.Time
+	size    int64
+}
+
+// New creates a Reloader, failing if the files cannot be loaded
+func New(config Config) (*Reloader, error) {
+	if config.CertFile == "" || config.KeyFile == "" {
+		return nil, fmt.Errorf("certificate and key files are required")
+	}
+
+	switch config.ClientAuth {
+	case "", ClientAuthRequire, ClientAuthOptional:
+	default:
+		return nil, fmt.Errorf("unsupported client auth mode: %s", config.ClientAuth)
+	}
+
+	if config.CheckInterval <= 0 {
+		config.CheckInterval = DefaultCheckInterval
+	}
+
+	r := &Reloader{config: config}
+	if err := r.reload(); err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// NextProtos returns the application protocols offered during the handshake
+func (r *Reloader) NextProtos() []string {
+	if r.config.HTTP2 {
+		return []string{"h2", "http/1.1"}
+	}
+	return []string{"http/1.1"}
+}
+
+// TLSConfig returns the configuration of the server, which resolves the
+// configuration of each handshake from the files
+func (r *Reloader) TLSConfig() *tls.Config {
+	return &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		NextProtos:         r.NextProtos(),
+		GetConfigForClient: r.GetConfigForClient,
+		// Handshakes use GetConfigForClient, but the server requires a certificate source
+		GetCertificate: func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+			return &r.Current().Certificates[0], nil
+		},
+	}
+}
+
+// GetConfigForClient returns the current configuration, reloading the files
+// first if they were last checked more than CheckInterval ago
+func (r *Reloader) GetConfigForClient(*tls.ClientHelloInfo) (*tls.Config, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if time.Since(r.checked) >= r.config.CheckInterval {
+		r.checked = time.Now()
+
+		stamps, err := r.stat()
+		if err != nil {
+			log.Error().Err(err).Msg("failed to check TLS files, serving the previous certificate")
+		} else if !equalStamps(stamps, r.stamps) {
+			if err := r.load(stamps); err != nil {
+				log.Error().Err(err).Msg("failed to reload TLS files, serving the previous certificate")
+			} else {
+				log.Info().Msgf("reloaded TLS certificate from %s", r.config.CertFile)
+			}
+		}
+	}
+
+	return r.current, nil
+}
+
+// Current returns the configuration built from the files last loaded
+func (r *Reloader) Current() *tls.Config {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.current
+}
+
+// reload loads the files unconditionally
+func (r *Reloader) reload() error {
+	stamps, err := r.stat()
+	if err != nil {
+		return err
+	}
+
+	r.checked = time.Now()
+	return r.load(stamps)
+}
+
+// load builds the configuration from the files, recording their stamps only
+// on success so a failed load is retried on the next check
+func (r *Reloader) load(stamps []fileStamp) error {
+	certificate, err := tls.LoadX509KeyPair(r.config.CertFile, r.config.KeyFile)
+	if err != nil {
+		return fmt.Errorf("failed to load TLS certificate: %w", err)
+	}
+
+	config := &tls.Config{
+		MinVersion:   tls.VersionTLS12,
+		NextProtos:   r.NextProtos(),
+		Certificates: []tls.Certificate{certificate},
+	}
+
+	if r.config.ClientCAFile != "" {
+		pool, err := loadCertPool(r.config.ClientCAFile)
+		if err != nil {
+			return err
+		}
+
+		config.ClientCAs = pool
+		config.ClientAuth = tls.RequireAndVerifyClientCert
+		if r.config.ClientAuth == ClientAuthOptional {
+			config.ClientAuth = tls.VerifyClientCertIfGiven
+		}
+	}
+
+	r.current = config
+	r.stamps = stamps
+	return nil
+}
+
+// files returns the paths of the files the configuration is built from
+func (r *Reloader) files() []string {
+	files := []string{r.config.CertFile, r.config.KeyFile}
+	if r.config.ClientCAFile != "" {
+		files = append(files, r.config.ClientCAFile)
+	}
+	return files
+}
+
+// stat returns the stamps of the files, following symlinks so the files
+// of mounted secrets, swapped through symlinks, are seen to change
+func (r *Reloader) stat() ([]fileStamp, error) {
+	files := r.files()
+	stamps := make([]fileStamp, len(files))
+	for i, file := range files {
+		info, err := os.Stat(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to stat %s: %w", file, err)
+		}
+		stamps[i] = fileStamp{modTime: info.ModTime(), size: info.Size()}
+	}
+	return stamps, nil
+}
+
+func equalStamps(a, b []fileStamp) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !a[i].modTime.Equal(b[i].modTime) || a[i].size != b[i].size {
+			return false
+		}
+	}
+	return true
+}
+
+// loadCertPool reads a PEM bundle of CA certificates
+func loadCertPool(path string) (*x509.CertPool, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read client CA file: %w", err)
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, fmt.Errorf("no certificates found in client CA file %s", path)
+	}
+	return pool, nil
+}
@@ -0,0 +1,252 @@
+package tlsconfig_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/machadovilaca/prometheus-rag/pkg/tlsconfig"
+)
+
+// authority is a CA issuing the certificates of the tests
+type authority struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pool *x509.CertPool
+	pem  []byte
+}
+
+func newAuthority() *authority {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	Expect(err).NotTo(HaveOccurred())
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test-ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	Expect(err).NotTo(HaveOccurred())
+	cert, err := x509.ParseCertificate(der)
+	Expect(err).NotTo(HaveOccurred())
+
+	pool := x509.NewCertPool()
+	pool.AddCert(cert)
+
+	return &authority{
+		cert: cert,
+		key:  key,
+		pool: pool,
+		pem:  pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+	}
+}
+
+// issue returns the PEM certificate and key of a leaf with the given serial number
+func (a *authority) issue(serial int64, usage x509.ExtKeyUsage) (certPEM, keyPEM []byte) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	Expect(err).NotTo(HaveOccurred())
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: "client"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, a.cert, &key.PublicKey, a.key)
+	Expect(err).NotTo(HaveOccurred())
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	Expect(err).NotTo(HaveOccurred())
+
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+}
+
+var _ = Describe("Reloader", func() {
+	var (
+		ca       *authority
+		dir      string
+		certFile string
+		keyFile  string
+		caFile   string
+	)
+
+	// writeServerCert writes a server certificate, dating the files after the previous ones
+	writeServerCert := func(serial int64, modTime time.Time) {
+		certPEM, keyPEM := ca.issue(serial, x509.ExtKeyUsageServerAuth)
+		Expect(os.WriteFile(certFile, certPEM, 0600)).To(Succeed())
+		Expect(os.WriteFile(keyFile, keyPEM, 0600)).To(Succeed())
+		Expect(os.Chtimes(certFile, modTime, modTime)).To(Succeed())
+		Expect(os.Chtimes(keyFile, modTime, modTime)).To(Succeed())
+	}
+
+	serve := func(reloader *tlsconfig.Reloader) string {
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		Expect(err).NotTo(HaveOccurred())
+
+		server := &http.Server{
+			Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			}),
+			TLSConfig: reloader.TLSConfig(),
+		}
+		go func() {
+			_ = server.ServeTLS(listener, "", "")
+		}()
+		DeferCleanup(server.Close)
+
+		return "https://" + listener.Addr().String()
+	}
+
+	get := func(url string, certificates ...tls.Certificate) (*http.Response, error) {
+		client := &http.Client{Transport: &http.Transport{
+			TLSClientConfig:   &tls.Config{RootCAs: ca.pool, Certificates: certificates},
+			ForceAttemptHTTP2: true,
+			DisableKeepAlives: true,
+		}}
+		resp, err := client.Get(url)
+		if err == nil {
+			_ = resp.Body.Close()
+		}
+		return resp, err
+	}
+
+	clientCertificate := func() tls.Certificate {
+		certPEM, keyPEM := ca.issue(100, x509.ExtKeyUsageClientAuth)
+		certificate, err := tls.X509KeyPair(certPEM, keyPEM)
+		Expect(err).NotTo(HaveOccurred())
+		return certificate
+	}
+
+	BeforeEach(func() {
+		ca = newAuthority()
+		dir = GinkgoT().TempDir()
+		certFile = filepath.Join(dir, "tls.crt")
+		keyFile = filepath.Join(dir, "tls.key")
+		caFile = filepath.Join(dir, "ca.crt")
+
+		writeServerCert(2, time.Now().Add(-time.Minute))
+		Expect(os.WriteFile(caFile, ca.pem, 0600)).To(Succeed())
+	})
+
+	It("should fail when the files cannot be loaded", func() {
+		_, err := tlsconfig.New(tlsconfig.Config{CertFile: certFile, KeyFile: filepath.Join(dir, "missing.key")})
+		Expect(err).To(HaveOccurred())
+
+		_, err = tlsconfig.New(tlsconfig.Config{CertFile: certFile, KeyFile: keyFile, ClientCAFile: keyFile})
+		Expect(err).To(MatchError(ContainSubstring("no certificates found")))
+	})
+
+	It("should serve HTTP/2 when enabled", func() {
+		reloader, err := tlsconfig.New(tlsconfig.Config{CertFile: certFile, KeyFile: keyFile, HTTP2: true})
+		Expect(err).NotTo(HaveOccurred())
+
+		resp, err := get(serve(reloader))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resp.Proto).To(Equal("HTTP/2.0"))
+		Expect(resp.TLS.PeerCertificates[0].SerialNumber.Int64()).To(BeEquivalentTo(2))
+	})
+
+	It("should serve HTTP/1.1 when HTTP/2 is disabled", func() {
+		reloader, err := tlsconfig.New(tlsconfig.Config{CertFile: certFile, KeyFile: keyFile})
+		Expect(err).NotTo(HaveOccurred())
+
+		resp, err := get(serve(reloader))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resp.Proto).To(Equal("HTTP/1.1"))
+	})
+
+	It("should serve the new certificate once the files change", func() {
+		reloader, err := tlsconfig.New(tlsconfig.Config{
+			CertFile:      certFile,
+			KeyFile:       keyFile,
+			CheckInterval: time.Millisecond,
+		})
+		Expect(err).NotTo(HaveOccurred())
+		url := serve(reloader)
+
+		writeServerCert(3, time.Now())
+		time.Sleep(5 * time.Millisecond)
+
+		resp, err := get(url)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resp.TLS.PeerCertificates[0].SerialNumber.Int64()).To(BeEquivalentTo(3))
+	})
+
+	It("should keep the previous certificate when the new files are invalid", func() {
+		reloader, err := tlsconfig.New(tlsconfig.Config{
+			CertFile:      certFile,
+			KeyFile:       keyFile,
+			CheckInterval: time.Millisecond,
+		})
+		Expect(err).NotTo(HaveOccurred())
+		url := serve(reloader)
+
+		Expect(os.WriteFile(certFile, []byte("not a certificate"), 0600)).To(Succeed())
+		time.Sleep(5 * time.Millisecond)
+
+		resp, err := get(url)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resp.TLS.PeerCertificates[0].SerialNumber.Int64()).To(BeEquivalentTo(2))
+	})
+
+	It("should require a client certificate signed by the client CA", func() {
+		reloader, err := tlsconfig.New(tlsconfig.Config{
+			CertFile:     certFile,
+			KeyFile:      keyFile,
+			ClientCAFile: caFile,
+			ClientAuth:   tlsconfig.ClientAuthRequire,
+		})
+		Expect(err).NotTo(HaveOccurred())
+		url := serve(reloader)
+
+		_, err = get(url)
+		Expect(err).To(HaveOccurred())
+
+		resp, err := get(url, clientCertificate())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+	})
+
+	It("should accept clients without a certificate when client auth is optional", func() {
+		reloader, err := tlsconfig.New(tlsconfig.Config{
+			CertFile:     certFile,
+			KeyFile:      keyFile,
+			ClientCAFile: caFile,
+			ClientAuth:   tlsconfig.ClientAuthOptional,
+		})
+		Expect(err).NotTo(HaveOccurred())
+		url := serve(reloader)
+
+		resp, err := get(url)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+
+		certPEM, keyPEM := newAuthority().issue(101, x509.ExtKeyUsageClientAuth)
+		untrusted, err := tls.X509KeyPair(certPEM, keyPEM)
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = get(url, untrusted)
+		Expect(err).To(HaveOccurred())
+	})
+})
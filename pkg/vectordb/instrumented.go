@@ -0,0 +1,71 @@
+package vectordb
+
+import (
+	"context"
+	"time"
+
+	"github.com/machadovilaca/prometheus-rag/pkg/examples"
+	"github.com/machadovilaca/prometheus-rag/pkg/prometheus"
+	"github.com/machadovilaca/prometheus-rag/pkg/telemetry"
+)
+
+// Operations reported in the retrieval metrics
+const (
+	operationSearchMetrics  = "search_metrics"
+	operationSearchExamples = "search_examples"
+)
+
+// instrumentedClient records the latency of the metric searches of a Client
+// and traces its searches and batch additions
+type instrumentedClient struct {
+	Client
+
+	backend string
+}
+
+func (c *instrumentedClient) BatchAddMetricMetadata(ctx context.Context, metadata []*prometheus.MetricMetadata) error {
+	ctx, span := telemetry.StartSpan(ctx, "vectordb.BatchAddMetricMetadata",
+		telemetry.AttributeBackend.String(c.backend),
+		telemetry.AttributeTexts.Int(len(metadata)),
+	)
+	err := c.Client.BatchAddMetricMetadata(ctx, metadata)
+	telemetry.EndSpan(span, err)
+
+	return err
+}
+
+func (c *instrumentedClient) SearchMetrics(ctx context.Context, query string, limit uint64) ([]*prometheus.MetricMetadata, error) {
+	ctx, span := telemetry.StartSpan(ctx, "vectordb.SearchMetrics", telemetry.AttributeBackend.String(c.backend))
+	start := time.Now()
+
+	metrics, err := c.Client.SearchMetrics(ctx, query, limit)
+	telemetry.ObserveRetrieval(c.backend, operationSearchMetrics, start, err)
+
+	names := make([]string, len(metrics))
+	for i, metric := range metrics {
+		names[i] = metric.Name
+	}
+	span.SetAttributes(telemetry.AttributeMetrics.StringSlice(names))
+	telemetry.EndSpan(span, err)
+
+	return metrics, err
+}
+
+// instrumentedExampleStore records the latency of the example searches of an
+// ExampleStore and traces them
+type instrumentedExampleStore struct {
+	ExampleStore
+
+	backend string
+}
+
+func (s *instrumentedExampleStore) SearchExamples(ctx context.Context, question string, limit uint64) ([]*examples.Example, error) {
+	ctx, span := telemetry.StartSpan(ctx, "vectordb.SearchExamples", telemetry.AttributeBackend.String(s.backend))
+	start := time.Now()
+
+	found, err := s.ExampleStore.SearchExamples(ctx, question, limit)
+	telemetry.ObserveRetrieval(s.backend, operationSearchExamples, start, err)
+	telemetry.EndSpan(span, err)
+
+	return found, err
+}
@@ -8,17 +8,18 @@ import (
 	"github.com/qdrant/go-client/qdrant"
 	"github.com/rs/zerolog/log"
 
+	"github.com/machadovilaca/prometheus-rag/pkg/embeddings"
 	"github.com/machadovilaca/prometheus-rag/pkg/prometheus"
 )
 
-func (v *qdrantDB) AddMetricMetadata(metadata *prometheus.MetricMetadata) error {
-	pointStruct, err := v.newPointStruct(metadata)
+func (v *qdrantDB) AddMetricMetadata(ctx context.Context, metadata *prometheus.MetricMetadata) error {
+	pointStruct, err := v.newPointStruct(ctx, metadata)
 	if err != nil {
 		return fmt.Errorf("failed to create point struct: %w", err)
 	}
 
 	_, err = v.client.Upsert(
-		context.Background(),
+		ctx,
 		&qdrant.UpsertPoints{
 			CollectionName: v.collectionName,
 			Points:         []*qdrant.PointStruct{pointStruct},
@@ -31,25 +32,33 @@ func (v *qdrantDB) AddMetricMetadata(metadata *prometheus.MetricMetadata) error
 	return nil
 }
 
-func (v *qdrantDB) BatchAddMetricMetadata(metadata []*prometheus.MetricMetadata) error {
+func (v *qdrantDB) BatchAddMetricMetadata(ctx context.Context, metadata []*prometheus.MetricMetadata) error {
 	if len(metadata) == 0 {
 		log.Info().Msg("skipping batch add of metric metadata because there are none")
 		return nil
 	}
 
-	points := make([]*qdrant.PointStruct, len(metadata))
-
+	entries := make([]prometheus.MetricMetadata, len(metadata))
 	for i, m := range metadata {
-		pointStruct, err := v.newPointStruct(m)
-		if err != nil {
-			return fmt.Errorf("failed to create point struct: %w", err)
+		if err := m.Validate(); err != nil {
+			return fmt.Errorf("invalid metric metadata '%s': %w", m.Name, err)
 		}
+		entries[i] = *m
+	}
+
+	// Encode all metric metadata in parallel before upserting
+	vectors, err := v.encoder.EncodeMetricMetadataBatch(ctx, entries, embeddings.LogProgress)
+	if err != nil {
+		return fmt.Errorf("failed to encode metric metadata: %w", err)
+	}
 
-		points[i] = pointStruct
+	points := make([]*qdrant.PointStruct, len(metadata))
+	for i, m := range metadata {
+		points[i] = newPoint(m, vectors[i])
 	}
 
-	_, err := v.client.Upsert(
-		context.Background(),
+	_, err = v.client.Upsert(
+		ctx,
 		&qdrant.UpsertPoints{
 			CollectionName: v.collectionName,
 			Points:         points,
@@ -62,17 +71,21 @@ func (v *qdrantDB) BatchAddMetricMetadata(metadata []*prometheus.MetricMetadata)
 	return nil
 }
 
-func (v *qdrantDB) newPointStruct(metadata *prometheus.MetricMetadata) (*qdrant.PointStruct, error) {
-	encodedMetadata, err := v.encoder.EncodeMetricMetadata(*metadata)
+func (v *qdrantDB) newPointStruct(ctx context.Context, metadata *prometheus.MetricMetadata) (*qdrant.PointStruct, error) {
+	encodedMetadata, err := v.encoder.EncodeMetricMetadata(ctx, *metadata)
 	if err != nil {
 		return nil, fmt.Errorf("failed to encode metric metadata: %w", err)
 	}
 
+	return newPoint(metadata, encodedMetadata), nil
+}
+
+func newPoint(metadata *prometheus.MetricMetadata, vector []float32) *qdrant.PointStruct {
 	deterministicUUID := uuid.NewSHA1(uuid.NameSpaceDNS, []byte(metadata.Name))
 
 	return &qdrant.PointStruct{
 		Id:      qdrant.NewID(deterministicUUID.String()),
-		Vectors: qdrant.NewVectorsDense(encodedMetadata),
+		Vectors: qdrant.NewVectorsDense(vector),
 		Payload: qdrant.NewValueMap(metadata.ToMap()),
-	}, nil
+	}
 }
@@ -1,6 +1,8 @@
 package qdrantdb_test
 
 import (
+	"context"
+
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 
@@ -26,7 +28,7 @@ var _ = Describe("Add", func() {
 	})
 
 	AfterEach(func() {
-		err := dbClient.DeleteCollection()
+		err := dbClient.DeleteCollection(context.Background())
 		Expect(err).NotTo(HaveOccurred())
 
 		err = dbClient.Close()
@@ -41,7 +43,7 @@ var _ = Describe("Add", func() {
 			Labels: []string{"label1", "label2"},
 		}
 
-		err := dbClient.AddMetricMetadata(metadata)
+		err := dbClient.AddMetricMetadata(context.Background(), metadata)
 		Expect(err).NotTo(HaveOccurred())
 	})
 
@@ -51,7 +53,7 @@ var _ = Describe("Add", func() {
 			Help: "Test help",
 		}
 
-		err := dbClient.AddMetricMetadata(metadata)
+		err := dbClient.AddMetricMetadata(context.Background(), metadata)
 		Expect(err).To(HaveOccurred())
 		Expect(err.Error()).To(ContainSubstring("name is required"))
 	})
@@ -63,15 +65,15 @@ var _ = Describe("Add", func() {
 			Type: "counter",
 		}
 
-		err := dbClient.AddMetricMetadata(metadata)
+		err := dbClient.AddMetricMetadata(context.Background(), metadata)
 		Expect(err).NotTo(HaveOccurred())
 
 		metadata.Help = "Updated help"
 
-		err = dbClient.AddMetricMetadata(metadata)
+		err = dbClient.AddMetricMetadata(context.Background(), metadata)
 		Expect(err).NotTo(HaveOccurred())
 
-		results, err := dbClient.SearchMetrics("test_metric", 10)
+		results, err := dbClient.SearchMetrics(context.Background(), "test_metric", 10)
 		Expect(err).NotTo(HaveOccurred())
 		Expect(results).To(HaveLen(1))
 		Expect(results[0].Help).To(Equal("Updated help"))
@@ -91,10 +93,10 @@ var _ = Describe("Add", func() {
 			},
 		}
 
-		err := dbClient.BatchAddMetricMetadata(metadata)
+		err := dbClient.BatchAddMetricMetadata(context.Background(), metadata)
 		Expect(err).NotTo(HaveOccurred())
 
-		results, err := dbClient.SearchMetrics("test", 10)
+		results, err := dbClient.SearchMetrics(context.Background(), "test", 10)
 		Expect(err).NotTo(HaveOccurred())
 		Expect(results).To(HaveLen(2))
 
@@ -103,10 +105,10 @@ var _ = Describe("Add", func() {
 	})
 
 	It("should skip batch add of metric metadata when there are none", func() {
-		err := dbClient.BatchAddMetricMetadata([]*prometheus.MetricMetadata{})
+		err := dbClient.BatchAddMetricMetadata(context.Background(), []*prometheus.MetricMetadata{})
 		Expect(err).NotTo(HaveOccurred())
 
-		results, err := dbClient.SearchMetrics("test", 10)
+		results, err := dbClient.SearchMetrics(context.Background(), "test", 10)
 		Expect(err).NotTo(HaveOccurred())
 		Expect(results).To(HaveLen(0))
 	})
@@ -0,0 +1,178 @@
+package qdrantdb
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/google/uuid"
+	"github.com/qdrant/go-client/qdrant"
+
+	"github.com/machadovilaca/prometheus-rag/pkg/examples"
+)
+
+// examplesScrollPageSize is the number of examples read per request when listing
+const examplesScrollPageSize = 256
+
+// qdrantExamples stores few-shot examples in their own collection, sharing
+// the collection management and model records of the metrics collection
+type qdrantExamples struct {
+	*qdrantDB
+}
+
+// NewExamples creates a new Qdrant client for the few-shot examples collection
+func NewExamples(cfg Config) (*qdrantExamples, error) {
+	v, err := New(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	return &qdrantExamples{v}, nil
+}
+
+func (v *qdrantExamples) AddExamples(ctx context.Context, entries []*examples.Example) error {
+	if len(entries) == 0 {
+		return nil
+	}
+
+	questions := make([]string, len(entries))
+	for i, example := range entries {
+		if err := example.Validate(); err != nil {
+			return fmt.Errorf("invalid example '%s': %w", example.Question, err)
+		}
+		example.Normalize()
+		questions[i] = example.Question
+	}
+
+	vectors, err := v.encoder.EncodeBatch(ctx, questions, nil)
+	if err != nil {
+		return fmt.Errorf("failed to encode examples: %w", err)
+	}
+
+	points := make([]*qdrant.PointStruct, len(entries))
+	for i, example := range entries {
+		points[i] = &qdrant.PointStruct{
+			Id:      examplePointID(example.ID),
+			Vectors: qdrant.NewVectorsDense(vectors[i]),
+			Payload: qdrant.NewValueMap(map[string]any{
+				"id":       example.ID,
+				"question": example.Question,
+				"promql":   example.PromQL,
+				"source":   example.Source,
+			}),
+		}
+	}
+
+	_, err = v.client.Upsert(ctx, &qdrant.UpsertPoints{
+		CollectionName: v.collectionName,
+		Points:         points,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to upsert examples: %w", err)
+	}
+
+	return nil
+}
+
+func (v *qdrantExamples) SearchExamples(ctx context.Context, question string, limit uint64) ([]*examples.Example, error) {
+	encodedQuestion, err := v.encoder.EncodeQuery(ctx, question)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode question: %w", err)
+	}
+
+	results, err := v.client.Query(ctx, &qdrant.QueryPoints{
+		CollectionName: v.collectionName,
+		Query:          qdrant.NewQueryDense(encodedQuestion),
+		Limit:          &limit,
+		WithPayload:    qdrant.NewWithPayloadEnable(true),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to search examples: %w", err)
+	}
+
+	found := make([]*examples.Example, len(results))
+	for i, result := range results {
+		found[i] = exampleFromQdrantMap(result.Payload)
+	}
+
+	return found, nil
+}
+
+func (v *qdrantExamples) ListExamples(ctx context.Context) ([]*examples.Example, error) {
+	var (
+		results = []*examples.Example{}
+		offset  *qdrant.PointId
+		limit   = uint32(examplesScrollPageSize + 1)
+	)
+
+	for {
+		points, err := v.client.Scroll(ctx, &qdrant.ScrollPoints{
+			CollectionName: v.collectionName,
+			Offset:         offset,
+			Limit:          &limit,
+			WithPayload:    qdrant.NewWithPayloadEnable(true),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list examples: %w", err)
+		}
+
+		// The extra point requested is the offset of the next page
+		page := points
+		if len(points) > examplesScrollPageSize {
+			page = points[:examplesScrollPageSize]
+		}
+		for _, point := range page {
+			results = append(results, exampleFromQdrantMap(point.Payload))
+		}
+
+		if len(points) <= examplesScrollPageSize {
+			break
+		}
+		offset = points[examplesScrollPageSize].Id
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].Question < results[j].Question
+	})
+
+	return results, nil
+}
+
+func (v *qdrantExamples) DeleteExample(ctx context.Context, id string) (bool, error) {
+	pointID := examplePointID(id)
+
+	points, err := v.client.Get(ctx, &qdrant.GetPoints{
+		CollectionName: v.collectionName,
+		Ids:            []*qdrant.PointId{pointID},
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to get example: %w", err)
+	}
+	if len(points) == 0 {
+		return false, nil
+	}
+
+	_, err = v.client.Delete(ctx, &qdrant.DeletePoints{
+		CollectionName: v.collectionName,
+		Points:         qdrant.NewPointsSelector(pointID),
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to delete example: %w", err)
+	}
+
+	return true, nil
+}
+
+// examplePointID maps the example ID to a UUID, as required by Qdrant
+func examplePointID(id string) *qdrant.PointId {
+	return qdrant.NewID(uuid.NewSHA1(uuid.NameSpaceDNS, []byte("prag-example-"+id)).String())
+}
+
+func exampleFromQdrantMap(m map[string]*qdrant.Value) *examples.Example {
+	return &examples.Example{
+		ID:       m["id"].GetStringValue(),
+		Question: m["question"].GetStringValue(),
+		PromQL:   m["promql"].GetStringValue(),
+		Source:   m["source"].GetStringValue(),
+	}
+}
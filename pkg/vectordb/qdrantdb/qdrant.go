@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 
+	"github.com/google/uuid"
 	"github.com/qdrant/go-client/qdrant"
 	"github.com/rs/zerolog/log"
 
@@ -41,17 +42,17 @@ func New(cfg Config) (*qdrantDB, error) {
 
 	v := &qdrantDB{client: client, encoder: cfg.Encoder, collectionName: cfg.CollectionName}
 
-	if err := v.CreateCollection(); err != nil {
+	if err := v.CreateCollection(context.Background()); err != nil {
 		return nil, fmt.Errorf("failed to create collection: %w", err)
 	}
 
 	return v, nil
 }
 
-func (v *qdrantDB) CreateCollection() error {
+func (v *qdrantDB) CreateCollection(ctx context.Context) error {
 	log.Info().Msgf("creating collection %s", v.collectionName)
 
-	exists, err := v.client.CollectionExists(context.Background(), v.collectionName)
+	exists, err := v.client.CollectionExists(ctx, v.collectionName)
 	if err != nil {
 		return fmt.Errorf("failed to check if collection exists: %w", err)
 	}
@@ -65,7 +66,7 @@ func (v *qdrantDB) CreateCollection() error {
 		return fmt.Errorf("failed to get encoding dimension: %w", err)
 	}
 
-	if err = v.client.CreateCollection(context.Background(), &qdrant.CreateCollection{
+	if err = v.client.CreateCollection(ctx, &qdrant.CreateCollection{
 		CollectionName: v.collectionName,
 		VectorsConfig: qdrant.NewVectorsConfig(&qdrant.VectorParams{
 			Size:     uint64(encodingDimension),
@@ -78,8 +79,114 @@ func (v *qdrantDB) CreateCollection() error {
 	return nil
 }
 
-func (v *qdrantDB) DeleteCollection() error {
-	return v.client.DeleteCollection(context.Background(), v.collectionName)
+func (v *qdrantDB) DeleteCollection(ctx context.Context) error {
+	infoExists, err := v.client.CollectionExists(ctx, v.infoCollectionName())
+	if err != nil {
+		return fmt.Errorf("failed to check if collection info exists: %w", err)
+	}
+
+	if infoExists {
+		if err := v.client.DeleteCollection(ctx, v.infoCollectionName()); err != nil {
+			return fmt.Errorf("failed to delete collection info: %w", err)
+		}
+	}
+
+	return v.client.DeleteCollection(ctx, v.collectionName)
+}
+
+// infoCollectionName is the companion collection holding the model the collection was built with
+func (v *qdrantDB) infoCollectionName() string {
+	return v.collectionName + "-info"
+}
+
+// infoPointID is the ID of the single point in the info collection
+var infoPointID = uuid.NewSHA1(uuid.NameSpaceDNS, []byte("prag-collection-info")).String()
+
+// GetCollectionModel returns the model the collection was built with, or nil if it was not recorded
+func (v *qdrantDB) GetCollectionModel(ctx context.Context) (*embeddings.ModelInfo, error) {
+	exists, err := v.client.CollectionExists(ctx, v.infoCollectionName())
+	if err != nil {
+		return nil, fmt.Errorf("failed to check if collection info exists: %w", err)
+	}
+	if !exists {
+		return nil, nil
+	}
+
+	points, err := v.client.Get(ctx, &qdrant.GetPoints{
+		CollectionName: v.infoCollectionName(),
+		Ids:            []*qdrant.PointId{qdrant.NewID(infoPointID)},
+		WithPayload:    qdrant.NewWithPayloadEnable(true),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read collection info: %w", err)
+	}
+	if len(points) == 0 {
+		return nil, nil
+	}
+
+	payload := points[0].Payload
+	return &embeddings.ModelInfo{
+		Name:              payload["model"].GetStringValue(),
+		Pooling:           payload["pooling"].GetStringValue(),
+		Dimension:         int(payload["dimension"].GetIntegerValue()),
+		MaxSequenceLength: int(payload["max_sequence_length"].GetIntegerValue()),
+		Document:          payload["document"].GetStringValue(),
+		Endpoint:          payload["endpoint"].GetStringValue(),
+	}, nil
+}
+
+// SetCollectionModel records the model the collection is built with
+func (v *qdrantDB) SetCollectionModel(ctx context.Context, info embeddings.ModelInfo) error {
+	exists, err := v.client.CollectionExists(ctx, v.infoCollectionName())
+	if err != nil {
+		return fmt.Errorf("failed to check if collection info exists: %w", err)
+	}
+
+	if !exists {
+		if err = v.client.CreateCollection(ctx, &qdrant.CreateCollection{
+			CollectionName: v.infoCollectionName(),
+			VectorsConfig: qdrant.NewVectorsConfig(&qdrant.VectorParams{
+				Size:     1,
+				Distance: qdrant.Distance_Cosine,
+			}),
+		}); err != nil {
+			return fmt.Errorf("failed to create collection info: %w", err)
+		}
+	}
+
+	_, err = v.client.Upsert(ctx, &qdrant.UpsertPoints{
+		CollectionName: v.infoCollectionName(),
+		Points: []*qdrant.PointStruct{{
+			Id:      qdrant.NewID(infoPointID),
+			Vectors: qdrant.NewVectorsDense([]float32{1}),
+			Payload: qdrant.NewValueMap(map[string]any{
+				"model":               info.Name,
+				"pooling":             info.Pooling,
+				"dimension":           info.Dimension,
+				"max_sequence_length": info.MaxSequenceLength,
+				"document":            info.Document,
+				"endpoint":            info.Endpoint,
+			}),
+		}},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to record collection model: %w", err)
+	}
+
+	return nil
+}
+
+// CountMetrics returns the number of metric metadata entries in the collection
+func (v *qdrantDB) CountMetrics(ctx context.Context) (uint64, error) {
+	count, err := v.client.Count(ctx, &qdrant.CountPoints{
+		CollectionName: v.collectionName,
+		Exact:          qdrant.PtrOf(true),
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to count metrics: %w", err)
+	}
+
+	return count, nil
 }
 
 func (v *qdrantDB) Close() error {
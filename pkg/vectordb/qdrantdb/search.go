@@ -10,13 +10,13 @@ import (
 	"github.com/machadovilaca/prometheus-rag/pkg/prometheus"
 )
 
-func (v *qdrantDB) SearchMetrics(query string, limit uint64) ([]*prometheus.MetricMetadata, error) {
-	encodedQuery, err := v.encoder.EncodeQuery(query)
+func (v *qdrantDB) SearchMetrics(ctx context.Context, query string, limit uint64) ([]*prometheus.MetricMetadata, error) {
+	encodedQuery, err := v.encoder.EncodeQuery(ctx, query)
 	if err != nil {
 		return nil, fmt.Errorf("failed to encode query: %w", err)
 	}
 
-	searchResults, err := v.client.Query(context.Background(), &qdrant.QueryPoints{
+	searchResults, err := v.client.Query(ctx, &qdrant.QueryPoints{
 		CollectionName: v.collectionName,
 		Query:          qdrant.NewQueryDense(encodedQuery),
 		Limit:          &limit,
@@ -39,9 +39,23 @@ func convertSearchResults(results []*qdrant.ScoredPoint) []*prometheus.MetricMet
 
 func fromQdrantMap(m map[string]*qdrant.Value) *prometheus.MetricMetadata {
 	return &prometheus.MetricMetadata{
-		Name:   m["name"].GetStringValue(),
-		Help:   m["help"].GetStringValue(),
-		Type:   m["type"].GetStringValue(),
-		Labels: strings.Split(m["labels"].GetStringValue(), ", "),
+		Name:            m["name"].GetStringValue(),
+		Help:            m["help"].GetStringValue(),
+		Description:     m["description"].GetStringValue(),
+		Type:            m["type"].GetStringValue(),
+		Unit:            m["unit"].GetStringValue(),
+		Labels:          splitList(m["labels"].GetStringValue()),
+		Series:          splitList(m["series"].GetStringValue()),
+		NativeHistogram: m["native_histogram"].GetBoolValue(),
+		TypeConflict:    m["type_conflict"].GetBoolValue(),
+		Variants:        prometheus.DecodeVariants(m["variants"].GetStringValue()),
+		Annotations:     prometheus.DecodeAnnotations(m["annotations"].GetStringValue()),
 	}
 }
+
+func splitList(s string) []string {
+	if s == "" {
+		return []string{}
+	}
+	return strings.Split(s, ", ")
+}
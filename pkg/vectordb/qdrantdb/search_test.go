@@ -1,6 +1,8 @@
 package qdrantdb_test
 
 import (
+	"context"
+
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 
@@ -26,7 +28,7 @@ var _ = Describe("Search", func() {
 	})
 
 	AfterEach(func() {
-		err := dbClient.DeleteCollection()
+		err := dbClient.DeleteCollection(context.Background())
 		Expect(err).NotTo(HaveOccurred())
 
 		err = dbClient.Close()
@@ -34,7 +36,7 @@ var _ = Describe("Search", func() {
 	})
 
 	It("should return best matching metrics first", func() {
-		err := dbClient.AddMetricMetadata(&prometheus.MetricMetadata{
+		err := dbClient.AddMetricMetadata(context.Background(), &prometheus.MetricMetadata{
 			Name:   "http_requests_total",
 			Help:   "Total number of HTTP requests",
 			Type:   "counter",
@@ -42,7 +44,7 @@ var _ = Describe("Search", func() {
 		})
 		Expect(err).NotTo(HaveOccurred())
 
-		err = dbClient.AddMetricMetadata(&prometheus.MetricMetadata{
+		err = dbClient.AddMetricMetadata(context.Background(), &prometheus.MetricMetadata{
 			Name:   "node_memory_usage",
 			Help:   "Memory usage of node",
 			Type:   "gauge",
@@ -50,13 +52,13 @@ var _ = Describe("Search", func() {
 		})
 		Expect(err).NotTo(HaveOccurred())
 
-		results, err := dbClient.SearchMetrics("http requests", 10)
+		results, err := dbClient.SearchMetrics(context.Background(), "http requests", 10)
 		Expect(err).NotTo(HaveOccurred())
 		Expect(results).To(HaveLen(2))
 		Expect(results[0].Name).To(Equal("http_requests_total"))
 		Expect(results[1].Name).To(Equal("node_memory_usage"))
 
-		results, err = dbClient.SearchMetrics("memory", 10)
+		results, err = dbClient.SearchMetrics(context.Background(), "memory", 10)
 		Expect(err).NotTo(HaveOccurred())
 		Expect(results).To(HaveLen(2))
 		Expect(results[0].Name).To(Equal("node_memory_usage"))
@@ -64,27 +66,27 @@ var _ = Describe("Search", func() {
 	})
 
 	It("should return empty results when no matches found", func() {
-		results, err := dbClient.SearchMetrics("does not exist", 10)
+		results, err := dbClient.SearchMetrics(context.Background(), "does not exist", 10)
 		Expect(err).NotTo(HaveOccurred())
 		Expect(results).To(BeEmpty())
 	})
 
 	It("should respect the limit parameter", func() {
-		err := dbClient.AddMetricMetadata(&prometheus.MetricMetadata{
+		err := dbClient.AddMetricMetadata(context.Background(), &prometheus.MetricMetadata{
 			Name: "metric1",
 			Help: "Test metric 1",
 			Type: "counter",
 		})
 		Expect(err).NotTo(HaveOccurred())
 
-		err = dbClient.AddMetricMetadata(&prometheus.MetricMetadata{
+		err = dbClient.AddMetricMetadata(context.Background(), &prometheus.MetricMetadata{
 			Name: "metric2",
 			Help: "Test metric 2",
 			Type: "counter",
 		})
 		Expect(err).NotTo(HaveOccurred())
 
-		results, err := dbClient.SearchMetrics("test metric", 1)
+		results, err := dbClient.SearchMetrics(context.Background(), "test metric", 1)
 		Expect(err).NotTo(HaveOccurred())
 		Expect(results).To(HaveLen(1))
 	})
@@ -1,29 +1,28 @@
 package sqlite3
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/rs/zerolog/log"
 
+	"github.com/machadovilaca/prometheus-rag/pkg/embeddings"
 	"github.com/machadovilaca/prometheus-rag/pkg/prometheus"
 )
 
-func (v *sqlite3DB) AddMetricMetadata(metadata *prometheus.MetricMetadata) error {
+func (v *sqlite3DB) AddMetricMetadata(ctx context.Context, metadata *prometheus.MetricMetadata) error {
 	if err := metadata.Validate(); err != nil {
 		return fmt.Errorf("invalid metric metadata: %w", err)
 	}
 
 	// Encode the metric metadata to a vector
-	embedding, err := v.encoder.EncodeMetricMetadata(*metadata)
+	embedding, err := v.encoder.EncodeMetricMetadata(ctx, *metadata)
 	if err != nil {
 		return fmt.Errorf("failed to encode metric metadata: %w", err)
 	}
 
 	// Convert embedding to bytes
-	embeddingBytes, err := v.encodeEmbedding(embedding)
-	if err != nil {
-		return fmt.Errorf("failed to encode embedding: %w", err)
-	}
+	embeddingBytes := embeddings.EncodeVector(embedding)
 
 	// Create deterministic ID based on metric name
 	id := v.createDeterministicID(metadata.Name)
@@ -35,13 +34,7 @@ func (v *sqlite3DB) AddMetricMetadata(metadata *prometheus.MetricMetadata) error
 	}
 
 	// Insert or replace the metric metadata
-	insertSQL := fmt.Sprintf(`
-		INSERT OR REPLACE INTO %s (id, name, help, type, labels, embedding)
-		VALUES (?, ?, ?, ?, ?, ?)
-	`, safeTableName)
-
-	_, err = v.db.Exec(insertSQL, id, metadata.Name, metadata.Help, metadata.Type,
-		v.joinLabels(metadata.Labels), embeddingBytes)
+	_, err = v.db.ExecContext(ctx, v.insertSQL(safeTableName), v.insertArgs(id, metadata, embeddingBytes)...)
 	if err != nil {
 		return fmt.Errorf("failed to insert metric metadata: %w", err)
 	}
@@ -49,14 +42,28 @@ func (v *sqlite3DB) AddMetricMetadata(metadata *prometheus.MetricMetadata) error
 	return nil
 }
 
-func (v *sqlite3DB) BatchAddMetricMetadata(metadataArray []*prometheus.MetricMetadata) error {
+func (v *sqlite3DB) BatchAddMetricMetadata(ctx context.Context, metadataArray []*prometheus.MetricMetadata) error {
 	if len(metadataArray) == 0 {
 		log.Info().Msg("skipping batch add of metric metadata because there are none")
 		return nil
 	}
 
+	entries := make([]prometheus.MetricMetadata, len(metadataArray))
+	for i, metadata := range metadataArray {
+		if err := metadata.Validate(); err != nil {
+			return fmt.Errorf("invalid metric metadata '%s': %w", metadata.Name, err)
+		}
+		entries[i] = *metadata
+	}
+
+	// Encode all metric metadata in parallel before writing
+	vectors, err := v.encoder.EncodeMetricMetadataBatch(ctx, entries, embeddings.LogProgress)
+	if err != nil {
+		return fmt.Errorf("failed to encode metric metadata: %w", err)
+	}
+
 	// Begin transaction for better performance
-	tx, err := v.db.Begin()
+	tx, err := v.db.BeginTx(ctx, nil)
 	if err != nil {
 		return fmt.Errorf("failed to begin transaction: %w", err)
 	}
@@ -71,12 +78,7 @@ func (v *sqlite3DB) BatchAddMetricMetadata(metadataArray []*prometheus.MetricMet
 	}
 
 	// Prepare statement
-	insertSQL := fmt.Sprintf(`
-		INSERT OR REPLACE INTO %s (id, name, help, type, labels, embedding)
-		VALUES (?, ?, ?, ?, ?, ?)
-	`, safeTableName)
-
-	stmt, err := tx.Prepare(insertSQL)
+	stmt, err := tx.PrepareContext(ctx, v.insertSQL(safeTableName))
 	if err != nil {
 		return fmt.Errorf("failed to prepare statement: %w", err)
 	}
@@ -84,29 +86,15 @@ func (v *sqlite3DB) BatchAddMetricMetadata(metadataArray []*prometheus.MetricMet
 		_ = stmt.Close()
 	}()
 
-	for _, metadata := range metadataArray {
-		if err := metadata.Validate(); err != nil {
-			return fmt.Errorf("invalid metric metadata '%s': %w", metadata.Name, err)
-		}
-
-		// Encode the metric metadata to a vector
-		embedding, err := v.encoder.EncodeMetricMetadata(*metadata)
-		if err != nil {
-			return fmt.Errorf("failed to encode metric metadata '%s': %w", metadata.Name, err)
-		}
-
+	for i, metadata := range metadataArray {
 		// Convert embedding to bytes
-		embeddingBytes, err := v.encodeEmbedding(embedding)
-		if err != nil {
-			return fmt.Errorf("failed to encode embedding for '%s': %w", metadata.Name, err)
-		}
+		embeddingBytes := embeddings.EncodeVector(vectors[i])
 
 		// Create deterministic ID based on metric name
 		id := v.createDeterministicID(metadata.Name)
 
 		// Execute statement
-		_, err = stmt.Exec(id, metadata.Name, metadata.Help, metadata.Type,
-			v.joinLabels(metadata.Labels), embeddingBytes)
+		_, err = stmt.ExecContext(ctx, v.insertArgs(id, metadata, embeddingBytes)...)
 		if err != nil {
 			return fmt.Errorf("failed to insert metric metadata '%s': %w", metadata.Name, err)
 		}
@@ -120,3 +108,20 @@ func (v *sqlite3DB) BatchAddMetricMetadata(metadataArray []*prometheus.MetricMet
 	log.Info().Msgf("batch added %d metric metadata entries", len(metadataArray))
 	return nil
 }
+
+func (v *sqlite3DB) insertSQL(safeTableName string) string {
+	return fmt.Sprintf(`
+		INSERT OR REPLACE INTO %s (id, name, help, type, unit, labels, series, native_histogram,
+			type_conflict, variants, description, annotations, embedding)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, safeTableName)
+}
+
+func (v *sqlite3DB) insertArgs(id string, metadata *prometheus.MetricMetadata, embeddingBytes []byte) []any {
+	return []any{
+		id, metadata.Name, metadata.Help, metadata.Type, metadata.Unit,
+		v.joinLabels(metadata.Labels), v.joinLabels(metadata.Series), metadata.NativeHistogram,
+		metadata.TypeConflict, prometheus.EncodeVariants(metadata.Variants), metadata.Description,
+		prometheus.EncodeAnnotations(metadata.Annotations), embeddingBytes,
+	}
+}
@@ -1,6 +1,7 @@
 package sqlite3_test
 
 import (
+	"context"
 	"os"
 	"path/filepath"
 
@@ -36,7 +37,7 @@ var _ = Describe("Add", func() {
 		Expect(err).NotTo(HaveOccurred())
 
 		// Create collection
-		err = dbClient.CreateCollection()
+		err = dbClient.CreateCollection(context.Background())
 		Expect(err).NotTo(HaveOccurred())
 	})
 
@@ -61,7 +62,7 @@ var _ = Describe("Add", func() {
 			Labels: []string{"label1", "label2"},
 		}
 
-		err := dbClient.AddMetricMetadata(metadata)
+		err := dbClient.AddMetricMetadata(context.Background(), metadata)
 		Expect(err).NotTo(HaveOccurred())
 	})
 
@@ -71,7 +72,7 @@ var _ = Describe("Add", func() {
 			Help: "Test help",
 		}
 
-		err := dbClient.AddMetricMetadata(metadata)
+		err := dbClient.AddMetricMetadata(context.Background(), metadata)
 		Expect(err).To(HaveOccurred())
 		Expect(err.Error()).To(ContainSubstring("name is required"))
 	})
@@ -83,15 +84,15 @@ var _ = Describe("Add", func() {
 			Type: "counter",
 		}
 
-		err := dbClient.AddMetricMetadata(metadata)
+		err := dbClient.AddMetricMetadata(context.Background(), metadata)
 		Expect(err).NotTo(HaveOccurred())
 
 		metadata.Help = "Updated help"
 
-		err = dbClient.AddMetricMetadata(metadata)
+		err = dbClient.AddMetricMetadata(context.Background(), metadata)
 		Expect(err).NotTo(HaveOccurred())
 
-		results, err := dbClient.SearchMetrics("test_metric", 10)
+		results, err := dbClient.SearchMetrics(context.Background(), "test_metric", 10)
 		Expect(err).NotTo(HaveOccurred())
 		Expect(results).To(HaveLen(1))
 		Expect(results[0].Help).To(Equal("Updated help"))
@@ -111,10 +112,10 @@ var _ = Describe("Add", func() {
 			},
 		}
 
-		err := dbClient.BatchAddMetricMetadata(metadata)
+		err := dbClient.BatchAddMetricMetadata(context.Background(), metadata)
 		Expect(err).NotTo(HaveOccurred())
 
-		results, err := dbClient.SearchMetrics("test", 10)
+		results, err := dbClient.SearchMetrics(context.Background(), "test", 10)
 		Expect(err).NotTo(HaveOccurred())
 		Expect(results).To(HaveLen(2))
 
@@ -123,10 +124,10 @@ var _ = Describe("Add", func() {
 	})
 
 	It("should skip batch add of metric metadata when there are none", func() {
-		err := dbClient.BatchAddMetricMetadata([]*prometheus.MetricMetadata{})
+		err := dbClient.BatchAddMetricMetadata(context.Background(), []*prometheus.MetricMetadata{})
 		Expect(err).NotTo(HaveOccurred())
 
-		results, err := dbClient.SearchMetrics("test", 10)
+		results, err := dbClient.SearchMetrics(context.Background(), "test", 10)
 		Expect(err).NotTo(HaveOccurred())
 		Expect(results).To(HaveLen(0))
 	})
@@ -145,7 +146,7 @@ var _ = Describe("Add", func() {
 			},
 		}
 
-		err := dbClient.BatchAddMetricMetadata(metadata)
+		err := dbClient.BatchAddMetricMetadata(context.Background(), metadata)
 		Expect(err).To(HaveOccurred())
 		Expect(err.Error()).To(ContainSubstring("name is required"))
 	})
@@ -158,10 +159,10 @@ var _ = Describe("Add", func() {
 			Labels: []string{"namespace", "pod", "container", "method", "status_code"},
 		}
 
-		err := dbClient.AddMetricMetadata(metadata)
+		err := dbClient.AddMetricMetadata(context.Background(), metadata)
 		Expect(err).NotTo(HaveOccurred())
 
-		results, err := dbClient.SearchMetrics("complex", 10)
+		results, err := dbClient.SearchMetrics(context.Background(), "complex", 10)
 		Expect(err).NotTo(HaveOccurred())
 		Expect(results).To(HaveLen(1))
 		Expect(results[0].Labels).To(Equal([]string{"namespace", "pod", "container", "method", "status_code"}))
@@ -175,10 +176,10 @@ var _ = Describe("Add", func() {
 			Labels: []string{},
 		}
 
-		err := dbClient.AddMetricMetadata(metadata)
+		err := dbClient.AddMetricMetadata(context.Background(), metadata)
 		Expect(err).NotTo(HaveOccurred())
 
-		results, err := dbClient.SearchMetrics("no labels", 10)
+		results, err := dbClient.SearchMetrics(context.Background(), "no labels", 10)
 		Expect(err).NotTo(HaveOccurred())
 		Expect(results).To(HaveLen(1))
 		Expect(results[0].Labels).To(BeEmpty())
@@ -0,0 +1,241 @@
+package sqlite3
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+
+	sqlite_vec "github.com/asg017/sqlite-vec-go-bindings/cgo"
+	"github.com/rs/zerolog/log"
+
+	"github.com/machadovilaca/prometheus-rag/pkg/embeddings"
+	"github.com/machadovilaca/prometheus-rag/pkg/examples"
+)
+
+// sqlite3Examples stores few-shot examples in their own collection table,
+// sharing the helpers and collection model records of the metrics collection
+type sqlite3Examples struct {
+	*sqlite3DB
+}
+
+// NewExamples creates a new SQLite3 client for the few-shot examples collection
+func NewExamples(cfg Config) (*sqlite3Examples, error) {
+	sqlite_vec.Auto()
+
+	validator := NewSQLIdentifierValidator()
+	if err := validator.ValidateIdentifier(cfg.CollectionName); err != nil {
+		return nil, fmt.Errorf("invalid collection name: %w", err)
+	}
+
+	db, err := open(cfg.DBPath)
+	if err != nil {
+		return nil, err
+	}
+
+	v := &sqlite3Examples{&sqlite3DB{
+		db:             db,
+		encoder:        cfg.Encoder,
+		collectionName: cfg.CollectionName,
+		validator:      validator,
+	}}
+
+	if err := v.CreateCollection(context.Background()); err != nil {
+		return nil, fmt.Errorf("failed to create collection: %w", err)
+	}
+
+	return v, nil
+}
+
+func (v *sqlite3Examples) CreateCollection(ctx context.Context) error {
+	safeTableName, err := v.validator.SafeIdentifier(v.collectionName)
+	if err != nil {
+		return fmt.Errorf("failed to validate collection name: %w", err)
+	}
+
+	_, err = v.db.ExecContext(ctx, fmt.Sprintf(`
+		CREATE TABLE IF NOT EXISTS %s (
+			id TEXT PRIMARY KEY,
+			question TEXT NOT NULL,
+			promql TEXT NOT NULL,
+			source TEXT,
+			embedding BLOB
+		)
+	`, safeTableName))
+	if err != nil {
+		return fmt.Errorf("failed to create collection table: %w", err)
+	}
+
+	if err := createCollectionsTable(ctx, v.db); err != nil {
+		return err
+	}
+
+	log.Info().Msgf("created examples collection table: %s", v.collectionName)
+	return nil
+}
+
+func (v *sqlite3Examples) AddExamples(ctx context.Context, entries []*examples.Example) error {
+	if len(entries) == 0 {
+		return nil
+	}
+
+	questions := make([]string, len(entries))
+	for i, example := range entries {
+		if err := example.Validate(); err != nil {
+			return fmt.Errorf("invalid example '%s': %w", example.Question, err)
+		}
+		example.Normalize()
+		questions[i] = example.Question
+	}
+
+	vectors, err := v.encoder.EncodeBatch(ctx, questions, nil)
+	if err != nil {
+		return fmt.Errorf("failed to encode examples: %w", err)
+	}
+
+	safeTableName, err := v.validator.SafeIdentifier(v.collectionName)
+	if err != nil {
+		return fmt.Errorf("failed to validate collection name: %w", err)
+	}
+
+	tx, err := v.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	stmt, err := tx.PrepareContext(ctx, fmt.Sprintf(`
+		INSERT OR REPLACE INTO %s (id, question, promql, source, embedding) VALUES (?, ?, ?, ?, ?)
+	`, safeTableName))
+	if err != nil {
+		return fmt.Errorf("failed to prepare statement: %w", err)
+	}
+	defer func() {
+		_ = stmt.Close()
+	}()
+
+	for i, example := range entries {
+		embeddingBytes := embeddings.EncodeVector(vectors[i])
+
+		if _, err := stmt.ExecContext(ctx, example.ID, example.Question, example.PromQL, example.Source, embeddingBytes); err != nil {
+			return fmt.Errorf("failed to insert example '%s': %w", example.Question, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+func (v *sqlite3Examples) SearchExamples(ctx context.Context, question string, limit uint64) ([]*examples.Example, error) {
+	queryEmbedding, err := v.encoder.EncodeQuery(ctx, question)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode question: %w", err)
+	}
+
+	type exampleWithScore struct {
+		example *examples.Example
+		score   float64
+	}
+
+	var candidates []exampleWithScore
+	err = v.scan(ctx, func(example *examples.Example, embedding []float32) {
+		candidates = append(candidates, exampleWithScore{
+			example: example,
+			score:   v.cosineSimilarity(queryEmbedding, embedding),
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].score > candidates[j].score
+	})
+
+	results := make([]*examples.Example, min(int(limit), len(candidates)))
+	for i := range results {
+		results[i] = candidates[i].example
+	}
+
+	return results, nil
+}
+
+func (v *sqlite3Examples) ListExamples(ctx context.Context) ([]*examples.Example, error) {
+	results := []*examples.Example{}
+	err := v.scan(ctx, func(example *examples.Example, _ []float32) {
+		results = append(results, example)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
+func (v *sqlite3Examples) DeleteExample(ctx context.Context, id string) (bool, error) {
+	safeTableName, err := v.validator.SafeIdentifier(v.collectionName)
+	if err != nil {
+		return false, fmt.Errorf("failed to validate collection name: %w", err)
+	}
+
+	result, err := v.db.ExecContext(ctx, fmt.Sprintf(`DELETE FROM %s WHERE id = ?`, safeTableName), id)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete example: %w", err)
+	}
+
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to delete example: %w", err)
+	}
+
+	return deleted > 0, nil
+}
+
+// scan calls fn for every example in the collection, ordered by question
+func (v *sqlite3Examples) scan(ctx context.Context, fn func(example *examples.Example, embedding []float32)) error {
+	safeTableName, err := v.validator.SafeIdentifier(v.collectionName)
+	if err != nil {
+		return fmt.Errorf("failed to validate collection name: %w", err)
+	}
+
+	rows, err := v.db.QueryContext(ctx, fmt.Sprintf(`
+		SELECT id, question, promql, source, embedding FROM %s ORDER BY question
+	`, safeTableName))
+	if err != nil {
+		return fmt.Errorf("failed to query examples: %w", err)
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	for rows.Next() {
+		var (
+			example        examples.Example
+			source         sql.NullString
+			embeddingBytes []byte
+		)
+		if err := rows.Scan(&example.ID, &example.Question, &example.PromQL, &source, &embeddingBytes); err != nil {
+			return fmt.Errorf("failed to scan row: %w", err)
+		}
+		example.Source = source.String
+
+		embedding, err := embeddings.DecodeVector(embeddingBytes)
+		if err != nil {
+			log.Error().Err(err).Msg("failed to decode embedding, skipping")
+			continue
+		}
+
+		fn(&example, embedding)
+	}
+
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	return nil
+}
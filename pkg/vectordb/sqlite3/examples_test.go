@@ -0,0 +1,191 @@
+package sqlite3_test
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"path/filepath"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/machadovilaca/prometheus-rag/pkg/embeddings"
+	"github.com/machadovilaca/prometheus-rag/pkg/examples"
+	"github.com/machadovilaca/prometheus-rag/pkg/vectordb"
+)
+
+// keywordEncoder embeds texts by the topics they mention, so similar
+// questions get similar vectors
+type keywordEncoder struct {
+	mockEncoder
+	model    string
+	document string
+}
+
+var keywordTopics = []string{"memory", "cpu", "request", "restart", "down"}
+
+func (k *keywordEncoder) GetDimension() (int, error) {
+	return len(keywordTopics), nil
+}
+
+func (k *keywordEncoder) Model() embeddings.ModelInfo {
+	return embeddings.ModelInfo{
+		Name:      k.model,
+		Pooling:   embeddings.PoolingMean,
+		Dimension: len(keywordTopics),
+		Document:  k.document,
+	}
+}
+
+func (k *keywordEncoder) EncodeQuery(ctx context.Context, query string) ([]float32, error) {
+	vector := make([]float32, len(keywordTopics))
+	for i, topic := range keywordTopics {
+		if strings.Contains(strings.ToLower(query), topic) {
+			vector[i] = 1
+		}
+	}
+	return vector, nil
+}
+
+func (k *keywordEncoder) EncodeBatch(ctx context.Context, texts []string, _ embeddings.ProgressFunc) ([][]float32, error) {
+	vectors := make([][]float32, len(texts))
+	for i, text := range texts {
+		vectors[i], _ = k.EncodeQuery(ctx, text)
+	}
+	return vectors, nil
+}
+
+var _ = Describe("SQLite3 Examples", func() {
+	var (
+		store   vectordb.ExampleStore
+		cfg     vectordb.Config
+		tempDir string
+	)
+
+	library := []*examples.Example{
+		{Question: "What is the memory used by each VM?", PromQL: "sum by (name) (kubevirt_vmi_memory_resident_bytes)"},
+		{Question: "What is the HTTP request rate?", PromQL: "sum(rate(http_requests_total[5m]))"},
+		{Question: "Which targets are down?", PromQL: "up == 0"},
+	}
+
+	BeforeEach(func() {
+		var err error
+		tempDir, err = os.MkdirTemp("", "sqlite3_examples_test")
+		Expect(err).NotTo(HaveOccurred())
+
+		cfg = vectordb.Config{
+			Provider:               "sqlite3",
+			Sqlite3DBPath:          filepath.Join(tempDir, "test.db"),
+			ExamplesCollectionName: "test_examples",
+		}
+
+		store, err = vectordb.NewExampleStore(cfg, &keywordEncoder{model: "keywords", document: embeddings.DocumentPlain})
+		Expect(err).NotTo(HaveOccurred())
+
+		entries := make([]*examples.Example, len(library))
+		for i, example := range library {
+			copied := *example
+			entries[i] = &copied
+		}
+		Expect(store.AddExamples(context.Background(), entries)).To(Succeed())
+	})
+
+	AfterEach(func() {
+		Expect(store.Close()).To(Succeed())
+		Expect(os.RemoveAll(tempDir)).To(Succeed())
+	})
+
+	It("should return the examples most similar to a question", func() {
+		found, err := store.SearchExamples(context.Background(), "How much memory do my VMs use?", 1)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(found).To(HaveLen(1))
+		Expect(found[0].PromQL).To(Equal("sum by (name) (kubevirt_vmi_memory_resident_bytes)"))
+		Expect(found[0].Source).To(Equal(examples.SourceCurated))
+	})
+
+	It("should replace the example of the same question", func() {
+		Expect(store.AddExamples(context.Background(), []*examples.Example{
+			{Question: "which targets are DOWN?", PromQL: "up{job!=\"\"} == 0", Source: examples.SourceApproved},
+		})).To(Succeed())
+
+		list, err := store.ListExamples(context.Background())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(list).To(HaveLen(3))
+		Expect(list).To(ContainElement(HaveField("PromQL", "up{job!=\"\"} == 0")))
+	})
+
+	It("should delete examples by ID", func() {
+		deleted, err := store.DeleteExample(context.Background(), examples.ID("Which targets are down?"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(deleted).To(BeTrue())
+
+		deleted, err = store.DeleteExample(context.Background(), examples.ID("Which targets are down?"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(deleted).To(BeFalse())
+
+		list, err := store.ListExamples(context.Background())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(list).To(HaveLen(2))
+	})
+
+	It("should re-embed the examples when the model changes", func() {
+		Expect(store.Close()).To(Succeed())
+
+		var err error
+		store, err = vectordb.NewExampleStore(cfg, &keywordEncoder{model: "keywords-v2"})
+		Expect(err).NotTo(HaveOccurred())
+
+		list, err := store.ListExamples(context.Background())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(list).To(HaveLen(3))
+
+		found, err := store.SearchExamples(context.Background(), "Which targets are down?", 1)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(found[0].PromQL).To(Equal("up == 0"))
+	})
+
+	recordedDocument := func() string {
+		db, err := sql.Open("sqlite3", cfg.Sqlite3DBPath)
+		Expect(err).NotTo(HaveOccurred())
+		defer func() {
+			_ = db.Close()
+		}()
+
+		var document string
+		Expect(db.QueryRow(`SELECT document FROM prag_collections WHERE collection = ?`, cfg.ExamplesCollectionName).
+			Scan(&document)).To(Succeed())
+		return document
+	}
+
+	It("should re-embed the examples when the document builder changes", func() {
+		Expect(store.Close()).To(Succeed())
+
+		var err error
+		store, err = vectordb.NewExampleStore(cfg, &keywordEncoder{model: "keywords", document: embeddings.DocumentTokenized})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(recordedDocument()).To(Equal(embeddings.DocumentTokenized))
+
+		list, err := store.ListExamples(context.Background())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(list).To(HaveLen(3))
+	})
+
+	It("should complete the model recorded by older versions", func() {
+		Expect(store.Close()).To(Succeed())
+
+		db, err := sql.Open("sqlite3", cfg.Sqlite3DBPath)
+		Expect(err).NotTo(HaveOccurred())
+		_, err = db.Exec(`UPDATE prag_collections SET document = '', max_sequence_length = 0`)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(db.Close()).To(Succeed())
+
+		store, err = vectordb.NewExampleStore(cfg, &keywordEncoder{model: "keywords", document: embeddings.DocumentPlain})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(recordedDocument()).To(Equal(embeddings.DocumentPlain))
+
+		list, err := store.ListExamples(context.Background())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(list).To(HaveLen(3))
+	})
+})
@@ -1,16 +1,19 @@
 package sqlite3
 
 import (
+	"context"
+	"database/sql"
 	"fmt"
 
 	"github.com/rs/zerolog/log"
 
+	"github.com/machadovilaca/prometheus-rag/pkg/embeddings"
 	"github.com/machadovilaca/prometheus-rag/pkg/prometheus"
 )
 
-func (v *sqlite3DB) SearchMetrics(query string, limit uint64) ([]*prometheus.MetricMetadata, error) {
+func (v *sqlite3DB) SearchMetrics(ctx context.Context, query string, limit uint64) ([]*prometheus.MetricMetadata, error) {
 	// Encode the query to a vector
-	queryEmbedding, err := v.encoder.EncodeQuery(query)
+	queryEmbedding, err := v.encoder.EncodeQuery(ctx, query)
 	if err != nil {
 		return nil, fmt.Errorf("failed to encode query: %w", err)
 	}
@@ -24,12 +27,13 @@ func (v *sqlite3DB) SearchMetrics(query string, limit uint64) ([]*prometheus.Met
 	// Query for similar metrics using cosine similarity
 	// We'll calculate similarity in Go since sqlite-vec might need setup
 	searchSQL := fmt.Sprintf(`
-		SELECT id, name, help, type, labels, embedding
+		SELECT id, name, help, type, unit, labels, series, native_histogram, type_conflict, variants,
+			description, annotations, embedding
 		FROM %s
 		ORDER BY name
 	`, safeTableName)
 
-	rows, err := v.db.Query(searchSQL)
+	rows, err := v.db.QueryContext(ctx, searchSQL)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query metrics: %w", err)
 	}
@@ -45,16 +49,19 @@ func (v *sqlite3DB) SearchMetrics(query string, limit uint64) ([]*prometheus.Met
 	var candidates []metricWithScore
 
 	for rows.Next() {
-		var id, name, help, metricType, labels string
+		var id, name string
+		var help, metricType, unit, labels, series, variants, description, annotations sql.NullString
+		var nativeHistogram, typeConflict sql.NullBool
 		var embeddingBytes []byte
 
-		err := rows.Scan(&id, &name, &help, &metricType, &labels, &embeddingBytes)
+		err := rows.Scan(&id, &name, &help, &metricType, &unit, &labels, &series, &nativeHistogram,
+			&typeConflict, &variants, &description, &annotations, &embeddingBytes)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan row: %w", err)
 		}
 
 		// Decode embedding
-		embedding, err := v.decodeEmbedding(embeddingBytes)
+		embedding, err := embeddings.DecodeVector(embeddingBytes)
 		if err != nil {
 			log.Error().Err(err).Msg("failed to decode embedding, skipping")
 			continue
@@ -64,10 +71,17 @@ func (v *sqlite3DB) SearchMetrics(query string, limit uint64) ([]*prometheus.Met
 		similarity := v.cosineSimilarity(queryEmbedding, embedding)
 
 		metadata := &prometheus.MetricMetadata{
-			Name:   name,
-			Help:   help,
-			Type:   metricType,
-			Labels: v.splitLabels(labels),
+			Name:            name,
+			Help:            help.String,
+			Description:     description.String,
+			Type:            metricType.String,
+			Unit:            unit.String,
+			Labels:          v.splitLabels(labels.String),
+			Series:          v.splitLabels(series.String),
+			NativeHistogram: nativeHistogram.Bool,
+			TypeConflict:    typeConflict.Bool,
+			Variants:        prometheus.DecodeVariants(variants.String),
+			Annotations:     prometheus.DecodeAnnotations(annotations.String),
 		}
 
 		candidates = append(candidates, metricWithScore{
@@ -1,6 +1,7 @@
 package sqlite3_test
 
 import (
+	"context"
 	"os"
 	"path/filepath"
 	"time"
@@ -37,7 +38,7 @@ var _ = Describe("Search", func() {
 		Expect(err).NotTo(HaveOccurred())
 
 		// Create collection
-		err = dbClient.CreateCollection()
+		err = dbClient.CreateCollection(context.Background())
 		Expect(err).NotTo(HaveOccurred())
 	})
 
@@ -55,7 +56,7 @@ var _ = Describe("Search", func() {
 	})
 
 	It("should return best matching metrics first", func() {
-		err := dbClient.AddMetricMetadata(&prometheus.MetricMetadata{
+		err := dbClient.AddMetricMetadata(context.Background(), &prometheus.MetricMetadata{
 			Name:   "http_requests_total",
 			Help:   "Total number of HTTP requests",
 			Type:   "counter",
@@ -63,7 +64,7 @@ var _ = Describe("Search", func() {
 		})
 		Expect(err).NotTo(HaveOccurred())
 
-		err = dbClient.AddMetricMetadata(&prometheus.MetricMetadata{
+		err = dbClient.AddMetricMetadata(context.Background(), &prometheus.MetricMetadata{
 			Name:   "node_memory_usage",
 			Help:   "Memory usage of node",
 			Type:   "gauge",
@@ -74,7 +75,7 @@ var _ = Describe("Search", func() {
 		// Give some time for processing
 		time.Sleep(100 * time.Millisecond)
 
-		results, err := dbClient.SearchMetrics("http requests", 10)
+		results, err := dbClient.SearchMetrics(context.Background(), "http requests", 10)
 		Expect(err).NotTo(HaveOccurred())
 		Expect(results).To(HaveLen(2))
 
@@ -88,7 +89,7 @@ var _ = Describe("Search", func() {
 		}
 		Expect(found).To(BeTrue())
 
-		results, err = dbClient.SearchMetrics("memory", 10)
+		results, err = dbClient.SearchMetrics(context.Background(), "memory", 10)
 		Expect(err).NotTo(HaveOccurred())
 		Expect(results).To(HaveLen(2))
 
@@ -104,39 +105,39 @@ var _ = Describe("Search", func() {
 	})
 
 	It("should return empty results when no matches found", func() {
-		results, err := dbClient.SearchMetrics("does not exist", 10)
+		results, err := dbClient.SearchMetrics(context.Background(), "does not exist", 10)
 		Expect(err).NotTo(HaveOccurred())
 		Expect(results).To(BeEmpty())
 	})
 
 	It("should respect the limit parameter", func() {
-		err := dbClient.AddMetricMetadata(&prometheus.MetricMetadata{
+		err := dbClient.AddMetricMetadata(context.Background(), &prometheus.MetricMetadata{
 			Name: "metric1",
 			Help: "Test metric 1",
 			Type: "counter",
 		})
 		Expect(err).NotTo(HaveOccurred())
 
-		err = dbClient.AddMetricMetadata(&prometheus.MetricMetadata{
+		err = dbClient.AddMetricMetadata(context.Background(), &prometheus.MetricMetadata{
 			Name: "metric2",
 			Help: "Test metric 2",
 			Type: "counter",
 		})
 		Expect(err).NotTo(HaveOccurred())
 
-		results, err := dbClient.SearchMetrics("test metric", 1)
+		results, err := dbClient.SearchMetrics(context.Background(), "test metric", 1)
 		Expect(err).NotTo(HaveOccurred())
 		Expect(results).To(HaveLen(1))
 	})
 
 	It("should handle search with empty collection", func() {
-		results, err := dbClient.SearchMetrics("anything", 10)
+		results, err := dbClient.SearchMetrics(context.Background(), "anything", 10)
 		Expect(err).NotTo(HaveOccurred())
 		Expect(results).To(BeEmpty())
 	})
 
 	It("should handle search with special characters", func() {
-		err := dbClient.AddMetricMetadata(&prometheus.MetricMetadata{
+		err := dbClient.AddMetricMetadata(context.Background(), &prometheus.MetricMetadata{
 			Name:   "metric_with_special-chars.test",
 			Help:   "Metric with special characters: !@#$%^&*()",
 			Type:   "histogram",
@@ -144,34 +145,34 @@ var _ = Describe("Search", func() {
 		})
 		Expect(err).NotTo(HaveOccurred())
 
-		results, err := dbClient.SearchMetrics("special characters", 10)
+		results, err := dbClient.SearchMetrics(context.Background(), "special characters", 10)
 		Expect(err).NotTo(HaveOccurred())
 		Expect(results).To(HaveLen(1))
 		Expect(results[0].Name).To(Equal("metric_with_special-chars.test"))
 	})
 
 	It("should handle zero limit", func() {
-		err := dbClient.AddMetricMetadata(&prometheus.MetricMetadata{
+		err := dbClient.AddMetricMetadata(context.Background(), &prometheus.MetricMetadata{
 			Name: "test_metric",
 			Help: "Test metric",
 			Type: "counter",
 		})
 		Expect(err).NotTo(HaveOccurred())
 
-		results, err := dbClient.SearchMetrics("test", 0)
+		results, err := dbClient.SearchMetrics(context.Background(), "test", 0)
 		Expect(err).NotTo(HaveOccurred())
 		Expect(results).To(BeEmpty())
 	})
 
 	It("should handle large limit", func() {
-		err := dbClient.AddMetricMetadata(&prometheus.MetricMetadata{
+		err := dbClient.AddMetricMetadata(context.Background(), &prometheus.MetricMetadata{
 			Name: "test_metric",
 			Help: "Test metric",
 			Type: "counter",
 		})
 		Expect(err).NotTo(HaveOccurred())
 
-		results, err := dbClient.SearchMetrics("test", 1000)
+		results, err := dbClient.SearchMetrics(context.Background(), "test", 1000)
 		Expect(err).NotTo(HaveOccurred())
 		Expect(results).To(HaveLen(1))
 	})
@@ -199,11 +200,11 @@ var _ = Describe("Search", func() {
 			},
 		}
 
-		err := dbClient.BatchAddMetricMetadata(metrics)
+		err := dbClient.BatchAddMetricMetadata(context.Background(), metrics)
 		Expect(err).NotTo(HaveOccurred())
 
 		// Search for CPU related metrics
-		results, err := dbClient.SearchMetrics("cpu usage", 10)
+		results, err := dbClient.SearchMetrics(context.Background(), "cpu usage", 10)
 		Expect(err).NotTo(HaveOccurred())
 		Expect(len(results)).To(BeNumerically(">=", 1))
 
@@ -218,7 +219,7 @@ var _ = Describe("Search", func() {
 		Expect(found).To(BeTrue())
 
 		// Search for disk related metrics
-		results, err = dbClient.SearchMetrics("disk operations", 10)
+		results, err = dbClient.SearchMetrics(context.Background(), "disk operations", 10)
 		Expect(err).NotTo(HaveOccurred())
 		Expect(len(results)).To(BeNumerically(">=", 1))
 
@@ -1,6 +1,7 @@
 package sqlite3_test
 
 import (
+	"context"
 	"os"
 	"path/filepath"
 
@@ -181,12 +182,32 @@ func (m *mockEncoder) GetDimension() (int, error) {
 	return 5, nil
 }
 
-func (m *mockEncoder) EncodeQuery(query string) ([]float32, error) {
+func (m *mockEncoder) Model() embeddings.ModelInfo {
+	return embeddings.ModelInfo{Name: "mock", Pooling: embeddings.PoolingMean, Dimension: 5}
+}
+
+func (m *mockEncoder) EncodeQuery(ctx context.Context, query string) ([]float32, error) {
 	// Return a dummy embedding for testing
 	return []float32{0.1, 0.2, 0.3, 0.4, 0.5}, nil
 }
 
-func (m *mockEncoder) EncodeMetricMetadata(metadata prometheus.MetricMetadata) ([]float32, error) {
+func (m *mockEncoder) EncodeMetricMetadata(ctx context.Context, metadata prometheus.MetricMetadata) ([]float32, error) {
 	// Return a dummy embedding for testing
 	return []float32{0.1, 0.2, 0.3, 0.4, 0.5}, nil
 }
+
+func (m *mockEncoder) EncodeBatch(ctx context.Context, texts []string, progress embeddings.ProgressFunc) ([][]float32, error) {
+	vectors := make([][]float32, len(texts))
+	for i := range texts {
+		vectors[i], _ = m.EncodeQuery(ctx, texts[i])
+	}
+	return vectors, nil
+}
+
+func (m *mockEncoder) EncodeMetricMetadataBatch(ctx context.Context, metadata []prometheus.MetricMetadata, progress embeddings.ProgressFunc) ([][]float32, error) {
+	vectors := make([][]float32, len(metadata))
+	for i := range metadata {
+		vectors[i], _ = m.EncodeMetricMetadata(ctx, metadata[i])
+	}
+	return vectors, nil
+}
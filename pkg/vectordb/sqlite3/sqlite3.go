@@ -1,9 +1,10 @@
 package sqlite3
 
 import (
+	"context"
 	"crypto/sha256"
 	"database/sql"
-	"encoding/binary"
+	"errors"
 	"fmt"
 	"math"
 	"os"
@@ -17,6 +18,9 @@ import (
 	"github.com/machadovilaca/prometheus-rag/pkg/embeddings"
 )
 
+// collectionsTable records the model each collection was built with
+const collectionsTable = "prag_collections"
+
 // Config holds the configuration for the SQLite3 client
 type Config struct {
 	DBPath         string
@@ -43,16 +47,9 @@ func New(cfg Config) (*sqlite3DB, error) {
 		return nil, fmt.Errorf("invalid collection name: %w", err)
 	}
 
-	// Ensure the directory for the database file exists
-	dbDir := filepath.Dir(cfg.DBPath)
-	if err := os.MkdirAll(dbDir, 0755); err != nil {
-		return nil, fmt.Errorf("failed to create database directory %s: %w", dbDir, err)
-	}
-
-	log.Info().Msgf("opening sqlite3 db at %s", cfg.DBPath)
-	db, err := sql.Open("sqlite3", cfg.DBPath)
+	db, err := open(cfg.DBPath)
 	if err != nil {
-		return nil, fmt.Errorf("failed to open sqlite3 db: %w", err)
+		return nil, err
 	}
 
 	v := &sqlite3DB{
@@ -62,14 +59,14 @@ func New(cfg Config) (*sqlite3DB, error) {
 		validator:      validator,
 	}
 
-	if err := v.CreateCollection(); err != nil {
+	if err := v.CreateCollection(context.Background()); err != nil {
 		return nil, fmt.Errorf("failed to create collection: %w", err)
 	}
 
 	return v, nil
 }
 
-func (v *sqlite3DB) CreateCollection() error {
+func (v *sqlite3DB) CreateCollection(ctx context.Context) error {
 	// Use secure identifier escaping for table name
 	safeTableName, err := v.validator.SafeIdentifier(v.collectionName)
 	if err != nil {
@@ -82,17 +79,28 @@ func (v *sqlite3DB) CreateCollection() error {
 			id TEXT PRIMARY KEY,
 			name TEXT NOT NULL,
 			help TEXT,
+			description TEXT,
 			type TEXT,
+			unit TEXT,
 			labels TEXT,
+			series TEXT,
+			native_histogram INTEGER DEFAULT 0,
+			type_conflict INTEGER DEFAULT 0,
+			variants TEXT,
+			annotations TEXT,
 			embedding BLOB
 		)
 	`, safeTableName)
 
-	_, err = v.db.Exec(createTableSQL)
+	_, err = v.db.ExecContext(ctx, createTableSQL)
 	if err != nil {
 		return fmt.Errorf("failed to create collection table: %w", err)
 	}
 
+	if err := v.migrateCollection(ctx, safeTableName); err != nil {
+		return fmt.Errorf("failed to migrate collection table: %w", err)
+	}
+
 	// Create index on name for faster lookups
 	safeIndexName, err := v.validator.SafeIdentifier("idx_" + v.collectionName + "_name")
 	if err != nil {
@@ -103,16 +111,166 @@ func (v *sqlite3DB) CreateCollection() error {
 		CREATE INDEX IF NOT EXISTS %s ON %s(name)
 	`, safeIndexName, safeTableName)
 
-	_, err = v.db.Exec(createIndexSQL)
+	_, err = v.db.ExecContext(ctx, createIndexSQL)
 	if err != nil {
 		return fmt.Errorf("failed to create name index: %w", err)
 	}
 
+	if err := createCollectionsTable(ctx, v.db); err != nil {
+		return err
+	}
+
 	log.Info().Msgf("created collection table: %s", v.collectionName)
 	return nil
 }
 
-func (v *sqlite3DB) DeleteCollection() error {
+// GetCollectionModel returns the model the collection was built with, or nil if it was not recorded
+func (v *sqlite3DB) GetCollectionModel(ctx context.Context) (*embeddings.ModelInfo, error) {
+	row := v.db.QueryRowContext(ctx, fmt.Sprintf(`
+		SELECT model, pooling, dimension, max_sequence_length, document, endpoint FROM %s WHERE collection = ?
+	`, collectionsTable), v.collectionName)
+
+	var info embeddings.ModelInfo
+	err := row.Scan(&info.Name, &info.Pooling, &info.Dimension, &info.MaxSequenceLength, &info.Document, &info.Endpoint)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read collection model: %w", err)
+	}
+
+	return &info, nil
+}
+
+// SetCollectionModel records the model the collection is built with
+func (v *sqlite3DB) SetCollectionModel(ctx context.Context, info embeddings.ModelInfo) error {
+	_, err := v.db.ExecContext(ctx, fmt.Sprintf(`
+		INSERT OR REPLACE INTO %s (collection, model, pooling, dimension, max_sequence_length, document, endpoint)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`, collectionsTable), v.collectionName, info.Name, info.Pooling, info.Dimension, info.MaxSequenceLength, info.Document, info.Endpoint)
+	if err != nil {
+		return fmt.Errorf("failed to record collection model: %w", err)
+	}
+
+	return nil
+}
+
+// open opens the sqlite3 database file, creating its directory if needed
+func open(dbPath string) (*sql.DB, error) {
+	dbDir := filepath.Dir(dbPath)
+	if err := os.MkdirAll(dbDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create database directory %s: %w", dbDir, err)
+	}
+
+	log.Info().Msgf("opening sqlite3 db at %s", dbPath)
+	db, err := sql.Open("sqlite3", dbPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open sqlite3 db: %w", err)
+	}
+
+	return db, nil
+}
+
+func createCollectionsTable(ctx context.Context, db *sql.DB) error {
+	_, err := db.ExecContext(ctx, fmt.Sprintf(`
+		CREATE TABLE IF NOT EXISTS %s (
+			collection TEXT PRIMARY KEY,
+			model TEXT NOT NULL,
+			pooling TEXT NOT NULL,
+			dimension INTEGER NOT NULL,
+			max_sequence_length INTEGER NOT NULL DEFAULT 0,
+			document TEXT NOT NULL DEFAULT '',
+			endpoint TEXT NOT NULL DEFAULT ''
+		)
+	`, collectionsTable))
+	if err != nil {
+		return fmt.Errorf("failed to create collections table: %w", err)
+	}
+
+	// Tables created by older versions lack the columns added to the model identity
+	for _, column := range []string{
+		"max_sequence_length INTEGER NOT NULL DEFAULT 0",
+		"document TEXT NOT NULL DEFAULT ''",
+		"endpoint TEXT NOT NULL DEFAULT ''",
+	} {
+		name, _, _ := strings.Cut(column, " ")
+
+		var count int
+		err := db.QueryRowContext(ctx, fmt.Sprintf(`SELECT COUNT(*) FROM pragma_table_info('%s') WHERE name = ?`, collectionsTable), name).Scan(&count)
+		if err != nil {
+			return fmt.Errorf("failed to read collections table info: %w", err)
+		}
+		if count > 0 {
+			continue
+		}
+
+		if _, err := db.ExecContext(ctx, fmt.Sprintf(`ALTER TABLE %s ADD COLUMN %s`, collectionsTable, column)); err != nil {
+			return fmt.Errorf("failed to add column %s to collections table: %w", name, err)
+		}
+		log.Info().Msgf("added column %s to collections table", name)
+	}
+
+	return nil
+}
+
+// collectionColumns lists the columns added after the initial table layout,
+// with their definitions, so existing collections can be upgraded in place
+var collectionColumns = []struct {
+	name       string
+	definition string
+}{
+	{"unit", "TEXT"},
+	{"series", "TEXT"},
+	{"native_histogram", "INTEGER DEFAULT 0"},
+	{"type_conflict", "INTEGER DEFAULT 0"},
+	{"variants", "TEXT"},
+	{"description", "TEXT"},
+	{"annotations", "TEXT"},
+}
+
+// migrateCollection adds any missing columns to a collection table created by an older version
+func (v *sqlite3DB) migrateCollection(ctx context.Context, safeTableName string) error {
+	rows, err := v.db.QueryContext(ctx, fmt.Sprintf(`PRAGMA table_info(%s)`, safeTableName))
+	if err != nil {
+		return fmt.Errorf("failed to read table info: %w", err)
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	existing := make(map[string]bool)
+	for rows.Next() {
+		var (
+			cid, notNull, pk int
+			name, colType    string
+			defaultValue     sql.NullString
+		)
+		if err := rows.Scan(&cid, &name, &colType, &notNull, &defaultValue, &pk); err != nil {
+			return fmt.Errorf("failed to scan table info: %w", err)
+		}
+		existing[name] = true
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error iterating table info: %w", err)
+	}
+	_ = rows.Close()
+
+	for _, column := range collectionColumns {
+		if existing[column.name] {
+			continue
+		}
+
+		alterSQL := fmt.Sprintf(`ALTER TABLE %s ADD COLUMN %s %s`, safeTableName, column.name, column.definition)
+		if _, err := v.db.ExecContext(ctx, alterSQL); err != nil {
+			return fmt.Errorf("failed to add column %s: %w", column.name, err)
+		}
+		log.Info().Msgf("added column %s to collection table: %s", column.name, v.collectionName)
+	}
+
+	return nil
+}
+
+func (v *sqlite3DB) DeleteCollection(ctx context.Context) error {
 	// Use secure identifier escaping for table name
 	safeTableName, err := v.validator.SafeIdentifier(v.collectionName)
 	if err != nil {
@@ -121,15 +279,36 @@ func (v *sqlite3DB) DeleteCollection() error {
 
 	dropTableSQL := fmt.Sprintf(`DROP TABLE IF EXISTS %s`, safeTableName)
 
-	_, err = v.db.Exec(dropTableSQL)
+	_, err = v.db.ExecContext(ctx, dropTableSQL)
 	if err != nil {
 		return fmt.Errorf("failed to delete collection table: %w", err)
 	}
 
+	_, err = v.db.ExecContext(ctx, fmt.Sprintf(`DELETE FROM %s WHERE collection = ?`, collectionsTable), v.collectionName)
+	if err != nil {
+		return fmt.Errorf("failed to delete collection model: %w", err)
+	}
+
 	log.Info().Msgf("deleted collection table: %s", v.collectionName)
 	return nil
 }
 
+// CountMetrics returns the number of metric metadata entries in the collection
+func (v *sqlite3DB) CountMetrics(ctx context.Context) (uint64, error) {
+	safeTableName, err := v.validator.SafeIdentifier(v.collectionName)
+	if err != nil {
+		return 0, fmt.Errorf("failed to validate collection name: %w", err)
+	}
+
+	var count uint64
+	row := v.db.QueryRowContext(ctx, fmt.Sprintf(`SELECT COUNT(*) FROM %s`, safeTableName))
+	if err := row.Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count metrics: %w", err)
+	}
+
+	return count, nil
+}
+
 func (v *sqlite3DB) Close() error {
 	return v.db.Close()
 }
@@ -152,27 +331,6 @@ func (v *sqlite3DB) splitLabels(labels string) []string {
 	return strings.Split(labels, ", ")
 }
 
-func (v *sqlite3DB) encodeEmbedding(embedding []float32) ([]byte, error) {
-	buf := make([]byte, len(embedding)*4)
-	for i, val := range embedding {
-		binary.LittleEndian.PutUint32(buf[i*4:(i+1)*4], math.Float32bits(val))
-	}
-	return buf, nil
-}
-
-func (v *sqlite3DB) decodeEmbedding(data []byte) ([]float32, error) {
-	if len(data)%4 != 0 {
-		return nil, fmt.Errorf("invalid embedding data length")
-	}
-
-	embedding := make([]float32, len(data)/4)
-	for i := 0; i < len(embedding); i++ {
-		bits := binary.LittleEndian.Uint32(data[i*4 : (i+1)*4])
-		embedding[i] = math.Float32frombits(bits)
-	}
-	return embedding, nil
-}
-
 func (v *sqlite3DB) cosineSimilarity(a, b []float32) float64 {
 	if len(a) != len(b) {
 		return 0.0
@@ -1,6 +1,7 @@
 package sqlite3_test
 
 import (
+	"context"
 	"os"
 	"path/filepath"
 	"strings"
@@ -38,7 +39,7 @@ var _ = Describe("SQLite3 VectorDB", func() {
 		Expect(err).NotTo(HaveOccurred())
 
 		// Create collection
-		err = dbClient.CreateCollection()
+		err = dbClient.CreateCollection(context.Background())
 		Expect(err).NotTo(HaveOccurred())
 	})
 
@@ -59,16 +60,16 @@ var _ = Describe("SQLite3 VectorDB", func() {
 		It("should create a collection successfully", func() {
 			// Collection was already created in BeforeEach
 			// Try to create it again to test idempotency
-			err := dbClient.CreateCollection()
+			err := dbClient.CreateCollection(context.Background())
 			Expect(err).NotTo(HaveOccurred())
 		})
 
 		It("should delete a collection successfully", func() {
-			err := dbClient.DeleteCollection()
+			err := dbClient.DeleteCollection(context.Background())
 			Expect(err).NotTo(HaveOccurred())
 
 			// Recreate for cleanup
-			err = dbClient.CreateCollection()
+			err = dbClient.CreateCollection(context.Background())
 			Expect(err).NotTo(HaveOccurred())
 		})
 	})
@@ -82,7 +83,7 @@ var _ = Describe("SQLite3 VectorDB", func() {
 				Labels: []string{"label1", "label2"},
 			}
 
-			err := dbClient.AddMetricMetadata(metadata)
+			err := dbClient.AddMetricMetadata(context.Background(), metadata)
 			Expect(err).NotTo(HaveOccurred())
 		})
 
@@ -92,7 +93,7 @@ var _ = Describe("SQLite3 VectorDB", func() {
 				Help: "Test help",
 			}
 
-			err := dbClient.AddMetricMetadata(metadata)
+			err := dbClient.AddMetricMetadata(context.Background(), metadata)
 			Expect(err).To(HaveOccurred())
 			Expect(err.Error()).To(ContainSubstring("name is required"))
 		})
@@ -104,15 +105,15 @@ var _ = Describe("SQLite3 VectorDB", func() {
 				Type: "counter",
 			}
 
-			err := dbClient.AddMetricMetadata(metadata)
+			err := dbClient.AddMetricMetadata(context.Background(), metadata)
 			Expect(err).NotTo(HaveOccurred())
 
 			metadata.Help = "Updated help"
 
-			err = dbClient.AddMetricMetadata(metadata)
+			err = dbClient.AddMetricMetadata(context.Background(), metadata)
 			Expect(err).NotTo(HaveOccurred())
 
-			results, err := dbClient.SearchMetrics("test_metric", 10)
+			results, err := dbClient.SearchMetrics(context.Background(), "test_metric", 10)
 			Expect(err).NotTo(HaveOccurred())
 			Expect(results).To(HaveLen(1))
 			Expect(results[0].Help).To(Equal("Updated help"))
@@ -132,22 +133,26 @@ var _ = Describe("SQLite3 VectorDB", func() {
 				},
 			}
 
-			err := dbClient.BatchAddMetricMetadata(metadata)
+			err := dbClient.BatchAddMetricMetadata(context.Background(), metadata)
 			Expect(err).NotTo(HaveOccurred())
 
-			results, err := dbClient.SearchMetrics("test", 10)
+			results, err := dbClient.SearchMetrics(context.Background(), "test", 10)
 			Expect(err).NotTo(HaveOccurred())
 			Expect(results).To(HaveLen(2))
 
 			Expect(results).To(ContainElement(HaveField("Name", "test_metric_1")))
 			Expect(results).To(ContainElement(HaveField("Name", "test_metric_2")))
+
+			count, err := dbClient.CountMetrics(context.Background())
+			Expect(err).NotTo(HaveOccurred())
+			Expect(count).To(BeEquivalentTo(2))
 		})
 
 		It("should skip batch add of metric metadata when there are none", func() {
-			err := dbClient.BatchAddMetricMetadata([]*prometheus.MetricMetadata{})
+			err := dbClient.BatchAddMetricMetadata(context.Background(), []*prometheus.MetricMetadata{})
 			Expect(err).NotTo(HaveOccurred())
 
-			results, err := dbClient.SearchMetrics("test", 10)
+			results, err := dbClient.SearchMetrics(context.Background(), "test", 10)
 			Expect(err).NotTo(HaveOccurred())
 			Expect(results).To(HaveLen(0))
 		})
@@ -166,7 +171,7 @@ var _ = Describe("SQLite3 VectorDB", func() {
 				},
 			}
 
-			err := dbClient.BatchAddMetricMetadata(metadata)
+			err := dbClient.BatchAddMetricMetadata(context.Background(), metadata)
 			Expect(err).To(HaveOccurred())
 			Expect(err.Error()).To(ContainSubstring("name is required"))
 		})
@@ -196,7 +201,7 @@ var _ = Describe("SQLite3 VectorDB", func() {
 				},
 			}
 
-			err := dbClient.BatchAddMetricMetadata(metadata)
+			err := dbClient.BatchAddMetricMetadata(context.Background(), metadata)
 			Expect(err).NotTo(HaveOccurred())
 
 			// Give some time for indexing
@@ -204,7 +209,7 @@ var _ = Describe("SQLite3 VectorDB", func() {
 		})
 
 		It("should return best matching metrics first", func() {
-			results, err := dbClient.SearchMetrics("http requests", 10)
+			results, err := dbClient.SearchMetrics(context.Background(), "http requests", 10)
 			Expect(err).NotTo(HaveOccurred())
 			Expect(results).To(HaveLen(3))
 
@@ -221,7 +226,7 @@ var _ = Describe("SQLite3 VectorDB", func() {
 		})
 
 		It("should return best matching metrics for memory query", func() {
-			results, err := dbClient.SearchMetrics("memory", 10)
+			results, err := dbClient.SearchMetrics(context.Background(), "memory", 10)
 			Expect(err).NotTo(HaveOccurred())
 			Expect(results).To(HaveLen(3))
 
@@ -238,24 +243,24 @@ var _ = Describe("SQLite3 VectorDB", func() {
 
 		It("should return empty results when no matches found", func() {
 			// Clear the collection first
-			err := dbClient.DeleteCollection()
+			err := dbClient.DeleteCollection(context.Background())
 			Expect(err).NotTo(HaveOccurred())
-			err = dbClient.CreateCollection()
+			err = dbClient.CreateCollection(context.Background())
 			Expect(err).NotTo(HaveOccurred())
 
-			results, err := dbClient.SearchMetrics("does not exist", 10)
+			results, err := dbClient.SearchMetrics(context.Background(), "does not exist", 10)
 			Expect(err).NotTo(HaveOccurred())
 			Expect(results).To(BeEmpty())
 		})
 
 		It("should respect the limit parameter", func() {
-			results, err := dbClient.SearchMetrics("usage", 1)
+			results, err := dbClient.SearchMetrics(context.Background(), "usage", 1)
 			Expect(err).NotTo(HaveOccurred())
 			Expect(results).To(HaveLen(1))
 		})
 
 		It("should return all results when limit is larger than available", func() {
-			results, err := dbClient.SearchMetrics("usage", 100)
+			results, err := dbClient.SearchMetrics(context.Background(), "usage", 100)
 			Expect(err).NotTo(HaveOccurred())
 			Expect(len(results)).To(BeNumerically("<=", 3))
 		})
@@ -276,14 +281,14 @@ var _ = Describe("SQLite3 VectorDB", func() {
 			}
 
 			// Add metrics concurrently (simulated)
-			err1 := dbClient.AddMetricMetadata(metadata1)
-			err2 := dbClient.AddMetricMetadata(metadata2)
+			err1 := dbClient.AddMetricMetadata(context.Background(), metadata1)
+			err2 := dbClient.AddMetricMetadata(context.Background(), metadata2)
 
 			Expect(err1).NotTo(HaveOccurred())
 			Expect(err2).NotTo(HaveOccurred())
 
 			// Verify both were added
-			results, err := dbClient.SearchMetrics("concurrent", 10)
+			results, err := dbClient.SearchMetrics(context.Background(), "concurrent", 10)
 			Expect(err).NotTo(HaveOccurred())
 			Expect(results).To(HaveLen(2))
 		})
@@ -296,10 +301,10 @@ var _ = Describe("SQLite3 VectorDB", func() {
 				Labels: []string{},
 			}
 
-			err := dbClient.AddMetricMetadata(metadata)
+			err := dbClient.AddMetricMetadata(context.Background(), metadata)
 			Expect(err).NotTo(HaveOccurred())
 
-			results, err := dbClient.SearchMetrics("no labels", 10)
+			results, err := dbClient.SearchMetrics(context.Background(), "no labels", 10)
 			Expect(err).NotTo(HaveOccurred())
 			Expect(results).To(HaveLen(1))
 			Expect(results[0].Labels).To(BeEmpty())
@@ -313,10 +318,10 @@ var _ = Describe("SQLite3 VectorDB", func() {
 				Labels: []string{"label-with-dash", "label.with.dots"},
 			}
 
-			err := dbClient.AddMetricMetadata(metadata)
+			err := dbClient.AddMetricMetadata(context.Background(), metadata)
 			Expect(err).NotTo(HaveOccurred())
 
-			results, err := dbClient.SearchMetrics("special", 10)
+			results, err := dbClient.SearchMetrics(context.Background(), "special", 10)
 			Expect(err).NotTo(HaveOccurred())
 			Expect(results).To(HaveLen(1))
 			Expect(results[0].Name).To(Equal("metric_with_special-chars.test"))
@@ -325,14 +330,14 @@ var _ = Describe("SQLite3 VectorDB", func() {
 
 	Context("Error Handling", func() {
 		It("should handle search on empty collection", func() {
-			results, err := dbClient.SearchMetrics("anything", 10)
+			results, err := dbClient.SearchMetrics(context.Background(), "anything", 10)
 			Expect(err).NotTo(HaveOccurred())
 			Expect(results).To(BeEmpty())
 		})
 
 		It("should handle very long query strings", func() {
 			longQuery := strings.Repeat("very long query string ", 100)
-			_, err := dbClient.SearchMetrics(longQuery, 10)
+			_, err := dbClient.SearchMetrics(context.Background(), longQuery, 10)
 			Expect(err).NotTo(HaveOccurred())
 			// Should not crash, results can be empty
 		})
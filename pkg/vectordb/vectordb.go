@@ -1,35 +1,66 @@
 package vectordb
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/rs/zerolog/log"
 
 	"github.com/machadovilaca/prometheus-rag/pkg/embeddings"
+	"github.com/machadovilaca/prometheus-rag/pkg/examples"
 	"github.com/machadovilaca/prometheus-rag/pkg/prometheus"
 	"github.com/machadovilaca/prometheus-rag/pkg/vectordb/qdrantdb"
 	"github.com/machadovilaca/prometheus-rag/pkg/vectordb/sqlite3"
 )
 
-// Client interface for interacting with the VectorDB
+// Client interface for interacting with the VectorDB. Operations stop when
+// the given context is cancelled or its deadline expires.
 type Client interface {
 	// CreateCollection creates the collection in the vector database
-	CreateCollection() error
+	CreateCollection(ctx context.Context) error
 
 	// DeleteCollection deletes the collection from the vector database
-	DeleteCollection() error
+	DeleteCollection(ctx context.Context) error
 
 	// AddMetricMetadata adds a metric metadata entry to the vector database
-	AddMetricMetadata(metadata *prometheus.MetricMetadata) error
+	AddMetricMetadata(ctx context.Context, metadata *prometheus.MetricMetadata) error
 
 	// BatchAddMetricMetadata adds a batch of metric metadata entries to the vector database
-	BatchAddMetricMetadata(metadata []*prometheus.MetricMetadata) error
+	BatchAddMetricMetadata(ctx context.Context, metadata []*prometheus.MetricMetadata) error
 
 	// SearchMetrics searches for relevant metrics based on a natural language query
 	// Returns a list of metric metadata entries sorted by relevance
-	SearchMetrics(query string, limit uint64) ([]*prometheus.MetricMetadata, error)
+	SearchMetrics(ctx context.Context, query string, limit uint64) ([]*prometheus.MetricMetadata, error)
+
+	// CountMetrics returns the number of metric metadata entries in the collection
+	CountMetrics(ctx context.Context) (uint64, error)
+
+	// Close closes the connection to the vector database
+	Close() error
+}
+
+// ExampleStore interface for the collection of few-shot examples
+type ExampleStore interface {
+	// CreateCollection creates the collection in the vector database
+	CreateCollection(ctx context.Context) error
+
+	// DeleteCollection deletes the collection from the vector database
+	DeleteCollection(ctx context.Context) error
+
+	// AddExamples adds examples to the vector database, replacing the ones with the same ID
+	AddExamples(ctx context.Context, examples []*examples.Example) error
+
+	// SearchExamples returns the examples whose questions are most similar to the given one
+	SearchExamples(ctx context.Context, question string, limit uint64) ([]*examples.Example, error)
+
+	// ListExamples returns all examples, ordered by question
+	ListExamples(ctx context.Context) ([]*examples.Example, error)
+
+	// DeleteExample deletes the example with the given ID, returning false if there is none
+	DeleteExample(ctx context.Context, id string) (bool, error)
 
 	// Close closes the connection to the vector database
 	Close() error
@@ -45,25 +76,103 @@ type Config struct {
 	QdrantPort int
 
 	CollectionName         string
+	ExamplesCollectionName string
 	EncoderOutputDirectory string
+	EncoderWorkers         int
+	EncoderCachePath       string
+
+	EncoderModelName         string
+	EncoderPooling           string
+	EncoderMaxSequenceLength int
+	EncoderDocument          string
+
+	// Remote encoder settings, used when EncoderProvider is embeddings.ProviderOpenAI
+	EncoderProvider   string
+	EncoderBaseURL    string
+	EncoderAPIKey     string
+	EncoderBatchSize  int
+	EncoderTimeout    time.Duration
+	EncoderMaxRetries int
+
+	// OnModelMismatch is the action taken when the collection was built with a
+	// different model than the configured one: ModelMismatchFail or ModelMismatchReindex
+	OnModelMismatch string
+}
+
+// Actions taken when the collection was built with a different model
+const (
+	// ModelMismatchFail refuses to use the collection
+	ModelMismatchFail = "fail"
+	// ModelMismatchReindex drops the collection so it is rebuilt with the configured model
+	ModelMismatchReindex = "reindex"
+)
+
+// ModelRecorder is implemented by clients that record the embedding model a collection was built with
+type ModelRecorder interface {
+	// GetCollectionModel returns the model the collection was built with, or nil if it was not recorded
+	GetCollectionModel(ctx context.Context) (*embeddings.ModelInfo, error)
+
+	// SetCollectionModel records the model the collection is built with
+	SetCollectionModel(ctx context.Context, info embeddings.ModelInfo) error
 }
 
 // ErrUnsupportedProvider is returned when an unsupported provider is specified
 var ErrUnsupportedProvider = errors.New("unsupported provider")
 
+// ErrModelMismatch is returned when the collection was built with a different model than the configured one
+var ErrModelMismatch = errors.New("collection was built with a different embedding model")
+
+// New creates a new vector database client with its own encoder
 func New(cfg Config) (Client, error) {
-	log.Info().Msgf("creating encoder with output directory %s", cfg.EncoderOutputDirectory)
+	encoder, err := NewEncoder(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewWithEncoder(cfg, encoder)
+}
+
+// NewEncoder creates the encoder configured for the vector database, so it can
+// be shared by the metrics and examples collections
+func NewEncoder(cfg Config) (embeddings.Encoder, error) {
+	if strings.EqualFold(cfg.EncoderProvider, embeddings.ProviderOpenAI) {
+		log.Info().Msgf("creating remote encoder with base URL %s", cfg.EncoderBaseURL)
+	} else {
+		log.Info().Msgf("creating encoder with output directory %s", cfg.EncoderOutputDirectory)
+	}
 	encoder, err := embeddings.NewEncoder(embeddings.Config{
-		ModelsDir: cfg.EncoderOutputDirectory,
+		Provider:          cfg.EncoderProvider,
+		BaseURL:           cfg.EncoderBaseURL,
+		APIKey:            cfg.EncoderAPIKey,
+		BatchSize:         cfg.EncoderBatchSize,
+		Timeout:           cfg.EncoderTimeout,
+		MaxRetries:        cfg.EncoderMaxRetries,
+		ModelsDir:         cfg.EncoderOutputDirectory,
+		ModelName:         cfg.EncoderModelName,
+		Pooling:           cfg.EncoderPooling,
+		MaxSequenceLength: cfg.EncoderMaxSequenceLength,
+		Document:          cfg.EncoderDocument,
+		Workers:           cfg.EncoderWorkers,
+		CachePath:         cfg.EncoderCachePath,
 	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to create encoder: %w", err)
 	}
 
+	return encoder, nil
+}
+
+// NewWithEncoder creates a new vector database client using the given encoder
+func NewWithEncoder(cfg Config, encoder embeddings.Encoder) (Client, error) {
+	var (
+		client Client
+		err    error
+	)
+
 	switch strings.ToLower(cfg.Provider) {
 	case "qdrant":
 		log.Info().Msg("starting Qdrant client")
-		return qdrantdb.New(qdrantdb.Config{
+		client, err = qdrantdb.New(qdrantdb.Config{
 			QdrantHost:     cfg.QdrantHost,
 			QdrantPort:     cfg.QdrantPort,
 			CollectionName: cfg.CollectionName,
@@ -71,7 +180,7 @@ func New(cfg Config) (Client, error) {
 		})
 	case "sqlite3":
 		log.Info().Msg("starting SQLite3 client")
-		return sqlite3.New(sqlite3.Config{
+		client, err = sqlite3.New(sqlite3.Config{
 			DBPath:         cfg.Sqlite3DBPath,
 			CollectionName: cfg.CollectionName,
 			Encoder:        encoder,
@@ -79,4 +188,187 @@ func New(cfg Config) (Client, error) {
 	default:
 		return nil, fmt.Errorf("%w: %s", ErrUnsupportedProvider, cfg.Provider)
 	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := ensureCollectionModel(context.Background(), client, encoder.Model(), cfg.OnModelMismatch); err != nil {
+		_ = client.Close()
+		return nil, err
+	}
+
+	return &instrumentedClient{Client: client, backend: strings.ToLower(cfg.Provider)}, nil
+}
+
+// NewExampleStore creates a client for the few-shot examples collection using
+// the given encoder, which should be the one used for the metrics collection
+func NewExampleStore(cfg Config, encoder embeddings.Encoder) (ExampleStore, error) {
+	if cfg.ExamplesCollectionName == "" {
+		return nil, errors.New("examples collection name is required")
+	}
+
+	var (
+		store ExampleStore
+		err   error
+	)
+
+	switch strings.ToLower(cfg.Provider) {
+	case "qdrant":
+		store, err = qdrantdb.NewExamples(qdrantdb.Config{
+			QdrantHost:     cfg.QdrantHost,
+			QdrantPort:     cfg.QdrantPort,
+			CollectionName: cfg.ExamplesCollectionName,
+			Encoder:        encoder,
+		})
+	case "sqlite3":
+		store, err = sqlite3.NewExamples(sqlite3.Config{
+			DBPath:         cfg.Sqlite3DBPath,
+			CollectionName: cfg.ExamplesCollectionName,
+			Encoder:        encoder,
+		})
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedProvider, cfg.Provider)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := ensureExamplesModel(context.Background(), store, encoder.Model()); err != nil {
+		_ = store.Close()
+		return nil, err
+	}
+
+	return &instrumentedExampleStore{ExampleStore: store, backend: strings.ToLower(cfg.Provider)}, nil
+}
+
+// ensureCollectionModel checks that the collection was built with the configured
+// model, recording it for new collections
+func ensureCollectionModel(ctx context.Context, client Client, model embeddings.ModelInfo, onMismatch string) error {
+	recorder, ok := client.(ModelRecorder)
+	if !ok {
+		return nil
+	}
+
+	stored, err := recorder.GetCollectionModel(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get collection model: %w", err)
+	}
+
+	legacy := completeLegacyModel(stored, model)
+	if stored == nil {
+		count, err := client.CountMetrics(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to count metrics: %w", err)
+		}
+		if count > 0 {
+			log.Warn().Msgf("collection holds %d metrics without a recorded embedding model, assuming %s", count, model)
+		}
+	}
+
+	if stored != nil && !stored.Matches(model) {
+		if !strings.EqualFold(onMismatch, ModelMismatchReindex) {
+			return fmt.Errorf("%w: collection uses %s, configured %s", ErrModelMismatch, stored, model)
+		}
+
+		log.Warn().Msgf("collection was built with %s, reindexing with %s", stored, model)
+		if err := client.DeleteCollection(ctx); err != nil {
+			return fmt.Errorf("failed to delete collection for reindex: %w", err)
+		}
+		if err := client.CreateCollection(ctx); err != nil {
+			return fmt.Errorf("failed to recreate collection for reindex: %w", err)
+		}
+	}
+
+	if stored == nil || legacy || !stored.Matches(model) {
+		if err := recorder.SetCollectionModel(ctx, model); err != nil {
+			return fmt.Errorf("failed to set collection model: %w", err)
+		}
+	}
+
+	log.Info().Msgf("collection uses embedding model %s", model)
+	return nil
+}
+
+// ensureExamplesModel checks that the examples collection was built with the
+// configured model. Unlike metrics, examples cannot be recovered from
+// Prometheus, so on a mismatch they are re-embedded instead of dropped.
+func ensureExamplesModel(ctx context.Context, store ExampleStore, model embeddings.ModelInfo) error {
+	recorder, ok := store.(ModelRecorder)
+	if !ok {
+		return nil
+	}
+
+	stored, err := recorder.GetCollectionModel(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get examples collection model: %w", err)
+	}
+
+	legacy := completeLegacyModel(stored, model)
+	if stored != nil && !legacy && stored.Matches(model) {
+		return nil
+	}
+
+	if stored == nil {
+		entries, err := store.ListExamples(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to list examples: %w", err)
+		}
+		if len(entries) > 0 {
+			log.Warn().Msgf("examples collection holds %d examples without a recorded embedding model, assuming %s",
+				len(entries), model)
+		}
+	}
+
+	if stored != nil && !stored.Matches(model) {
+		entries, err := store.ListExamples(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to list examples for reindex: %w", err)
+		}
+
+		log.Warn().Msgf("examples collection was built with %s, re-embedding %d examples with %s",
+			stored, len(entries), model)
+		if err := store.DeleteCollection(ctx); err != nil {
+			return fmt.Errorf("failed to delete examples collection for reindex: %w", err)
+		}
+		if err := store.CreateCollection(ctx); err != nil {
+			return fmt.Errorf("failed to recreate examples collection for reindex: %w", err)
+		}
+		if err := store.AddExamples(ctx, entries); err != nil {
+			return fmt.Errorf("failed to re-embed examples: %w", err)
+		}
+	}
+
+	if err := recorder.SetCollectionModel(ctx, model); err != nil {
+		return fmt.Errorf("failed to set examples collection model: %w", err)
+	}
+
+	return nil
+}
+
+// completeLegacyModel fills in the fields added to the model identity after
+// the stored model was recorded, returning whether it did. They cannot be
+// recovered, so the configured ones are assumed.
+func completeLegacyModel(stored *embeddings.ModelInfo, model embeddings.ModelInfo) bool {
+	if stored == nil {
+		return false
+	}
+
+	legacy := false
+	if stored.Document == "" {
+		log.Warn().Msgf("collection model %s was recorded without its max sequence length and document builder, assuming %d and %s",
+			stored.Name, model.MaxSequenceLength, model.Document)
+		stored.MaxSequenceLength = model.MaxSequenceLength
+		stored.Document = model.Document
+		legacy = true
+	}
+
+	// Local models have no endpoint and a different pooling, so a stored
+	// model with the pooling of a remote one was recorded without its endpoint
+	if stored.Endpoint == "" && model.Endpoint != "" && stored.Pooling == model.Pooling {
+		log.Warn().Msgf("collection model %s was recorded without its endpoint, assuming %s", stored.Name, model.Endpoint)
+		stored.Endpoint = model.Endpoint
+		legacy = true
+	}
+
+	return legacy
 }
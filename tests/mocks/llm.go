@@ -0,0 +1,49 @@
+package mocks
+
+import (
+	"context"
+
+	"github.com/machadovilaca/prometheus-rag/pkg/llm"
+	"github.com/machadovilaca/prometheus-rag/pkg/prometheus"
+)
+
+type LLMMock struct {
+	RunFunc            func(ctx context.Context, query string) (string, error)
+	GenerateFunc       func(ctx context.Context, query string) (*llm.Result, error)
+	DescribeMetricFunc func(ctx context.Context, metric *prometheus.MetricMetadata) (string, error)
+	PingFunc           func(ctx context.Context) error
+}
+
+func NewLLMMock() *LLMMock {
+	return &LLMMock{}
+}
+
+func (l *LLMMock) Run(ctx context.Context, query string) (string, error) {
+	if l.RunFunc != nil {
+		return l.RunFunc(ctx, query)
+	}
+	return "", nil
+}
+
+func (l *LLMMock) Generate(ctx context.Context, query string) (*llm.Result, error) {
+	if l.GenerateFunc != nil {
+		return l.GenerateFunc(ctx, query)
+	}
+	return &llm.Result{}, nil
+}
+
+func (l *LLMMock) DescribeMetric(ctx context.Context, metric *prometheus.MetricMetadata) (string, error) {
+	if l.DescribeMetricFunc != nil {
+		return l.DescribeMetricFunc(ctx, metric)
+	}
+	return "", nil
+}
+
+func (l *LLMMock) Ping(ctx context.Context) error {
+	if l.PingFunc != nil {
+		return l.PingFunc(ctx)
+	}
+	return nil
+}
+
+var _ llm.Client = &LLMMock{}
@@ -1,16 +1,19 @@
 package mocks
 
 import (
+	"context"
+
 	"github.com/machadovilaca/prometheus-rag/pkg/prometheus"
 	"github.com/machadovilaca/prometheus-rag/pkg/vectordb"
 )
 
 type VectorDBMock struct {
-	AddMetricMetadataFunc      func(metadata *prometheus.MetricMetadata) error
-	BatchAddMetricMetadataFunc func(metadata []*prometheus.MetricMetadata) error
-	CreateCollectionFunc       func() error
-	DeleteCollectionFunc       func() error
-	SearchMetricsFunc          func(query string, limit uint64) ([]*prometheus.MetricMetadata, error)
+	AddMetricMetadataFunc      func(ctx context.Context, metadata *prometheus.MetricMetadata) error
+	BatchAddMetricMetadataFunc func(ctx context.Context, metadata []*prometheus.MetricMetadata) error
+	CreateCollectionFunc       func(ctx context.Context) error
+	DeleteCollectionFunc       func(ctx context.Context) error
+	SearchMetricsFunc          func(ctx context.Context, query string, limit uint64) ([]*prometheus.MetricMetadata, error)
+	CountMetricsFunc           func(ctx context.Context) (uint64, error)
 	CloseFunc                  func() error
 }
 
@@ -18,41 +21,48 @@ func NewVectorDBMock() *VectorDBMock {
 	return &VectorDBMock{}
 }
 
-func (v *VectorDBMock) AddMetricMetadata(metadata *prometheus.MetricMetadata) error {
+func (v *VectorDBMock) AddMetricMetadata(ctx context.Context, metadata *prometheus.MetricMetadata) error {
 	if v.AddMetricMetadataFunc != nil {
-		return v.AddMetricMetadataFunc(metadata)
+		return v.AddMetricMetadataFunc(ctx, metadata)
 	}
 	return nil
 }
 
-func (v *VectorDBMock) BatchAddMetricMetadata(metadata []*prometheus.MetricMetadata) error {
+func (v *VectorDBMock) BatchAddMetricMetadata(ctx context.Context, metadata []*prometheus.MetricMetadata) error {
 	if v.BatchAddMetricMetadataFunc != nil {
-		return v.BatchAddMetricMetadataFunc(metadata)
+		return v.BatchAddMetricMetadataFunc(ctx, metadata)
 	}
 	return nil
 }
 
-func (v *VectorDBMock) CreateCollection() error {
+func (v *VectorDBMock) CreateCollection(ctx context.Context) error {
 	if v.CreateCollectionFunc != nil {
-		return v.CreateCollectionFunc()
+		return v.CreateCollectionFunc(ctx)
 	}
 	return nil
 }
 
-func (v *VectorDBMock) DeleteCollection() error {
+func (v *VectorDBMock) DeleteCollection(ctx context.Context) error {
 	if v.DeleteCollectionFunc != nil {
-		return v.DeleteCollectionFunc()
+		return v.DeleteCollectionFunc(ctx)
 	}
 	return nil
 }
 
-func (v *VectorDBMock) SearchMetrics(query string, limit uint64) ([]*prometheus.MetricMetadata, error) {
+func (v *VectorDBMock) SearchMetrics(ctx context.Context, query string, limit uint64) ([]*prometheus.MetricMetadata, error) {
 	if v.SearchMetricsFunc != nil {
-		return v.SearchMetricsFunc(query, limit)
+		return v.SearchMetricsFunc(ctx, query, limit)
 	}
 	return nil, nil
 }
 
+func (v *VectorDBMock) CountMetrics(ctx context.Context) (uint64, error) {
+	if v.CountMetricsFunc != nil {
+		return v.CountMetricsFunc(ctx)
+	}
+	return 0, nil
+}
+
 func (v *VectorDBMock) Close() error {
 	if v.CloseFunc != nil {
 		return v.CloseFunc()